@@ -2,9 +2,11 @@ package crud
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/pakkasys/fluidapi-extended/api"
 	apiendpoint "github.com/pakkasys/fluidapi-extended/api/endpoint"
+	"github.com/pakkasys/fluidapi-extended/api/openapi"
 	"github.com/pakkasys/fluidapi-extended/api/repository"
 	"github.com/pakkasys/fluidapi-extended/api/types"
 	extendeddatabase "github.com/pakkasys/fluidapi-extended/database"
@@ -18,17 +20,80 @@ import (
 
 // CRUDCommonParams bundles configuration shared by all CRUD operations.
 type CRUDCommonParams[Entity database.CRUDEntity] struct {
-	URL             string
-	ConnFn          repository.ConnFn
-	EntityFn        func(opts ...extendeddatabase.EntityOption[Entity]) Entity
-	LoggerFactoryFn apiendpoint.LoggerFactoryFn
-	TableName       string
-	MutatorRepo     repository.MutatorRepo[Entity]
-	ReaderRepo      repository.ReaderRepo[Entity]
-	TxManager       repository.TxManager[Entity]
-	ConversionRules map[string]func(any) any
-	CustomRules     map[string]func(any) error
-	SystemId        string
+	URL              string
+	ConnFn           repository.ConnFn
+	EntityFn         func(opts ...extendeddatabase.EntityOption[Entity]) Entity
+	LoggerFactoryFn  apiendpoint.LoggerFactoryFn
+	TableName        string
+	MutatorRepo      repository.MutatorRepo[Entity]
+	ReaderRepo       repository.ReaderRepo[Entity]
+	TxManager        repository.TxManager[Entity]
+	ConversionRules  map[string]func(any) any
+	CustomRules      map[string]func(any) error
+	SystemId         string
+	VersionColumn    string
+	SoftDeleteColumn string
+}
+
+// versionField returns the DB field of the version column or nil if the
+// entity is not versioned.
+func (c CRUDCommonParams[Entity]) versionField() *endpoint.DBField {
+	if c.VersionColumn == "" {
+		return nil
+	}
+	return &endpoint.DBField{
+		Table:  c.TableName,
+		Column: c.VersionColumn,
+	}
+}
+
+// softDeleteField returns the DB field of the soft delete column or nil if the
+// entity is not soft deleted.
+func (c CRUDCommonParams[Entity]) softDeleteField() *endpoint.DBField {
+	if c.SoftDeleteColumn == "" {
+		return nil
+	}
+	return &endpoint.DBField{
+		Table:  c.TableName,
+		Column: c.SoftDeleteColumn,
+	}
+}
+
+// etagFn returns a function that creates the ETag of an entity from its
+// version column or nil if the entity is not versioned.
+func (c CRUDCommonParams[Entity]) etagFn() apiendpoint.ETagFn[Entity] {
+	if c.VersionColumn == "" {
+		return nil
+	}
+	return func(entity Entity) (string, error) {
+		outMap, err := dbEntityToMap(
+			entity,
+			types.APIFields{{APIName: FieldVersion, DBColumn: c.VersionColumn}},
+			map[string]any{},
+		)
+		if err != nil {
+			return "", err
+		}
+		return apiendpoint.FormatETag((*outMap)[FieldVersion]), nil
+	}
+}
+
+// txManagerFor returns the configured transaction manager adapted to the
+// result type of an endpoint, or a DefaultTxManager if none is configured.
+// The DefaultTxManager uses the error checker of a DefaultMutatorRepo.
+func txManagerFor[Result any, Entity database.CRUDEntity](
+	c CRUDCommonParams[Entity],
+) repository.TxManager[Result] {
+	if c.TxManager == nil {
+		txManager := repository.NewDefaultTxManager[Result]()
+		// The transaction errors are checked as the errors of the repository.
+		mutatorRepo, ok := c.MutatorRepo.(*repository.DefaultMutatorRepo[Entity])
+		if ok {
+			txManager.WithErrorChecker(mutatorRepo.ErrorChecker)
+		}
+		return txManager
+	}
+	return repository.NewTxManagerAdapter[Entity, Result](c.TxManager)
 }
 
 // ---------------------------------------------------------------------
@@ -74,17 +139,21 @@ func NewCreateCRUD[CreateInput any, Entity database.CRUDEntity](
 }
 
 func (c *CreateCRUD[CreateInput, Entity]) EndpointHandler() *apiendpoint.EndpointHandler[CreateInput] {
-	opts := []apiendpoint.GenericEndpointOptions{}
+	expectedErrors := apiendpoint.WithConstraintFieldErrors(
+		apiendpoint.NewErrorBuilder(c.SystemId).
+			With(apiendpoint.CreateErrors()).Build(),
+	)
 	if c.ErrorMapping != nil {
-		mappedErrors := mustApplyErrorMapping(
-			apiendpoint.NewErrorBuilder(c.SystemId).
-				With(apiendpoint.CreateErrors()).Build(),
-			c.ErrorMapping,
+		expectedErrors = mustApplyErrorMapping(
+			expectedErrors, c.ErrorMapping,
 		)
-		opts = append(opts, apiendpoint.GenericEndpointOptions{
-			ExpectedErrors: &mappedErrors,
-		})
 	}
+	opts := []apiendpoint.GenericEndpointOptions{{
+		ExpectedErrors: &expectedErrors,
+		MapErrorFn: apiendpoint.ConstraintFieldErrorFn(
+			c.APIFields.MustGetAPIField(c.InputKey).Nested, c.InputKey,
+		),
+	}}
 	return apiendpoint.GenericCreateDefinition(
 		c.URL,
 		api.NewMapInputHandler(
@@ -133,10 +202,148 @@ func (c *CreateCRUD[CreateInput, Entity]) NewInput() any {
 	return &input
 }
 
+// Operation returns the OpenAPI operation of the endpoint.
+func (c *CreateCRUD[CreateInput, Entity]) Operation() openapi.Operation {
+	return openapi.HandlerOperation(
+		c.EndpointHandler(), "Create", c.OutputAPIFields,
+	)
+}
+
+// ---------------------------------------------------------------------
+// Create Many CRUD
+// ---------------------------------------------------------------------
+
+type CreateManyCRUD[Entity database.CRUDEntity] struct {
+	CRUDCommonParams[Entity]
+	APIFields  types.APIFields
+	DBOptionFn func(
+		field string, value any,
+	) extendeddatabase.EntityOption[Entity]
+	InputKey        string
+	OutputAPIFields types.APIFields
+	OutputKey       string
+	BeforeCallback  func(context.Context, []Entity, *apiendpoint.CreateManyInput) error
+	ErrorMapping    map[string]api.ExpectedError
+}
+
+func NewCreateManyCRUD[Entity database.CRUDEntity](
+	common CRUDCommonParams[Entity],
+	apiFields types.APIFields,
+	dbOptionFn func(string, any) extendeddatabase.EntityOption[Entity],
+	inputKey string,
+	outputAPIFields types.APIFields,
+	outputKey string,
+	beforeCallback func(context.Context, []Entity, *apiendpoint.CreateManyInput) error,
+	errorMapping map[string]api.ExpectedError,
+) *CreateManyCRUD[Entity] {
+	return &CreateManyCRUD[Entity]{
+		CRUDCommonParams: common,
+		APIFields:        apiFields,
+		DBOptionFn:       dbOptionFn,
+		InputKey:         inputKey,
+		OutputAPIFields:  outputAPIFields,
+		OutputKey:        outputKey,
+		BeforeCallback:   beforeCallback,
+		ErrorMapping:     errorMapping,
+	}
+}
+
+func (c *CreateManyCRUD[Entity]) EndpointHandler() *apiendpoint.EndpointHandler[apiendpoint.CreateManyInput] {
+	expectedErrors := apiendpoint.WithConstraintFieldErrors(
+		apiendpoint.NewErrorBuilder(c.SystemId).
+			With(apiendpoint.CreateManyErrors()).Build(),
+	)
+	if c.ErrorMapping != nil {
+		expectedErrors = mustApplyErrorMapping(
+			expectedErrors, c.ErrorMapping,
+		)
+	}
+	opts := []apiendpoint.GenericEndpointOptions{{
+		ExpectedErrors: &expectedErrors,
+		MapErrorFn: apiendpoint.ConstraintFieldErrorFn(
+			c.APIFields.MustGetAPIField(c.InputKey).Nested, c.InputKey,
+		),
+	}}
+	return apiendpoint.GenericCreateManyDefinition(
+		bulkURL(c.URL),
+		api.NewMapInputHandler(
+			c.APIFields, c.ConversionRules, c.CustomRules,
+		).WithFieldPaths(),
+		func() apiendpoint.CreateManyInput {
+			return apiendpoint.CreateManyInput{}
+		},
+		c.ConnFn,
+		func(
+			ctx context.Context, input *apiendpoint.CreateManyInput,
+		) ([]Entity, error) {
+			items, err := mapsToColsAndValues(
+				(*input)[c.InputKey],
+				c.APIFields.MustGetAPIField(c.InputKey).Nested,
+			)
+			if err != nil {
+				return nil, err
+			}
+			entities := make([]Entity, len(items))
+			for i, colsAndValues := range items {
+				var dbOpts []extendeddatabase.EntityOption[Entity]
+				for col, value := range colsAndValues {
+					dbOpts = append(dbOpts, c.DBOptionFn(col, value))
+				}
+				entities[i] = c.EntityFn(dbOpts...)
+			}
+			return entities, nil
+		},
+		func(entities []Entity) (any, error) {
+			outMaps := make([]map[string]any, len(entities))
+			for i, entity := range entities {
+				outMap, err := dbEntityToMap(
+					entity,
+					c.OutputAPIFields.MustGetAPIField(c.OutputKey).Nested,
+					map[string]any{},
+				)
+				if err != nil {
+					return nil, err
+				}
+				outMaps[i] = *outMap
+			}
+			return map[string]any{c.OutputKey: outMaps}, nil
+		},
+		c.BeforeCallback,
+		c.LoggerFactoryFn,
+		c.MutatorRepo,
+		txManagerFor[[]Entity](c.CRUDCommonParams),
+		c.SystemId,
+		opts...,
+	)
+}
+
+func (c *CreateManyCRUD[Entity]) NewInput() any {
+	return &apiendpoint.CreateManyInput{}
+}
+
+// Operation returns the OpenAPI operation of the endpoint.
+func (c *CreateManyCRUD[Entity]) Operation() openapi.Operation {
+	operation := openapi.HandlerOperation(
+		c.EndpointHandler(), "Create many", listAPIFields(c.OutputAPIFields),
+	)
+	operation.Input = listAPIFields(operation.Input)
+	return operation
+}
+
 // ---------------------------------------------------------------------
 // Get CRUD
 // ---------------------------------------------------------------------
 
+// Relation declares an entity related to the entities of a CRUD resource.
+// The related entities can be embedded in the get output with the include
+// input under the relation name. The columns of the relation are the DB
+// columns of its APIFields.
+type Relation struct {
+	extendeddatabase.Relation
+	// APIFields are the output fields of the related entity.
+	APIFields types.APIFields
+}
+
 type GetCRUD[Entity database.CRUDEntity, Output any] struct {
 	CRUDCommonParams[Entity]
 	APIFields        types.APIFields
@@ -144,7 +351,17 @@ type GetCRUD[Entity database.CRUDEntity, Output any] struct {
 	OutputKey        string
 	OutputCountField string
 	OrderableFields  []string
-	BeforeCallback   func(context.Context, Entity, *apiendpoint.GetInput) error
+	// CursorKeyFields enables cursor pagination. The key fields are used as
+	// the tiebreaker of the cursor.
+	CursorKeyFields []string
+	PageOptions     *apiendpoint.GetPageOptions
+	// FilterOptions enables filter expressions.
+	FilterOptions *apiendpoint.FilterOptions
+	// SearchOptions enables full-text search.
+	SearchOptions *apiendpoint.SearchOptions
+	// Relations are the relations that can be included.
+	Relations      []Relation
+	BeforeCallback func(context.Context, Entity, *apiendpoint.GetInput) error
 }
 
 func NewGetCRUD[Entity database.CRUDEntity, Output any](
@@ -154,6 +371,11 @@ func NewGetCRUD[Entity database.CRUDEntity, Output any](
 	outputKey string,
 	outputCountField string,
 	orderableFields []string,
+	cursorKeyFields []string,
+	pageOptions *apiendpoint.GetPageOptions,
+	filterOptions *apiendpoint.FilterOptions,
+	searchOptions *apiendpoint.SearchOptions,
+	relations []Relation,
 	beforeCallback func(context.Context, Entity, *apiendpoint.GetInput) error,
 ) *GetCRUD[Entity, Output] {
 	return &GetCRUD[Entity, Output]{
@@ -163,6 +385,11 @@ func NewGetCRUD[Entity database.CRUDEntity, Output any](
 		OutputKey:        outputKey,
 		OutputCountField: outputCountField,
 		OrderableFields:  orderableFields,
+		CursorKeyFields:  cursorKeyFields,
+		PageOptions:      pageOptions,
+		FilterOptions:    filterOptions,
+		SearchOptions:    searchOptions,
+		Relations:        relations,
 		BeforeCallback:   beforeCallback,
 	}
 }
@@ -177,13 +404,13 @@ func (g *GetCRUD[Entity, Output]) EndpointHandler() *apiendpoint.EndpointHandler
 			g.APIFields.MustGetAPIField(FieldSelectors).Nested,
 			g.TableName,
 		),
-		func(entities []Entity, count int) (*Output, error) {
+		func(result *apiendpoint.GetResult[Entity]) (*Output, error) {
 			return toGenericGetOutput(
-				entities,
-				count,
+				result,
 				g.OutputAPIFields,
 				g.OutputKey,
 				g.OutputCountField,
+				g.Relations,
 				new(Output),
 			)
 		},
@@ -192,8 +419,25 @@ func (g *GetCRUD[Entity, Output]) EndpointHandler() *apiendpoint.EndpointHandler
 		g.BeforeCallback,
 		g.LoggerFactoryFn,
 		g.ReaderRepo,
-		g.TxManager,
+		txManagerFor[Entity](g.CRUDCommonParams),
 		g.SystemId,
+		apiendpoint.GetEndpointOptions[Entity]{
+			OutputToDBFields: getAPIFieldToDBColumnMapping(
+				g.OutputAPIFields.MustGetAPIField(g.OutputKey).Nested,
+				g.TableName,
+			),
+			FilterOptions:   g.FilterOptions,
+			SearchOptions:   g.SearchOptions,
+			Relations:       databaseRelations(g.Relations),
+			SoftDeleteField: g.softDeleteField(),
+			CursorKeyFields: g.cursorKeyFields(),
+			CursorValuesFn: func(
+				entity Entity, columns []string,
+			) ([]any, error) {
+				return entityColumnValues(entity, columns)
+			},
+			PageOptions: g.PageOptions,
+		},
 	)
 }
 
@@ -201,6 +445,104 @@ func (g *GetCRUD[Entity, Output]) NewInput() any {
 	return &apiendpoint.GetInput{}
 }
 
+// Operation returns the OpenAPI operation of the endpoint.
+func (g *GetCRUD[Entity, Output]) Operation() openapi.Operation {
+	return openapi.HandlerOperation(
+		g.EndpointHandler(),
+		"Get",
+		getOperationOutput(g.OutputAPIFields, g.OutputKey, g.Relations),
+	)
+}
+
+// cursorKeyFields returns the DB fields of the cursor key fields.
+func (g *GetCRUD[Entity, Output]) cursorKeyFields() []endpoint.DBField {
+	if len(g.CursorKeyFields) == 0 {
+		return nil
+	}
+	apiFields := g.OutputAPIFields.MustGetAPIField(g.OutputKey).Nested
+	fields := make([]endpoint.DBField, len(g.CursorKeyFields))
+	for i, keyField := range g.CursorKeyFields {
+		fields[i] = endpoint.DBField{
+			Table:  g.TableName,
+			Column: apiFields.MustGetAPIField(keyField).DBColumn,
+		}
+	}
+	return fields
+}
+
+// ---------------------------------------------------------------------
+// Get One CRUD
+// ---------------------------------------------------------------------
+
+type GetOneCRUD[Entity database.CRUDEntity] struct {
+	CRUDCommonParams[Entity]
+	APIFields       types.APIFields
+	KeyFields       []string
+	OutputAPIFields types.APIFields
+	OutputKey       string
+	BeforeCallback  func(context.Context, Entity, *apiendpoint.GetOneInput) error
+}
+
+func NewGetOneCRUD[Entity database.CRUDEntity](
+	common CRUDCommonParams[Entity],
+	apiFields types.APIFields,
+	keyFields []string,
+	outputAPIFields types.APIFields,
+	outputKey string,
+	beforeCallback func(context.Context, Entity, *apiendpoint.GetOneInput) error,
+) *GetOneCRUD[Entity] {
+	return &GetOneCRUD[Entity]{
+		CRUDCommonParams: common,
+		APIFields:        apiFields,
+		KeyFields:        keyFields,
+		OutputAPIFields:  outputAPIFields,
+		OutputKey:        outputKey,
+		BeforeCallback:   beforeCallback,
+	}
+}
+
+func (g *GetOneCRUD[Entity]) EndpointHandler() *apiendpoint.EndpointHandler[apiendpoint.GetOneInput] {
+	return apiendpoint.GenericGetOneDefinition(
+		keyedURL(g.URL, g.KeyFields),
+		api.NewMapInputHandler(
+			g.APIFields, g.ConversionRules, g.CustomRules,
+		),
+		getAPIFieldToDBColumnMapping(g.APIFields, g.TableName),
+		g.KeyFields,
+		g.softDeleteField(),
+		func(entity Entity) (any, error) {
+			outMap, err := dbEntityToMap(
+				entity,
+				g.OutputAPIFields.MustGetAPIField(g.OutputKey).Nested,
+				map[string]any{},
+			)
+			if err != nil {
+				return nil, err
+			}
+			return map[string]any{g.OutputKey: *outMap}, nil
+		},
+		g.etagFn(),
+		g.ConnFn,
+		func() Entity { return g.EntityFn() },
+		g.BeforeCallback,
+		g.LoggerFactoryFn,
+		g.ReaderRepo,
+		txManagerFor[Entity](g.CRUDCommonParams),
+		g.SystemId,
+	)
+}
+
+func (g *GetOneCRUD[Entity]) NewInput() any {
+	return &apiendpoint.GetOneInput{}
+}
+
+// Operation returns the OpenAPI operation of the endpoint.
+func (g *GetOneCRUD[Entity]) Operation() openapi.Operation {
+	return openapi.HandlerOperation(
+		g.EndpointHandler(), "Get one", g.OutputAPIFields,
+	)
+}
+
 // ---------------------------------------------------------------------
 // Update CRUD
 // ---------------------------------------------------------------------
@@ -224,6 +566,20 @@ func NewUpdateCRUD[Entity database.CRUDEntity](
 }
 
 func (u *UpdateCRUD[Entity]) EndpointHandler() *apiendpoint.EndpointHandler[apiendpoint.UpdateInput] {
+	expectedErrors := apiendpoint.WithConstraintFieldErrors(
+		apiendpoint.NewErrorBuilder(u.SystemId).
+			With(apiendpoint.UpdateErrors()).
+			With(apiendpoint.VersionErrors()).
+			Build(),
+	)
+	// The updated fields are named by their API names, so the field errors
+	// are not nested under a path.
+	opts := []apiendpoint.GenericEndpointOptions{{
+		ExpectedErrors: &expectedErrors,
+		MapErrorFn: apiendpoint.ConstraintFieldErrorFn(
+			u.APIFields.MustGetAPIField(FieldSelectors).Nested, "",
+		),
+	}}
 	return apiendpoint.GenericUpdateDefinition(
 		u.URL,
 		api.NewMapInputHandler(
@@ -233,11 +589,16 @@ func (u *UpdateCRUD[Entity]) EndpointHandler() *apiendpoint.EndpointHandler[apie
 			u.APIFields.MustGetAPIField(FieldSelectors).Nested,
 			u.TableName,
 		),
+		u.versionField(),
+		u.softDeleteField(),
 		u.ConnFn,
 		func() database.Mutator { return u.EntityFn() },
 		u.BeforeCallback,
 		u.LoggerFactoryFn,
+		repository.NewMutatorRepoAdapter(u.MutatorRepo),
+		txManagerFor[*apiendpoint.UpdateResult](u.CRUDCommonParams),
 		u.SystemId,
+		opts...,
 	)
 }
 
@@ -245,6 +606,13 @@ func (u *UpdateCRUD[Entity]) NewInput() any {
 	return &apiendpoint.UpdateInput{}
 }
 
+// Operation returns the OpenAPI operation of the endpoint.
+func (u *UpdateCRUD[Entity]) Operation() openapi.Operation {
+	return openapi.HandlerOperation(
+		u.EndpointHandler(), "Update", countOperationOutput(true),
+	)
+}
+
 // ---------------------------------------------------------------------
 // Delete CRUD
 // ---------------------------------------------------------------------
@@ -277,10 +645,14 @@ func (d *DeleteCRUD[Entity]) EndpointHandler() *apiendpoint.EndpointHandler[apie
 			d.APIFields.MustGetAPIField(FieldSelectors).Nested,
 			d.TableName,
 		),
+		d.versionField(),
+		d.softDeleteField(),
 		d.ConnFn,
 		func() database.Mutator { return d.EntityFn() },
 		d.BeforeCallback,
 		d.LoggerFactoryFn,
+		repository.NewMutatorRepoAdapter(d.MutatorRepo),
+		txManagerFor[*int64](d.CRUDCommonParams),
 		d.SystemId,
 	)
 }
@@ -289,48 +661,258 @@ func (d *DeleteCRUD[Entity]) NewInput() any {
 	return &apiendpoint.DeleteInput{}
 }
 
+// Operation returns the OpenAPI operation of the endpoint.
+func (d *DeleteCRUD[Entity]) Operation() openapi.Operation {
+	return openapi.HandlerOperation(
+		d.EndpointHandler(), "Delete", countOperationOutput(false),
+	)
+}
+
+// ---------------------------------------------------------------------
+// Restore CRUD
+// ---------------------------------------------------------------------
+
+type RestoreCRUD[Entity database.CRUDEntity] struct {
+	CRUDCommonParams[Entity]
+	APIFields      types.APIFields
+	BeforeCallback func(context.Context, database.Mutator, *apiendpoint.RestoreInput) error
+}
+
+func NewRestoreCRUD[Entity database.CRUDEntity](
+	common CRUDCommonParams[Entity],
+	apiFields types.APIFields,
+	beforeCallback func(context.Context, database.Mutator, *apiendpoint.RestoreInput) error,
+) *RestoreCRUD[Entity] {
+	if common.SoftDeleteColumn == "" {
+		panic("NewRestoreCRUD: soft delete column is required")
+	}
+	return &RestoreCRUD[Entity]{
+		CRUDCommonParams: common,
+		APIFields:        apiFields,
+		BeforeCallback:   beforeCallback,
+	}
+}
+
+func (r *RestoreCRUD[Entity]) EndpointHandler() *apiendpoint.EndpointHandler[apiendpoint.RestoreInput] {
+	expectedErrors := apiendpoint.WithConstraintFieldErrors(
+		apiendpoint.NewErrorBuilder(r.SystemId).
+			With(apiendpoint.RestoreErrors()).Build(),
+	)
+	// A restored row can conflict with the unique values of another row.
+	opts := []apiendpoint.GenericEndpointOptions{{
+		ExpectedErrors: &expectedErrors,
+		MapErrorFn: apiendpoint.ConstraintFieldErrorFn(
+			r.APIFields.MustGetAPIField(FieldSelectors).Nested, "",
+		),
+	}}
+	return apiendpoint.GenericRestoreDefinition(
+		restoreURL(r.URL),
+		api.NewMapInputHandler(
+			r.APIFields, r.ConversionRules, r.CustomRules,
+		),
+		getAPIFieldToDBColumnMapping(
+			r.APIFields.MustGetAPIField(FieldSelectors).Nested,
+			r.TableName,
+		),
+		r.softDeleteField(),
+		r.ConnFn,
+		func() database.Mutator { return r.EntityFn() },
+		r.BeforeCallback,
+		r.LoggerFactoryFn,
+		repository.NewMutatorRepoAdapter(r.MutatorRepo),
+		txManagerFor[*apiendpoint.UpdateResult](r.CRUDCommonParams),
+		r.SystemId,
+		opts...,
+	)
+}
+
+func (r *RestoreCRUD[Entity]) NewInput() any {
+	return &apiendpoint.RestoreInput{}
+}
+
+// Operation returns the OpenAPI operation of the endpoint.
+func (r *RestoreCRUD[Entity]) Operation() openapi.Operation {
+	return openapi.HandlerOperation(
+		r.EndpointHandler(), "Restore", countOperationOutput(false),
+	)
+}
+
+// ---------------------------------------------------------------------
+// Aggregate CRUD
+// ---------------------------------------------------------------------
+
+type AggregateCRUD[Entity database.CRUDEntity] struct {
+	CRUDCommonParams[Entity]
+	APIFields        types.APIFields
+	AggregateOptions *apiendpoint.AggregateOptions
+	PageOptions      *apiendpoint.GetPageOptions
+	BeforeCallback   func(context.Context, *apiendpoint.AggregateInput) error
+}
+
+func NewAggregateCRUD[Entity database.CRUDEntity](
+	common CRUDCommonParams[Entity],
+	apiFields types.APIFields,
+	aggregateOptions *apiendpoint.AggregateOptions,
+	pageOptions *apiendpoint.GetPageOptions,
+	beforeCallback func(context.Context, *apiendpoint.AggregateInput) error,
+) *AggregateCRUD[Entity] {
+	return &AggregateCRUD[Entity]{
+		CRUDCommonParams: common,
+		APIFields:        apiFields,
+		AggregateOptions: aggregateOptions,
+		PageOptions:      pageOptions,
+		BeforeCallback:   beforeCallback,
+	}
+}
+
+func (a *AggregateCRUD[Entity]) EndpointHandler() *apiendpoint.EndpointHandler[apiendpoint.AggregateInput] {
+	aggregator, ok := a.ReaderRepo.(repository.Aggregator)
+	if !ok {
+		panic("AggregateCRUD: reader repository can not aggregate rows")
+	}
+	return apiendpoint.GenericAggregateDefinition(
+		aggregateURL(a.URL),
+		api.NewMapInputHandler(
+			a.APIFields, a.ConversionRules, a.CustomRules,
+		),
+		getAPIFieldToDBColumnMapping(
+			a.APIFields.MustGetAPIField(FieldSelectors).Nested,
+			a.TableName,
+		),
+		a.AggregateOptions,
+		a.softDeleteField(),
+		a.PageOptions,
+		a.ConnFn,
+		a.BeforeCallback,
+		a.LoggerFactoryFn,
+		aggregator,
+		txManagerFor[[]map[string]any](a.CRUDCommonParams),
+		a.SystemId,
+	)
+}
+
+func (a *AggregateCRUD[Entity]) NewInput() any {
+	return &apiendpoint.AggregateInput{}
+}
+
+// Operation returns the OpenAPI operation of the endpoint.
+func (a *AggregateCRUD[Entity]) Operation() openapi.Operation {
+	return openapi.HandlerOperation(
+		a.EndpointHandler(),
+		"Aggregate",
+		types.APIFields{
+			{APIName: "groups", Type: "slice", Required: true},
+			{APIName: FieldCount, Type: "int", Required: true},
+		},
+	)
+}
+
 // ---------------------------------------------------------------------
 // CRUD Config & Builder
 // ---------------------------------------------------------------------
 
 type CRUDConfig[Entity database.CRUDEntity, CreateInput any, CreateOutput any, GetOutput any] struct {
-	URL                  string
-	TableName            string
-	EntityFn             func(...extendeddatabase.EntityOption[Entity]) Entity
-	Predicates           map[string]endpoint.Predicates
-	Orderable            []string
-	ConnFn               repository.ConnFn
-	AllAPIFields         types.APIFields
-	UpdateAPIFields      types.APIFields
-	EntityName           string
-	EntityNamePlural     string
-	BeforeCreateCallback func(context.Context, Entity, *CreateInput) error
-	BeforeGetCallback    func(context.Context, Entity, *apiendpoint.GetInput) error
-	BeforeUpdateCallback func(context.Context, database.Mutator, *apiendpoint.UpdateInput) error
-	BeforeDeleteCallback func(context.Context, database.Mutator, *apiendpoint.DeleteInput) error
-	ErrorMapping         map[string]api.ExpectedError
-	LoggerFactoryFn      apiendpoint.LoggerFactoryFn
-	MutatorRepo          repository.MutatorRepo[Entity]
-	ReaderRepo           repository.ReaderRepo[Entity]
-	TxManager            repository.TxManager[Entity]
-	ConversionRules      map[string]func(any) any
-	CustomRules          map[string]func(any) error
+	URL                      string
+	TableName                string
+	EntityFn                 func(...extendeddatabase.EntityOption[Entity]) Entity
+	Predicates               map[string]endpoint.Predicates
+	Orderable                []string
+	KeyFields                []string
+	CursorPagination         bool
+	FilterExpressions        bool
+	FilterMaxDepth           int
+	FilterMaxTerms           int
+	SearchFields             []string
+	SearchRelevance          bool
+	SearchFTSTable           string
+	SearchFTSKey             string
+	Relations                []Relation
+	GroupableFields          []string
+	DateBucketFields         []string
+	AggregatableFields       []string
+	DefaultPageLimit         int
+	MaxPageLimit             int
+	IncludeTotal             bool
+	TotalCountHeader         bool
+	MaxCreateManyItems       int
+	VersionField             string
+	SoftDeleteField          string
+	ConnFn                   repository.ConnFn
+	AllAPIFields             types.APIFields
+	UpdateAPIFields          types.APIFields
+	EntityName               string
+	EntityNamePlural         string
+	BeforeCreateCallback     func(context.Context, Entity, *CreateInput) error
+	BeforeCreateManyCallback func(context.Context, []Entity, *apiendpoint.CreateManyInput) error
+	BeforeGetCallback        func(context.Context, Entity, *apiendpoint.GetInput) error
+	BeforeGetOneCallback     func(context.Context, Entity, *apiendpoint.GetOneInput) error
+	BeforeUpdateCallback     func(context.Context, database.Mutator, *apiendpoint.UpdateInput) error
+	BeforeDeleteCallback     func(context.Context, database.Mutator, *apiendpoint.DeleteInput) error
+	BeforeRestoreCallback    func(context.Context, database.Mutator, *apiendpoint.RestoreInput) error
+	BeforeAggregateCallback  func(context.Context, *apiendpoint.AggregateInput) error
+	ErrorMapping             map[string]api.ExpectedError
+	LoggerFactoryFn          apiendpoint.LoggerFactoryFn
+	MutatorRepo              repository.MutatorRepo[Entity]
+	ReaderRepo               repository.ReaderRepo[Entity]
+	TxManager                repository.TxManager[Entity]
+	ConversionRules          map[string]func(any) any
+	CustomRules              map[string]func(any) error
+}
+
+// getPageOptions returns the page options of the get endpoint. The default
+// limits are used if they are not set.
+func (c CRUDConfig[Entity, CreateInput, CreateOutput, GetOutput]) getPageOptions() *apiendpoint.GetPageOptions {
+	pageOptions := apiendpoint.DefaultGetPageOptions()
+	if c.DefaultPageLimit != 0 {
+		pageOptions.DefaultLimit = c.DefaultPageLimit
+	}
+	if c.MaxPageLimit != 0 {
+		pageOptions.MaxLimit = c.MaxPageLimit
+	}
+	if pageOptions.DefaultLimit > pageOptions.MaxLimit {
+		panic(fmt.Sprintf(
+			"getPageOptions: default page limit %d exceeds max page limit %d",
+			pageOptions.DefaultLimit,
+			pageOptions.MaxLimit,
+		))
+	}
+	pageOptions.IncludeTotal = c.IncludeTotal
+	pageOptions.TotalCountHeader = c.TotalCountHeader
+	return pageOptions
+}
+
+// createManyMaxItems returns the maximum number of entities in the input of
+// the bulk create endpoint. DefaultMaxCreateManyItems is used if it is not
+// set.
+func (c CRUDConfig[Entity, CreateInput, CreateOutput, GetOutput]) createManyMaxItems() int {
+	if c.MaxCreateManyItems != 0 {
+		return c.MaxCreateManyItems
+	}
+	return apiendpoint.DefaultMaxCreateManyItems
 }
 
 type CRUDDefinitions struct {
-	Create *endpoint.Definition
-	Get    *endpoint.Definition
-	Update *endpoint.Definition
-	Delete *endpoint.Definition
+	Create     *endpoint.Definition
+	CreateMany *endpoint.Definition
+	Get        *endpoint.Definition
+	GetOne     *endpoint.Definition
+	Update     *endpoint.Definition
+	Delete     *endpoint.Definition
+	Restore    *endpoint.Definition
+	Aggregate  *endpoint.Definition
 }
 
 type CRUDBuilder[Entity database.CRUDEntity, CreateInput any,
 	CreateOutput any, GetOutput any] struct {
-	Config     CRUDConfig[Entity, CreateInput, CreateOutput, GetOutput]
-	createFlag bool
-	getFlag    bool
-	updateFlag bool
-	deleteFlag bool
+	Config         CRUDConfig[Entity, CreateInput, CreateOutput, GetOutput]
+	createFlag     bool
+	createManyFlag bool
+	getFlag        bool
+	getOneFlag     bool
+	updateFlag     bool
+	deleteFlag     bool
+	restoreFlag    bool
+	aggregateFlag  bool
 }
 
 func NewCRUDBuilder[
@@ -341,11 +923,15 @@ func NewCRUDBuilder[
 ](config CRUDConfig[Entity, CreateInput, CreateOutput, GetOutput],
 ) *CRUDBuilder[Entity, CreateInput, CreateOutput, GetOutput] {
 	return &CRUDBuilder[Entity, CreateInput, CreateOutput, GetOutput]{
-		Config:     config,
-		createFlag: true,
-		getFlag:    true,
-		updateFlag: true,
-		deleteFlag: true,
+		Config:      config,
+		createFlag:  true,
+		getFlag:     true,
+		getOneFlag:  len(config.KeyFields) != 0,
+		updateFlag:  true,
+		deleteFlag:  true,
+		restoreFlag: config.SoftDeleteField != "",
+		aggregateFlag: len(config.GroupableFields) != 0 ||
+			len(config.AggregatableFields) != 0,
 	}
 }
 
@@ -356,6 +942,13 @@ func (b *CRUDBuilder[Entity, CreateInput, CreateOutput,
 	return b
 }
 
+func (b *CRUDBuilder[Entity, CreateInput, CreateOutput,
+	GetOutput]) WithCreateMany(enabled bool) *CRUDBuilder[Entity, CreateInput,
+	CreateOutput, GetOutput] {
+	b.createManyFlag = enabled
+	return b
+}
+
 func (b *CRUDBuilder[Entity, CreateInput, CreateOutput,
 	GetOutput]) WithGet(enabled bool) *CRUDBuilder[Entity, CreateInput,
 	CreateOutput, GetOutput] {
@@ -363,6 +956,13 @@ func (b *CRUDBuilder[Entity, CreateInput, CreateOutput,
 	return b
 }
 
+func (b *CRUDBuilder[Entity, CreateInput, CreateOutput,
+	GetOutput]) WithGetOne(enabled bool) *CRUDBuilder[Entity, CreateInput,
+	CreateOutput, GetOutput] {
+	b.getOneFlag = enabled
+	return b
+}
+
 func (b *CRUDBuilder[Entity, CreateInput, CreateOutput,
 	GetOutput]) WithUpdate(enabled bool) *CRUDBuilder[Entity, CreateInput,
 	CreateOutput, GetOutput] {
@@ -377,11 +977,29 @@ func (b *CRUDBuilder[Entity, CreateInput, CreateOutput,
 	return b
 }
 
+func (b *CRUDBuilder[Entity, CreateInput, CreateOutput,
+	GetOutput]) WithRestore(enabled bool) *CRUDBuilder[Entity, CreateInput,
+	CreateOutput, GetOutput] {
+	b.restoreFlag = enabled
+	return b
+}
+
+func (b *CRUDBuilder[Entity, CreateInput, CreateOutput,
+	GetOutput]) WithAggregate(enabled bool) *CRUDBuilder[Entity, CreateInput,
+	CreateOutput, GetOutput] {
+	b.aggregateFlag = enabled
+	return b
+}
+
 type CRUDEndpoints[Entity database.CRUDEntity, CreateInput any, CreateOutput any, GetOutput any] struct {
-	Create *CreateCRUD[CreateInput, Entity]
-	Get    *GetCRUD[Entity, GetOutput]
-	Update *UpdateCRUD[Entity]
-	Delete *DeleteCRUD[Entity]
+	Create     *CreateCRUD[CreateInput, Entity]
+	CreateMany *CreateManyCRUD[Entity]
+	Get        *GetCRUD[Entity, GetOutput]
+	GetOne     *GetOneCRUD[Entity]
+	Update     *UpdateCRUD[Entity]
+	Delete     *DeleteCRUD[Entity]
+	Restore    *RestoreCRUD[Entity]
+	Aggregate  *AggregateCRUD[Entity]
 }
 
 func (b *CRUDBuilder[Entity, CreateInput, CreateOutput, GetOutput]) BuildCRUDEndpoints(
@@ -401,6 +1019,16 @@ func (b *CRUDBuilder[Entity, CreateInput, CreateOutput, GetOutput]) BuildCRUDEnd
 		CustomRules:     b.Config.CustomRules,
 		SystemId:        systemId,
 	}
+	if b.Config.VersionField != "" {
+		common.VersionColumn = b.Config.AllAPIFields.
+			MustGetAPIField(b.Config.VersionField).DBColumn
+	}
+	var softDeleteAPIFields types.APIFields
+	if b.Config.SoftDeleteField != "" {
+		common.SoftDeleteColumn = b.Config.AllAPIFields.
+			MustGetAPIField(b.Config.SoftDeleteField).DBColumn
+		softDeleteAPIFields = types.APIFields{includeDeletedFieldEntry()}
+	}
 	if b.createFlag {
 		endpoints.Create = NewCreateCRUD(
 			common,
@@ -414,26 +1042,126 @@ func (b *CRUDBuilder[Entity, CreateInput, CreateOutput, GetOutput]) BuildCRUDEnd
 			b.Config.ErrorMapping,
 		)
 	}
+	if b.createManyFlag {
+		endpoints.CreateMany = NewCreateManyCRUD(
+			common,
+			genericCreateManyAPIFields(
+				MustStructToAPIFields[CreateInput](b.Config.AllAPIFields),
+				b.Config.EntityName,
+				b.Config.EntityNamePlural,
+				b.Config.createManyMaxItems(),
+			),
+			extendeddatabase.WithOption[Entity],
+			b.Config.EntityNamePlural,
+			genericCreateManyAPIFields(
+				MustStructToAPIFields[CreateOutput](b.Config.AllAPIFields),
+				b.Config.EntityName,
+				b.Config.EntityNamePlural,
+				0,
+			),
+			b.Config.EntityNamePlural,
+			b.Config.BeforeCreateManyCallback,
+			b.Config.ErrorMapping,
+		)
+	}
 	if b.getFlag {
 		MustValidateGetOutput[GetOutput](
 			b.Config.AllAPIFields,
 			b.Config.EntityNamePlural,
 		)
+		var cursorAPIFields types.APIFields
+		var cursorKeyFields []string
+		if b.Config.CursorPagination {
+			if len(b.Config.KeyFields) == 0 {
+				panic("BuildCRUDEndpoints: cursor pagination requires key fields")
+			}
+			MustValidateCursorGetOutput[GetOutput]()
+			cursorAPIFields = types.APIFields{cursorFieldEntry()}
+			cursorKeyFields = b.Config.KeyFields
+		}
+		if b.Config.IncludeTotal {
+			MustValidateTotalGetOutput[GetOutput]()
+		}
+		var filterAPIFields types.APIFields
+		var filterOptions *apiendpoint.FilterOptions
+		if b.Config.FilterExpressions {
+			filterAPIFields = types.APIFields{filterFieldEntry()}
+			filterOptions = genericFilterOptions(
+				b.Config.AllAPIFields,
+				b.Config.Predicates,
+				b.Config.TableName,
+				b.Config.FilterMaxDepth,
+				b.Config.FilterMaxTerms,
+			)
+		}
+		var searchAPIFields types.APIFields
+		var searchOptions *apiendpoint.SearchOptions
+		if len(b.Config.SearchFields) != 0 {
+			if b.Config.SearchRelevance && b.Config.CursorPagination {
+				panic("BuildCRUDEndpoints: relevance order can not be used with cursor pagination")
+			}
+			searchAPIFields = types.APIFields{searchFieldEntry()}
+			searchOptions = mustSearchOptions(
+				b.Config.AllAPIFields,
+				b.Config.SearchFields,
+				b.Config.TableName,
+				b.Config.SearchRelevance,
+				b.Config.SearchFTSTable,
+				b.Config.SearchFTSKey,
+			)
+		}
+		var includeAPIFields types.APIFields
+		var relations []Relation
+		if len(b.Config.Relations) != 0 {
+			includeAPIFields = types.APIFields{includeFieldEntry()}
+			relations = mustRelations(
+				b.Config.Relations, b.Config.TableName,
+			)
+		}
+		pageOptions := b.Config.getPageOptions()
 		endpoints.Get = NewGetCRUD[Entity, GetOutput](
 			common,
 			genericGetAPIFields(
-				b.Config.AllAPIFields, b.Config.Predicates, b.Config.Orderable,
-			),
+				b.Config.AllAPIFields,
+				b.Config.Predicates,
+				b.Config.Orderable,
+				pageOptions,
+			).With(softDeleteAPIFields...).
+				With(cursorAPIFields...).
+				With(filterAPIFields...).
+				With(searchAPIFields...).
+				With(includeAPIFields...),
 			genericGetOutputAPIFields(
 				b.Config.EntityNamePlural,
 				b.Config.AllAPIFields,
+				b.Config.CursorPagination,
+				b.Config.IncludeTotal,
 			),
 			b.Config.EntityNamePlural,
 			FieldCount,
 			b.Config.Orderable,
+			cursorKeyFields,
+			pageOptions,
+			filterOptions,
+			searchOptions,
+			relations,
 			b.Config.BeforeGetCallback,
 		)
 	}
+	if b.getOneFlag {
+		endpoints.GetOne = NewGetOneCRUD(
+			common,
+			mustGetOneAPIFields(b.Config.AllAPIFields, b.Config.KeyFields).
+				With(softDeleteAPIFields...),
+			b.Config.KeyFields,
+			types.APIFields{{
+				APIName: b.Config.EntityName,
+				Nested:  b.Config.AllAPIFields,
+			}},
+			b.Config.EntityName,
+			b.Config.BeforeGetOneCallback,
+		)
+	}
 	if b.updateFlag {
 		endpoints.Update = NewUpdateCRUD(
 			common,
@@ -441,7 +1169,8 @@ func (b *CRUDBuilder[Entity, CreateInput, CreateOutput, GetOutput]) BuildCRUDEnd
 				b.Config.AllAPIFields,
 				b.Config.Predicates,
 				b.Config.UpdateAPIFields,
-			),
+				b.Config.VersionField != "",
+			).With(softDeleteAPIFields...),
 			b.Config.BeforeUpdateCallback,
 		)
 	}
@@ -451,9 +1180,70 @@ func (b *CRUDBuilder[Entity, CreateInput, CreateOutput, GetOutput]) BuildCRUDEnd
 			genericDeleteAPIFields(
 				b.Config.AllAPIFields,
 				b.Config.Predicates,
+				b.Config.VersionField != "",
 			),
 			b.Config.BeforeDeleteCallback,
 		)
 	}
+	if b.restoreFlag {
+		endpoints.Restore = NewRestoreCRUD(
+			common,
+			genericDeleteAPIFields(
+				b.Config.AllAPIFields,
+				b.Config.Predicates,
+				false,
+			),
+			b.Config.BeforeRestoreCallback,
+		)
+	}
+	if b.aggregateFlag {
+		endpoints.Aggregate = NewAggregateCRUD(
+			common,
+			genericAggregateAPIFields(
+				b.Config.AllAPIFields,
+				b.Config.Predicates,
+				b.Config.getPageOptions(),
+			).With(softDeleteAPIFields...),
+			mustAggregateOptions(
+				b.Config.AllAPIFields,
+				b.Config.TableName,
+				b.Config.GroupableFields,
+				b.Config.DateBucketFields,
+				b.Config.AggregatableFields,
+			),
+			b.Config.getPageOptions(),
+			b.Config.BeforeAggregateCallback,
+		)
+	}
 	return &endpoints
 }
+
+// Operations returns the OpenAPI operations of the built endpoints.
+func (e *CRUDEndpoints[Entity, CreateInput, CreateOutput, GetOutput]) Operations() []openapi.Operation {
+	var operations []openapi.Operation
+	if e.Create != nil {
+		operations = append(operations, e.Create.Operation())
+	}
+	if e.CreateMany != nil {
+		operations = append(operations, e.CreateMany.Operation())
+	}
+	if e.Get != nil {
+		operations = append(operations, e.Get.Operation())
+	}
+	if e.GetOne != nil {
+		operations = append(operations, e.GetOne.Operation())
+	}
+	if e.Update != nil {
+		operations = append(operations, e.Update.Operation())
+	}
+	if e.Delete != nil {
+		operations = append(operations, e.Delete.Operation())
+	}
+	if e.Restore != nil {
+		operations = append(operations, e.Restore.Operation())
+	}
+	if e.Aggregate != nil {
+		operations = append(operations, e.Aggregate.Operation())
+	}
+	return operations
+}
@@ -16,22 +16,7 @@ import (
 func MustValidateGetOutput[T any](apiFields types.APIFields, outputKey string) {
 	t := reflect.TypeOf((*T)(nil)).Elem()
 
-	// Check that T has a "count" field.
-	countFound := false
-	for i := 0; i < t.NumField(); i++ {
-		field := t.Field(i)
-		if strings.Split(field.Tag.Get("json"), ",")[0] == FieldCount {
-			countFound = true
-			break
-		}
-	}
-	if !countFound {
-		panic(fmt.Sprintf(
-			"MustValidateGetOutput: type %s must have a field with JSON tag %q",
-			t.Name(),
-			FieldCount,
-		))
-	}
+	mustHaveJSONField("MustValidateGetOutput", t, FieldCount)
 
 	// Find the field with JSON tag matching outputKey.
 	var targetField reflect.StructField
@@ -68,6 +53,42 @@ func MustValidateGetOutput[T any](apiFields types.APIFields, outputKey string) {
 	mustStructToAPIFieldsFromType(underlyingType, apiFields)
 }
 
+// MustValidateCursorGetOutput validates output for a GET endpoint that uses
+// cursor pagination. It expects that type T contains the "next_cursor" and
+// "prev_cursor" fields in addition to the fields required by
+// MustValidateGetOutput.
+func MustValidateCursorGetOutput[T any]() {
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	mustHaveJSONField("MustValidateCursorGetOutput", t, FieldNextCursor)
+	mustHaveJSONField("MustValidateCursorGetOutput", t, FieldPrevCursor)
+}
+
+// MustValidateTotalGetOutput validates output for a GET endpoint that returns
+// the total count. It expects that type T contains the "total" and "page"
+// fields in addition to the fields required by MustValidateGetOutput.
+func MustValidateTotalGetOutput[T any]() {
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	mustHaveJSONField("MustValidateTotalGetOutput", t, FieldTotal)
+	mustHaveJSONField("MustValidateTotalGetOutput", t, FieldPage)
+}
+
+// mustHaveJSONField panics if the struct type t does not have a field with the
+// given JSON tag. The panic message is prefixed with the name of the caller.
+func mustHaveJSONField(caller string, t reflect.Type, jsonTag string) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if strings.Split(field.Tag.Get("json"), ",")[0] == jsonTag {
+			return
+		}
+	}
+	panic(fmt.Sprintf(
+		"%s: type %s must have a field with JSON tag %q",
+		caller,
+		t.Name(),
+		jsonTag,
+	))
+}
+
 // MustStructToAPIFields is a generic function that returns a slice of APIFields
 // for any struct type T. For each field, if an APIField with a matching JSON
 // name exists in allFields, that APIField is used. If the struct field has a
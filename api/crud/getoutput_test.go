@@ -490,3 +490,23 @@ func TestStructToAPIFields_Offensive(t *testing.T) {
 		}
 	})
 }
+
+func TestMustValidatePaginationGetOutput(t *testing.T) {
+	type cursorOutput struct {
+		NextCursor string `json:"next_cursor"`
+		PrevCursor string `json:"prev_cursor"`
+	}
+	type totalOutput struct {
+		Total int `json:"total"`
+		Page  int `json:"page"`
+	}
+	type emptyOutput struct{}
+
+	MustValidateCursorGetOutput[cursorOutput]()
+	MustValidateTotalGetOutput[totalOutput]()
+
+	expectPanic(t, MustValidateCursorGetOutput[emptyOutput],
+		`MustValidateCursorGetOutput: type emptyOutput must have a field with JSON tag "next_cursor"`)
+	expectPanic(t, MustValidateTotalGetOutput[emptyOutput],
+		`MustValidateTotalGetOutput: type emptyOutput must have a field with JSON tag "total"`)
+}
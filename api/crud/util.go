@@ -3,11 +3,14 @@ package crud
 import (
 	"fmt"
 	"reflect"
+	"slices"
 	"strings"
 
 	"github.com/mitchellh/mapstructure"
 	"github.com/pakkasys/fluidapi-extended/api"
+	apiendpoint "github.com/pakkasys/fluidapi-extended/api/endpoint"
 	"github.com/pakkasys/fluidapi-extended/api/types"
+	extendeddatabase "github.com/pakkasys/fluidapi-extended/database"
 	"github.com/pakkasys/fluidapi/endpoint"
 )
 
@@ -16,11 +19,22 @@ import (
 // ---------------------------------------------------------------------
 
 const (
-	FieldSelectors = "selectors"
-	FieldUpdates   = "updates"
-	FieldCount     = "count"
-	FieldValue     = "value"
-	FieldPredicate = "predicate"
+	FieldSelectors  = "selectors"
+	FieldUpdates    = "updates"
+	FieldCount      = "count"
+	FieldValue      = "value"
+	FieldPredicate  = "predicate"
+	FieldVersion    = "version"
+	FieldNextCursor = "next_cursor"
+	FieldPrevCursor = "prev_cursor"
+	FieldTotal      = "total"
+	FieldPage       = "page"
+)
+
+// Input sources used by the generated APIFields.
+const (
+	sourcePath   = "path"
+	sourceHeader = "header"
 )
 
 // ---------------------------------------------------------------------
@@ -300,22 +314,31 @@ func structToDBEntity[Struct any, Entity any](
 	return colsAndValues, nil
 }
 
-// toGenericGetOutput creates an output map from a slice of entities.
+// toGenericGetOutput creates an output map from the result of a get
+// operation.
 func toGenericGetOutput[Entity any, Output any](
-	entities []Entity,
-	count int,
+	result *apiendpoint.GetResult[Entity],
 	apiFields types.APIFields,
 	pluralField string,
 	countField string,
+	relations []Relation,
 	obj *Output,
 ) (*Output, error) {
 	outputMap := make(map[string]any)
 	for _, field := range apiFields {
 		switch field.APIName {
 		case pluralField:
+			nested := field.Nested
+			if result.Fields != nil {
+				nested = selectedAPIFields(field.Nested, result.Fields)
+			}
 			var objects []map[string]any
-			for _, e := range entities {
-				mapped, err := dbEntityToMap(e, field.Nested, map[string]any{})
+			for _, e := range result.Entities {
+				mapped, err := dbEntityToMap(e, nested, map[string]any{})
+				if err != nil {
+					return nil, err
+				}
+				err = embedRelations(e, result, relations, *mapped)
 				if err != nil {
 					return nil, err
 				}
@@ -323,7 +346,22 @@ func toGenericGetOutput[Entity any, Output any](
 			}
 			outputMap[pluralField] = objects
 		case countField:
-			outputMap[countField] = count
+			outputMap[countField] = result.Count
+		case FieldNextCursor:
+			outputMap[FieldNextCursor] = result.NextCursor
+		case FieldPrevCursor:
+			outputMap[FieldPrevCursor] = result.PrevCursor
+		case FieldTotal:
+			if result.Total != nil {
+				outputMap[FieldTotal] = *result.Total
+			}
+		case FieldPage:
+			page := map[string]any{"has_more": result.HasMore}
+			if result.Page != nil {
+				page["offset"] = result.Page.Offset
+				page["limit"] = result.Page.Limit
+			}
+			outputMap[FieldPage] = page
 		default:
 			return nil, fmt.Errorf(
 				"toGenericGetOutput: unknown output field: %s", field.APIName,
@@ -331,35 +369,126 @@ func toGenericGetOutput[Entity any, Output any](
 		}
 	}
 
-	err := mapstructure.Decode(outputMap, obj)
+	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		TagName: "json",
+		Result:  obj,
+	})
 	if err != nil {
 		return nil, err
 	}
+	if err := decoder.Decode(outputMap); err != nil {
+		return nil, err
+	}
 
 	return obj, nil
 }
 
+// embedRelations adds the included related entities of an entity to its
+// output map. Belongs-to relations are embedded as an object, or nil if there
+// is no related entity, and the other relations as a list.
+func embedRelations[Entity any](
+	entity Entity,
+	result *apiendpoint.GetResult[Entity],
+	relations []Relation,
+	output map[string]any,
+) error {
+	for _, included := range result.Includes {
+		index := slices.IndexFunc(relations, func(relation Relation) bool {
+			return relation.Name == included.Name
+		})
+		if index == -1 {
+			return fmt.Errorf(
+				"embedRelations: unknown relation: %s", included.Name,
+			)
+		}
+		relation := relations[index]
+		values, err := extendeddatabase.ColumnValues(
+			entity, []string{relation.LocalColumn},
+		)
+		if err != nil {
+			return err
+		}
+		var related []any
+		if key, ok := extendeddatabase.RelationKey(values[0]); ok {
+			related = result.Related[relation.Name][key]
+		}
+		objects := make([]map[string]any, 0, len(related))
+		for _, relatedEntity := range related {
+			mapped, err := dbEntityToMap(
+				relatedEntity, relation.APIFields, map[string]any{},
+			)
+			if err != nil {
+				return err
+			}
+			objects = append(objects, *mapped)
+		}
+		if relation.Kind != extendeddatabase.RelationBelongsTo {
+			output[relation.Name] = objects
+		} else if len(objects) != 0 {
+			output[relation.Name] = objects[0]
+		} else {
+			output[relation.Name] = nil
+		}
+	}
+	return nil
+}
+
+// selectedAPIFields returns the APIFields whose API names are selected, in
+// the order of the APIFields.
+func selectedAPIFields(
+	apiFields types.APIFields, selected []string,
+) types.APIFields {
+	var fields types.APIFields
+	for _, field := range apiFields {
+		if slices.Contains(selected, field.APIName) {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+// entityColumnValues returns the values of the given DB columns of an entity.
+func entityColumnValues(entity any, columns []string) ([]any, error) {
+	apiFields := make(types.APIFields, len(columns))
+	for i, column := range columns {
+		apiFields[i] = types.APIField{APIName: column, DBColumn: column}
+	}
+	outMap, err := dbEntityToMap(entity, apiFields, map[string]any{})
+	if err != nil {
+		return nil, err
+	}
+	values := make([]any, len(columns))
+	for i, column := range columns {
+		values[i] = (*outMap)[column]
+	}
+	return values, nil
+}
+
 // genericGetAPIFields creates types.APIFields for a get request.
 func genericGetAPIFields(
 	apiFields types.APIFields,
 	predicates map[string]endpoint.Predicates,
 	orderable []string,
+	pageOptions *apiendpoint.GetPageOptions,
 ) types.APIFields {
 	mustMatchPredicates(predicates, apiFields)
 	mustMatchFields(orderable, apiFields)
 	return types.APIFields{
 		selectorFieldsEntry(apiFields, predicates),
 		orderFieldsEntry(apiFields, orderable),
-		pageFieldEntry(),
+		pageFieldEntry(pageOptions.DefaultLimit, pageOptions.MaxLimit),
 		countFieldEntry(),
+		fieldsFieldEntry(),
 	}
 }
 
-// genericGetOutputAPIFields creates types.APIFields for a get request.
+// genericGetOutputAPIFields creates types.APIFields for a get request. If
+// cursor is set, the next and previous cursors are included. If total is set,
+// the total count and page metadata are included.
 func genericGetOutputAPIFields(
-	outputKey string, apiFields types.APIFields,
+	outputKey string, apiFields types.APIFields, cursor bool, total bool,
 ) types.APIFields {
-	return types.APIFields{
+	fields := types.APIFields{
 		{
 			APIName: outputKey,
 			Nested:  apiFields,
@@ -368,29 +497,369 @@ func genericGetOutputAPIFields(
 			APIName: FieldCount,
 		},
 	}
+	if cursor {
+		fields = append(
+			fields,
+			types.APIField{APIName: FieldNextCursor},
+			types.APIField{APIName: FieldPrevCursor},
+		)
+	}
+	if total {
+		fields = append(
+			fields,
+			types.APIField{APIName: FieldTotal},
+			types.APIField{APIName: FieldPage},
+		)
+	}
+	return fields
+}
+
+// mustGetOneAPIFields creates APIFields for a get one request. The key fields
+// are read from the URL path and are required. It panics if no key fields are
+// given or if a key field is not found.
+func mustGetOneAPIFields(
+	apiFields types.APIFields, keyFields []string,
+) types.APIFields {
+	if len(keyFields) == 0 {
+		panic("mustGetOneAPIFields: at least one key field is required")
+	}
+	var fields types.APIFields
+	for _, field := range apiFields.MustGetAPIFields(keyFields) {
+		field.Source = sourcePath
+		field.Required = true
+		field.Default = nil
+		fields = append(fields, field)
+	}
+	return fields
+}
+
+// keyedURL returns the URL for a single entity by appending a path wildcard
+// for each key field.
+//
+// Example:
+//
+//	keyedURL("/users", []string{"org_id", "id"})
+//
+// Output:
+//
+//	"/users/{org_id}/{id}"
+func keyedURL(url string, keyFields []string) string {
+	var builder strings.Builder
+	builder.WriteString(strings.TrimSuffix(url, "/"))
+	for _, keyField := range keyFields {
+		builder.WriteString(fmt.Sprintf("/{%s}", keyField))
+	}
+	return builder.String()
+}
+
+// bulkURL returns the URL for bulk operations.
+//
+// Example:
+//
+//	bulkURL("/users")
+//
+// Output:
+//
+//	"/users/bulk"
+func bulkURL(url string) string {
+	return strings.TrimSuffix(url, "/") + "/bulk"
+}
+
+// genericCreateManyAPIFields creates APIFields for a bulk create request or
+// output from the single entity APIFields. The fields of the entity are
+// expected as an array under the plural name. If maxItems is set, the array
+// must have at least one and at most maxItems items.
+func genericCreateManyAPIFields(
+	apiFields types.APIFields,
+	entityName string,
+	entityNamePlural string,
+	maxItems int,
+) types.APIFields {
+	field := types.APIField{
+		APIName:  entityNamePlural,
+		Nested:   apiFields.MustGetAPIField(entityName).Nested,
+		Required: true,
+	}
+	if maxItems > 0 {
+		field.Validate = []string{
+			"slice", "min=1", fmt.Sprintf("max=%d", maxItems),
+		}
+	}
+	return types.APIFields{field}
 }
 
-// genericUpdateAPIFields creates APIFields for an update request.
+// mapsToColsAndValues creates column to value maps from a list of input
+// objects. Each object is mapped by the APIName of the APIFields and only the
+// fields present in the object are included.
+//
+// Example:
+//
+//	items := []any{
+//	    map[string]any{"user_name": "Alice"},
+//	    map[string]any{"user_name": "Bob"},
+//	}
+//	apiFields := APIFields{
+//	    {APIName: "user_name", DBColumn: "username"},
+//	}
+//
+// Output:
+//
+//	[]map[string]any{{"username": "Alice"}, {"username": "Bob"}}
+func mapsToColsAndValues(
+	items any, apiFields types.APIFields,
+) ([]map[string]any, error) {
+	list, ok := items.([]any)
+	if !ok && items != nil {
+		return nil, fmt.Errorf(
+			"mapsToColsAndValues: expected a list but got %T", items,
+		)
+	}
+	result := make([]map[string]any, len(list))
+	for i, item := range list {
+		object, ok := item.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf(
+				"mapsToColsAndValues: item %d is not an object", i,
+			)
+		}
+		colsAndValues := map[string]any{}
+		for _, apiField := range apiFields {
+			if value, ok := object[apiField.APIName]; ok {
+				colsAndValues[apiField.DBColumn] = value
+			}
+		}
+		result[i] = colsAndValues
+	}
+	return result, nil
+}
+
+// genericUpdateAPIFields creates APIFields for an update request. If versioned
+// is set, the expected version can be given as input or If-Match header.
 func genericUpdateAPIFields(
 	selectableAPIFields types.APIFields,
 	predicates map[string]endpoint.Predicates,
 	updatableAPIFields types.APIFields,
+	versioned bool,
 ) types.APIFields {
-	return types.APIFields{
+	fields := types.APIFields{
 		selectorFieldsEntry(selectableAPIFields, predicates),
 		updatesEntry(updatableAPIFields),
 	}
+	if versioned {
+		fields = append(fields, versionFieldEntries()...)
+	}
+	return fields
 }
 
-// genericDeleteAPIFields creates APIFields for a delete request.
+// restoreURL returns the URL for restoring soft deleted entities.
+//
+// Example:
+//
+//	restoreURL("/users")
+//
+// Output:
+//
+//	"/users/restore"
+func restoreURL(url string) string {
+	return strings.TrimSuffix(url, "/") + "/restore"
+}
+
+// aggregateURL returns the URL of the aggregate endpoint.
+//
+// Example:
+//
+//	aggregateURL("/users")
+//
+// Output:
+//
+//	"/users/aggregate"
+func aggregateURL(url string) string {
+	return strings.TrimSuffix(url, "/") + "/aggregate"
+}
+
+// genericAggregateAPIFields creates APIFields for an aggregate request. The
+// rows can be selected with the same selectors as in a get request.
+func genericAggregateAPIFields(
+	apiFields types.APIFields,
+	predicates map[string]endpoint.Predicates,
+	pageOptions *apiendpoint.GetPageOptions,
+) types.APIFields {
+	mustMatchPredicates(predicates, apiFields)
+	fields := types.APIFields{
+		selectorFieldsEntry(apiFields, predicates),
+		pageFieldEntry(pageOptions.DefaultLimit, pageOptions.MaxLimit),
+	}
+	for _, name := range []string{
+		apiendpoint.FieldGroupBy,
+		apiendpoint.FieldAggregates,
+		apiendpoint.FieldHaving,
+		"orders",
+	} {
+		fields = append(fields, types.APIField{
+			APIName:  name,
+			Validate: []string{"string"},
+			Type:     "string",
+		})
+	}
+	return fields
+}
+
+// mustAggregateOptions creates the aggregate options of a table. It panics if
+// a field is not found or if a date bucket field can not be grouped.
+func mustAggregateOptions(
+	apiFields types.APIFields,
+	tableName string,
+	groupableFields []string,
+	dateBucketFields []string,
+	aggregatableFields []string,
+) *apiendpoint.AggregateOptions {
+	for _, field := range dateBucketFields {
+		if !slices.Contains(groupableFields, field) {
+			panic(fmt.Sprintf(
+				"mustAggregateOptions: date bucket field %q is not groupable",
+				field,
+			))
+		}
+	}
+	return &apiendpoint.AggregateOptions{
+		Table: tableName,
+		GroupFields: getAPIFieldToDBColumnMapping(
+			apiFields.MustGetAPIFields(groupableFields), tableName,
+		),
+		DateFields: dateBucketFields,
+		Fields: getAPIFieldToDBColumnMapping(
+			apiFields.MustGetAPIFields(aggregatableFields), tableName,
+		),
+	}
+}
+
+// includeDeletedFieldEntry creates an APIField for including soft deleted
+// entities.
+func includeDeletedFieldEntry() types.APIField {
+	return types.APIField{
+		APIName:  apiendpoint.FieldIncludeDeleted,
+		Validate: []string{"bool"},
+		Type:     "bool",
+	}
+}
+
+// cursorFieldEntry creates an APIField for the cursor of a get request.
+func cursorFieldEntry() types.APIField {
+	return types.APIField{
+		APIName:  apiendpoint.FieldCursor,
+		Validate: []string{"string"},
+		Type:     "string",
+	}
+}
+
+// filterFieldEntry creates an APIField for the filter expression of a get
+// request.
+func filterFieldEntry() types.APIField {
+	return types.APIField{
+		APIName:  apiendpoint.FieldFilter,
+		Validate: []string{"string"},
+		Type:     "string",
+	}
+}
+
+// genericFilterOptions creates the filter options of a get request. The fields
+// with predicates can be used in filter expressions with the same predicates
+// as in selectors.
+func genericFilterOptions(
+	apiFields types.APIFields,
+	predicates map[string]endpoint.Predicates,
+	tableName string,
+	maxDepth int,
+	maxTerms int,
+) *apiendpoint.FilterOptions {
+	fields := make(map[string]apiendpoint.FilterField)
+	for _, field := range apiFields {
+		fieldPredicates, ok := predicates[field.APIName]
+		if !ok {
+			continue
+		}
+		fields[field.APIName] = apiendpoint.FilterField{
+			DBField: endpoint.DBField{
+				Table:  tableName,
+				Column: field.DBColumn,
+			},
+			Predicates: fieldPredicates,
+			Type:       field.Type,
+		}
+	}
+	return &apiendpoint.FilterOptions{
+		Fields:   fields,
+		MaxDepth: maxDepth,
+		MaxTerms: maxTerms,
+	}
+}
+
+// searchFieldEntry creates an APIField for the full-text search query of a get
+// request.
+func searchFieldEntry() types.APIField {
+	return types.APIField{
+		APIName:  apiendpoint.FieldSearch,
+		Validate: []string{"string", "max=256"},
+		Type:     "string",
+	}
+}
+
+// mustSearchOptions creates the search options of a get request from the
+// searchable fields. It panics if a field is not found.
+func mustSearchOptions(
+	apiFields types.APIFields,
+	searchFields []string,
+	tableName string,
+	relevance bool,
+	ftsTable string,
+	ftsKey string,
+) *apiendpoint.SearchOptions {
+	var columns []string
+	for _, field := range apiFields.MustGetAPIFields(searchFields) {
+		columns = append(columns, field.DBColumn)
+	}
+	return &apiendpoint.SearchOptions{
+		Table:     tableName,
+		Columns:   columns,
+		Relevance: relevance,
+		FTSTable:  ftsTable,
+		FTSKey:    ftsKey,
+	}
+}
+
+// genericDeleteAPIFields creates APIFields for a delete request. If versioned
+// is set, the expected version can be given as input or If-Match header.
 func genericDeleteAPIFields(
 	selectableAPIFields types.APIFields,
 	predicates map[string]endpoint.Predicates,
+	versioned bool,
 ) types.APIFields {
 	mustMatchPredicates(predicates, selectableAPIFields)
-	return types.APIFields{
+	fields := types.APIFields{
 		selectorFieldsEntry(selectableAPIFields, predicates),
 	}
+	if versioned {
+		fields = append(fields, versionFieldEntries()...)
+	}
+	return fields
+}
+
+// versionFieldEntries creates APIFields for the expected version of a
+// versioned entity.
+func versionFieldEntries() types.APIFields {
+	return types.APIFields{
+		{
+			APIName:  FieldVersion,
+			Validate: []string{"int64", "min=0"},
+			Type:     "int64",
+		},
+		{
+			APIName:  apiendpoint.HeaderIfMatch,
+			Source:   sourceHeader,
+			Validate: []string{"string"},
+			Type:     "string",
+		},
+	}
 }
 
 // mustMatchPredicates returns a map of predicates that matches the APIFields.
@@ -516,7 +985,7 @@ func orderFields(from types.APIFields, orderableFields []string) types.APIFields
 }
 
 // pageFieldEntry creates an APIField for pagination.
-func pageFieldEntry() types.APIField {
+func pageFieldEntry(defaultLimit int, maxLimit int) types.APIField {
 	return types.APIField{
 		APIName: "page",
 		Nested: []types.APIField{
@@ -526,15 +995,87 @@ func pageFieldEntry() types.APIField {
 				Type:     "int64",
 			},
 			{
-				APIName:  "limit",
-				Validate: []string{"int64", "min=1", "max=1000"},
-				Default:  int64(1),
-				Type:     "int64",
+				APIName: "limit",
+				Validate: []string{
+					"int64", "min=1", fmt.Sprintf("max=%d", maxLimit),
+				},
+				Default: int64(defaultLimit),
+				Type:    "int64",
 			},
 		},
 	}
 }
 
+// fieldsFieldEntry creates an APIField for selecting the returned fields.
+func fieldsFieldEntry() types.APIField {
+	return types.APIField{
+		APIName:  apiendpoint.FieldFields,
+		Validate: []string{"string"},
+		Type:     "string",
+	}
+}
+
+// includeFieldEntry creates an APIField for the included relations of a get
+// request.
+func includeFieldEntry() types.APIField {
+	return types.APIField{
+		APIName:  apiendpoint.FieldInclude,
+		Validate: []string{"string"},
+		Type:     "string",
+	}
+}
+
+// mustRelations completes the relations of a table. The columns of each
+// relation are the DB columns of its APIFields. It panics if a relation is
+// not complete or its kind is not valid.
+func mustRelations(relations []Relation, tableName string) []Relation {
+	completed := make([]Relation, len(relations))
+	for i, relation := range relations {
+		if relation.Name == "" || relation.Table == "" ||
+			relation.LocalColumn == "" || relation.ForeignColumn == "" ||
+			relation.EntityFn == nil || len(relation.APIFields) == 0 {
+			panic(fmt.Sprintf(
+				"mustRelations: relation %q is not complete", relation.Name,
+			))
+		}
+		switch relation.Kind {
+		case extendeddatabase.RelationBelongsTo,
+			extendeddatabase.RelationHasMany,
+			extendeddatabase.RelationManyToMany:
+		default:
+			panic(fmt.Sprintf(
+				"mustRelations: relation %q has invalid kind %q",
+				relation.Name,
+				relation.Kind,
+			))
+		}
+		if relation.Kind == extendeddatabase.RelationManyToMany &&
+			(relation.JoinTable == "" || relation.JoinLocalColumn == "" ||
+				relation.JoinForeignColumn == "") {
+			panic(fmt.Sprintf(
+				"mustRelations: relation %q requires a join table",
+				relation.Name,
+			))
+		}
+		relation.LocalTable = tableName
+		relation.Columns = nil
+		for _, field := range relation.APIFields {
+			relation.Columns = append(relation.Columns, field.DBColumn)
+		}
+		completed[i] = relation
+	}
+	return completed
+}
+
+// databaseRelations returns the DB relations of the relations.
+func databaseRelations(relations []Relation) []extendeddatabase.Relation {
+	var dbRelations []extendeddatabase.Relation
+	for _, relation := range relations {
+		dbRelations = append(dbRelations, relation.Relation)
+	}
+	return dbRelations
+}
+
 // updatesEntry creates an APIField for updating fields.
 func updatesEntry(from types.APIFields) types.APIField {
 	return types.APIField{
@@ -590,3 +1131,72 @@ func mustApplyErrorMapping(
 	}
 	return newDefaultErrors
 }
+
+// listAPIFields returns a copy of the APIFields with the nested fields typed
+// as lists of objects.
+func listAPIFields(apiFields types.APIFields) types.APIFields {
+	fields := make(types.APIFields, len(apiFields))
+	for i, field := range apiFields {
+		if len(field.Nested) != 0 {
+			field.Type = "slice"
+		}
+		fields[i] = field
+	}
+	return fields
+}
+
+// getOperationOutput creates the typed output fields of a get operation. The
+// entities are a list and the included relations are added to the fields of
+// an entity.
+func getOperationOutput(
+	outputAPIFields types.APIFields, outputKey string, relations []Relation,
+) types.APIFields {
+	var fields types.APIFields
+	for _, field := range outputAPIFields {
+		switch field.APIName {
+		case outputKey:
+			field.Type = "slice"
+			field.Nested = append(types.APIFields{}, field.Nested...)
+			for _, relation := range relations {
+				relationField := types.APIField{
+					APIName: relation.Name,
+					Nested:  relation.APIFields,
+				}
+				if relation.Kind != extendeddatabase.RelationBelongsTo {
+					relationField.Type = "slice"
+				}
+				field.Nested = append(field.Nested, relationField)
+			}
+		case FieldCount:
+			field.Type = "int"
+			field.Required = true
+		case FieldNextCursor, FieldPrevCursor:
+			field.Type = "string"
+		case FieldTotal:
+			field.Type = "int64"
+		case FieldPage:
+			field.Nested = types.APIFields{
+				{APIName: "offset", Type: "int64"},
+				{APIName: "limit", Type: "int64"},
+				{APIName: "has_more", Type: "bool", Required: true},
+			}
+		}
+		fields = append(fields, field)
+	}
+	return fields
+}
+
+// countOperationOutput creates the typed output fields of an update, delete
+// or restore operation. If inserted is set, the inserted flag of an update is
+// included.
+func countOperationOutput(inserted bool) types.APIFields {
+	fields := types.APIFields{
+		{APIName: FieldCount, Type: "int64", Required: true},
+	}
+	if inserted {
+		fields = append(fields, types.APIField{
+			APIName: "inserted", Type: "bool", Required: true,
+		})
+	}
+	return fields
+}
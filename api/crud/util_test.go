@@ -0,0 +1,306 @@
+package crud
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	apiendpoint "github.com/pakkasys/fluidapi-extended/api/endpoint"
+	"github.com/pakkasys/fluidapi-extended/api/types"
+	extendeddatabase "github.com/pakkasys/fluidapi-extended/database"
+	"github.com/pakkasys/fluidapi/database"
+)
+
+func TestKeyedURL(t *testing.T) {
+	tests := []struct {
+		url       string
+		keyFields []string
+		expected  string
+	}{
+		{"/users", []string{"id"}, "/users/{id}"},
+		{"/users/", []string{"id"}, "/users/{id}"},
+		{"/users", []string{"org_id", "id"}, "/users/{org_id}/{id}"},
+	}
+	for _, tt := range tests {
+		if got := keyedURL(tt.url, tt.keyFields); got != tt.expected {
+			t.Errorf("keyedURL(%q, %v) = %q, want %q",
+				tt.url, tt.keyFields, got, tt.expected)
+		}
+	}
+}
+
+func TestBulkURL(t *testing.T) {
+	for _, url := range []string{"/users", "/users/"} {
+		if got := bulkURL(url); got != "/users/bulk" {
+			t.Errorf("bulkURL(%q) = %q, want %q", url, got, "/users/bulk")
+		}
+	}
+}
+
+func TestGenericCreateManyAPIFields(t *testing.T) {
+	apiFields := types.APIFields{
+		{APIName: "user", Nested: types.APIFields{{APIName: "name"}}},
+	}
+	fields := genericCreateManyAPIFields(apiFields, "user", "users", 10)
+	expected := []string{"slice", "min=1", "max=10"}
+	if len(fields) != 1 || fields[0].APIName != "users" ||
+		!reflect.DeepEqual(fields[0].Validate, expected) {
+		t.Errorf("expected users limited by %v, got %+v", expected, fields)
+	}
+	fields = genericCreateManyAPIFields(apiFields, "user", "users", 0)
+	if fields[0].Validate != nil {
+		t.Errorf("expected no limit, got %v", fields[0].Validate)
+	}
+}
+
+func TestMustGetOneAPIFields(t *testing.T) {
+	all := types.APIFields{
+		{APIName: "id", DBColumn: "id", Type: "int64", Default: int64(1)},
+		{APIName: "name", DBColumn: "name", Type: "string"},
+	}
+
+	t.Run("KeyFieldsFromPath", func(t *testing.T) {
+		fields := mustGetOneAPIFields(all, []string{"id"})
+		if len(fields) != 1 {
+			t.Fatalf("expected 1 field, got %d", len(fields))
+		}
+		f := fields[0]
+		if f.APIName != "id" || f.DBColumn != "id" || f.Type != "int64" {
+			t.Errorf("unexpected field: %+v", f)
+		}
+		if f.Source != sourcePath {
+			t.Errorf("expected source %q, got %q", sourcePath, f.Source)
+		}
+		if !f.Required {
+			t.Errorf("expected key field to be required")
+		}
+		if f.Default != nil {
+			t.Errorf("expected key field to have no default, got %v", f.Default)
+		}
+		if all[0].Source != "" {
+			t.Errorf("expected master field to be left untouched")
+		}
+	})
+
+	t.Run("NoKeyFields", func(t *testing.T) {
+		expectPanic(t, func() {
+			mustGetOneAPIFields(all, nil)
+		}, "at least one key field")
+	})
+
+	t.Run("UnknownKeyField", func(t *testing.T) {
+		expectPanic(t, func() {
+			mustGetOneAPIFields(all, []string{"missing"})
+		}, "unknown API field")
+	})
+}
+
+func TestMapsToColsAndValues(t *testing.T) {
+	apiFields := types.APIFields{
+		{APIName: "user_name", DBColumn: "username"},
+		{APIName: "age", DBColumn: "user_age"},
+	}
+
+	t.Run("MapsPresentFields", func(t *testing.T) {
+		items := []any{
+			map[string]any{"user_name": "Alice", "age": int64(30)},
+			map[string]any{"user_name": "Bob", "other": true},
+		}
+		got, err := mapsToColsAndValues(items, apiFields)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		expected := []map[string]any{
+			{"username": "Alice", "user_age": int64(30)},
+			{"username": "Bob"},
+		}
+		if !reflect.DeepEqual(got, expected) {
+			t.Errorf("expected %v, got %v", expected, got)
+		}
+	})
+
+	t.Run("NilItems", func(t *testing.T) {
+		got, err := mapsToColsAndValues(nil, apiFields)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(got) != 0 {
+			t.Errorf("expected no items, got %v", got)
+		}
+	})
+
+	t.Run("ItemNotObject", func(t *testing.T) {
+		_, err := mapsToColsAndValues([]any{"Alice"}, apiFields)
+		if err == nil || !strings.Contains(err.Error(), "item 0") {
+			t.Errorf("expected item error, got %v", err)
+		}
+	})
+}
+
+func TestToGenericGetOutput(t *testing.T) {
+	type entity struct {
+		ID   int64  `db:"id"`
+		Name string `db:"name"`
+	}
+	type user struct {
+		ID   int64  `json:"id"`
+		Name string `json:"name"`
+	}
+	type output struct {
+		Users      []user                 `json:"users"`
+		Count      int                    `json:"count"`
+		Total      int                    `json:"total"`
+		Page       apiendpoint.PageOutput `json:"page"`
+		NextCursor string                 `json:"next_cursor"`
+		PrevCursor string                 `json:"prev_cursor"`
+	}
+	apiFields := types.APIFields{
+		{APIName: "id", DBColumn: "id"},
+		{APIName: "name", DBColumn: "name"},
+	}
+	total := 3
+	result := &apiendpoint.GetResult[entity]{
+		Entities:   []entity{{ID: 1, Name: "alice"}, {ID: 2, Name: "bob"}},
+		Count:      2,
+		Total:      &total,
+		Page:       &database.Page{Offset: 0, Limit: 2},
+		HasMore:    true,
+		NextCursor: "next",
+	}
+
+	out, err := toGenericGetOutput(
+		result,
+		genericGetOutputAPIFields("users", apiFields, true, true),
+		"users",
+		FieldCount,
+		nil,
+		new(output),
+	)
+	if err != nil {
+		t.Fatalf("toGenericGetOutput: %v", err)
+	}
+	expected := &output{
+		Users:      []user{{ID: 1, Name: "alice"}, {ID: 2, Name: "bob"}},
+		Count:      2,
+		Total:      3,
+		Page:       apiendpoint.PageOutput{Offset: 0, Limit: 2, HasMore: true},
+		NextCursor: "next",
+	}
+	if !reflect.DeepEqual(out, expected) {
+		t.Errorf("expected %+v, got %+v", expected, out)
+	}
+
+	t.Run("SelectedFields", func(t *testing.T) {
+		result.Fields = []string{"name"}
+		out, err := toGenericGetOutput(
+			result,
+			genericGetOutputAPIFields("users", apiFields, false, false),
+			"users",
+			FieldCount,
+			nil,
+			new(struct {
+				Users []map[string]any `json:"users"`
+				Count int              `json:"count"`
+			}),
+		)
+		if err != nil {
+			t.Fatalf("toGenericGetOutput: %v", err)
+		}
+		expected := []map[string]any{{"name": "alice"}, {"name": "bob"}}
+		if !reflect.DeepEqual(out.Users, expected) {
+			t.Errorf("expected %v, got %v", expected, out.Users)
+		}
+	})
+	t.Run("Includes", func(t *testing.T) {
+		type post struct {
+			Title string `db:"title"`
+		}
+		relations := []Relation{
+			{
+				Relation: extendeddatabase.Relation{
+					Name:        "posts",
+					Kind:        extendeddatabase.RelationHasMany,
+					LocalColumn: "id",
+				},
+				APIFields: types.APIFields{{APIName: "title", DBColumn: "title"}},
+			},
+			{
+				Relation: extendeddatabase.Relation{
+					Name:        "pinned",
+					Kind:        extendeddatabase.RelationBelongsTo,
+					LocalColumn: "id",
+				},
+				APIFields: types.APIFields{{APIName: "title", DBColumn: "title"}},
+			},
+		}
+		result.Fields = nil
+		result.Includes = databaseRelations(relations)
+		result.Related = map[string]map[string][]any{
+			"posts":  {"1": {&post{Title: "a"}, &post{Title: "b"}}},
+			"pinned": {"1": {&post{Title: "a"}}},
+		}
+		out, err := toGenericGetOutput(
+			result,
+			genericGetOutputAPIFields("users", apiFields, false, false),
+			"users",
+			FieldCount,
+			relations,
+			new(struct {
+				Users []map[string]any `json:"users"`
+				Count int              `json:"count"`
+			}),
+		)
+		if err != nil {
+			t.Fatalf("toGenericGetOutput: %v", err)
+		}
+		expected := []map[string]any{
+			{
+				"id":     int64(1),
+				"name":   "alice",
+				"posts":  []map[string]any{{"title": "a"}, {"title": "b"}},
+				"pinned": map[string]any{"title": "a"},
+			},
+			{
+				"id":     int64(2),
+				"name":   "bob",
+				"posts":  []map[string]any{},
+				"pinned": nil,
+			},
+		}
+		if !reflect.DeepEqual(out.Users, expected) {
+			t.Errorf("expected %v, got %v", expected, out.Users)
+		}
+	})
+}
+
+func TestMustRelations(t *testing.T) {
+	relation := Relation{
+		Relation: extendeddatabase.Relation{
+			Name:          "posts",
+			Kind:          extendeddatabase.RelationHasMany,
+			Table:         "posts",
+			LocalColumn:   "id",
+			ForeignColumn: "user_id",
+			EntityFn:      func() any { return &struct{}{} },
+		},
+		APIFields: types.APIFields{{APIName: "title", DBColumn: "title"}},
+	}
+
+	completed := mustRelations([]Relation{relation}, "users")
+	if completed[0].LocalTable != "users" ||
+		!reflect.DeepEqual(completed[0].Columns, []string{"title"}) {
+		t.Errorf("expected a completed relation, got %+v", completed[0])
+	}
+
+	for _, kind := range []extendeddatabase.RelationKind{"", "has_one"} {
+		invalid := relation
+		invalid.Kind = kind
+		expectPanic(t, func() { mustRelations([]Relation{invalid}, "users") },
+			`mustRelations: relation "posts" has invalid kind`)
+	}
+
+	manyToMany := relation
+	manyToMany.Kind = extendeddatabase.RelationManyToMany
+	expectPanic(t, func() { mustRelations([]Relation{manyToMany}, "users") },
+		`mustRelations: relation "posts" requires a join table`)
+}
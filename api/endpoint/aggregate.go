@@ -0,0 +1,417 @@
+package endpoint
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/pakkasys/fluidapi-extended/api"
+	"github.com/pakkasys/fluidapi-extended/api/repository"
+	extendeddatabase "github.com/pakkasys/fluidapi-extended/database"
+	"github.com/pakkasys/fluidapi/core"
+	"github.com/pakkasys/fluidapi/database"
+	"github.com/pakkasys/fluidapi/endpoint"
+)
+
+// Input fields of the aggregate endpoint.
+const (
+	FieldGroupBy    = "group_by"
+	FieldAggregates = "aggregates"
+	FieldHaving     = "having"
+)
+
+// InvalidAggregateError is returned when the groups, aggregates, having
+// conditions or orders of an aggregate request are invalid.
+var InvalidAggregateError = core.NewAPIError("INVALID_AGGREGATE")
+
+// AggregateInput is the input of the aggregate endpoint. GroupBy is a comma
+// separated list of fields with an optional date bucket, Aggregates a comma
+// separated list of functions with an optional field, Having a comma separated
+// list of alias:predicate:value conditions and Orders a comma separated list
+// of aliases with an optional direction.
+//
+// Example:
+//
+//	group_by=status,created_at:month
+//	aggregates=count,sum:amount
+//	having=sum_amount:gte:100
+//	orders=sum_amount:desc
+type AggregateInput struct {
+	Selectors      endpoint.Selectors `json:"selectors"`
+	GroupBy        string             `json:"group_by"`
+	Aggregates     string             `json:"aggregates"`
+	Having         string             `json:"having"`
+	Orders         string             `json:"orders"`
+	Page           *endpoint.Page     `json:"page"`
+	IncludeDeleted bool               `json:"include_deleted"`
+}
+
+// AggregateOutput is the output of the aggregate endpoint. Each group holds
+// the values of the groups and aggregates by alias.
+type AggregateOutput struct {
+	Groups []map[string]any `json:"groups"`
+	Count  int              `json:"count"`
+}
+
+// AggregateOptions configures the groups and aggregates of the aggregate
+// endpoint.
+type AggregateOptions struct {
+	// Table is the aggregated table.
+	Table string
+	// GroupFields are the fields the rows can be grouped by.
+	GroupFields APIToDBFields
+	// DateFields are the group fields that can be bucketed by date.
+	DateFields []string
+	// Fields are the numeric fields that can be aggregated.
+	Fields APIToDBFields
+}
+
+// aggregateFunctions maps the functions of aggregate requests to SQL
+// aggregate functions.
+var aggregateFunctions = map[string]extendeddatabase.AggregateFunction{
+	"count": extendeddatabase.AggregateCount,
+	"sum":   extendeddatabase.AggregateSum,
+	"avg":   extendeddatabase.AggregateAvg,
+	"min":   extendeddatabase.AggregateMin,
+	"max":   extendeddatabase.AggregateMax,
+}
+
+// dateBuckets are the date buckets of aggregate requests.
+var dateBuckets = []extendeddatabase.DateBucket{
+	extendeddatabase.BucketHour,
+	extendeddatabase.BucketDay,
+	extendeddatabase.BucketWeek,
+	extendeddatabase.BucketMonth,
+	extendeddatabase.BucketYear,
+}
+
+func AggregateErrors() api.ExpectedErrors {
+	return api.ExpectedErrors{
+		{ID: endpoint.InvalidPredicateError.ID, Status: http.StatusBadRequest, PublicData: true},
+		{ID: endpoint.PredicateNotAllowedError.ID, Status: http.StatusBadRequest, PublicData: true},
+		{ID: endpoint.InvalidSelectorFieldError.ID, Status: http.StatusBadRequest, PublicData: true},
+		{ID: endpoint.MaxPageLimitExceededError.ID, Status: http.StatusBadRequest, PublicData: true},
+		{ID: InvalidAggregateError.ID, Status: http.StatusBadRequest, PublicData: true},
+	}.With(TransientErrors()...)
+}
+
+// GenericAggregateDefinition builds the endpoint definition for an aggregate
+// operation. The rows that match the selectors are grouped and the aggregates
+// of each group are returned. If softDeleteField is set, soft deleted rows are
+// excluded unless the input includes them. If pageOptions is nil,
+// DefaultGetPageOptions is used.
+func GenericAggregateDefinition(
+	url string,
+	inputHandler InputHandler,
+	apiToDBFields APIToDBFields,
+	aggregateOptions *AggregateOptions,
+	softDeleteField *endpoint.DBField,
+	pageOptions *GetPageOptions,
+	connFn repository.ConnFn,
+	beforeCallback func(ctx context.Context, input *AggregateInput) error,
+	loggerFactoryFn LoggerFactoryFn,
+	aggregator repository.Aggregator,
+	txManager repository.TxManager[[]map[string]any],
+	systemId string,
+) *EndpointHandler[AggregateInput] {
+	if pageOptions == nil {
+		pageOptions = DefaultGetPageOptions()
+	}
+	invokeFn := func(
+		w http.ResponseWriter, r *http.Request, input *AggregateInput,
+	) (any, error) {
+		opts, err := ParseAggregateEndpointInput(
+			apiToDBFields, aggregateOptions, pageOptions, input,
+		)
+		if err != nil {
+			return nil, err
+		}
+		opts.Selectors = notDeletedSelectors(
+			opts.Selectors, softDeleteField, input.IncludeDeleted,
+		)
+		if beforeCallback != nil {
+			if err := beforeCallback(r.Context(), input); err != nil {
+				return nil, err
+			}
+		}
+		groups, err := txManager.WithTransaction(
+			r.Context(),
+			connFn,
+			func(
+				ctx context.Context, tx database.Tx,
+			) ([]map[string]any, error) {
+				return aggregator.Aggregate(tx, aggregateOptions.Table, opts)
+			},
+		)
+		if err != nil {
+			return nil, err
+		}
+		return &AggregateOutput{Groups: groups, Count: len(groups)}, nil
+	}
+	return GenericEndpointDefinition(
+		url,
+		http.MethodGet,
+		inputHandler,
+		func() AggregateInput { return AggregateInput{} },
+		NewErrorBuilder(systemId).With(AggregateErrors()).Build(),
+		invokeFn,
+		loggerFactoryFn,
+		systemId,
+	)
+}
+
+// ParseAggregateEndpointInput translates an aggregate request to the options
+// of an aggregate query. If no aggregates are given, the rows of each group
+// are counted.
+//
+// Parameters:
+//   - apiToDBFields: The DB fields of the selectable API fields.
+//   - aggregateOptions: The groups and aggregates of the endpoint.
+//   - pageOptions: The page limits of the endpoint.
+//   - input: The aggregate request.
+//
+// Returns:
+//   - *extendeddatabase.AggregateOptions: The options of the query.
+//   - error: InvalidAggregateError if the request is invalid. Invalid
+//     selectors and page limits return the corresponding errors.
+func ParseAggregateEndpointInput(
+	apiToDBFields APIToDBFields,
+	aggregateOptions *AggregateOptions,
+	pageOptions *GetPageOptions,
+	input *AggregateInput,
+) (*extendeddatabase.AggregateOptions, error) {
+	selectors, err := input.Selectors.ToDBSelectors(apiToDBFields)
+	if err != nil {
+		return nil, err
+	}
+	page := input.Page
+	if page == nil {
+		page = &endpoint.Page{Offset: 0, Limit: pageOptions.DefaultLimit}
+	}
+	if page.Limit == 0 {
+		page.Limit = pageOptions.DefaultLimit
+	}
+	if page.Limit > pageOptions.MaxLimit {
+		return nil, endpoint.MaxPageLimitExceededError.WithData(
+			pageOptions.MaxLimit,
+		)
+	}
+	groupBy, err := parseGroupBy(aggregateOptions, input.GroupBy)
+	if err != nil {
+		return nil, err
+	}
+	aggregates, err := parseAggregates(aggregateOptions, input.Aggregates)
+	if err != nil {
+		return nil, err
+	}
+	aliases := make([]string, 0, len(groupBy)+len(aggregates))
+	for _, group := range groupBy {
+		aliases = append(aliases, group.Alias)
+	}
+	for _, aggregate := range aggregates {
+		if slices.Contains(aliases, aggregate.Alias) {
+			return nil, InvalidAggregateError.WithData(
+				fmt.Sprintf("duplicate alias %q", aggregate.Alias),
+			)
+		}
+		aliases = append(aliases, aggregate.Alias)
+	}
+	having, err := parseHaving(aggregates, input.Having)
+	if err != nil {
+		return nil, err
+	}
+	orders, err := parseAggregateOrders(aliases, input.Orders)
+	if err != nil {
+		return nil, err
+	}
+	return &extendeddatabase.AggregateOptions{
+		Selectors:  selectors,
+		GroupBy:    groupBy,
+		Aggregates: aggregates,
+		Having:     having,
+		Orders:     orders,
+		Page:       page.ToDBPage(),
+	}, nil
+}
+
+// parseGroupBy parses the groups of an aggregate request. The alias of a
+// bucketed group is the field name and the bucket joined with an underscore.
+func parseGroupBy(
+	options *AggregateOptions, groupBy string,
+) ([]extendeddatabase.GroupBy, error) {
+	var groups []extendeddatabase.GroupBy
+	for _, part := range splitList(groupBy) {
+		name, bucket, bucketed := strings.Cut(part, ":")
+		dbField, ok := options.GroupFields[name]
+		if !ok {
+			return nil, InvalidAggregateError.WithData(
+				fmt.Sprintf("field %q can not be grouped", name),
+			)
+		}
+		group := extendeddatabase.GroupBy{
+			Table:  dbField.Table,
+			Column: dbField.Column,
+			Alias:  name,
+		}
+		if bucketed {
+			dateBucket := extendeddatabase.DateBucket(bucket)
+			if !slices.Contains(options.DateFields, name) ||
+				!slices.Contains(dateBuckets, dateBucket) {
+				return nil, InvalidAggregateError.WithData(
+					fmt.Sprintf("invalid date bucket %q", part),
+				)
+			}
+			group.Bucket = dateBucket
+			group.Alias = name + "_" + bucket
+		}
+		if slices.ContainsFunc(groups, func(g extendeddatabase.GroupBy) bool {
+			return g.Alias == group.Alias
+		}) {
+			return nil, InvalidAggregateError.WithData(
+				fmt.Sprintf("duplicate group %q", part),
+			)
+		}
+		groups = append(groups, group)
+	}
+	return groups, nil
+}
+
+// parseAggregates parses the aggregates of an aggregate request. The alias of
+// an aggregate is the function, and the field name joined with an underscore
+// if the aggregate has a field. Only count can be used without a field.
+func parseAggregates(
+	options *AggregateOptions, aggregates string,
+) ([]extendeddatabase.Aggregate, error) {
+	parts := splitList(aggregates)
+	if len(parts) == 0 {
+		parts = []string{"count"}
+	}
+	var parsed []extendeddatabase.Aggregate
+	for _, part := range parts {
+		name, fieldName, hasField := strings.Cut(part, ":")
+		function, ok := aggregateFunctions[name]
+		if !ok {
+			return nil, InvalidAggregateError.WithData(
+				fmt.Sprintf("invalid aggregate function %q", name),
+			)
+		}
+		aggregate := extendeddatabase.Aggregate{
+			Function: function,
+			Alias:    name,
+		}
+		if hasField {
+			dbField, ok := options.Fields[fieldName]
+			if !ok {
+				return nil, InvalidAggregateError.WithData(
+					fmt.Sprintf("field %q can not be aggregated", fieldName),
+				)
+			}
+			aggregate.Table = dbField.Table
+			aggregate.Column = dbField.Column
+			aggregate.Alias = name + "_" + fieldName
+		} else if function != extendeddatabase.AggregateCount {
+			return nil, InvalidAggregateError.WithData(
+				fmt.Sprintf("aggregate %q requires a field", name),
+			)
+		}
+		if slices.ContainsFunc(
+			parsed,
+			func(a extendeddatabase.Aggregate) bool {
+				return a.Alias == aggregate.Alias
+			},
+		) {
+			continue
+		}
+		parsed = append(parsed, aggregate)
+	}
+	return parsed, nil
+}
+
+// parseHaving parses the having conditions of an aggregate request. The
+// conditions compare the aggregates by alias to numbers and all of them must
+// match.
+func parseHaving(
+	aggregates []extendeddatabase.Aggregate, having string,
+) ([]extendeddatabase.Having, error) {
+	var conditions []extendeddatabase.Having
+	for _, part := range splitList(having) {
+		fields := strings.SplitN(part, ":", 3)
+		if len(fields) != 3 {
+			return nil, InvalidAggregateError.WithData(
+				fmt.Sprintf("invalid having condition %q", part),
+			)
+		}
+		index := slices.IndexFunc(
+			aggregates,
+			func(a extendeddatabase.Aggregate) bool {
+				return a.Alias == fields[0]
+			},
+		)
+		if index == -1 {
+			return nil, InvalidAggregateError.WithData(
+				fmt.Sprintf("unknown aggregate %q", fields[0]),
+			)
+		}
+		predicate, ok := filterPredicates[fields[1]]
+		if !ok || predicate == "IN" || predicate == "NOT IN" {
+			return nil, InvalidAggregateError.WithData(
+				fmt.Sprintf("invalid having predicate %q", fields[1]),
+			)
+		}
+		value, err := strconv.ParseFloat(fields[2], 64)
+		if err != nil {
+			return nil, InvalidAggregateError.WithData(
+				fmt.Sprintf("invalid having value %q", fields[2]),
+			)
+		}
+		conditions = append(conditions, extendeddatabase.Having{
+			Aggregate: aggregates[index],
+			Predicate: predicate,
+			Value:     value,
+		})
+	}
+	return conditions, nil
+}
+
+// parseAggregateOrders parses the orders of an aggregate request. The groups
+// are ordered by the aliases of the groups and aggregates.
+func parseAggregateOrders(
+	aliases []string, orders string,
+) ([]database.Order, error) {
+	var parsed []database.Order
+	for _, part := range splitList(orders) {
+		alias, direction, _ := strings.Cut(part, ":")
+		if !slices.Contains(aliases, alias) {
+			return nil, InvalidAggregateError.WithData(
+				fmt.Sprintf("unknown order alias %q", alias),
+			)
+		}
+		order := database.Order{Field: alias, Direction: "ASC"}
+		switch strings.ToLower(direction) {
+		case "", "asc":
+		case "desc":
+			order.Direction = "DESC"
+		default:
+			return nil, InvalidAggregateError.WithData(
+				fmt.Sprintf("invalid order direction %q", direction),
+			)
+		}
+		parsed = append(parsed, order)
+	}
+	return parsed, nil
+}
+
+// splitList splits a comma separated list and trims the items. Empty items
+// are skipped.
+func splitList(list string) []string {
+	var items []string
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
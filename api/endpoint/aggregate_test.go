@@ -0,0 +1,111 @@
+package endpoint
+
+import (
+	"reflect"
+	"testing"
+
+	extendeddatabase "github.com/pakkasys/fluidapi-extended/database"
+	"github.com/pakkasys/fluidapi/database"
+	"github.com/pakkasys/fluidapi/endpoint"
+)
+
+func TestParseAggregateEndpointInput(t *testing.T) {
+	options := &AggregateOptions{
+		Table: "orders",
+		GroupFields: APIToDBFields{
+			"status":  {Table: "orders", Column: "status"},
+			"created": {Table: "orders", Column: "created_at"},
+		},
+		DateFields: []string{"created"},
+		Fields: APIToDBFields{
+			"amount": {Table: "orders", Column: "amount"},
+		},
+	}
+	pageOptions := DefaultGetPageOptions()
+
+	t.Run("Aggregates", func(t *testing.T) {
+		opts, err := ParseAggregateEndpointInput(
+			nil,
+			options,
+			pageOptions,
+			&AggregateInput{
+				GroupBy:    "status, created:month",
+				Aggregates: "count,sum:amount",
+				Having:     "sum_amount:gte:100",
+				Orders:     "sum_amount:desc,status",
+			},
+		)
+		if err != nil {
+			t.Fatalf("ParseAggregateEndpointInput: %v", err)
+		}
+		sum := extendeddatabase.Aggregate{
+			Function: extendeddatabase.AggregateSum,
+			Table:    "orders",
+			Column:   "amount",
+			Alias:    "sum_amount",
+		}
+		expected := &extendeddatabase.AggregateOptions{
+			GroupBy: []extendeddatabase.GroupBy{
+				{Table: "orders", Column: "status", Alias: "status"},
+				{
+					Table:  "orders",
+					Column: "created_at",
+					Bucket: extendeddatabase.BucketMonth,
+					Alias:  "created_month",
+				},
+			},
+			Aggregates: []extendeddatabase.Aggregate{
+				{Function: extendeddatabase.AggregateCount, Alias: "count"},
+				sum,
+			},
+			Having: []extendeddatabase.Having{
+				{Aggregate: sum, Predicate: ">=", Value: float64(100)},
+			},
+			Orders: []database.Order{
+				{Field: "sum_amount", Direction: "DESC"},
+				{Field: "status", Direction: "ASC"},
+			},
+			Page: &database.Page{Offset: 0, Limit: pageOptions.DefaultLimit},
+		}
+		opts.Selectors = nil
+		if !reflect.DeepEqual(opts, expected) {
+			t.Errorf("expected %+v, got %+v", expected, opts)
+		}
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		tests := []AggregateInput{
+			{GroupBy: "amount"},
+			{GroupBy: "status:month"},
+			{GroupBy: "created:decade"},
+			{Aggregates: "median:amount"},
+			{Aggregates: "sum"},
+			{Aggregates: "sum:status"},
+			{Having: "count:gt"},
+			{Having: "sum_amount:gt:1"},
+			{Having: "count:in:1"},
+			{Having: "count:gt:many"},
+			{Orders: "amount"},
+			{Orders: "count:up"},
+			{GroupBy: "status,status"},
+		}
+		for _, input := range tests {
+			_, err := ParseAggregateEndpointInput(
+				nil, options, pageOptions, &input,
+			)
+			expectAPIError(t, err, InvalidAggregateError.ID)
+		}
+	})
+
+	t.Run("MaxPageLimit", func(t *testing.T) {
+		_, err := ParseAggregateEndpointInput(
+			nil,
+			options,
+			pageOptions,
+			&AggregateInput{
+				Page: &endpoint.Page{Limit: pageOptions.MaxLimit + 1},
+			},
+		)
+		expectAPIError(t, err, endpoint.MaxPageLimitExceededError.ID)
+	})
+}
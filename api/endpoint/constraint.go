@@ -0,0 +1,142 @@
+package endpoint
+
+import (
+	"net/http"
+
+	"github.com/pakkasys/fluidapi-extended/api"
+	"github.com/pakkasys/fluidapi-extended/api/types"
+	extendeddatabase "github.com/pakkasys/fluidapi-extended/database"
+	"github.com/pakkasys/fluidapi/core"
+)
+
+// Messages of the field errors of violated constraints.
+const (
+	DuplicateEntryMessage    = "value already exists"
+	ForeignConstraintMessage = "referenced value does not exist"
+)
+
+// ConstraintFieldErrorFn returns a function that maps the constraint errors
+// of the database to the input fields. The columns of a DuplicateEntryError
+// or a ForeignConstraintError are mapped to the APIFields by their DB columns
+// and returned as ValidationErrorData, the same way as the validation errors
+// of the input. The other data of the constraint errors is removed, so the
+// database errors can be public.
+//
+// Example:
+//
+//	mapErrorFn := ConstraintFieldErrorFn(
+//	    types.APIFields{{APIName: "email", DBColumn: "email_address"}},
+//	    "user",
+//	)
+//	mapErrorFn(extendeddatabase.DuplicateEntryError.WithData(
+//	    &extendeddatabase.ConstraintViolation{Columns: []string{"email_address"}},
+//	))
+//
+// Output:
+//
+//	DuplicateEntryError with data:
+//	api.ValidationErrorData{
+//	    Errors: []api.FieldError{
+//	        {Field: "user.email", Message: "value already exists"},
+//	    },
+//	}
+//
+// Parameters:
+//   - apiFields: The APIFields of the input.
+//   - path: The path of the APIFields in the input, or empty at the root.
+//
+// Returns:
+//   - func(error) error: The function that maps the errors.
+func ConstraintFieldErrorFn(
+	apiFields types.APIFields, path string,
+) func(error) error {
+	return func(err error) error {
+		apiErr, ok := err.(*core.APIError)
+		if !ok {
+			return err
+		}
+		var message string
+		switch apiErr.ID {
+		case extendeddatabase.DuplicateEntryError.ID:
+			message = DuplicateEntryMessage
+		case extendeddatabase.ForeignConstraintError.ID:
+			message = ForeignConstraintMessage
+		default:
+			return err
+		}
+		violation, ok := apiErr.Data.(*extendeddatabase.ConstraintViolation)
+		if !ok {
+			return apiErr.WithData(nil)
+		}
+		var fieldErrors []api.FieldError
+		for _, column := range violation.Columns {
+			for _, field := range apiFields {
+				if field.DBColumn != column {
+					continue
+				}
+				fieldErrors = append(fieldErrors, api.FieldError{
+					Field:   constraintFieldPath(path, field.APIName),
+					Message: message,
+				})
+			}
+		}
+		if len(fieldErrors) == 0 {
+			return apiErr.WithData(nil)
+		}
+		return apiErr.WithData(api.ValidationErrorData{Errors: fieldErrors})
+	}
+}
+
+// WithConstraintFieldErrors returns a copy of the expected errors where the
+// data of the constraint errors that ConstraintFieldErrorFn maps to field
+// errors is public.
+//
+// Parameters:
+//   - expectedErrors: The expected errors.
+//
+// Returns:
+//   - api.ExpectedErrors: The expected errors with public field errors.
+func WithConstraintFieldErrors(
+	expectedErrors api.ExpectedErrors,
+) api.ExpectedErrors {
+	newErrors := append(api.ExpectedErrors{}, expectedErrors...)
+	for i := range newErrors {
+		switch newErrors[i].ID {
+		case extendeddatabase.DuplicateEntryError.ID,
+			extendeddatabase.ForeignConstraintError.ID:
+			newErrors[i].PublicData = true
+		}
+	}
+	return newErrors
+}
+
+// mapHandlerErrors returns the handler logic with its errors mapped by
+// mapErrorFn.
+func mapHandlerErrors[Input any](
+	handlerLogic func(
+		w http.ResponseWriter, r *http.Request, i *Input,
+	) (any, error),
+	mapErrorFn func(error) error,
+) func(w http.ResponseWriter, r *http.Request, i *Input) (any, error) {
+	if mapErrorFn == nil {
+		return handlerLogic
+	}
+	return func(
+		w http.ResponseWriter, r *http.Request, i *Input,
+	) (any, error) {
+		out, err := handlerLogic(w, r, i)
+		if err != nil {
+			return nil, mapErrorFn(err)
+		}
+		return out, nil
+	}
+}
+
+// constraintFieldPath returns the path of a field within its parent path, as
+// the paths of the validation errors.
+func constraintFieldPath(parentPath string, field string) string {
+	if parentPath == "" {
+		return field
+	}
+	return parentPath + "." + field
+}
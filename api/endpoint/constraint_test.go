@@ -0,0 +1,116 @@
+package endpoint
+
+import (
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/pakkasys/fluidapi-extended/api"
+	"github.com/pakkasys/fluidapi-extended/api/types"
+	extendeddatabase "github.com/pakkasys/fluidapi-extended/database"
+	"github.com/pakkasys/fluidapi/core"
+)
+
+func TestConstraintFieldErrorFn(t *testing.T) {
+	mapErrorFn := ConstraintFieldErrorFn(types.APIFields{
+		{APIName: "email", DBColumn: "email_address"},
+		{APIName: "group", DBColumn: "group_id"},
+	}, "user")
+	dbErr := errors.New("UNIQUE constraint failed: users.email_address")
+
+	t.Run("DuplicateEntry", func(t *testing.T) {
+		err := mapErrorFn(extendeddatabase.DuplicateEntryError.WithData(
+			&extendeddatabase.ConstraintViolation{
+				Columns: []string{"email_address"}, Err: dbErr,
+			},
+		).WithOrigin("db"))
+		expected := api.ValidationErrorData{Errors: []api.FieldError{
+			{Field: "user.email", Message: DuplicateEntryMessage},
+		}}
+		expectAPIError(t, err, extendeddatabase.DuplicateEntryError.ID)
+		apiErr := err.(*core.APIError)
+		if !reflect.DeepEqual(apiErr.Data, expected) || apiErr.Origin != "db" {
+			t.Errorf("expected %v from db, got %v", expected, apiErr)
+		}
+	})
+
+	t.Run("ForeignConstraint", func(t *testing.T) {
+		err := mapErrorFn(extendeddatabase.ForeignConstraintError.WithData(
+			&extendeddatabase.ConstraintViolation{
+				Columns: []string{"group_id"}, Err: dbErr,
+			},
+		))
+		expected := api.ValidationErrorData{Errors: []api.FieldError{
+			{Field: "user.group", Message: ForeignConstraintMessage},
+		}}
+		if data := err.(*core.APIError).Data; !reflect.DeepEqual(data, expected) {
+			t.Errorf("expected %v, got %v", expected, data)
+		}
+	})
+
+	t.Run("UnknownColumns", func(t *testing.T) {
+		for _, data := range []any{
+			&extendeddatabase.ConstraintViolation{
+				Columns: []string{"id"}, Err: dbErr,
+			},
+			&extendeddatabase.ConstraintViolation{Err: dbErr},
+			dbErr,
+		} {
+			err := mapErrorFn(extendeddatabase.DuplicateEntryError.WithData(data))
+			expectAPIError(t, err, extendeddatabase.DuplicateEntryError.ID)
+			if data := err.(*core.APIError).Data; data != nil {
+				t.Errorf("expected the data to be removed, got %v", data)
+			}
+		}
+	})
+
+	t.Run("OtherErrors", func(t *testing.T) {
+		for _, err := range []error{
+			extendeddatabase.NotNullViolationError.WithData(dbErr), dbErr,
+		} {
+			if mapped := mapErrorFn(err); mapped != err {
+				t.Errorf("expected %v to be returned as is, got %v", err, mapped)
+			}
+		}
+	})
+}
+
+func TestWithConstraintFieldErrors(t *testing.T) {
+	expectedErrors := CreateErrors()
+	publicErrors := WithConstraintFieldErrors(expectedErrors)
+	for _, id := range []string{
+		extendeddatabase.DuplicateEntryError.ID,
+		extendeddatabase.ForeignConstraintError.ID,
+	} {
+		if !publicErrors.GetByID(id).PublicData {
+			t.Errorf("expected the data of %s to be public", id)
+		}
+		if expectedErrors.GetByID(id).PublicData {
+			t.Errorf("expected the data of %s to be copied", id)
+		}
+	}
+	if publicErrors.GetByID(extendeddatabase.NotNullViolationError.ID).PublicData {
+		t.Errorf("expected the data of the other errors to stay private")
+	}
+}
+
+func TestMapHandlerErrors(t *testing.T) {
+	handlerErr := errors.New("handler")
+	mappedErr := errors.New("mapped")
+	handlerLogic := mapHandlerErrors(
+		func(w http.ResponseWriter, r *http.Request, i *string) (any, error) {
+			return *i, handlerErr
+		},
+		func(err error) error {
+			if err != handlerErr {
+				t.Errorf("expected the handler error, got %v", err)
+			}
+			return mappedErr
+		},
+	)
+	input := "input"
+	if out, err := handlerLogic(nil, nil, &input); out != nil || err != mappedErr {
+		t.Errorf("expected the mapped error, got %v %v", out, err)
+	}
+}
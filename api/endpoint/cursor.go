@@ -0,0 +1,364 @@
+package endpoint
+
+import (
+	"database/sql/driver"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"strings"
+	"time"
+
+	extendeddatabase "github.com/pakkasys/fluidapi-extended/database"
+	"github.com/pakkasys/fluidapi/core"
+	"github.com/pakkasys/fluidapi/database"
+	"github.com/pakkasys/fluidapi/endpoint"
+)
+
+// FieldCursor is the input field used to request a page by cursor.
+const FieldCursor = "cursor"
+
+// InvalidCursorError is returned when a cursor cannot be decoded or does not
+// match the orders of the request.
+var InvalidCursorError = core.NewAPIError("INVALID_CURSOR")
+
+// CursorValuesFn returns the values of the given columns of an entity.
+type CursorValuesFn[Entity any] func(
+	entity Entity, columns []string,
+) ([]any, error)
+
+// ParsedCursor holds the cursor pagination state of a get request.
+type ParsedCursor struct {
+	// Orders are the orders of the returned page, including the key columns.
+	Orders []database.Order
+	// Keyset selects the rows after the cursor. It is nil on the first page.
+	Keyset *extendeddatabase.Keyset
+	// Backward is set if the page is read backwards from the cursor.
+	Backward bool
+	// Limit is the maximum number of rows in the page.
+	Limit int
+	// HasPrevious is set if there are rows before the page.
+	HasPrevious bool
+}
+
+// cursor is the decoded content of a cursor.
+type cursor struct {
+	Orders   string        `json:"o"`
+	Backward bool          `json:"b,omitempty"`
+	Values   []cursorValue `json:"v"`
+}
+
+// cursorValue is a typed cursor value. The type is kept so that the value is
+// bound to the query with the same type it was read with.
+type cursorValue struct {
+	Type  string          `json:"t"`
+	Value json.RawMessage `json:"v,omitempty"`
+}
+
+// Cursor value types.
+const (
+	cursorTypeNil    = "n"
+	cursorTypeInt    = "i"
+	cursorTypeUint   = "u"
+	cursorTypeFloat  = "f"
+	cursorTypeBool   = "b"
+	cursorTypeString = "s"
+	cursorTypeBytes  = "x"
+	cursorTypeTime   = "t"
+)
+
+// EncodeCursor encodes the order values of a row into an opaque cursor.
+//
+// Parameters:
+//   - orders: The orders of the page, including the key columns.
+//   - values: The values of the ordered columns of the row.
+//   - backward: Whether the cursor reads the rows before the row.
+//
+// Returns:
+//   - string: The cursor.
+//   - error: An error if a value cannot be encoded.
+func EncodeCursor(
+	orders []database.Order, values []any, backward bool,
+) (string, error) {
+	if len(orders) != len(values) {
+		return "", fmt.Errorf(
+			"EncodeCursor: got %d values for %d orders",
+			len(values),
+			len(orders),
+		)
+	}
+	c := cursor{
+		Orders:   ordersFingerprint(orders),
+		Backward: backward,
+		Values:   make([]cursorValue, len(values)),
+	}
+	for i, value := range values {
+		encoded, err := encodeCursorValue(value)
+		if err != nil {
+			return "", err
+		}
+		c.Values[i] = *encoded
+	}
+	data, err := json.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// DecodeCursor decodes a cursor created with EncodeCursor.
+//
+// Parameters:
+//   - encoded: The cursor.
+//   - orders: The orders of the page, including the key columns.
+//
+// Returns:
+//   - []any: The values of the ordered columns.
+//   - bool: Whether the cursor reads the rows before the row.
+//   - error: InvalidCursorError if the cursor is invalid or was created for
+//     other orders.
+func DecodeCursor(
+	encoded string, orders []database.Order,
+) ([]any, bool, error) {
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, false, InvalidCursorError
+	}
+	var c cursor
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, false, InvalidCursorError
+	}
+	if c.Orders != ordersFingerprint(orders) ||
+		len(c.Values) != len(orders) {
+		return nil, false, InvalidCursorError
+	}
+	values := make([]any, len(c.Values))
+	for i, value := range c.Values {
+		decoded, err := decodeCursorValue(value)
+		if err != nil {
+			return nil, false, InvalidCursorError
+		}
+		values[i] = decoded
+	}
+	return values, c.Backward, nil
+}
+
+// ParseCursor parses the cursor pagination state of a get request. The key
+// columns are appended to the orders as a tiebreaker unless they are already
+// ordered. If the cursor reads backwards, the orders used in the query are
+// reversed.
+//
+// Parameters:
+//   - orders: The orders of the request.
+//   - keyFields: The key columns that uniquely identify a row.
+//   - encoded: The cursor of the request, empty for the first page.
+//   - page: The page of the request.
+//
+// Returns:
+//   - *ParsedCursor: The cursor pagination state.
+//   - []database.Order: The orders of the query.
+//   - error: InvalidCursorError if the cursor is invalid.
+func ParseCursor(
+	orders []database.Order,
+	keyFields []endpoint.DBField,
+	encoded string,
+	page *database.Page,
+) (*ParsedCursor, []database.Order, error) {
+	pageOrders := append([]database.Order{}, orders...)
+	for _, keyField := range keyFields {
+		if !hasOrder(pageOrders, keyField) {
+			pageOrders = append(pageOrders, database.Order{
+				Table:     keyField.Table,
+				Field:     keyField.Column,
+				Direction: "ASC",
+			})
+		}
+	}
+	parsed := &ParsedCursor{
+		Orders:      pageOrders,
+		Limit:       page.Limit,
+		HasPrevious: page.Offset > 0,
+	}
+	if encoded == "" {
+		return parsed, pageOrders, nil
+	}
+
+	values, backward, err := DecodeCursor(encoded, pageOrders)
+	if err != nil {
+		return nil, nil, err
+	}
+	queryOrders := pageOrders
+	if backward {
+		queryOrders = reverseOrders(pageOrders)
+	}
+	parsed.Keyset = extendeddatabase.NewKeyset(queryOrders, values)
+	parsed.Backward = backward
+	parsed.HasPrevious = true
+	return parsed, queryOrders, nil
+}
+
+// pageCursors returns the next and previous cursors of a page.
+func pageCursors[Entity any](
+	parsedCursor *ParsedCursor,
+	entities []Entity,
+	hasMore bool,
+	cursorValuesFn CursorValuesFn[Entity],
+) (string, string, error) {
+	if len(entities) == 0 {
+		return "", "", nil
+	}
+	hasNext, hasPrevious := hasMore, parsedCursor.HasPrevious
+	if parsedCursor.Backward {
+		hasNext, hasPrevious = true, hasMore
+	}
+
+	columns := make([]string, len(parsedCursor.Orders))
+	for i, order := range parsedCursor.Orders {
+		columns[i] = order.Field
+	}
+	var next, previous string
+	if hasNext {
+		values, err := cursorValuesFn(entities[len(entities)-1], columns)
+		if err != nil {
+			return "", "", err
+		}
+		next, err = EncodeCursor(parsedCursor.Orders, values, false)
+		if err != nil {
+			return "", "", err
+		}
+	}
+	if hasPrevious {
+		values, err := cursorValuesFn(entities[0], columns)
+		if err != nil {
+			return "", "", err
+		}
+		previous, err = EncodeCursor(parsedCursor.Orders, values, true)
+		if err != nil {
+			return "", "", err
+		}
+	}
+	return next, previous, nil
+}
+
+// hasOrder returns true if the field is ordered.
+func hasOrder(orders []database.Order, field endpoint.DBField) bool {
+	for _, order := range orders {
+		if order.Table == field.Table && order.Field == field.Column {
+			return true
+		}
+	}
+	return false
+}
+
+// reverseOrders returns the orders with their directions reversed.
+func reverseOrders(orders []database.Order) []database.Order {
+	reversed := make([]database.Order, len(orders))
+	for i, order := range orders {
+		reversed[i] = order
+		if extendeddatabase.IsDescending(string(order.Direction)) {
+			reversed[i].Direction = "ASC"
+		} else {
+			reversed[i].Direction = "DESC"
+		}
+	}
+	return reversed
+}
+
+// ordersFingerprint returns a short fingerprint of the orders. It is used to
+// reject cursors that were created for other orders.
+func ordersFingerprint(orders []database.Order) string {
+	parts := make([]string, len(orders))
+	for i, order := range orders {
+		direction := "a"
+		if extendeddatabase.IsDescending(string(order.Direction)) {
+			direction = "d"
+		}
+		parts[i] = order.Table + "." + order.Field + ":" + direction
+	}
+	hash := fnv.New32a()
+	hash.Write([]byte(strings.Join(parts, ",")))
+	return fmt.Sprintf("%08x", hash.Sum32())
+}
+
+// encodeCursorValue encodes a value with its type.
+func encodeCursorValue(value any) (*cursorValue, error) {
+	if valuer, ok := value.(driver.Valuer); ok {
+		driverValue, err := valuer.Value()
+		if err != nil {
+			return nil, err
+		}
+		value = driverValue
+	}
+	var valueType string
+	switch v := value.(type) {
+	case nil:
+		return &cursorValue{Type: cursorTypeNil}, nil
+	case int, int8, int16, int32, int64:
+		valueType = cursorTypeInt
+	case uint, uint8, uint16, uint32, uint64:
+		valueType = cursorTypeUint
+	case float32, float64:
+		valueType = cursorTypeFloat
+	case bool:
+		valueType = cursorTypeBool
+	case string:
+		valueType = cursorTypeString
+	case []byte:
+		valueType = cursorTypeBytes
+	case time.Time:
+		valueType = cursorTypeTime
+		value = v.Format(time.RFC3339Nano)
+	default:
+		return nil, fmt.Errorf(
+			"encodeCursorValue: unsupported cursor value type %T", value,
+		)
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	return &cursorValue{Type: valueType, Value: data}, nil
+}
+
+// decodeCursorValue decodes a typed value.
+func decodeCursorValue(value cursorValue) (any, error) {
+	var err error
+	switch value.Type {
+	case cursorTypeNil:
+		return nil, nil
+	case cursorTypeInt:
+		var v int64
+		err = json.Unmarshal(value.Value, &v)
+		return v, err
+	case cursorTypeUint:
+		var v uint64
+		err = json.Unmarshal(value.Value, &v)
+		return v, err
+	case cursorTypeFloat:
+		var v float64
+		err = json.Unmarshal(value.Value, &v)
+		return v, err
+	case cursorTypeBool:
+		var v bool
+		err = json.Unmarshal(value.Value, &v)
+		return v, err
+	case cursorTypeString:
+		var v string
+		err = json.Unmarshal(value.Value, &v)
+		return v, err
+	case cursorTypeBytes:
+		var v []byte
+		err = json.Unmarshal(value.Value, &v)
+		return v, err
+	case cursorTypeTime:
+		var v string
+		if err = json.Unmarshal(value.Value, &v); err != nil {
+			return nil, err
+		}
+		return time.Parse(time.RFC3339Nano, v)
+	default:
+		return nil, fmt.Errorf(
+			"decodeCursorValue: unknown cursor value type %q", value.Type,
+		)
+	}
+}
@@ -0,0 +1,97 @@
+package endpoint
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/pakkasys/fluidapi/database"
+	"github.com/pakkasys/fluidapi/endpoint"
+)
+
+func TestEncodeDecodeCursor(t *testing.T) {
+	orders := []database.Order{
+		{Table: "users", Field: "created", Direction: "DESC"},
+		{Table: "users", Field: "name", Direction: "ASC"},
+		{Table: "users", Field: "id", Direction: "ASC"},
+	}
+	created := time.Date(2024, 1, 2, 3, 4, 5, 6, time.UTC)
+	values := []any{created, "alice", int64(7)}
+
+	encoded, err := EncodeCursor(orders, values, true)
+	if err != nil {
+		t.Fatalf("EncodeCursor: %v", err)
+	}
+	got, backward, err := DecodeCursor(encoded, orders)
+	if err != nil {
+		t.Fatalf("DecodeCursor: %v", err)
+	}
+	if !backward {
+		t.Errorf("expected backward cursor")
+	}
+	if !reflect.DeepEqual(got, values) {
+		t.Errorf("expected values %v, got %v", values, got)
+	}
+
+	t.Run("OtherOrders", func(t *testing.T) {
+		other := append([]database.Order{}, orders...)
+		other[0].Direction = "ASC"
+		_, _, err := DecodeCursor(encoded, other)
+		expectAPIError(t, err, InvalidCursorError.ID)
+	})
+
+	t.Run("Malformed", func(t *testing.T) {
+		_, _, err := DecodeCursor("not a cursor", orders)
+		expectAPIError(t, err, InvalidCursorError.ID)
+	})
+}
+
+func TestParseCursor(t *testing.T) {
+	orders := []database.Order{
+		{Table: "users", Field: "name", Direction: "DESC"},
+	}
+	keyFields := []endpoint.DBField{{Table: "users", Column: "id"}}
+	page := &database.Page{Offset: 0, Limit: 10}
+
+	t.Run("FirstPage", func(t *testing.T) {
+		parsed, queryOrders, err := ParseCursor(orders, keyFields, "", page)
+		if err != nil {
+			t.Fatalf("ParseCursor: %v", err)
+		}
+		if len(queryOrders) != 2 || queryOrders[1].Field != "id" {
+			t.Errorf("expected key field appended, got %v", queryOrders)
+		}
+		if parsed.Keyset != nil || parsed.HasPrevious {
+			t.Errorf("expected first page, got %+v", parsed)
+		}
+	})
+
+	t.Run("Backward", func(t *testing.T) {
+		pageOrders := append(orders, database.Order{
+			Table: "users", Field: "id", Direction: "ASC",
+		})
+		encoded, err := EncodeCursor(
+			pageOrders, []any{"bob", int64(3)}, true,
+		)
+		if err != nil {
+			t.Fatalf("EncodeCursor: %v", err)
+		}
+		parsed, queryOrders, err := ParseCursor(
+			orders, keyFields, encoded, page,
+		)
+		if err != nil {
+			t.Fatalf("ParseCursor: %v", err)
+		}
+		if queryOrders[0].Direction != "ASC" ||
+			queryOrders[1].Direction != "DESC" {
+			t.Errorf("expected reversed orders, got %v", queryOrders)
+		}
+		if !parsed.Backward || !parsed.HasPrevious || parsed.Keyset == nil {
+			t.Errorf("expected backward page, got %+v", parsed)
+		}
+		if parsed.Keyset.Columns[0].Descending ||
+			!parsed.Keyset.Columns[1].Descending {
+			t.Errorf("expected keyset in query order, got %+v", parsed.Keyset)
+		}
+	})
+}
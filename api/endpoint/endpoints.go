@@ -4,6 +4,9 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	neturl "net/url"
+	"slices"
+	"strings"
 
 	"github.com/pakkasys/fluidapi-extended/api"
 	"github.com/pakkasys/fluidapi-extended/api/repository"
@@ -29,40 +32,129 @@ type Update interface {
 
 // Input types for the generic endpoints.
 type GetInput struct {
-	Selectors endpoint.Selectors `json:"selectors"`
-	Orders    endpoint.Orders    `json:"orders"`
-	Page      *endpoint.Page     `json:"page"`
-	Count     bool               `json:"count"`
+	Selectors      endpoint.Selectors `json:"selectors"`
+	Orders         endpoint.Orders    `json:"orders"`
+	Page           *endpoint.Page     `json:"page"`
+	Count          bool               `json:"count"`
+	IncludeDeleted bool               `json:"include_deleted"`
+	Cursor         string             `json:"cursor"`
+	Fields         string             `json:"fields"`
+	Filter         string             `json:"filter"`
+	Search         string             `json:"q"`
+	Include        string             `json:"include"`
 }
 
+// GetOneInput holds the key values of a get one request by API field name.
+type GetOneInput map[string]any
+
+// CreateManyInput holds the objects of a bulk create request by API field
+// name.
+type CreateManyInput map[string]any
+
 type UpdateInput struct {
-	Selectors endpoint.Selectors `json:"selectors"`
-	Updates   endpoint.Updates   `json:"updates"`
-	Upsert    bool               `json:"upsert"`
+	Selectors      endpoint.Selectors `json:"selectors"`
+	Updates        endpoint.Updates   `json:"updates"`
+	Upsert         bool               `json:"upsert"`
+	Version        *int64             `json:"version"`
+	IfMatch        string             `json:"If-Match"`
+	IncludeDeleted bool               `json:"include_deleted"`
 }
 
 type DeleteInput struct {
 	Selectors endpoint.Selectors `json:"selectors"`
+	Version   *int64             `json:"version"`
+	IfMatch   string             `json:"If-Match"`
+}
+
+type RestoreInput struct {
+	Selectors endpoint.Selectors `json:"selectors"`
 }
 
 // Output types.
 type UpdateOutput struct {
-	Count int64 `json:"count"`
+	Count    int64 `json:"count"`
+	Inserted bool  `json:"inserted"`
 }
 
 type DeleteOutput struct {
 	Count int64 `json:"count"`
 }
 
+type RestoreOutput struct {
+	Count int64 `json:"count"`
+}
+
 // Options to override default expected errors.
 type GenericEndpointOptions struct {
 	ExpectedErrors *api.ExpectedErrors
+	// MapErrorFn maps the errors of the operation before they are handled,
+	// e.g. ConstraintFieldErrorFn.
+	MapErrorFn func(error) error
+}
+
+// resolveEndpointOptions returns the expected errors and the error mapping of
+// the options. The default expected errors are used if the options do not
+// override them.
+func resolveEndpointOptions(
+	options []GenericEndpointOptions,
+	defaultErrors func() api.ExpectedErrors,
+) (api.ExpectedErrors, func(error) error) {
+	if len(options) == 0 {
+		return defaultErrors(), nil
+	}
+	if options[0].ExpectedErrors != nil {
+		return *options[0].ExpectedErrors, options[0].MapErrorFn
+	}
+	return defaultErrors(), options[0].MapErrorFn
+}
+
+// Default page limits of the get endpoint.
+const (
+	DefaultPageLimit = 100
+	MaxPageLimit     = 1000
+)
+
+// DefaultMaxCreateManyItems is the default maximum number of entities in the
+// input of a bulk create.
+const DefaultMaxCreateManyItems = 1000
+
+// HeaderTotalCount is the header used to return the total count of rows.
+const HeaderTotalCount = "X-Total-Count"
+
+// GetPageOptions configures the pagination of the get endpoint.
+type GetPageOptions struct {
+	// DefaultLimit is used if the request does not set a page limit.
+	DefaultLimit int
+	// MaxLimit is the maximum page limit of a request.
+	MaxLimit int
+	// IncludeTotal reads the total count of matching rows with the page.
+	IncludeTotal bool
+	// TotalCountHeader returns the total count in the X-Total-Count header.
+	TotalCountHeader bool
+}
+
+// DefaultGetPageOptions returns the default page options.
+func DefaultGetPageOptions() *GetPageOptions {
+	return &GetPageOptions{
+		DefaultLimit: DefaultPageLimit,
+		MaxLimit:     MaxPageLimit,
+	}
+}
+
+// PageOutput is the page metadata of a get output.
+type PageOutput struct {
+	Offset  int  `json:"offset"`
+	Limit   int  `json:"limit"`
+	HasMore bool `json:"has_more"`
 }
 
 // Error variables.
 var (
 	NeedAtLeastOneUpdateError   = core.NewAPIError("NEED_AT_LEAST_ONE_UPDATE")
 	NeedAtLeastOneSelectorError = core.NewAPIError("NEED_AT_LEAST_ONE_SELECTOR")
+	UpsertSelectorNotEqualError = core.NewAPIError("UPSERT_SELECTOR_NOT_EQUAL")
+	NeedAtLeastOneEntityError   = core.NewAPIError("NEED_AT_LEAST_ONE_ENTITY")
+	InvalidPageError            = core.NewAPIError("INVALID_PAGE")
 )
 
 type ErrorBuilder struct {
@@ -85,6 +177,28 @@ func (b *ErrorBuilder) Build() api.ExpectedErrors {
 	return api.ExpectedErrors(b.errs).WithOrigin(b.systemId)
 }
 
+// ConstraintErrors returns the expected errors of values that violate the
+// constraints of a table.
+func ConstraintErrors() api.ExpectedErrors {
+	return []api.ExpectedError{
+		{ID: extendeddatabase.NotNullViolationError.ID, Status: http.StatusBadRequest, PublicData: false},
+		{ID: extendeddatabase.CheckConstraintError.ID, Status: http.StatusBadRequest, PublicData: false},
+		{ID: extendeddatabase.DataTooLongError.ID, Status: http.StatusBadRequest, PublicData: false},
+		{ID: extendeddatabase.OutOfRangeError.ID, Status: http.StatusBadRequest, PublicData: false},
+	}
+}
+
+// TransientErrors returns the expected errors of queries that could not be
+// run at the time and may succeed when they are retried.
+func TransientErrors() api.ExpectedErrors {
+	return []api.ExpectedError{
+		{ID: extendeddatabase.DeadlockError.ID, Status: http.StatusConflict, PublicData: false},
+		{ID: extendeddatabase.LockTimeoutError.ID, Status: http.StatusConflict, PublicData: false},
+		{ID: extendeddatabase.DatabaseBusyError.ID, Status: http.StatusServiceUnavailable, PublicData: false},
+		{ID: extendeddatabase.ReadOnlyDatabaseError.ID, Status: http.StatusServiceUnavailable, PublicData: false},
+	}
+}
+
 func GenericErrors() api.ExpectedErrors {
 	return []api.ExpectedError{
 		{ID: MapToObjectDecodingError.ID, Status: http.StatusBadRequest, PublicData: true},
@@ -94,43 +208,77 @@ func GenericErrors() api.ExpectedErrors {
 }
 
 func CreateErrors() api.ExpectedErrors {
-	return []api.ExpectedError{
+	return api.ExpectedErrors{
 		{ID: extendeddatabase.DuplicateEntryError.ID, Status: http.StatusBadRequest, PublicData: false},
 		{ID: extendeddatabase.ForeignConstraintError.ID, Status: http.StatusBadRequest, PublicData: false},
-	}
+	}.With(ConstraintErrors()...).With(TransientErrors()...)
+}
+
+func CreateManyErrors() api.ExpectedErrors {
+	return api.ExpectedErrors{
+		{ID: NeedAtLeastOneEntityError.ID, Status: http.StatusBadRequest, PublicData: true},
+		{ID: extendeddatabase.DuplicateEntryError.ID, Status: http.StatusBadRequest, PublicData: false},
+		{ID: extendeddatabase.ForeignConstraintError.ID, Status: http.StatusBadRequest, PublicData: false},
+	}.With(ConstraintErrors()...).With(TransientErrors()...)
 }
 
 func GetErrors() api.ExpectedErrors {
-	return []api.ExpectedError{
+	return api.ExpectedErrors{
 		{ID: endpoint.InvalidPredicateError.ID, Status: http.StatusBadRequest, PublicData: true},
 		{ID: endpoint.PredicateNotAllowedError.ID, Status: http.StatusBadRequest, PublicData: true},
 		{ID: endpoint.InvalidSelectorFieldError.ID, Status: http.StatusBadRequest, PublicData: true},
 		{ID: endpoint.InvalidOrderFieldError.ID, Status: http.StatusBadRequest, PublicData: true},
 		{ID: endpoint.MaxPageLimitExceededError.ID, Status: http.StatusBadRequest, PublicData: true},
+		{ID: InvalidPageError.ID, Status: http.StatusBadRequest, PublicData: true},
+		{ID: InvalidCursorError.ID, Status: http.StatusBadRequest, PublicData: true},
+		{ID: InvalidFieldError.ID, Status: http.StatusBadRequest, PublicData: true},
+		{ID: InvalidFilterError.ID, Status: http.StatusBadRequest, PublicData: true},
+		{ID: FilterTooComplexError.ID, Status: http.StatusBadRequest, PublicData: true},
+		{ID: InvalidSearchError.ID, Status: http.StatusBadRequest, PublicData: true},
+		{ID: InvalidIncludeError.ID, Status: http.StatusBadRequest, PublicData: true},
 		{ID: extendeddatabase.NoRowsError.ID, Status: http.StatusNotFound, PublicData: true},
-	}
+	}.With(TransientErrors()...)
+}
+
+func GetOneErrors() api.ExpectedErrors {
+	return api.ExpectedErrors{
+		{ID: endpoint.InvalidSelectorFieldError.ID, Status: http.StatusBadRequest, PublicData: true},
+		{ID: NeedAtLeastOneSelectorError.ID, Status: http.StatusBadRequest, PublicData: true},
+		{ID: extendeddatabase.NoRowsError.ID, Status: http.StatusNotFound, PublicData: true},
+	}.With(TransientErrors()...)
 }
 
 func UpdateErrors() api.ExpectedErrors {
-	return []api.ExpectedError{
+	return api.ExpectedErrors{
 		{ID: endpoint.InvalidPredicateError.ID, Status: http.StatusBadRequest, PublicData: true},
 		{ID: endpoint.InvalidSelectorFieldError.ID, Status: http.StatusBadRequest, PublicData: true},
 		{ID: endpoint.PredicateNotAllowedError.ID, Status: http.StatusBadRequest, PublicData: true},
 		{ID: NeedAtLeastOneSelectorError.ID, Status: http.StatusBadRequest, PublicData: true},
 		{ID: NeedAtLeastOneUpdateError.ID, Status: http.StatusBadRequest, PublicData: true},
+		{ID: UpsertSelectorNotEqualError.ID, Status: http.StatusBadRequest, PublicData: true},
 		{ID: endpoint.InvalidOrderFieldError.ID, Status: http.StatusBadRequest, PublicData: true},
 		{ID: extendeddatabase.DuplicateEntryError.ID, Status: http.StatusBadRequest, PublicData: false},
 		{ID: extendeddatabase.ForeignConstraintError.ID, Status: http.StatusBadRequest, PublicData: false},
-	}
+	}.With(ConstraintErrors()...).With(TransientErrors()...)
+}
+
+func RestoreErrors() api.ExpectedErrors {
+	return api.ExpectedErrors{
+		{ID: endpoint.InvalidPredicateError.ID, Status: http.StatusBadRequest, PublicData: true},
+		{ID: endpoint.InvalidSelectorFieldError.ID, Status: http.StatusBadRequest, PublicData: true},
+		{ID: endpoint.PredicateNotAllowedError.ID, Status: http.StatusBadRequest, PublicData: true},
+		{ID: NeedAtLeastOneSelectorError.ID, Status: http.StatusBadRequest, PublicData: true},
+		{ID: extendeddatabase.DuplicateEntryError.ID, Status: http.StatusBadRequest, PublicData: false},
+	}.With(ConstraintErrors()...).With(TransientErrors()...)
 }
 
 func DeleteErrors() api.ExpectedErrors {
-	return []api.ExpectedError{
+	return api.ExpectedErrors{
 		{ID: endpoint.InvalidPredicateError.ID, Status: http.StatusBadRequest, PublicData: true},
 		{ID: endpoint.InvalidSelectorFieldError.ID, Status: http.StatusBadRequest, PublicData: true},
 		{ID: endpoint.PredicateNotAllowedError.ID, Status: http.StatusBadRequest, PublicData: true},
 		{ID: NeedAtLeastOneSelectorError.ID, Status: http.StatusBadRequest, PublicData: true},
-	}
+	}.With(TransientErrors()...)
 }
 
 // GenericEndpointDefinition builds the endpoint definition for any operation.
@@ -174,6 +322,32 @@ func SendRequest[Input any, Output any](
 	return SendParsedRequest[Input](ctx, httpClient, url, method, parsedInput)
 }
 
+// SendRequestPayload sends a request to the target host and returns the
+// payload of the response. The API error of the response is returned as the
+// error.
+func SendRequestPayload[Input any, Output any](
+	ctx context.Context,
+	httpClient *client.JSONClient[api.APIOutput[Output]],
+	url string,
+	method string,
+	input *Input,
+) (*Output, error) {
+	response, err := SendRequest[Input](ctx, httpClient, url, method, input)
+	if err != nil {
+		return nil, err
+	}
+	if response.Output.Error != nil {
+		return nil, response.Output.Error
+	}
+	if response.Output.Payload == nil {
+		return nil, fmt.Errorf(
+			"SendRequestPayload: no payload, status: %d",
+			response.Response.StatusCode,
+		)
+	}
+	return response.Output.Payload, nil
+}
+
 // SendParsedRequest sends a request to the target host using the parsed input.
 func SendParsedRequest[Input any, Output any](
 	ctx context.Context,
@@ -191,6 +365,15 @@ func SendParsedRequest[Input any, Output any](
 		opts.Body = parsedInput.Body
 	}
 
+	// Replace path parameters in the URL
+	for key, value := range parsedInput.PathParameters {
+		url = strings.ReplaceAll(
+			url,
+			fmt.Sprintf("{%s}", key),
+			neturl.PathEscape(fmt.Sprintf("%v", value)),
+		)
+	}
+
 	// Add query parameters to the URL
 	urlValues, err := api.NewURLEncoder().Encode(parsedInput.URLParameters)
 	if err != nil {
@@ -224,12 +407,12 @@ func GenericCreateDefinition[Input any, Entity database.Mutator](
 	systemId string,
 	options ...GenericEndpointOptions,
 ) *EndpointHandler[Input] {
-	var expectedErrors api.ExpectedErrors
-	if len(options) > 0 && options[0].ExpectedErrors != nil {
-		expectedErrors = *options[0].ExpectedErrors
-	} else {
-		expectedErrors = NewErrorBuilder(systemId).With(CreateErrors()).Build()
-	}
+	expectedErrors, mapErrorFn := resolveEndpointOptions(
+		options,
+		func() api.ExpectedErrors {
+			return NewErrorBuilder(systemId).With(CreateErrors()).Build()
+		},
+	)
 	handler := &CreateHandler[Entity, Input]{
 		createInvokeFn: CreateInvoke[Entity],
 		toOutputFn:     toOutputFn,
@@ -246,7 +429,7 @@ func GenericCreateDefinition[Input any, Entity database.Mutator](
 		http.MethodPost,
 		inputHandler,
 		inputFactory,
-		handler.Handle,
+		mapHandlerErrors(handler.Handle, mapErrorFn),
 		expectedErrors,
 		loggerFactoryFn,
 		systemId,
@@ -270,7 +453,111 @@ func CreateInvoke[Entity database.Mutator](
 	)
 }
 
+// GenericCreateManyDefinition builds the endpoint definition for a bulk create
+// operation. All entities are inserted in a single transaction.
+func GenericCreateManyDefinition[Input any, Entity database.Mutator](
+	url string,
+	inputHandler InputHandler,
+	inputFactory func() Input,
+	getConnectionFn repository.ConnFn,
+	entityFactoryFn CreateManyEntityFactoryFn[Input, Entity],
+	toOutputFn ToCreateManyOutputFn[Entity],
+	beforeCallback func(ctx context.Context, entities []Entity, input *Input) error,
+	loggerFactoryFn LoggerFactoryFn,
+	mutatorRepo repository.MutatorRepo[Entity],
+	txManager repository.TxManager[[]Entity],
+	systemId string,
+	options ...GenericEndpointOptions,
+) *EndpointHandler[Input] {
+	expectedErrors, mapErrorFn := resolveEndpointOptions(
+		options,
+		func() api.ExpectedErrors {
+			return NewErrorBuilder(systemId).With(CreateManyErrors()).Build()
+		},
+	)
+	handler := &CreateManyHandler[Entity, Input]{
+		createManyInvokeFn: CreateManyInvoke[Entity],
+		toOutputFn:         toOutputFn,
+		connFn:             getConnectionFn,
+		entityFactoryFn:    entityFactoryFn,
+		beforeCallback:     beforeCallback,
+		mutatorRepo:        mutatorRepo,
+		txManager:          txManager,
+	}
+	return NewEndpointHandler(
+		url,
+		http.MethodPost,
+		inputHandler,
+		inputFactory,
+		mapHandlerErrors(handler.Handle, mapErrorFn),
+		expectedErrors,
+		loggerFactoryFn,
+		systemId,
+	)
+}
+
+// CreateManyInvoke wraps the bulk create database operation in a transaction.
+// The entities are inserted with InsertMany if the repository implements
+// repository.BulkInserter and one by one otherwise.
+func CreateManyInvoke[Entity database.Mutator](
+	ctx context.Context,
+	connFn repository.ConnFn,
+	entities []Entity,
+	mutatorRepo repository.MutatorRepo[Entity],
+	txManager repository.TxManager[[]Entity],
+) ([]Entity, error) {
+	if len(entities) == 0 {
+		return nil, NeedAtLeastOneEntityError
+	}
+	return txManager.WithTransaction(
+		ctx,
+		connFn,
+		func(ctx context.Context, tx database.Tx) ([]Entity, error) {
+			bulkInserter, ok := mutatorRepo.(repository.BulkInserter[Entity])
+			if ok {
+				return bulkInserter.InsertMany(tx, entities)
+			}
+			inserted := make([]Entity, len(entities))
+			for i, entity := range entities {
+				var err error
+				inserted[i], err = mutatorRepo.Insert(tx, entity)
+				if err != nil {
+					return nil, err
+				}
+			}
+			return inserted, nil
+		},
+	)
+}
+
+// GetEndpointOptions are the optional features of the get endpoint.
+type GetEndpointOptions[Entity database.Getter] struct {
+	// OutputToDBFields are the output fields that the input can select. Only
+	// the columns of the selected fields are read.
+	OutputToDBFields APIToDBFields
+	// FilterOptions let the input filter the rows with a filter expression.
+	FilterOptions *FilterOptions
+	// SearchOptions let the input search the rows with a full-text query,
+	// which is combined with the filter expression.
+	SearchOptions *SearchOptions
+	// Relations are the relations that the input can include. Their rows are
+	// read after the page.
+	Relations []extendeddatabase.Relation
+	// SoftDeleteField excludes the soft deleted rows unless the input
+	// includes them.
+	SoftDeleteField *endpoint.DBField
+	// CursorKeyFields enable cursor pagination with the key fields as the
+	// tiebreaker. CursorValuesFn reads the cursor values of the returned
+	// entities.
+	CursorKeyFields []endpoint.DBField
+	CursorValuesFn  CursorValuesFn[Entity]
+	// PageOptions are the page limits, DefaultGetPageOptions if nil.
+	PageOptions *GetPageOptions
+}
+
 // GenericGetDefinition builds the endpoint definition for a get operation.
+// The optional features of the endpoint, e.g. filtering, relations and cursor
+// pagination, are enabled with the options.
 func GenericGetDefinition[Entity database.Getter, Output any](
 	url string,
 	inputHandler InputHandler,
@@ -285,28 +572,102 @@ func GenericGetDefinition[Entity database.Getter, Output any](
 	readerRepo repository.ReaderRepo[Entity],
 	txManager repository.TxManager[Entity],
 	systemId string,
+	getOptions ...GetEndpointOptions[Entity],
 ) *EndpointHandler[GetInput] {
+	var options GetEndpointOptions[Entity]
+	if len(getOptions) != 0 {
+		options = getOptions[0]
+	}
+	if options.PageOptions == nil {
+		options.PageOptions = DefaultGetPageOptions()
+	}
 	parseInputFn := func(
 		input *GetInput,
 	) (*ParsedGetEndpointInput, error) {
-		return ParseGetEndpointInput(
+		parsedInput, err := ParseGetEndpointInput(
 			apiToDBFields,
 			input.Selectors,
 			input.Orders,
 			input.Page,
-			100,
+			options.PageOptions.DefaultLimit,
+			options.PageOptions.MaxLimit,
 			input.Count,
 		)
+		if err != nil {
+			return nil, err
+		}
+		parsedInput.Fields, parsedInput.Projections, err = ParseFields(
+			options.OutputToDBFields, input.Fields,
+		)
+		if err != nil {
+			return nil, err
+		}
+		parsedInput.Filter, err = ParseFilter(options.FilterOptions, input.Filter)
+		if err != nil {
+			return nil, err
+		}
+		search, err := ParseSearch(options.SearchOptions, input.Search)
+		if err != nil {
+			return nil, err
+		}
+		parsedInput.Filter = extendeddatabase.AndFilters(
+			parsedInput.Filter, search,
+		)
+		parsedInput.Includes, err = ParseInclude(options.Relations, input.Include)
+		if err != nil {
+			return nil, err
+		}
+		// The relation keys are read from the local columns.
+		if parsedInput.Projections != nil {
+			parsedInput.Projections = withRelationProjections(
+				parsedInput.Projections, parsedInput.Includes,
+			)
+		}
+		parsedInput.Total = options.PageOptions.IncludeTotal ||
+			options.PageOptions.TotalCountHeader
+		parsedInput.Selectors = notDeletedSelectors(
+			parsedInput.Selectors, options.SoftDeleteField, input.IncludeDeleted,
+		)
+		if len(options.CursorKeyFields) != 0 && !parsedInput.Count {
+			parsedInput.Cursor, parsedInput.Orders, err = ParseCursor(
+				parsedInput.Orders,
+				options.CursorKeyFields,
+				input.Cursor,
+				parsedInput.Page,
+			)
+			if err != nil {
+				return nil, err
+			}
+			// The cursor values are read from the ordered columns.
+			if parsedInput.Projections != nil {
+				parsedInput.Projections = withOrderProjections(
+					parsedInput.Projections, parsedInput.Orders,
+				)
+			}
+			// The offset is only used on the first page. One extra row is
+			// read to know if there are more rows.
+			offset := 0
+			if parsedInput.Cursor.Keyset == nil {
+				offset = parsedInput.Page.Offset
+			}
+			parsedInput.Page = &database.Page{
+				Offset: offset,
+				Limit:  parsedInput.Cursor.Limit + 1,
+			}
+		}
+		return parsedInput, nil
 	}
 	handler := &GetHandler[Entity, GetInput, Output]{
-		parseInputFn:    parseInputFn,
-		getInvokeFn:     GetInvoke[Entity],
-		toOutputFn:      toOutputFn,
-		connFn:          connFn,
-		entityFactoryFn: entityFactoryFn,
-		beforeCallback:  beforeCallback,
-		readerRepo:      readerRepo,
-		txManager:       txManager,
+		parseInputFn:     parseInputFn,
+		getInvokeFn:      GetInvoke[Entity],
+		toOutputFn:       toOutputFn,
+		cursorValuesFn:   options.CursorValuesFn,
+		totalCountHeader: options.PageOptions.TotalCountHeader,
+		connFn:           connFn,
+		entityFactoryFn:  entityFactoryFn,
+		beforeCallback:   beforeCallback,
+		readerRepo:       readerRepo,
+		txManager:        txManager,
 	}
 	return NewEndpointHandler(
 		url,
@@ -320,57 +681,197 @@ func GenericGetDefinition[Entity database.Getter, Output any](
 	)
 }
 
-// GetInvoke executes the get operation.
+// GetInvoke executes the get operation. In cursor pagination mode, the rows
+// after the cursor are read and the page is returned in the requested order.
+// If the input has a filter, only the rows that match it are read.
 func GetInvoke[Getter database.Getter](
 	ctx context.Context,
 	parsedInput *ParsedGetEndpointInput,
 	connFn repository.ConnFn,
 	entityFactoryFn repository.GetterFactoryFn[Getter],
 	readerRepo repository.ReaderRepo[Getter],
-	_ repository.TxManager[Getter],
-) ([]Getter, int, error) {
-	conn, err := connFn()
-	if err != nil {
-		return nil, 0, err
+	txManager repository.TxManager[Getter],
+) (*GetResult[Getter], error) {
+	// The page, the relations and the total are read in one transaction.
+	return repository.NewTxManagerAdapter[Getter, *GetResult[Getter]](
+		txManager,
+	).WithTransaction(
+		ctx,
+		connFn,
+		func(ctx context.Context, tx database.Tx) (*GetResult[Getter], error) {
+			return getInvoke(tx, parsedInput, entityFactoryFn, readerRepo)
+		},
+	)
+}
+
+// getInvoke reads the rows of the get operation in the transaction. One extra
+// row is read to know if there are more rows after the page.
+func getInvoke[Getter database.Getter](
+	tx database.Tx,
+	parsedInput *ParsedGetEndpointInput,
+	entityFactoryFn repository.GetterFactoryFn[Getter],
+	readerRepo repository.ReaderRepo[Getter],
+) (*GetResult[Getter], error) {
+	if parsedInput.Count {
+		count, err := countInvoke(
+			tx, parsedInput, parsedInput.Page, entityFactoryFn, readerRepo,
+		)
+		if err != nil {
+			return nil, err
+		}
+		return &GetResult[Getter]{Count: count}, nil
+	}
+	getOptions := &database.GetOptions{
+		Selectors:   parsedInput.Selectors,
+		Orders:      parsedInput.Orders,
+		Page:        parsedInput.Page,
+		Projections: parsedInput.Projections,
+	}
+	// In cursor pagination mode, the page already has the extra row.
+	if parsedInput.Cursor == nil && parsedInput.Page != nil {
+		getOptions.Page = &database.Page{
+			Offset: parsedInput.Page.Offset,
+			Limit:  parsedInput.Page.Limit + 1,
+		}
+	}
+	var keyset *extendeddatabase.Keyset
+	if parsedInput.Cursor != nil {
+		keyset = parsedInput.Cursor.Keyset
+	}
+	var entities []Getter
+	var err error
+	switch {
+	case parsedInput.Filter != nil:
+		var filteredReader repository.FilteredReader[Getter]
+		filteredReader, err = filteredReaderOf(readerRepo)
+		if err != nil {
+			return nil, err
+		}
+		entities, err = filteredReader.GetManyFiltered(
+			tx, entityFactoryFn, getOptions, keyset, parsedInput.Filter,
+		)
+	case keyset != nil:
+		var keysetReader repository.KeysetReader[Getter]
+		keysetReader, err = keysetReaderOf(readerRepo)
+		if err != nil {
+			return nil, err
+		}
+		entities, err = keysetReader.GetManyKeyset(
+			tx, entityFactoryFn, getOptions, keyset,
+		)
+	default:
+		entities, err = readerRepo.GetMany(tx, entityFactoryFn, getOptions)
 	}
-	tx, err := conn.BeginTx(ctx, nil)
 	if err != nil {
-		return nil, 0, err
+		return nil, err
 	}
-	if parsedInput.Count {
-		count, err := readerRepo.Count(
+	result := &GetResult[Getter]{
+		Entities: entities,
+		Page:     parsedInput.Page,
+		Fields:   parsedInput.Fields,
+	}
+	if parsedInput.Cursor != nil {
+		result.Page = &database.Page{
+			Offset: parsedInput.Page.Offset,
+			Limit:  parsedInput.Cursor.Limit,
+		}
+		if len(entities) > parsedInput.Cursor.Limit {
+			result.Entities = entities[:parsedInput.Cursor.Limit]
+			result.HasMore = true
+		}
+		if parsedInput.Cursor.Backward {
+			slices.Reverse(result.Entities)
+		}
+	} else if parsedInput.Page != nil &&
+		len(entities) > parsedInput.Page.Limit {
+		result.Entities = entities[:parsedInput.Page.Limit]
+		result.HasMore = true
+	}
+	result.Count = len(result.Entities)
+	if len(parsedInput.Includes) != 0 {
+		result.Includes = parsedInput.Includes
+		result.Related, err = loadRelations(
+			tx, result.Entities, parsedInput.Includes, readerRepo,
+		)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if parsedInput.Total {
+		total, err := countInvoke(
+			tx, parsedInput, nil, entityFactoryFn, readerRepo,
+		)
+		if err != nil {
+			return nil, err
+		}
+		result.Total = &total
+	}
+	return result, nil
+}
+
+// countInvoke counts the rows that match the selectors and filter of the
+// parsed input.
+func countInvoke[Getter database.Getter](
+	tx database.Tx,
+	parsedInput *ParsedGetEndpointInput,
+	page *database.Page,
+	entityFactoryFn repository.GetterFactoryFn[Getter],
+	readerRepo repository.ReaderRepo[Getter],
+) (int, error) {
+	if parsedInput.Filter != nil {
+		filteredReader, err := filteredReaderOf(readerRepo)
+		if err != nil {
+			return 0, err
+		}
+		return filteredReader.CountFiltered(
 			tx,
 			parsedInput.Selectors,
-			parsedInput.Page,
+			page,
+			parsedInput.Filter,
 			entityFactoryFn,
 		)
-		if err != nil {
-			return nil, 0, err
-		}
-		return nil, count, nil
 	}
-	entities, err := readerRepo.GetMany(
-		tx,
-		entityFactoryFn,
-		&database.GetOptions{
-			Selectors: parsedInput.Selectors,
-			Orders:    parsedInput.Orders,
-			Page:      parsedInput.Page,
-		},
-	)
-	if err != nil {
-		return nil, 0, err
+	return readerRepo.Count(tx, parsedInput.Selectors, page, entityFactoryFn)
+}
+
+// filteredReaderOf returns the reader repository as a FilteredReader.
+func filteredReaderOf[Getter database.Getter](
+	readerRepo repository.ReaderRepo[Getter],
+) (repository.FilteredReader[Getter], error) {
+	filteredReader, ok := readerRepo.(repository.FilteredReader[Getter])
+	if !ok {
+		return nil, fmt.Errorf(
+			"filteredReaderOf: reader repository %T does not support filters",
+			readerRepo,
+		)
+	}
+	return filteredReader, nil
+}
+
+// keysetReaderOf returns the reader repository as a KeysetReader.
+func keysetReaderOf[Getter database.Getter](
+	readerRepo repository.ReaderRepo[Getter],
+) (repository.KeysetReader[Getter], error) {
+	keysetReader, ok := readerRepo.(repository.KeysetReader[Getter])
+	if !ok {
+		return nil, fmt.Errorf(
+			"keysetReaderOf: reader repository %T does not support keysets",
+			readerRepo,
+		)
 	}
-	return entities, len(entities), nil
+	return keysetReader, nil
 }
 
-// ParseGetEndpointInput translates API parameters to DB parameters.
+// ParseGetEndpointInput translates API parameters to DB parameters. If the
+// page is not set, the first page with the default limit is used. A negative
+// offset or limit is rejected with InvalidPageError.
 func ParseGetEndpointInput(
 	apiToDBFields APIToDBFields,
 	selectors endpoint.Selectors,
 	orders endpoint.Orders,
 	inputPage *endpoint.Page,
-	maxPage int,
+	defaultLimit int,
+	maxLimit int,
 	count bool,
 ) (*ParsedGetEndpointInput, error) {
 	dbOrders, err := orders.TranslateToDBOrders(apiToDBFields)
@@ -378,7 +879,16 @@ func ParseGetEndpointInput(
 		return nil, err
 	}
 	if inputPage == nil {
-		inputPage = &endpoint.Page{Offset: 0, Limit: maxPage}
+		inputPage = &endpoint.Page{Offset: 0, Limit: defaultLimit}
+	}
+	if inputPage.Offset < 0 || inputPage.Limit < 0 {
+		return nil, InvalidPageError
+	}
+	if inputPage.Limit == 0 {
+		inputPage.Limit = defaultLimit
+	}
+	if inputPage.Limit > maxLimit {
+		return nil, endpoint.MaxPageLimitExceededError.WithData(maxLimit)
 	}
 	dbSelectors, err := selectors.ToDBSelectors(apiToDBFields)
 	if err != nil {
@@ -392,12 +902,134 @@ func ParseGetEndpointInput(
 	}, nil
 }
 
+// GenericGetOneDefinition builds the endpoint definition for a get one
+// operation. The key fields are the API fields that identify a single entity.
+// If etagFn is set, the ETag of the entity is set in the response headers. If
+// softDeleteField is set, soft deleted rows are excluded unless the input
+// includes them.
+func GenericGetOneDefinition[Entity database.Getter](
+	url string,
+	inputHandler InputHandler,
+	apiToDBFields APIToDBFields,
+	keyFields []string,
+	softDeleteField *endpoint.DBField,
+	toOutputFn ToGetOneOutputFn[Entity],
+	etagFn ETagFn[Entity],
+	connFn repository.ConnFn,
+	entityFactoryFn repository.GetterFactoryFn[Entity],
+	beforeCallback func(
+		ctx context.Context, entity Entity, input *GetOneInput,
+	) error,
+	loggerFactoryFn LoggerFactoryFn,
+	readerRepo repository.ReaderRepo[Entity],
+	txManager repository.TxManager[Entity],
+	systemId string,
+) *EndpointHandler[GetOneInput] {
+	parseInputFn := func(
+		input *GetOneInput,
+	) (*ParsedGetOneEndpointInput, error) {
+		parsedInput, err := ParseGetOneEndpointInput(
+			apiToDBFields, keyFields, *input,
+		)
+		if err != nil {
+			return nil, err
+		}
+		includeDeleted, _ := (*input)[FieldIncludeDeleted].(bool)
+		parsedInput.Selectors = notDeletedSelectors(
+			parsedInput.Selectors, softDeleteField, includeDeleted,
+		)
+		return parsedInput, nil
+	}
+	handler := &GetOneHandler[Entity, GetOneInput]{
+		parseInputFn:    parseInputFn,
+		getOneInvokeFn:  GetOneInvoke[Entity],
+		toOutputFn:      toOutputFn,
+		etagFn:          etagFn,
+		connFn:          connFn,
+		entityFactoryFn: entityFactoryFn,
+		beforeCallback:  beforeCallback,
+		readerRepo:      readerRepo,
+		txManager:       txManager,
+	}
+	return NewEndpointHandler(
+		url,
+		http.MethodGet,
+		inputHandler,
+		func() GetOneInput { return GetOneInput{} },
+		handler.Handle,
+		NewErrorBuilder(systemId).With(GetOneErrors()).Build(),
+		loggerFactoryFn,
+		systemId,
+	)
+}
+
+// GetOneInvoke executes the get one operation.
+func GetOneInvoke[Getter database.Getter](
+	ctx context.Context,
+	parsedInput *ParsedGetOneEndpointInput,
+	connFn repository.ConnFn,
+	entityFactoryFn repository.GetterFactoryFn[Getter],
+	readerRepo repository.ReaderRepo[Getter],
+	txManager repository.TxManager[Getter],
+) (Getter, error) {
+	return txManager.WithTransaction(
+		ctx,
+		connFn,
+		func(ctx context.Context, tx database.Tx) (Getter, error) {
+			return readerRepo.GetOne(
+				tx,
+				entityFactoryFn,
+				&database.GetOptions{
+					Selectors: parsedInput.Selectors,
+				},
+			)
+		},
+	)
+}
+
+// ParseGetOneEndpointInput translates the key values of a get one request to
+// equality selectors. Every key field must have a value.
+func ParseGetOneEndpointInput(
+	apiToDBFields APIToDBFields,
+	keyFields []string,
+	input GetOneInput,
+) (*ParsedGetOneEndpointInput, error) {
+	var dbSelectors database.Selectors
+	for _, keyField := range keyFields {
+		dbField, ok := apiToDBFields[keyField]
+		if !ok {
+			return nil, endpoint.InvalidSelectorFieldError.WithData(keyField)
+		}
+		value, ok := input[keyField]
+		if !ok {
+			return nil, NeedAtLeastOneSelectorError
+		}
+		dbSelectors = append(dbSelectors, database.Selector{
+			Table:     dbField.Table,
+			Column:    dbField.Column,
+			Predicate: "=",
+			Value:     value,
+		})
+	}
+	if len(dbSelectors) == 0 {
+		return nil, NeedAtLeastOneSelectorError
+	}
+	return &ParsedGetOneEndpointInput{
+		Selectors: dbSelectors,
+	}, nil
+}
+
 // GenericUpdateDefinition builds the endpoint definition for an update
-// operation.
+// operation. If versionField is set, the update requires the expected version
+// of the entity and increments it. If softDeleteField is set, soft deleted rows
+// are not updated unless the input includes them. The errors of the updates
+// and upserts are mapped by the MapErrorFn of the options.
 func GenericUpdateDefinition(
 	url string,
 	inputHandler InputHandler,
 	apiToDBFields APIToDBFields,
+	versionField *endpoint.DBField,
+	softDeleteField *endpoint.DBField,
 	connFn repository.ConnFn,
 	entityFactoryFn UpdateEntityFactoryFn,
 	beforeCallback func(
@@ -406,17 +1038,45 @@ func GenericUpdateDefinition(
 		input *UpdateInput,
 	) error,
 	loggerFactoryFn LoggerFactoryFn,
+	mutatorRepo repository.MutatorRepo[database.Mutator],
+	txManager repository.TxManager[*UpdateResult],
 	systemId string,
+	options ...GenericEndpointOptions,
 ) *EndpointHandler[UpdateInput] {
+	expectedErrors, mapErrorFn := resolveEndpointOptions(
+		options,
+		func() api.ExpectedErrors {
+			return NewErrorBuilder(systemId).
+				With(UpdateErrors()).
+				With(VersionErrors()).
+				Build()
+		},
+	)
 	parseInputFn := func(
 		input *UpdateInput,
 	) (*ParsedUpdateEndpointInput, error) {
-		return ParseUpdateEndpointInput(
+		version, err := ResolveVersion(
+			versionField, input.Version, input.IfMatch,
+		)
+		if err != nil {
+			return nil, err
+		}
+		parsedInput, err := ParseUpdateEndpointInput(
 			apiToDBFields,
 			input.Selectors,
 			input.Updates,
 			input.Upsert,
+			versionField,
+			version,
 		)
+		if err != nil {
+			return nil, err
+		}
+		parsedInput.Selectors = notDeletedSelectors(
+			parsedInput.Selectors, softDeleteField, input.IncludeDeleted,
+		)
+		parsedInput.SoftDelete = softDeleteField
+		return parsedInput, nil
 	}
 	handler := &UpdateHandler[UpdateInput]{
 		parseInputFn:    parseInputFn,
@@ -425,52 +1085,140 @@ func GenericUpdateDefinition(
 		connFn:          connFn,
 		entityFactoryFn: entityFactoryFn,
 		beforeCallback:  beforeCallback,
+		mutatorRepo:     mutatorRepo,
+		txManager:       txManager,
 	}
 	return NewEndpointHandler(
 		url,
 		http.MethodPatch,
 		inputHandler,
 		func() UpdateInput { return UpdateInput{} },
-		handler.Handle,
-		NewErrorBuilder(systemId).With(UpdateErrors()).Build(),
+		mapHandlerErrors(handler.Handle, mapErrorFn),
+		expectedErrors,
 		loggerFactoryFn,
 		systemId,
 	)
 }
 
-// UpdateInvoke executes the update operation.
+// UpdateInvoke executes the update operation. If upsert is requested and no
+// rows match the selectors, a row built from the selectors and updates is
+// upserted instead. If a version is expected and no rows match, the update
+// fails with NoRowsError if the row does not exist and otherwise with
+// VersionConflictError.
 func UpdateInvoke(
 	ctx context.Context,
 	parsedInput *ParsedUpdateEndpointInput,
 	connFn repository.ConnFn,
 	entity database.Mutator,
 	mutatorRepo repository.MutatorRepo[database.Mutator],
-	txManager repository.TxManager[*int64],
-) (int64, error) {
-	count, err := txManager.WithTransaction(
+	txManager repository.TxManager[*UpdateResult],
+) (*UpdateResult, error) {
+	return txManager.WithTransaction(
 		ctx,
 		connFn,
-		func(ctx context.Context, tx database.Tx) (*int64, error) {
+		func(ctx context.Context, tx database.Tx) (*UpdateResult, error) {
 			c, err := mutatorRepo.Update(
 				tx,
 				entity,
 				parsedInput.Selectors,
 				parsedInput.Updates,
 			)
-			return &c, err
+			if err != nil {
+				return nil, err
+			}
+			if c == 0 && parsedInput.Version != nil {
+				return nil, versionMismatchError(
+					tx,
+					entity.TableName(),
+					parsedInput.Selectors,
+					parsedInput.VersionField,
+					*parsedInput.Version,
+					mutatorRepo,
+				)
+			}
+			if c != 0 || !parsedInput.Upsert {
+				return &UpdateResult{Count: c}, nil
+			}
+			return upsertInvoke(tx, parsedInput, entity, mutatorRepo)
 		})
+}
+
+// upsertInvoke upserts the row built from the equality selectors of the input
+// and the updates of the parsed input. The selector columns are used as the
+// conflict target and the update columns are updated on conflict. A soft
+// deleted row does not exist for the API, so it is restored with the updates
+// and reported as inserted.
+func upsertInvoke(
+	tx database.Tx,
+	parsedInput *ParsedUpdateEndpointInput,
+	entity database.Mutator,
+	mutatorRepo repository.MutatorRepo[database.Mutator],
+) (*UpdateResult, error) {
+	upserter, ok := mutatorRepo.(repository.Upserter)
+	if !ok {
+		return nil, fmt.Errorf(
+			"upsertInvoke: mutator repository %T does not support upsert",
+			mutatorRepo,
+		)
+	}
+	updates := parsedInput.Updates
+	if parsedInput.SoftDelete != nil {
+		updates = append(slices.Clip(updates), database.Update{
+			Field: parsedInput.SoftDelete.Column,
+			Value: nil,
+		})
+		restored, err := mutatorRepo.Update(
+			tx,
+			entity,
+			deletedSelectors(
+				parsedInput.UpsertSelectors, parsedInput.SoftDelete,
+			),
+			updates,
+		)
+		if err != nil {
+			return nil, err
+		}
+		if restored != 0 {
+			return &UpdateResult{Count: restored, Inserted: true}, nil
+		}
+	}
+	var columns []string
+	var values []any
+	var conflictColumns []string
+	for _, selector := range parsedInput.UpsertSelectors {
+		columns = append(columns, selector.Column)
+		values = append(values, selector.Value)
+		conflictColumns = append(conflictColumns, selector.Column)
+	}
+	var updateColumns []string
+	for _, update := range updates {
+		columns = append(columns, update.Field)
+		values = append(values, update.Value)
+		updateColumns = append(updateColumns, update.Field)
+	}
+	inserted, err := upserter.Upsert(
+		tx,
+		parsedInput.UpsertSelectors[0].Table,
+		func() ([]string, []any) { return columns, values },
+		conflictColumns,
+		updateColumns,
+	)
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
-	return *count, nil
+	return &UpdateResult{Count: 1, Inserted: inserted}, nil
 }
 
 // ParseUpdateEndpointInput translates API update input into DB update input.
+// If a version is given, the version field is added to the selectors and
+// incremented by the updates.
 func ParseUpdateEndpointInput(
 	apiToDBFields APIToDBFields,
 	selectors endpoint.Selectors,
 	updates endpoint.Updates,
 	upsert bool,
+	versionField *endpoint.DBField,
+	version *ExpectedVersion,
 ) (*ParsedUpdateEndpointInput, error) {
 	dbSelectors, err := selectors.ToDBSelectors(apiToDBFields)
 	if err != nil {
@@ -486,24 +1234,49 @@ func ParseUpdateEndpointInput(
 	if len(dbUpdates) == 0 {
 		return nil, NeedAtLeastOneUpdateError
 	}
+	var upsertSelectors database.Selectors
+	if upsert {
+		for _, selector := range dbSelectors {
+			if selector.Predicate != "=" || selector.Value == nil {
+				return nil, UpsertSelectorNotEqualError.WithData(
+					selector.Column,
+				)
+			}
+		}
+		upsertSelectors = slices.Clone(dbSelectors)
+	}
+	if version != nil {
+		dbSelectors = versionSelectors(dbSelectors, versionField, *version)
+		dbUpdates = append(dbUpdates, versionUpdate(versionField, *version))
+	}
 	return &ParsedUpdateEndpointInput{
-		Selectors: dbSelectors,
-		Updates:   dbUpdates,
-		Upsert:    upsert,
+		Selectors:       dbSelectors,
+		UpsertSelectors: upsertSelectors,
+		Updates:         dbUpdates,
+		Upsert:          upsert,
+		Version:         version,
+		VersionField:    versionField,
 	}, nil
 }
 
-// ToUpdateOutput wraps the update count.
-func ToUpdateOutput(count int64) (any, error) {
-	return &UpdateOutput{Count: count}, nil
+// ToUpdateOutput wraps the update result.
+func ToUpdateOutput(result *UpdateResult) (any, error) {
+	return &UpdateOutput{
+		Count:    result.Count,
+		Inserted: result.Inserted,
+	}, nil
 }
 
 // GenericDeleteDefinition builds the endpoint definition for a delete
-// operation.
+// operation. If versionField is set, the delete requires the expected version
+// of the entity. If softDeleteField is set, rows are soft deleted by setting
+// the deletion time instead of being deleted.
 func GenericDeleteDefinition(
 	url string,
 	inputHandler InputHandler,
 	apiToDBFields APIToDBFields,
+	versionField *endpoint.DBField,
+	softDeleteField *endpoint.DBField,
 	connFn repository.ConnFn,
 	entityFactoryFn DeleteEntityFactoryFn,
 	beforeCallback func(
@@ -512,17 +1285,37 @@ func GenericDeleteDefinition(
 		input *DeleteInput,
 	) error,
 	loggerFactoryFn LoggerFactoryFn,
+	mutatorRepo repository.MutatorRepo[database.Mutator],
+	txManager repository.TxManager[*int64],
 	systemId string,
 ) *EndpointHandler[DeleteInput] {
 	parseInputFn := func(
 		input *DeleteInput,
 	) (*ParsedDeleteEndpointInput, error) {
-		return ParseDeleteEndpointInput(
+		version, err := ResolveVersion(
+			versionField, input.Version, input.IfMatch,
+		)
+		if err != nil {
+			return nil, err
+		}
+		parsedInput, err := ParseDeleteEndpointInput(
 			apiToDBFields,
 			input.Selectors,
 			nil,
 			0,
+			versionField,
+			version,
 		)
+		if err != nil {
+			return nil, err
+		}
+		if softDeleteField != nil {
+			parsedInput.Selectors = notDeletedSelectors(
+				parsedInput.Selectors, softDeleteField, false,
+			)
+			parsedInput.SoftDelete = softDeleteField
+		}
+		return parsedInput, nil
 	}
 	handler := &DeleteHandler[DeleteInput]{
 		parseInputFn:    parseInputFn,
@@ -531,6 +1324,8 @@ func GenericDeleteDefinition(
 		connFn:          connFn,
 		entityFactoryFn: entityFactoryFn,
 		beforeCallback:  beforeCallback,
+		mutatorRepo:     mutatorRepo,
+		txManager:       txManager,
 	}
 	return NewEndpointHandler(
 		url,
@@ -538,13 +1333,19 @@ func GenericDeleteDefinition(
 		inputHandler,
 		func() DeleteInput { return DeleteInput{} },
 		handler.Handle,
-		NewErrorBuilder(systemId).With(DeleteErrors()).Build(),
+		NewErrorBuilder(systemId).
+			With(DeleteErrors()).
+			With(VersionErrors()).
+			Build(),
 		loggerFactoryFn,
 		systemId,
 	)
 }
 
-// DeleteInvoke executes the delete operation.
+// DeleteInvoke executes the delete operation. If soft delete is set, the rows
+// are updated with the deletion time instead. If a version is expected and no
+// rows match, the delete fails with NoRowsError if the row does not exist and
+// otherwise with VersionConflictError.
 func DeleteInvoke[Entity database.Mutator](
 	ctx context.Context,
 	parsedInput *ParsedDeleteEndpointInput,
@@ -557,13 +1358,37 @@ func DeleteInvoke[Entity database.Mutator](
 		ctx,
 		connFn,
 		func(ctx context.Context, tx database.Tx) (*int64, error) {
-			c, err := mutatorRepo.Delete(
-				tx,
-				entity,
-				parsedInput.Selectors,
-				parsedInput.DeleteOpts,
-			)
-			return &c, err
+			var c int64
+			var err error
+			if parsedInput.SoftDelete != nil {
+				c, err = mutatorRepo.Update(
+					tx,
+					entity,
+					parsedInput.Selectors,
+					softDeleteUpdates(parsedInput),
+				)
+			} else {
+				c, err = mutatorRepo.Delete(
+					tx,
+					entity,
+					parsedInput.Selectors,
+					parsedInput.DeleteOpts,
+				)
+			}
+			if err != nil {
+				return nil, err
+			}
+			if c == 0 && parsedInput.Version != nil {
+				return nil, versionMismatchError(
+					tx,
+					entity.TableName(),
+					parsedInput.Selectors,
+					parsedInput.VersionField,
+					*parsedInput.Version,
+					mutatorRepo,
+				)
+			}
+			return &c, nil
 		})
 	if err != nil {
 		return 0, err
@@ -572,11 +1397,14 @@ func DeleteInvoke[Entity database.Mutator](
 }
 
 // ParseDeleteEndpointInput translates API delete input into DB delete input.
+// If a version is given, the version field is added to the selectors.
 func ParseDeleteEndpointInput(
 	apiToDBFields APIToDBFields,
 	selectors endpoint.Selectors,
 	orders endpoint.Orders,
 	limit int,
+	versionField *endpoint.DBField,
+	version *ExpectedVersion,
 ) (*ParsedDeleteEndpointInput, error) {
 	dbSelectors, err := selectors.ToDBSelectors(apiToDBFields)
 	if err != nil {
@@ -589,12 +1417,17 @@ func ParseDeleteEndpointInput(
 	if err != nil {
 		return nil, err
 	}
+	if version != nil {
+		dbSelectors = versionSelectors(dbSelectors, versionField, *version)
+	}
 	return &ParsedDeleteEndpointInput{
 		Selectors: dbSelectors,
 		DeleteOpts: &database.DeleteOptions{
 			Limit:  limit,
 			Orders: dbOrders,
 		},
+		Version:      version,
+		VersionField: versionField,
 	}, nil
 }
 
@@ -602,3 +1435,90 @@ func ParseDeleteEndpointInput(
 func ToDeleteOutput(count int64) (any, error) {
 	return &DeleteOutput{Count: count}, nil
 }
+
+// GenericRestoreDefinition builds the endpoint definition for a restore
+// operation. It clears the deletion time of the soft deleted rows that match
+// the selectors. The errors of the restore are mapped by the MapErrorFn of the
+// options.
+func GenericRestoreDefinition(
+	url string,
+	inputHandler InputHandler,
+	apiToDBFields APIToDBFields,
+	softDeleteField *endpoint.DBField,
+	connFn repository.ConnFn,
+	entityFactoryFn UpdateEntityFactoryFn,
+	beforeCallback func(
+		ctx context.Context,
+		entity database.Mutator,
+		input *RestoreInput,
+	) error,
+	loggerFactoryFn LoggerFactoryFn,
+	mutatorRepo repository.MutatorRepo[database.Mutator],
+	txManager repository.TxManager[*UpdateResult],
+	systemId string,
+	options ...GenericEndpointOptions,
+) *EndpointHandler[RestoreInput] {
+	expectedErrors, mapErrorFn := resolveEndpointOptions(
+		options,
+		func() api.ExpectedErrors {
+			return NewErrorBuilder(systemId).With(RestoreErrors()).Build()
+		},
+	)
+	parseInputFn := func(
+		input *RestoreInput,
+	) (*ParsedUpdateEndpointInput, error) {
+		return ParseRestoreEndpointInput(
+			apiToDBFields, input.Selectors, softDeleteField,
+		)
+	}
+	handler := &UpdateHandler[RestoreInput]{
+		parseInputFn:    parseInputFn,
+		updateInvokeFn:  UpdateInvoke,
+		toOutputFn:      ToRestoreOutput,
+		connFn:          connFn,
+		entityFactoryFn: entityFactoryFn,
+		beforeCallback:  beforeCallback,
+		mutatorRepo:     mutatorRepo,
+		txManager:       txManager,
+	}
+	return NewEndpointHandler(
+		url,
+		http.MethodPost,
+		inputHandler,
+		func() RestoreInput { return RestoreInput{} },
+		mapHandlerErrors(handler.Handle, mapErrorFn),
+		expectedErrors,
+		loggerFactoryFn,
+		systemId,
+	)
+}
+
+// ParseRestoreEndpointInput translates API restore input into a DB update
+// that clears the deletion time of the matching soft deleted rows.
+func ParseRestoreEndpointInput(
+	apiToDBFields APIToDBFields,
+	selectors endpoint.Selectors,
+	softDeleteField *endpoint.DBField,
+) (*ParsedUpdateEndpointInput, error) {
+	dbSelectors, err := selectors.ToDBSelectors(apiToDBFields)
+	if err != nil {
+		return nil, err
+	}
+	if len(dbSelectors) == 0 {
+		return nil, NeedAtLeastOneSelectorError
+	}
+	return &ParsedUpdateEndpointInput{
+		Selectors: deletedSelectors(dbSelectors, softDeleteField),
+		Updates: []database.Update{
+			{
+				Field: softDeleteField.Column,
+				Value: nil,
+			},
+		},
+	}, nil
+}
+
+// ToRestoreOutput wraps the restore count.
+func ToRestoreOutput(result *UpdateResult) (any, error) {
+	return &RestoreOutput{Count: result.Count}, nil
+}
@@ -0,0 +1,297 @@
+package endpoint
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/pakkasys/fluidapi-extended/api"
+	"github.com/pakkasys/fluidapi-extended/api/repository"
+	extendeddatabase "github.com/pakkasys/fluidapi-extended/database"
+	"github.com/pakkasys/fluidapi/database"
+	"github.com/pakkasys/fluidapi/endpoint"
+)
+
+// upsertRepo records the arguments of its upserts. Its updates restore the
+// given count of soft deleted rows.
+type upsertRepo struct {
+	repository.MutatorRepo[database.Mutator]
+	restored        int64
+	restoreUpdates  database.Updates
+	inserted        bool
+	tableName       string
+	columns         []string
+	values          []any
+	conflictColumns []string
+	updateColumns   []string
+}
+
+func (r *upsertRepo) Upsert(
+	preparer database.Preparer,
+	tableName string,
+	insertedValues database.InsertedValuesFn,
+	conflictColumns []string,
+	updateColumns []string,
+) (bool, error) {
+	r.tableName = tableName
+	r.columns, r.values = insertedValues()
+	r.conflictColumns = conflictColumns
+	r.updateColumns = updateColumns
+	return r.inserted, nil
+}
+
+func (r *upsertRepo) Update(
+	preparer database.Preparer,
+	updater database.Mutator,
+	selectors database.Selectors,
+	updates database.Updates,
+) (int64, error) {
+	r.restoreUpdates = updates
+	return r.restored, nil
+}
+
+func TestUpsertInvoke(t *testing.T) {
+	emailSelector := database.Selector{
+		Table: "users", Column: "email", Predicate: "=", Value: "a@b.c",
+	}
+	parsedInput := &ParsedUpdateEndpointInput{
+		Selectors: database.Selectors{
+			emailSelector,
+			{Table: "users", Column: "deleted_at", Predicate: "=", Value: nil},
+		},
+		UpsertSelectors: database.Selectors{emailSelector},
+		Updates:         []database.Update{{Field: "name", Value: "A"}},
+		Upsert:          true,
+	}
+
+	for _, inserted := range []bool{true, false} {
+		repo := &upsertRepo{inserted: inserted}
+		result, err := upsertInvoke(nil, parsedInput, user{}, repo)
+		if err != nil {
+			t.Fatalf("upsertInvoke: %v", err)
+		}
+		expected := &UpdateResult{Count: 1, Inserted: inserted}
+		if !reflect.DeepEqual(result, expected) {
+			t.Errorf("expected %v, got %v", expected, result)
+		}
+		if repo.tableName != "users" ||
+			!reflect.DeepEqual(repo.columns, []string{"email", "name"}) ||
+			!reflect.DeepEqual(repo.values, []any{"a@b.c", "A"}) ||
+			!reflect.DeepEqual(repo.conflictColumns, []string{"email"}) ||
+			!reflect.DeepEqual(repo.updateColumns, []string{"name"}) {
+			t.Errorf("unexpected upsert arguments: %+v", repo)
+		}
+	}
+
+	t.Run("SoftDelete", func(t *testing.T) {
+		softDeleteInput := *parsedInput
+		softDeleteInput.SoftDelete = deletedAtField
+		expectedUpdates := database.Updates{
+			{Field: "name", Value: "A"},
+			{Field: "deleted_at", Value: nil},
+		}
+
+		repo := &upsertRepo{restored: 1}
+		result, err := upsertInvoke(nil, &softDeleteInput, user{}, repo)
+		if err != nil || !result.Inserted || repo.tableName != "" {
+			t.Errorf("expected the deleted row to be restored, got %+v %v",
+				result, err)
+		}
+		if !reflect.DeepEqual(repo.restoreUpdates, expectedUpdates) {
+			t.Errorf("expected updates %v, got %v",
+				expectedUpdates, repo.restoreUpdates)
+		}
+
+		repo = &upsertRepo{}
+		if _, err := upsertInvoke(nil, &softDeleteInput, user{}, repo); err != nil {
+			t.Fatalf("upsertInvoke: %v", err)
+		}
+		if !reflect.DeepEqual(
+			repo.updateColumns, []string{"name", "deleted_at"},
+		) {
+			t.Errorf("expected the deletion time to be cleared on conflict,"+
+				" got %v", repo.updateColumns)
+		}
+	})
+
+	t.Run("NotUpserter", func(t *testing.T) {
+		repo := struct {
+			repository.MutatorRepo[database.Mutator]
+		}{}
+		if _, err := upsertInvoke(nil, parsedInput, user{}, repo); err == nil {
+			t.Errorf("expected an error without an Upserter")
+		}
+	})
+}
+
+// countingTxManager counts its transactions and runs the callback without a
+// transaction.
+type countingTxManager[Result any] struct {
+	transactions int
+}
+
+func (m *countingTxManager[Result]) WithTransaction(
+	ctx context.Context,
+	connFn repository.ConnFn,
+	callback func(ctx context.Context, tx database.Tx) (Result, error),
+) (Result, error) {
+	m.transactions++
+	return callback(ctx, nil)
+}
+
+// pageRepo returns the entities of a page and their total count, and records
+// the read page.
+type pageRepo struct {
+	repository.ReaderRepo[user]
+	entities []user
+	total    int
+	page     *database.Page
+}
+
+func (r *pageRepo) GetMany(
+	preparer database.Preparer,
+	entityFactoryFn repository.GetterFactoryFn[user],
+	getOptions *database.GetOptions,
+) ([]user, error) {
+	r.page = getOptions.Page
+	return r.entities, nil
+}
+
+func (r *pageRepo) Count(
+	preparer database.Preparer,
+	selectors database.Selectors,
+	page *database.Page,
+	entityFactoryFn repository.GetterFactoryFn[user],
+) (int, error) {
+	return r.total, nil
+}
+
+func TestGetInvoke(t *testing.T) {
+	for _, tt := range []struct {
+		name     string
+		entities []user
+		total    bool
+		hasMore  bool
+	}{
+		{"HasMore", []user{{}, {}, {}}, false, true},
+		{"LastPage", []user{{}, {}}, false, false},
+		{"Total", []user{{}, {}, {}}, true, true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			txManager := &countingTxManager[user]{}
+			repo := &pageRepo{entities: tt.entities, total: 3}
+			result, err := GetInvoke(
+				context.Background(),
+				&ParsedGetEndpointInput{
+					Page:  &database.Page{Offset: 0, Limit: 2},
+					Total: tt.total,
+				},
+				nil,
+				func() user { return user{} },
+				repo,
+				txManager,
+			)
+			if err != nil {
+				t.Fatalf("GetInvoke: %v", err)
+			}
+			if txManager.transactions != 1 {
+				t.Errorf("expected 1 transaction, got %d", txManager.transactions)
+			}
+			if repo.page == nil || repo.page.Limit != 3 {
+				t.Errorf("expected the extra row to be read, got %+v", repo.page)
+			}
+			if result.Count != 2 || result.HasMore != tt.hasMore ||
+				result.Page.Limit != 2 {
+				t.Errorf("expected a page of 2 rows, got %+v", result)
+			}
+			if tt.total != (result.Total != nil) ||
+				(tt.total && *result.Total != 3) {
+				t.Errorf("expected the total only if requested, got %v",
+					result.Total)
+			}
+		})
+	}
+}
+
+func TestParseGetEndpointInputInvalidPage(t *testing.T) {
+	for _, page := range []*endpoint.Page{
+		{Offset: 0, Limit: -1},
+		{Offset: -1, Limit: 10},
+	} {
+		_, err := ParseGetEndpointInput(
+			nil, nil, nil, page, DefaultPageLimit, MaxPageLimit, false,
+		)
+		expectAPIError(t, err, InvalidPageError.ID)
+	}
+}
+
+func TestGetInvokeNotFilteredReader(t *testing.T) {
+	_, err := GetInvoke(
+		context.Background(),
+		&ParsedGetEndpointInput{
+			Page:   &database.Page{Offset: 0, Limit: 2},
+			Filter: &extendeddatabase.Filter{},
+		},
+		nil,
+		func() user { return user{} },
+		&pageRepo{},
+		&countingTxManager[user]{},
+	)
+	if err == nil {
+		t.Errorf("expected an error without a FilteredReader")
+	}
+}
+
+func TestGetInvokeNotKeysetReader(t *testing.T) {
+	_, err := GetInvoke(
+		context.Background(),
+		&ParsedGetEndpointInput{
+			Page: &database.Page{Offset: 0, Limit: 2},
+			Cursor: &ParsedCursor{
+				Keyset: &extendeddatabase.Keyset{},
+				Limit:  1,
+			},
+		},
+		nil,
+		func() user { return user{} },
+		&pageRepo{},
+		&countingTxManager[user]{},
+	)
+	if err == nil {
+		t.Errorf("expected an error without a KeysetReader")
+	}
+}
+
+func TestGenericMutationDefinitionOptions(t *testing.T) {
+	mappedErr := errors.New("mapped")
+	expectedErrors := WithConstraintFieldErrors(UpdateErrors())
+	options := GenericEndpointOptions{
+		ExpectedErrors: &expectedErrors,
+		MapErrorFn:     func(err error) error { return mappedErr },
+	}
+
+	update := GenericUpdateDefinition(
+		"/users", nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, "api",
+		options,
+	)
+	restore := GenericRestoreDefinition(
+		"/users/restore", nil, nil, nil, nil, nil, nil, nil, nil, nil, "api",
+		options,
+	)
+	for name, handler := range map[string]interface {
+		ExpectedErrors() []api.ExpectedError
+	}{"Update": update, "Restore": restore} {
+		got := api.ExpectedErrors(handler.ExpectedErrors())
+		if !reflect.DeepEqual(got, expectedErrors) {
+			t.Errorf("%s: expected the expected errors of the options", name)
+		}
+	}
+	// The inputs have no selectors, so the handlers fail before the database.
+	if _, err := update.handlerLogic(nil, nil, &UpdateInput{}); err != mappedErr {
+		t.Errorf("Update: expected the mapped error, got %v", err)
+	}
+	if _, err := restore.handlerLogic(nil, nil, &RestoreInput{}); err != mappedErr {
+		t.Errorf("Restore: expected the mapped error, got %v", err)
+	}
+}
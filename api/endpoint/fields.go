@@ -0,0 +1,89 @@
+package endpoint
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/pakkasys/fluidapi/core"
+	"github.com/pakkasys/fluidapi/database"
+)
+
+// FieldFields is the input field used to select the returned fields.
+const FieldFields = "fields"
+
+// InvalidFieldError is returned when a selected field is not an output field.
+var InvalidFieldError = core.NewAPIError("INVALID_FIELD")
+
+// ParseFields parses a comma separated list of output API field names and
+// returns the selected field names with the projections that select their DB
+// columns. If no fields are given, nil is returned and all fields are
+// selected.
+//
+// Example:
+//
+//	ParseFields(outputToDBFields, "id,name")
+//
+// Output:
+//
+//	[]string{"id", "name"}
+//	[]database.Projection{
+//	    {Table: "users", Column: "id"},
+//	    {Table: "users", Column: "name"},
+//	}
+//
+// Parameters:
+//   - outputToDBFields: The DB fields of the output API fields.
+//   - fields: The comma separated field names.
+//
+// Returns:
+//   - []string: The selected field names.
+//   - []database.Projection: The projections of the selected fields.
+//   - error: InvalidFieldError if a field is not an output field.
+func ParseFields(
+	outputToDBFields APIToDBFields, fields string,
+) ([]string, []database.Projection, error) {
+	if strings.TrimSpace(fields) == "" {
+		return nil, nil, nil
+	}
+	var names []string
+	var projections []database.Projection
+	for _, name := range strings.Split(fields, ",") {
+		name = strings.TrimSpace(name)
+		dbField, ok := outputToDBFields[name]
+		if !ok {
+			return nil, nil, InvalidFieldError.WithData(name)
+		}
+		if slices.Contains(names, name) {
+			continue
+		}
+		names = append(names, name)
+		projections = append(projections, database.Projection{
+			Table:  dbField.Table,
+			Column: dbField.Column,
+		})
+	}
+	return names, projections, nil
+}
+
+// withOrderProjections returns the projections with the ordered columns that
+// are not projected appended. It is used to read the cursor values of a page.
+func withOrderProjections(
+	projections []database.Projection, orders []database.Order,
+) []database.Projection {
+	for _, order := range orders {
+		projected := slices.ContainsFunc(
+			projections,
+			func(projection database.Projection) bool {
+				return projection.Table == order.Table &&
+					projection.Column == order.Field
+			},
+		)
+		if !projected {
+			projections = append(projections, database.Projection{
+				Table:  order.Table,
+				Column: order.Field,
+			})
+		}
+	}
+	return projections
+}
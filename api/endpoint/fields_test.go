@@ -0,0 +1,60 @@
+package endpoint
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/pakkasys/fluidapi/database"
+)
+
+func TestParseFields(t *testing.T) {
+	outputToDBFields := APIToDBFields{
+		"id":   {Table: "users", Column: "id"},
+		"name": {Table: "users", Column: "user_name"},
+	}
+
+	t.Run("SelectedFields", func(t *testing.T) {
+		names, projections, err := ParseFields(outputToDBFields, "name, id,name")
+		if err != nil {
+			t.Fatalf("ParseFields: %v", err)
+		}
+		if !reflect.DeepEqual(names, []string{"name", "id"}) {
+			t.Errorf("unexpected names: %v", names)
+		}
+		expected := []database.Projection{
+			{Table: "users", Column: "user_name"},
+			{Table: "users", Column: "id"},
+		}
+		if !reflect.DeepEqual(projections, expected) {
+			t.Errorf("expected projections %v, got %v", expected, projections)
+		}
+	})
+
+	t.Run("NoFields", func(t *testing.T) {
+		names, projections, err := ParseFields(outputToDBFields, "")
+		if err != nil || names != nil || projections != nil {
+			t.Errorf("expected all fields, got %v %v %v", names, projections, err)
+		}
+	})
+
+	t.Run("UnknownField", func(t *testing.T) {
+		_, _, err := ParseFields(outputToDBFields, "id,password")
+		expectAPIError(t, err, InvalidFieldError.ID)
+	})
+}
+
+func TestWithOrderProjections(t *testing.T) {
+	projections := []database.Projection{{Table: "users", Column: "name"}}
+	orders := []database.Order{
+		{Table: "users", Field: "name", Direction: "ASC"},
+		{Table: "users", Field: "id", Direction: "ASC"},
+	}
+	got := withOrderProjections(projections, orders)
+	expected := []database.Projection{
+		{Table: "users", Column: "name"},
+		{Table: "users", Column: "id"},
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+}
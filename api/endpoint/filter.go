@@ -0,0 +1,481 @@
+package endpoint
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	extendeddatabase "github.com/pakkasys/fluidapi-extended/database"
+	"github.com/pakkasys/fluidapi/core"
+	"github.com/pakkasys/fluidapi/database"
+	"github.com/pakkasys/fluidapi/endpoint"
+)
+
+// FieldFilter is the input field used to filter the rows with a filter
+// expression.
+const FieldFilter = "filter"
+
+// Default limits of filter expressions.
+const (
+	DefaultFilterMaxDepth = 5
+	DefaultFilterMaxTerms = 20
+)
+
+// Filter errors.
+var (
+	InvalidFilterError    = core.NewAPIError("INVALID_FILTER")
+	FilterTooComplexError = core.NewAPIError("FILTER_TOO_COMPLEX")
+)
+
+// FilterField is a field that can be used in filter expressions.
+type FilterField struct {
+	DBField endpoint.DBField
+	// Predicates are the predicates allowed for the field.
+	Predicates endpoint.Predicates
+	// Type is the API type of the field. The term values are converted to it.
+	Type string
+}
+
+// FilterOptions configures the filter expressions of the get endpoint.
+type FilterOptions struct {
+	// Fields are the filterable fields by API field name.
+	Fields map[string]FilterField
+	// MaxDepth limits the nesting depth of the groups. DefaultFilterMaxDepth
+	// is used if it is not set.
+	MaxDepth int
+	// MaxTerms limits the number of terms. DefaultFilterMaxTerms is used if it
+	// is not set.
+	MaxTerms int
+}
+
+// filterPredicates maps the predicates of filter terms to DB predicates.
+var filterPredicates = map[string]database.Predicate{
+	"=":      "=",
+	"eq":     "=",
+	"!=":     "!=",
+	"ne":     "!=",
+	">":      ">",
+	"gt":     ">",
+	">=":     ">=",
+	"ge":     ">=",
+	"gte":    ">=",
+	"<":      "<",
+	"lt":     "<",
+	"<=":     "<=",
+	"le":     "<=",
+	"lte":    "<=",
+	"in":     "IN",
+	"not_in": "NOT IN",
+}
+
+// filterNull is the term value that matches NULL.
+const filterNull = "null"
+
+// ParseFilter parses a filter expression. The expression combines terms of the
+// form field:predicate:value with AND, OR and NOT, and parentheses group them.
+// AND binds tighter than OR. The values of in and not_in terms are separated
+// by commas, values with spaces, commas or parentheses can be double quoted
+// and the value null matches NULL with the eq and ne predicates.
+//
+// Example:
+//
+//	status:eq:active OR (age:gte:18 AND country:in:FI,SE)
+//
+// Parameters:
+//   - options: The filter options. Filters are not allowed if it is nil.
+//   - expression: The filter expression, empty for no filter.
+//
+// Returns:
+//   - *extendeddatabase.Filter: The filter, nil if the expression is empty.
+//   - error: InvalidFilterError if the expression is invalid and
+//     FilterTooComplexError if it exceeds the limits. A term with an unknown
+//     field or predicate returns the corresponding selector error.
+func ParseFilter(
+	options *FilterOptions, expression string,
+) (*extendeddatabase.Filter, error) {
+	if strings.TrimSpace(expression) == "" {
+		return nil, nil
+	}
+	if options == nil {
+		return nil, InvalidFilterError.WithData("filters are not allowed")
+	}
+	tokens, err := tokenizeFilter(expression)
+	if err != nil {
+		return nil, err
+	}
+	parser := &filterParser{
+		tokens:   tokens,
+		fields:   options.Fields,
+		maxDepth: options.MaxDepth,
+		maxTerms: options.MaxTerms,
+	}
+	if parser.maxDepth <= 0 {
+		parser.maxDepth = DefaultFilterMaxDepth
+	}
+	if parser.maxTerms <= 0 {
+		parser.maxTerms = DefaultFilterMaxTerms
+	}
+	filter, err := parser.parseOr(0)
+	if err != nil {
+		return nil, err
+	}
+	if parser.pos != len(tokens) {
+		return nil, InvalidFilterError.WithData(
+			fmt.Sprintf("unexpected %q", tokens[parser.pos].text),
+		)
+	}
+	if filterDepth(*filter) > parser.maxDepth {
+		return nil, FilterTooComplexError.WithData(parser.maxDepth)
+	}
+	return filter, nil
+}
+
+// filterTokenKind is the kind of a filter expression token.
+type filterTokenKind int
+
+// Filter expression token kinds.
+const (
+	filterTokenTerm filterTokenKind = iota
+	filterTokenAnd
+	filterTokenOr
+	filterTokenNot
+	filterTokenOpen
+	filterTokenClose
+)
+
+// filterToken is a token of a filter expression.
+type filterToken struct {
+	kind filterTokenKind
+	text string
+}
+
+// tokenizeFilter splits a filter expression into tokens. The tokens are
+// separated by whitespace and parentheses outside of double quotes.
+func tokenizeFilter(expression string) ([]filterToken, error) {
+	var tokens []filterToken
+	for i := 0; i < len(expression); {
+		switch c := expression[i]; {
+		case isFilterSpace(c):
+			i++
+		case c == '(':
+			tokens = append(tokens, filterToken{kind: filterTokenOpen, text: "("})
+			i++
+		case c == ')':
+			tokens = append(tokens, filterToken{kind: filterTokenClose, text: ")"})
+			i++
+		default:
+			start := i
+			quoted := false
+			for i < len(expression) {
+				c := expression[i]
+				if quoted {
+					if c == '\\' {
+						i += 2
+						continue
+					}
+					if c == '"' {
+						quoted = false
+					}
+					i++
+					continue
+				}
+				if c == '"' {
+					quoted = true
+				} else if c == '(' || c == ')' || isFilterSpace(c) {
+					break
+				}
+				i++
+			}
+			if quoted {
+				return nil, InvalidFilterError.WithData("unterminated quote")
+			}
+			word := expression[start:min(i, len(expression))]
+			tokens = append(tokens, filterWordToken(word))
+		}
+	}
+	return tokens, nil
+}
+
+// filterWordToken returns the token of a word. The operators are case
+// insensitive and all other words are terms.
+func filterWordToken(word string) filterToken {
+	switch strings.ToUpper(word) {
+	case string(extendeddatabase.FilterAnd):
+		return filterToken{kind: filterTokenAnd, text: word}
+	case string(extendeddatabase.FilterOr):
+		return filterToken{kind: filterTokenOr, text: word}
+	case string(extendeddatabase.FilterNot):
+		return filterToken{kind: filterTokenNot, text: word}
+	default:
+		return filterToken{kind: filterTokenTerm, text: word}
+	}
+}
+
+// isFilterSpace returns true if the character separates filter tokens.
+func isFilterSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
+
+// filterParser is a recursive descent parser of filter expressions.
+type filterParser struct {
+	tokens   []filterToken
+	pos      int
+	fields   map[string]FilterField
+	maxDepth int
+	maxTerms int
+	terms    int
+}
+
+// parseOr parses filters separated by OR.
+func (p *filterParser) parseOr(depth int) (*extendeddatabase.Filter, error) {
+	return p.parseGroup(
+		depth, filterTokenOr, extendeddatabase.FilterOr, p.parseAnd,
+	)
+}
+
+// parseAnd parses filters separated by AND.
+func (p *filterParser) parseAnd(depth int) (*extendeddatabase.Filter, error) {
+	return p.parseGroup(
+		depth, filterTokenAnd, extendeddatabase.FilterAnd, p.parseNot,
+	)
+}
+
+// parseGroup parses filters separated by the operator token. A single filter
+// is returned as is.
+func (p *filterParser) parseGroup(
+	depth int,
+	kind filterTokenKind,
+	operator extendeddatabase.FilterOperator,
+	next func(depth int) (*extendeddatabase.Filter, error),
+) (*extendeddatabase.Filter, error) {
+	first, err := next(depth)
+	if err != nil {
+		return nil, err
+	}
+	filters := []extendeddatabase.Filter{*first}
+	for p.pos < len(p.tokens) && p.tokens[p.pos].kind == kind {
+		p.pos++
+		filter, err := next(depth)
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, *filter)
+	}
+	if len(filters) == 1 {
+		return first, nil
+	}
+	return extendeddatabase.NewFilterGroup(operator, filters...), nil
+}
+
+// parseNot parses a negated filter or a primary filter.
+func (p *filterParser) parseNot(depth int) (*extendeddatabase.Filter, error) {
+	if p.pos < len(p.tokens) && p.tokens[p.pos].kind == filterTokenNot {
+		p.pos++
+		if depth+1 > p.maxDepth {
+			return nil, FilterTooComplexError.WithData(p.maxDepth)
+		}
+		filter, err := p.parseNot(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		return extendeddatabase.NewFilterGroup(
+			extendeddatabase.FilterNot, *filter,
+		), nil
+	}
+	return p.parsePrimary(depth)
+}
+
+// parsePrimary parses a parenthesized filter or a term.
+func (p *filterParser) parsePrimary(
+	depth int,
+) (*extendeddatabase.Filter, error) {
+	if p.pos >= len(p.tokens) {
+		return nil, InvalidFilterError.WithData("unexpected end of filter")
+	}
+	token := p.tokens[p.pos]
+	switch token.kind {
+	case filterTokenOpen:
+		if depth+1 > p.maxDepth {
+			return nil, FilterTooComplexError.WithData(p.maxDepth)
+		}
+		p.pos++
+		filter, err := p.parseOr(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		if p.pos >= len(p.tokens) || p.tokens[p.pos].kind != filterTokenClose {
+			return nil, InvalidFilterError.WithData("missing closing parenthesis")
+		}
+		p.pos++
+		return filter, nil
+	case filterTokenTerm:
+		p.pos++
+		return p.parseTerm(token.text)
+	default:
+		return nil, InvalidFilterError.WithData(
+			fmt.Sprintf("unexpected %q", token.text),
+		)
+	}
+}
+
+// parseTerm parses a term of the form field:predicate:value.
+func (p *filterParser) parseTerm(text string) (*extendeddatabase.Filter, error) {
+	p.terms++
+	if p.terms > p.maxTerms {
+		return nil, FilterTooComplexError.WithData(p.maxTerms)
+	}
+	parts := strings.SplitN(text, ":", 3)
+	if len(parts) != 3 {
+		return nil, InvalidFilterError.WithData(
+			fmt.Sprintf("invalid term %q", text),
+		)
+	}
+	field, ok := p.fields[parts[0]]
+	if !ok {
+		return nil, endpoint.InvalidSelectorFieldError.WithData(parts[0])
+	}
+	predicate, err := filterPredicate(field, parts[1])
+	if err != nil {
+		return nil, err
+	}
+	value, err := filterValue(field, predicate, parts[2])
+	if err != nil {
+		return nil, err
+	}
+	return extendeddatabase.NewFilterTerm(database.Selector{
+		Table:     field.DBField.Table,
+		Column:    field.DBField.Column,
+		Predicate: predicate,
+		Value:     value,
+	}), nil
+}
+
+// filterPredicate returns the DB predicate of a term predicate. The predicate
+// must be one of the predicates allowed for the field.
+func filterPredicate(
+	field FilterField, predicate string,
+) (database.Predicate, error) {
+	dbPredicate, ok := filterPredicates[strings.ToLower(predicate)]
+	if !ok {
+		return "", endpoint.InvalidPredicateError.WithData(predicate)
+	}
+	for _, allowed := range field.Predicates.StrSlice() {
+		if filterPredicates[strings.ToLower(allowed)] == dbPredicate {
+			return dbPredicate, nil
+		}
+	}
+	return "", endpoint.PredicateNotAllowedError.WithData(predicate)
+}
+
+// filterValue returns the value of a term converted to the field type. The
+// value of in and not_in terms is a list.
+func filterValue(
+	field FilterField, predicate database.Predicate, value string,
+) (any, error) {
+	if predicate == "IN" || predicate == "NOT IN" {
+		items, err := splitFilterList(value)
+		if err != nil {
+			return nil, err
+		}
+		values := make([]any, len(items))
+		for i, item := range items {
+			converted, err := convertFilterValue(field.Type, item)
+			if err != nil {
+				return nil, err
+			}
+			if converted == nil {
+				return nil, InvalidFilterError.WithData(
+					fmt.Sprintf("null in list %q", value),
+				)
+			}
+			values[i] = converted
+		}
+		return values, nil
+	}
+	converted, err := convertFilterValue(field.Type, value)
+	if err != nil {
+		return nil, err
+	}
+	if converted == nil && predicate != "=" && predicate != "!=" {
+		return nil, InvalidFilterError.WithData(
+			fmt.Sprintf("null with predicate %q", predicate),
+		)
+	}
+	return converted, nil
+}
+
+// splitFilterList splits a list value by the commas outside of double quotes.
+func splitFilterList(value string) ([]string, error) {
+	var items []string
+	start := 0
+	quoted := false
+	for i := 0; i < len(value); i++ {
+		switch value[i] {
+		case '\\':
+			if quoted {
+				i++
+			}
+		case '"':
+			quoted = !quoted
+		case ',':
+			if !quoted {
+				items = append(items, value[start:i])
+				start = i + 1
+			}
+		}
+	}
+	items = append(items, value[start:])
+	for _, item := range items {
+		if item == "" {
+			return nil, InvalidFilterError.WithData(
+				fmt.Sprintf("empty item in list %q", value),
+			)
+		}
+	}
+	return items, nil
+}
+
+// convertFilterValue converts a term value to the API type. Double quoted
+// values are unquoted and the unquoted value null is converted to nil.
+func convertFilterValue(apiType string, value string) (any, error) {
+	if value == filterNull {
+		return nil, nil
+	}
+	if strings.HasPrefix(value, `"`) {
+		unquoted, err := strconv.Unquote(value)
+		if err != nil {
+			return nil, InvalidFilterError.WithData(
+				fmt.Sprintf("invalid quoted value %s", value),
+			)
+		}
+		value = unquoted
+	}
+	var converted any
+	var err error
+	switch apiType {
+	case "int", "int64":
+		converted, err = strconv.ParseInt(value, 10, 64)
+	case "bool":
+		converted, err = strconv.ParseBool(value)
+	default:
+		converted = value
+	}
+	if err != nil {
+		return nil, InvalidFilterError.WithData(
+			fmt.Sprintf("invalid %s value %q", apiType, value),
+		)
+	}
+	return converted, nil
+}
+
+// filterDepth returns the nesting depth of the groups of a filter.
+func filterDepth(filter extendeddatabase.Filter) int {
+	depth := 0
+	for _, child := range filter.Filters {
+		depth = max(depth, filterDepth(child))
+	}
+	if filter.Selector != nil {
+		return depth
+	}
+	return depth + 1
+}
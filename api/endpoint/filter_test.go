@@ -0,0 +1,113 @@
+package endpoint
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	extendeddatabase "github.com/pakkasys/fluidapi-extended/database"
+	"github.com/pakkasys/fluidapi/database"
+	"github.com/pakkasys/fluidapi/endpoint"
+)
+
+func TestParseFilter(t *testing.T) {
+	options := &FilterOptions{
+		Fields: map[string]FilterField{
+			"status": {
+				DBField:    endpoint.DBField{Table: "users", Column: "status"},
+				Predicates: endpoint.Predicates{"=", "!="},
+				Type:       "string",
+			},
+			"age": {
+				DBField:    endpoint.DBField{Table: "users", Column: "age"},
+				Predicates: endpoint.Predicates{">="},
+				Type:       "int64",
+			},
+			"country": {
+				DBField:    endpoint.DBField{Table: "users", Column: "country"},
+				Predicates: endpoint.Predicates{"in"},
+				Type:       "string",
+			},
+		},
+	}
+
+	t.Run("Groups", func(t *testing.T) {
+		filter, err := ParseFilter(
+			options,
+			`status:eq:active OR (age:gte:18 AND country:in:FI,"S,E")`,
+		)
+		if err != nil {
+			t.Fatalf("ParseFilter: %v", err)
+		}
+		expected := extendeddatabase.NewFilterGroup(
+			extendeddatabase.FilterOr,
+			*extendeddatabase.NewFilterTerm(database.Selector{
+				Table: "users", Column: "status", Predicate: "=", Value: "active",
+			}),
+			*extendeddatabase.NewFilterGroup(
+				extendeddatabase.FilterAnd,
+				*extendeddatabase.NewFilterTerm(database.Selector{
+					Table: "users", Column: "age", Predicate: ">=", Value: int64(18),
+				}),
+				*extendeddatabase.NewFilterTerm(database.Selector{
+					Table:     "users",
+					Column:    "country",
+					Predicate: "IN",
+					Value:     []any{"FI", "S,E"},
+				}),
+			),
+		)
+		if !reflect.DeepEqual(filter, expected) {
+			t.Errorf("expected %+v, got %+v", expected, filter)
+		}
+	})
+
+	t.Run("NotAndNull", func(t *testing.T) {
+		filter, err := ParseFilter(options, "not status:ne:null")
+		if err != nil {
+			t.Fatalf("ParseFilter: %v", err)
+		}
+		if filter.Operator != extendeddatabase.FilterNot ||
+			filter.Filters[0].Selector.Value != nil {
+			t.Errorf("unexpected filter: %+v", filter)
+		}
+	})
+
+	t.Run("Empty", func(t *testing.T) {
+		filter, err := ParseFilter(nil, " ")
+		if err != nil || filter != nil {
+			t.Errorf("expected no filter, got %+v, %v", filter, err)
+		}
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		tests := []struct {
+			expression string
+			id         string
+		}{
+			{"status:eq:active AND", InvalidFilterError.ID},
+			{"(status:eq:active", InvalidFilterError.ID},
+			{"status:eq:active)", InvalidFilterError.ID},
+			{`status:eq:"active`, InvalidFilterError.ID},
+			{"status", InvalidFilterError.ID},
+			{"age:gte:old", InvalidFilterError.ID},
+			{"age:gte:null", InvalidFilterError.ID},
+			{"name:eq:alice", endpoint.InvalidSelectorFieldError.ID},
+			{"status:like:a", endpoint.InvalidPredicateError.ID},
+			{"age:lt:18", endpoint.PredicateNotAllowedError.ID},
+			{strings.Repeat("(", 6) + "status:eq:a" + strings.Repeat(")", 6),
+				FilterTooComplexError.ID},
+			{strings.Repeat("status:eq:a OR ", 20) + "status:eq:a",
+				FilterTooComplexError.ID},
+		}
+		for _, tt := range tests {
+			_, err := ParseFilter(options, tt.expression)
+			expectAPIError(t, err, tt.id)
+		}
+	})
+
+	t.Run("NotAllowed", func(t *testing.T) {
+		_, err := ParseFilter(nil, "status:eq:active")
+		expectAPIError(t, err, InvalidFilterError.ID)
+	})
+}
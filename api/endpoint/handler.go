@@ -6,6 +6,7 @@ import (
 
 	"github.com/mitchellh/mapstructure"
 	"github.com/pakkasys/fluidapi-extended/api"
+	"github.com/pakkasys/fluidapi-extended/api/repository"
 	"github.com/pakkasys/fluidapi/core"
 	"github.com/pakkasys/fluidapi/endpoint"
 )
@@ -83,7 +84,14 @@ func (h *EndpointHandler[Input]) Handle(
 		return
 	}
 
+	r = r.WithContext(repository.WithTxAttempts(r.Context()))
 	out, err := h.handlerLogic(w, r, input)
+	if attempts := repository.TxAttempts(r.Context()); attempts > 1 &&
+		h.loggerFactoryFn != nil {
+		h.loggerFactoryFn(r).Trace(
+			fmt.Sprintf("Transaction attempts: %d", attempts),
+		)
+	}
 	if err != nil {
 		h.handleError(w, r, err, h.expectedErrors, h.systemId)
 		return
@@ -103,6 +111,26 @@ func (h *EndpointHandler[Input]) Build(
 	}
 }
 
+// URL returns the URL of the endpoint.
+func (h *EndpointHandler[Input]) URL() string {
+	return h.url
+}
+
+// Method returns the HTTP method of the endpoint.
+func (h *EndpointHandler[Input]) Method() string {
+	return h.method
+}
+
+// InputHandler returns the input handler of the endpoint.
+func (h *EndpointHandler[Input]) InputHandler() InputHandler {
+	return h.inputHandler
+}
+
+// ExpectedErrors returns the errors the endpoint can return.
+func (h *EndpointHandler[Input]) ExpectedErrors() []api.ExpectedError {
+	return h.expectedErrors
+}
+
 // mapToObject decodes a map into the provided object.
 func mapToObject[T any](value map[string]any, obj *T) (*T, error) {
 	cfg := &mapstructure.DecoderConfig{
@@ -3,9 +3,12 @@ package endpoint
 import (
 	"context"
 	"net/http"
+	"strconv"
 
 	"github.com/pakkasys/fluidapi-extended/api/repository"
+	extendeddatabase "github.com/pakkasys/fluidapi-extended/database"
 	"github.com/pakkasys/fluidapi/database"
+	"github.com/pakkasys/fluidapi/endpoint"
 )
 
 // GenericInvoke is the logic callback for a generic endpoint.
@@ -35,6 +38,18 @@ type ParsedGetEndpointInput struct {
 	Orders    []database.Order
 	Page      *database.Page
 	Count     bool
+	// Total is set if the total count is read with the page.
+	Total bool
+	// Cursor is set if the endpoint uses cursor pagination.
+	Cursor *ParsedCursor
+	// Fields are the selected output fields and Projections their columns.
+	// Both are nil if all fields are selected.
+	Fields      []string
+	Projections []database.Projection
+	// Filter is set if the rows are filtered with a filter expression.
+	Filter *extendeddatabase.Filter
+	// Includes are the relations whose rows are embedded in the output.
+	Includes []extendeddatabase.Relation
 }
 
 // Output and invoke funcs for the create endpoint.
@@ -90,6 +105,59 @@ func (h *CreateHandler[Mutator, Input]) Handle(
 	return h.toOutputFn(createdEntity)
 }
 
+// Output and invoke funcs for the bulk create endpoint.
+type CreateManyInvokeFn[Entity database.Mutator] func(
+	ctx context.Context,
+	connFn repository.ConnFn,
+	entities []Entity,
+	mutatorRepo repository.MutatorRepo[Entity],
+	txManager repository.TxManager[[]Entity],
+) ([]Entity, error)
+
+type CreateManyEntityFactoryFn[Input any, Entity database.Mutator] func(
+	ctx context.Context, input *Input,
+) ([]Entity, error)
+
+type ToCreateManyOutputFn[Entity any] func(entities []Entity) (any, error)
+
+// CreateManyHandler is the handler for the bulk create endpoint.
+type CreateManyHandler[Entity database.Mutator, Input any] struct {
+	entityFactoryFn    CreateManyEntityFactoryFn[Input, Entity]
+	createManyInvokeFn CreateManyInvokeFn[Entity]
+	toOutputFn         ToCreateManyOutputFn[Entity]
+	connFn             repository.ConnFn
+	beforeCallback     func(ctx context.Context, entities []Entity, input *Input) error
+	mutatorRepo        repository.MutatorRepo[Entity]
+	txManager          repository.TxManager[[]Entity]
+}
+
+// Handle processes the bulk create endpoint.
+func (h *CreateManyHandler[Entity, Input]) Handle(
+	w http.ResponseWriter, r *http.Request, i *Input,
+) (any, error) {
+	entities, err := h.entityFactoryFn(r.Context(), i)
+	if err != nil {
+		return nil, err
+	}
+	// Call the optional callback if provided.
+	if h.beforeCallback != nil {
+		if err := h.beforeCallback(r.Context(), entities, i); err != nil {
+			return nil, err
+		}
+	}
+	createdEntities, err := h.createManyInvokeFn(
+		r.Context(),
+		h.connFn,
+		entities,
+		h.mutatorRepo,
+		h.txManager,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return h.toOutputFn(createdEntities)
+}
+
 // Invoke and output funcs for the get endpoint.
 type GetInvokeFn[Entity database.Getter] func(
 	ctx context.Context,
@@ -98,17 +166,109 @@ type GetInvokeFn[Entity database.Getter] func(
 	entityFactoryFn repository.GetterFactoryFn[Entity],
 	readerRepo repository.ReaderRepo[Entity],
 	txManager repository.TxManager[Entity],
-) ([]Entity, int, error)
+) (*GetResult[Entity], error)
+
+// GetResult is the result of a get operation. Total is set only if the total
+// count was requested. Fields is set only if the output fields were selected.
+type GetResult[Entity any] struct {
+	Entities   []Entity
+	Count      int
+	Total      *int
+	Page       *database.Page
+	HasMore    bool
+	NextCursor string
+	PrevCursor string
+	Fields     []string
+	// Includes are the included relations and Related their rows by relation
+	// name and relation key.
+	Includes []extendeddatabase.Relation
+	Related  map[string]map[string][]any
+}
 
 type ToGetOutputFn[Entity any, Output any] func(
-	entities []Entity, count int,
+	result *GetResult[Entity],
 ) (*Output, error)
 
 // GetHandler is the handler for the get endpoint.
 type GetHandler[Entity database.Getter, Input any, Output any] struct {
-	parseInputFn    func(input *Input) (*ParsedGetEndpointInput, error)
-	getInvokeFn     GetInvokeFn[Entity]
-	toOutputFn      ToGetOutputFn[Entity, Output]
+	parseInputFn     func(input *Input) (*ParsedGetEndpointInput, error)
+	getInvokeFn      GetInvokeFn[Entity]
+	toOutputFn       ToGetOutputFn[Entity, Output]
+	cursorValuesFn   CursorValuesFn[Entity]
+	totalCountHeader bool
+	connFn           repository.ConnFn
+	entityFactoryFn  repository.GetterFactoryFn[Entity]
+	beforeCallback   func(ctx context.Context, entity Entity, input *Input) error
+	readerRepo       repository.ReaderRepo[Entity]
+	txManager        repository.TxManager[Entity]
+}
+
+// Handle processes the get endpoint.
+func (h *GetHandler[Entity, Input, Output]) Handle(
+	w http.ResponseWriter, r *http.Request, i *Input,
+) (any, error) {
+	parsedInput, err := h.parseInputFn(i)
+	if err != nil {
+		return nil, err
+	}
+	// Instantiate an entity instance.
+	entity := h.entityFactoryFn()
+	// If the before callback is provided, call it.
+	if h.beforeCallback != nil {
+		if err := h.beforeCallback(r.Context(), entity, i); err != nil {
+			return nil, err
+		}
+	}
+	result, err := h.getInvokeFn(
+		r.Context(),
+		parsedInput,
+		h.connFn,
+		h.entityFactoryFn,
+		h.readerRepo,
+		h.txManager,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if parsedInput.Cursor != nil {
+		result.NextCursor, result.PrevCursor, err = pageCursors(
+			parsedInput.Cursor,
+			result.Entities,
+			result.HasMore,
+			h.cursorValuesFn,
+		)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if h.totalCountHeader && result.Total != nil {
+		w.Header().Set(HeaderTotalCount, strconv.Itoa(*result.Total))
+	}
+	return h.toOutputFn(result)
+}
+
+type ParsedGetOneEndpointInput struct {
+	Selectors database.Selectors
+}
+
+// Invoke and output funcs for the get one endpoint.
+type GetOneInvokeFn[Entity database.Getter] func(
+	ctx context.Context,
+	parsedInput *ParsedGetOneEndpointInput,
+	connFn repository.ConnFn,
+	entityFactoryFn repository.GetterFactoryFn[Entity],
+	readerRepo repository.ReaderRepo[Entity],
+	txManager repository.TxManager[Entity],
+) (Entity, error)
+
+type ToGetOneOutputFn[Entity any] func(entity Entity) (any, error)
+
+// GetOneHandler is the handler for the get one endpoint.
+type GetOneHandler[Entity database.Getter, Input any] struct {
+	parseInputFn    func(input *Input) (*ParsedGetOneEndpointInput, error)
+	getOneInvokeFn  GetOneInvokeFn[Entity]
+	toOutputFn      ToGetOneOutputFn[Entity]
+	etagFn          ETagFn[Entity]
 	connFn          repository.ConnFn
 	entityFactoryFn repository.GetterFactoryFn[Entity]
 	beforeCallback  func(ctx context.Context, entity Entity, input *Input) error
@@ -116,8 +276,8 @@ type GetHandler[Entity database.Getter, Input any, Output any] struct {
 	txManager       repository.TxManager[Entity]
 }
 
-// Handle processes the get endpoint.
-func (h *GetHandler[Entity, Input, Output]) Handle(
+// Handle processes the get one endpoint.
+func (h *GetOneHandler[Entity, Input]) Handle(
 	w http.ResponseWriter, r *http.Request, i *Input,
 ) (any, error) {
 	parsedInput, err := h.parseInputFn(i)
@@ -132,7 +292,7 @@ func (h *GetHandler[Entity, Input, Output]) Handle(
 			return nil, err
 		}
 	}
-	entities, count, err := h.getInvokeFn(
+	foundEntity, err := h.getOneInvokeFn(
 		r.Context(),
 		parsedInput,
 		h.connFn,
@@ -143,17 +303,40 @@ func (h *GetHandler[Entity, Input, Output]) Handle(
 	if err != nil {
 		return nil, err
 	}
-	return h.toOutputFn(entities, count)
+	if h.etagFn != nil {
+		etag, err := h.etagFn(foundEntity)
+		if err != nil {
+			return nil, err
+		}
+		w.Header().Set(HeaderETag, etag)
+	}
+	return h.toOutputFn(foundEntity)
 }
 
 type ParsedUpdateEndpointInput struct {
 	Selectors database.Selectors
-	Updates   []database.Update
-	Upsert    bool
+	// UpsertSelectors are the selectors of the input. Unlike Selectors, they
+	// do not include the version and soft delete selectors, so they are used
+	// as the conflict target of an upsert.
+	UpsertSelectors database.Selectors
+	Updates         []database.Update
+	Upsert          bool
+	Version         *ExpectedVersion
+	VersionField    *endpoint.DBField
+	// SoftDelete is the soft delete field of the entity, if any. An upsert
+	// restores the soft deleted row that it updates.
+	SoftDelete *endpoint.DBField
+}
+
+// UpdateResult is the result of an update operation. Inserted is set when an
+// upsert inserted a new row.
+type UpdateResult struct {
+	Count    int64
+	Inserted bool
 }
 
 // Invoke and output funcs for the update endpoint.
-type ToUpdateOutputFn func(count int64) (any, error)
+type ToUpdateOutputFn func(result *UpdateResult) (any, error)
 type UpdateEntityFactoryFn func() database.Mutator
 
 type UpdateInvokeFn func(
@@ -162,8 +345,8 @@ type UpdateInvokeFn func(
 	connFn repository.ConnFn,
 	updater database.Mutator,
 	mutatorRepo repository.MutatorRepo[database.Mutator],
-	txManager repository.TxManager[*int64],
-) (int64, error)
+	txManager repository.TxManager[*UpdateResult],
+) (*UpdateResult, error)
 
 // UpdateHandler is the handler for the update endpoint.
 type UpdateHandler[Input any] struct {
@@ -178,7 +361,7 @@ type UpdateHandler[Input any] struct {
 		input *Input,
 	) error
 	mutatorRepo repository.MutatorRepo[database.Mutator]
-	txManager   repository.TxManager[*int64]
+	txManager   repository.TxManager[*UpdateResult]
 }
 
 // Handle processes the update endpoint.
@@ -197,18 +380,24 @@ func (h *UpdateHandler[Input]) Handle(
 			return nil, err
 		}
 	}
-	count, err := h.updateInvokeFn(
+	result, err := h.updateInvokeFn(
 		r.Context(), parsedInput, h.connFn, entity, h.mutatorRepo, h.txManager,
 	)
 	if err != nil {
 		return nil, err
 	}
-	return h.toOutputFn(count)
+	if version, ok := updatedVersion(parsedInput, result); ok {
+		w.Header().Set(HeaderETag, FormatETag(version))
+	}
+	return h.toOutputFn(result)
 }
 
 type ParsedDeleteEndpointInput struct {
-	Selectors  database.Selectors
-	DeleteOpts *database.DeleteOptions
+	Selectors    database.Selectors
+	DeleteOpts   *database.DeleteOptions
+	Version      *ExpectedVersion
+	VersionField *endpoint.DBField
+	SoftDelete   *endpoint.DBField
 }
 
 // Invoke and output funcs for the delete endpoint.
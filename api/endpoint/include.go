@@ -0,0 +1,133 @@
+package endpoint
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/pakkasys/fluidapi-extended/api/repository"
+	extendeddatabase "github.com/pakkasys/fluidapi-extended/database"
+	"github.com/pakkasys/fluidapi/core"
+	"github.com/pakkasys/fluidapi/database"
+)
+
+// FieldInclude is the input field used to embed related entities.
+const FieldInclude = "include"
+
+// InvalidIncludeError is returned when an included relation does not exist.
+var InvalidIncludeError = core.NewAPIError("INVALID_INCLUDE")
+
+// ParseInclude parses a comma separated list of relation names and returns
+// the included relations. If no relations are given, nil is returned.
+//
+// Example:
+//
+//	ParseInclude(relations, "author,tags")
+//
+// Output:
+//
+//	[]extendeddatabase.Relation{authorRelation, tagsRelation}
+//
+// Parameters:
+//   - relations: The relations of the endpoint.
+//   - include: The comma separated relation names.
+//
+// Returns:
+//   - []extendeddatabase.Relation: The included relations.
+//   - error: InvalidIncludeError if a relation does not exist.
+func ParseInclude(
+	relations []extendeddatabase.Relation, include string,
+) ([]extendeddatabase.Relation, error) {
+	if strings.TrimSpace(include) == "" {
+		return nil, nil
+	}
+	var included []extendeddatabase.Relation
+	for _, name := range strings.Split(include, ",") {
+		name = strings.TrimSpace(name)
+		index := slices.IndexFunc(
+			relations,
+			func(relation extendeddatabase.Relation) bool {
+				return relation.Name == name
+			},
+		)
+		if index == -1 {
+			return nil, InvalidIncludeError.WithData(name)
+		}
+		isIncluded := slices.ContainsFunc(
+			included,
+			func(relation extendeddatabase.Relation) bool {
+				return relation.Name == name
+			},
+		)
+		if !isIncluded {
+			included = append(included, relations[index])
+		}
+	}
+	return included, nil
+}
+
+// withRelationProjections returns the projections with the local columns of
+// the relations that are not projected appended. It is used to read the
+// relation keys of a page.
+func withRelationProjections(
+	projections []database.Projection,
+	relations []extendeddatabase.Relation,
+) []database.Projection {
+	var orders []database.Order
+	for _, relation := range relations {
+		orders = append(orders, database.Order{
+			Table: relation.LocalTable,
+			Field: relation.LocalColumn,
+		})
+	}
+	return withOrderProjections(projections, orders)
+}
+
+// loadRelations reads the rows related to the entities with one query per
+// relation. The rows are returned by relation name and relation key.
+func loadRelations[Entity database.Getter](
+	preparer database.Preparer,
+	entities []Entity,
+	relations []extendeddatabase.Relation,
+	readerRepo repository.ReaderRepo[Entity],
+) (map[string]map[string][]any, error) {
+	loader, ok := readerRepo.(repository.RelationLoader)
+	if !ok {
+		return nil, fmt.Errorf(
+			"loadRelations: reader repository can not load relations",
+		)
+	}
+	related := make(map[string]map[string][]any, len(relations))
+	for _, relation := range relations {
+		keys, err := relationKeys(entities, relation.LocalColumn)
+		if err != nil {
+			return nil, err
+		}
+		rows, err := loader.LoadRelation(preparer, relation, keys)
+		if err != nil {
+			return nil, err
+		}
+		related[relation.Name] = rows
+	}
+	return related, nil
+}
+
+// relationKeys returns the distinct non-nil values of the local column of the
+// entities.
+func relationKeys[Entity any](entities []Entity, column string) ([]any, error) {
+	seen := make(map[string]bool)
+	var keys []any
+	for _, entity := range entities {
+		values, err := extendeddatabase.ColumnValues(entity, []string{column})
+		if err != nil {
+			return nil, err
+		}
+		key, ok := extendeddatabase.RelationKey(values[0])
+		if !ok || seen[key] {
+			continue
+		}
+		seen[key] = true
+		keys = append(keys, values[0])
+	}
+	return keys, nil
+}
@@ -0,0 +1,38 @@
+package endpoint
+
+import (
+	"reflect"
+	"testing"
+
+	extendeddatabase "github.com/pakkasys/fluidapi-extended/database"
+)
+
+func TestParseInclude(t *testing.T) {
+	relations := []extendeddatabase.Relation{
+		{Name: "author", Kind: extendeddatabase.RelationBelongsTo},
+		{Name: "tags", Kind: extendeddatabase.RelationManyToMany},
+	}
+
+	t.Run("Relations", func(t *testing.T) {
+		included, err := ParseInclude(relations, "tags, author,tags")
+		if err != nil {
+			t.Fatalf("ParseInclude: %v", err)
+		}
+		expected := []extendeddatabase.Relation{relations[1], relations[0]}
+		if !reflect.DeepEqual(included, expected) {
+			t.Errorf("expected %v, got %v", expected, included)
+		}
+	})
+
+	t.Run("NoRelations", func(t *testing.T) {
+		included, err := ParseInclude(relations, "")
+		if err != nil || included != nil {
+			t.Errorf("expected no relations, got %v, %v", included, err)
+		}
+	})
+
+	t.Run("UnknownRelation", func(t *testing.T) {
+		_, err := ParseInclude(relations, "author,comments")
+		expectAPIError(t, err, InvalidIncludeError.ID)
+	})
+}
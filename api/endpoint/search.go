@@ -0,0 +1,64 @@
+package endpoint
+
+import (
+	"strings"
+
+	extendeddatabase "github.com/pakkasys/fluidapi-extended/database"
+	"github.com/pakkasys/fluidapi/core"
+)
+
+// FieldSearch is the input field used to search the rows with a full-text
+// query.
+const FieldSearch = "q"
+
+// InvalidSearchError is returned when a search query is given but the
+// endpoint is not searchable.
+var InvalidSearchError = core.NewAPIError("INVALID_SEARCH")
+
+// SearchOptions configures the full-text search of the get endpoint.
+type SearchOptions struct {
+	// Table is the table of the searched columns.
+	Table string
+	// Columns are the searched columns. MySQL requires a FULLTEXT index over
+	// exactly these columns.
+	Columns []string
+	// Relevance orders the rows by relevance before the input orders. It can
+	// not be used with cursor pagination.
+	Relevance bool
+	// FTSTable is the FTS5 virtual table of the table in SQLite. The table
+	// name with the "_fts" suffix is used if it is not set.
+	FTSTable string
+	// FTSKey is the column of the table that matches the rowid of the FTS5
+	// table. The rowid is used if it is not set.
+	FTSKey string
+}
+
+// ParseSearch parses a full-text search query into a filter term. If the
+// query is empty, nil is returned.
+//
+// Parameters:
+//   - options: The search options of the endpoint. Nil disables search.
+//   - query: The search query.
+//
+// Returns:
+//   - *extendeddatabase.Filter: The search filter term.
+//   - error: InvalidSearchError if the endpoint is not searchable.
+func ParseSearch(
+	options *SearchOptions, query string,
+) (*extendeddatabase.Filter, error) {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return nil, nil
+	}
+	if options == nil {
+		return nil, InvalidSearchError.WithData("search is not allowed")
+	}
+	return extendeddatabase.NewFilterSearch(extendeddatabase.Search{
+		Table:     options.Table,
+		Columns:   options.Columns,
+		Query:     query,
+		Relevance: options.Relevance,
+		FTSTable:  options.FTSTable,
+		FTSKey:    options.FTSKey,
+	}), nil
+}
@@ -0,0 +1,44 @@
+package endpoint
+
+import (
+	"reflect"
+	"testing"
+
+	extendeddatabase "github.com/pakkasys/fluidapi-extended/database"
+)
+
+func TestParseSearch(t *testing.T) {
+	options := &SearchOptions{
+		Table:     "posts",
+		Columns:   []string{"title", "body"},
+		Relevance: true,
+	}
+
+	t.Run("Query", func(t *testing.T) {
+		filter, err := ParseSearch(options, " go sql ")
+		if err != nil {
+			t.Fatalf("ParseSearch: %v", err)
+		}
+		expected := extendeddatabase.NewFilterSearch(extendeddatabase.Search{
+			Table:     "posts",
+			Columns:   []string{"title", "body"},
+			Query:     "go sql",
+			Relevance: true,
+		})
+		if !reflect.DeepEqual(filter, expected) {
+			t.Errorf("expected %+v, got %+v", expected, filter)
+		}
+	})
+
+	t.Run("Empty", func(t *testing.T) {
+		filter, err := ParseSearch(nil, "")
+		if err != nil || filter != nil {
+			t.Errorf("expected no search, got %+v, %v", filter, err)
+		}
+	})
+
+	t.Run("NotAllowed", func(t *testing.T) {
+		_, err := ParseSearch(nil, "go")
+		expectAPIError(t, err, InvalidSearchError.ID)
+	})
+}
@@ -0,0 +1,65 @@
+package endpoint
+
+import (
+	"slices"
+	"time"
+
+	"github.com/pakkasys/fluidapi/database"
+	"github.com/pakkasys/fluidapi/endpoint"
+)
+
+// FieldIncludeDeleted is the input field used to include soft deleted rows.
+const FieldIncludeDeleted = "include_deleted"
+
+// notDeletedSelectors returns a copy of the selectors with a selector that
+// excludes soft deleted rows appended. The selectors are returned as is if the
+// entity is not soft deleted or if deleted rows are included.
+func notDeletedSelectors(
+	selectors database.Selectors,
+	softDeleteField *endpoint.DBField,
+	includeDeleted bool,
+) database.Selectors {
+	if softDeleteField == nil || includeDeleted {
+		return selectors
+	}
+	return append(slices.Clip(selectors), database.Selector{
+		Table:     softDeleteField.Table,
+		Column:    softDeleteField.Column,
+		Predicate: "=",
+		Value:     nil,
+	})
+}
+
+// deletedSelectors returns a copy of the selectors with a selector that
+// matches only soft deleted rows appended.
+func deletedSelectors(
+	selectors database.Selectors, softDeleteField *endpoint.DBField,
+) database.Selectors {
+	return append(slices.Clip(selectors), database.Selector{
+		Table:     softDeleteField.Table,
+		Column:    softDeleteField.Column,
+		Predicate: "!=",
+		Value:     nil,
+	})
+}
+
+// softDeleteUpdates returns the updates that soft delete a row by setting the
+// deletion time. The time is stored as Unix nanoseconds like the inserted
+// times. If a version is expected, it is incremented.
+func softDeleteUpdates(
+	parsedInput *ParsedDeleteEndpointInput,
+) database.Updates {
+	updates := database.Updates{
+		{
+			Field: parsedInput.SoftDelete.Column,
+			Value: time.Now().UnixNano(),
+		},
+	}
+	if parsedInput.Version != nil {
+		updates = append(
+			updates,
+			versionUpdate(parsedInput.VersionField, *parsedInput.Version),
+		)
+	}
+	return updates
+}
@@ -0,0 +1,202 @@
+package endpoint
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/pakkasys/fluidapi-extended/api/repository"
+	"github.com/pakkasys/fluidapi/database"
+	"github.com/pakkasys/fluidapi/endpoint"
+)
+
+// callbackTxManager runs the callback without a transaction.
+type callbackTxManager[Result any] struct{}
+
+func (callbackTxManager[Result]) WithTransaction(
+	ctx context.Context,
+	connFn repository.ConnFn,
+	callback func(ctx context.Context, tx database.Tx) (Result, error),
+) (Result, error) {
+	return callback(ctx, nil)
+}
+
+// mutationRepo records the selectors and updates of its updates and deletes.
+type mutationRepo struct {
+	repository.MutatorRepo[database.Mutator]
+	count     int64
+	updated   bool
+	deleted   bool
+	selectors database.Selectors
+	updates   database.Updates
+}
+
+func (r *mutationRepo) Update(
+	preparer database.Preparer,
+	updater database.Mutator,
+	selectors database.Selectors,
+	updates database.Updates,
+) (int64, error) {
+	r.updated = true
+	r.selectors = selectors
+	r.updates = updates
+	return r.count, nil
+}
+
+func (r *mutationRepo) Delete(
+	preparer database.Preparer,
+	deleter database.Mutator,
+	selectors database.Selectors,
+	deleteOpts *database.DeleteOptions,
+) (int64, error) {
+	r.deleted = true
+	r.selectors = selectors
+	return r.count, nil
+}
+
+// user is the entity of the reads and mutations.
+type user struct{}
+
+func (user) TableName() string                 { return "users" }
+func (user) InsertedValues() ([]string, []any) { return nil, nil }
+func (user) ScanRow(row database.Row) error    { return nil }
+
+var (
+	deletedAtField = &endpoint.DBField{Table: "users", Column: "deleted_at"}
+	idSelector     = database.Selector{
+		Table: "users", Column: "id", Predicate: "=", Value: 1,
+	}
+	notDeletedSelector = database.Selector{
+		Table: "users", Column: "deleted_at", Predicate: "=", Value: nil,
+	}
+)
+
+func TestNotDeletedSelectors(t *testing.T) {
+	selectors := make(database.Selectors, 1, 2)
+	selectors[0] = idSelector
+
+	got := notDeletedSelectors(selectors, deletedAtField, false)
+	expected := database.Selectors{idSelector, notDeletedSelector}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected the deleted rows to be hidden, got %v", got)
+	}
+	if extended := selectors[:2]; extended[1] == notDeletedSelector {
+		t.Errorf("expected the selectors not to be modified")
+	}
+
+	for _, tt := range []struct {
+		field          *endpoint.DBField
+		includeDeleted bool
+	}{
+		{nil, false},
+		{deletedAtField, true},
+	} {
+		got := notDeletedSelectors(selectors, tt.field, tt.includeDeleted)
+		if !reflect.DeepEqual(got, selectors) {
+			t.Errorf("expected the selectors as is, got %v", got)
+		}
+	}
+}
+
+func TestDeleteInvokeSoftDelete(t *testing.T) {
+	version := ExpectedVersion{Value: 3}
+	versionField := &endpoint.DBField{Table: "users", Column: "version"}
+	parsedInput := &ParsedDeleteEndpointInput{
+		Selectors: notDeletedSelectors(
+			versionSelectors(database.Selectors{idSelector}, versionField, version),
+			deletedAtField,
+			false,
+		),
+		Version:      &version,
+		VersionField: versionField,
+		SoftDelete:   deletedAtField,
+	}
+	repo := &mutationRepo{count: 1}
+	before := time.Now().UnixNano()
+
+	count, err := DeleteInvoke(
+		context.Background(),
+		parsedInput,
+		nil,
+		user{},
+		repo,
+		callbackTxManager[*int64]{},
+	)
+	if err != nil || count != 1 {
+		t.Fatalf("expected 1 deleted row, got %d %v", count, err)
+	}
+	if repo.deleted || !repo.updated {
+		t.Fatalf("expected the row to be updated instead of deleted")
+	}
+	if !reflect.DeepEqual(repo.selectors, parsedInput.Selectors) {
+		t.Errorf("expected selectors %v, got %v",
+			parsedInput.Selectors, repo.selectors)
+	}
+	if len(repo.updates) != 2 {
+		t.Fatalf("expected the deletion time and version, got %v",
+			repo.updates)
+	}
+	deletedAt, ok := repo.updates[0].Value.(int64)
+	if repo.updates[0].Field != "deleted_at" || !ok || deletedAt < before {
+		t.Errorf("expected the deletion time to be set, got %v",
+			repo.updates[0])
+	}
+	expectedUpdate := database.Update{Field: "version", Value: int64(4)}
+	if repo.updates[1] != expectedUpdate {
+		t.Errorf("expected the version to be incremented, got %v",
+			repo.updates[1])
+	}
+}
+
+func TestDeleteInvokeHardDelete(t *testing.T) {
+	repo := &mutationRepo{count: 2}
+	count, err := DeleteInvoke(
+		context.Background(),
+		&ParsedDeleteEndpointInput{Selectors: database.Selectors{idSelector}},
+		nil,
+		user{},
+		repo,
+		callbackTxManager[*int64]{},
+	)
+	if err != nil || count != 2 {
+		t.Fatalf("expected 2 deleted rows, got %d %v", count, err)
+	}
+	if !repo.deleted || repo.updated {
+		t.Errorf("expected the rows to be deleted")
+	}
+}
+
+func TestRestoreInvoke(t *testing.T) {
+	parsedInput := &ParsedUpdateEndpointInput{
+		Selectors: deletedSelectors(
+			database.Selectors{idSelector}, deletedAtField,
+		),
+		Updates: []database.Update{{Field: "deleted_at", Value: nil}},
+	}
+	repo := &mutationRepo{count: 1}
+
+	result, err := UpdateInvoke(
+		context.Background(),
+		parsedInput,
+		nil,
+		user{},
+		repo,
+		callbackTxManager[*UpdateResult]{},
+	)
+	if err != nil || result.Count != 1 {
+		t.Fatalf("expected 1 restored row, got %v %v", result, err)
+	}
+	expected := database.Selectors{
+		idSelector,
+		{Table: "users", Column: "deleted_at", Predicate: "!=", Value: nil},
+	}
+	if !reflect.DeepEqual(repo.selectors, expected) {
+		t.Errorf("expected only deleted rows to be restored, got %v",
+			repo.selectors)
+	}
+	if !reflect.DeepEqual(repo.updates, database.Updates(parsedInput.Updates)) {
+		t.Errorf("expected the deletion time to be cleared, got %v",
+			repo.updates)
+	}
+}
@@ -0,0 +1,214 @@
+package endpoint
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/pakkasys/fluidapi-extended/api"
+	"github.com/pakkasys/fluidapi-extended/api/repository"
+	extendeddatabase "github.com/pakkasys/fluidapi-extended/database"
+	"github.com/pakkasys/fluidapi/core"
+	"github.com/pakkasys/fluidapi/database"
+	"github.com/pakkasys/fluidapi/endpoint"
+)
+
+// Headers used for optimistic concurrency.
+const (
+	HeaderETag    = "ETag"
+	HeaderIfMatch = "If-Match"
+)
+
+// ExpectedVersion is the expected version of a request to a versioned
+// resource.
+type ExpectedVersion struct {
+	// Value is the expected version of the row.
+	Value int64
+	// Any is set by an If-Match: * header. It matches any version of an
+	// existing row, and Value is not used.
+	Any bool
+}
+
+// Optimistic concurrency errors.
+var (
+	VersionRequiredError = core.NewAPIError("VERSION_REQUIRED")
+	InvalidVersionError  = core.NewAPIError("INVALID_VERSION")
+	VersionConflictError = core.NewAPIError("VERSION_CONFLICT")
+)
+
+// ETagFn returns the ETag of an entity.
+type ETagFn[Entity any] func(entity Entity) (string, error)
+
+func VersionErrors() api.ExpectedErrors {
+	return []api.ExpectedError{
+		{ID: VersionRequiredError.ID, Status: http.StatusPreconditionRequired, PublicData: true},
+		{ID: InvalidVersionError.ID, Status: http.StatusBadRequest, PublicData: true},
+		{ID: VersionConflictError.ID, Status: http.StatusConflict, PublicData: true},
+		{ID: extendeddatabase.NoRowsError.ID, Status: http.StatusNotFound, PublicData: true},
+	}
+}
+
+// FormatETag returns a strong ETag for a version value.
+//
+// Example:
+//
+//	FormatETag(int64(3))
+//
+// Output:
+//
+//	"\"3\""
+func FormatETag(version any) string {
+	return strconv.Quote(fmt.Sprint(version))
+}
+
+// ParseETag parses a version from an ETag. Weak ETags are accepted.
+//
+// Parameters:
+//   - etag: The ETag to parse.
+//
+// Returns:
+//   - int64: The version.
+//   - error: InvalidVersionError if the ETag is not a non-negative version.
+func ParseETag(etag string) (int64, error) {
+	value := strings.TrimPrefix(strings.TrimSpace(etag), "W/")
+	if unquoted, err := strconv.Unquote(value); err == nil {
+		value = unquoted
+	}
+	version, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || version < 0 {
+		return 0, InvalidVersionError.WithData(etag)
+	}
+	return version, nil
+}
+
+// ResolveVersion returns the expected version of a request to a versioned
+// resource. The version is read from the If-Match header or the version
+// input. If both are given, they must match. If the If-Match header is "*",
+// any version matches unless a version input is given. Versions are never
+// negative. If the resource is not versioned, nil is returned.
+//
+// Parameters:
+//   - versionField: The version field, nil if the resource is not versioned.
+//   - version: The version input.
+//   - ifMatch: The If-Match header value.
+//
+// Returns:
+//   - *ExpectedVersion: The expected version.
+//   - error: VersionRequiredError if no version is given or
+//     InvalidVersionError if the version is invalid.
+func ResolveVersion(
+	versionField *endpoint.DBField, version *int64, ifMatch string,
+) (*ExpectedVersion, error) {
+	if versionField == nil {
+		return nil, nil
+	}
+	if version != nil && *version < 0 {
+		return nil, InvalidVersionError.WithData(*version)
+	}
+	if ifMatch == "" {
+		if version == nil {
+			return nil, VersionRequiredError
+		}
+		return &ExpectedVersion{Value: *version}, nil
+	}
+	if strings.TrimSpace(ifMatch) == "*" {
+		if version != nil {
+			return &ExpectedVersion{Value: *version}, nil
+		}
+		return &ExpectedVersion{Any: true}, nil
+	}
+	headerVersion, err := ParseETag(ifMatch)
+	if err != nil {
+		return nil, err
+	}
+	if version != nil && *version != headerVersion {
+		return nil, InvalidVersionError.WithData(ifMatch)
+	}
+	return &ExpectedVersion{Value: headerVersion}, nil
+}
+
+// versionSelectors returns the selectors with the selector that matches the
+// expected version. No selector is added if any version matches.
+func versionSelectors(
+	selectors database.Selectors,
+	versionField *endpoint.DBField,
+	version ExpectedVersion,
+) database.Selectors {
+	if version.Any {
+		return selectors
+	}
+	return append(selectors, database.Selector{
+		Table:     versionField.Table,
+		Column:    versionField.Column,
+		Predicate: "=",
+		Value:     version.Value,
+	})
+}
+
+// versionUpdate returns an update that increments the expected version.
+func versionUpdate(
+	versionField *endpoint.DBField, version ExpectedVersion,
+) database.Update {
+	if version.Any {
+		return database.Update{
+			Field: versionField.Column,
+			Value: extendeddatabase.Increment{By: 1},
+		}
+	}
+	return database.Update{
+		Field: versionField.Column,
+		Value: version.Value + 1,
+	}
+}
+
+// updatedVersion returns the version that an update persisted. The version is
+// known only if a single existing row with the expected version was updated.
+// An update with any version increments the version of the row in the DB, and
+// an upserted row has the version of its input.
+func updatedVersion(
+	parsedInput *ParsedUpdateEndpointInput, result *UpdateResult,
+) (int64, bool) {
+	version := parsedInput.Version
+	if version == nil || version.Any || result.Inserted || result.Count != 1 {
+		return 0, false
+	}
+	return version.Value + 1, true
+}
+
+// versionMismatchError returns the error of a versioned operation that
+// matched no rows. NoRowsError is returned if no row matches the selectors
+// without the version, otherwise VersionConflictError. If the repository
+// cannot count rows, VersionConflictError is returned.
+func versionMismatchError(
+	preparer database.Preparer,
+	tableName string,
+	selectors database.Selectors,
+	versionField *endpoint.DBField,
+	version ExpectedVersion,
+	mutatorRepo repository.MutatorRepo[database.Mutator],
+) error {
+	if version.Any {
+		return extendeddatabase.NoRowsError
+	}
+	counter, ok := mutatorRepo.(repository.Counter)
+	if !ok {
+		return VersionConflictError
+	}
+	var rowSelectors database.Selectors
+	for _, selector := range selectors {
+		if selector.Table == versionField.Table &&
+			selector.Column == versionField.Column {
+			continue
+		}
+		rowSelectors = append(rowSelectors, selector)
+	}
+	count, err := counter.Count(preparer, tableName, rowSelectors)
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return extendeddatabase.NoRowsError
+	}
+	return VersionConflictError
+}
@@ -0,0 +1,202 @@
+package endpoint
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/pakkasys/fluidapi-extended/api/repository"
+	extendeddatabase "github.com/pakkasys/fluidapi-extended/database"
+	"github.com/pakkasys/fluidapi/core"
+	"github.com/pakkasys/fluidapi/database"
+	"github.com/pakkasys/fluidapi/endpoint"
+)
+
+func TestParseETag(t *testing.T) {
+	tests := []struct {
+		etag     string
+		expected int64
+		wantErr  bool
+	}{
+		{`"3"`, 3, false},
+		{`W/"3"`, 3, false},
+		{`3`, 3, false},
+		{FormatETag(int64(42)), 42, false},
+		{`"abc"`, 0, true},
+		{``, 0, true},
+		{`"-1"`, 0, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseETag(tt.etag)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseETag(%q) error = %v, wantErr %v", tt.etag, err, tt.wantErr)
+			continue
+		}
+		if got != tt.expected {
+			t.Errorf("ParseETag(%q) = %d, want %d", tt.etag, got, tt.expected)
+		}
+	}
+}
+
+func TestResolveVersion(t *testing.T) {
+	field := &endpoint.DBField{Table: "users", Column: "version"}
+	version := int64(3)
+	other := int64(4)
+
+	t.Run("NotVersioned", func(t *testing.T) {
+		got, err := ResolveVersion(nil, nil, "")
+		if err != nil || got != nil {
+			t.Errorf("expected nil version and error, got %v, %v", got, err)
+		}
+	})
+
+	t.Run("FromInput", func(t *testing.T) {
+		got, err := ResolveVersion(field, &version, "")
+		if err != nil || got == nil || *got != (ExpectedVersion{Value: 3}) {
+			t.Errorf("expected version 3, got %v, %v", got, err)
+		}
+	})
+
+	t.Run("FromHeader", func(t *testing.T) {
+		got, err := ResolveVersion(field, nil, `"3"`)
+		if err != nil || got == nil || *got != (ExpectedVersion{Value: 3}) {
+			t.Errorf("expected version 3, got %v, %v", got, err)
+		}
+	})
+
+	t.Run("Missing", func(t *testing.T) {
+		_, err := ResolveVersion(field, nil, "")
+		expectAPIError(t, err, VersionRequiredError.ID)
+	})
+
+	t.Run("Mismatch", func(t *testing.T) {
+		_, err := ResolveVersion(field, &other, `"3"`)
+		expectAPIError(t, err, InvalidVersionError.ID)
+	})
+
+	t.Run("Negative", func(t *testing.T) {
+		negative := int64(-1)
+		_, err := ResolveVersion(field, &negative, "")
+		expectAPIError(t, err, InvalidVersionError.ID)
+		_, err = ResolveVersion(field, &negative, "*")
+		expectAPIError(t, err, InvalidVersionError.ID)
+		_, err = ResolveVersion(field, nil, `"-1"`)
+		expectAPIError(t, err, InvalidVersionError.ID)
+	})
+
+	t.Run("AnyVersion", func(t *testing.T) {
+		got, err := ResolveVersion(field, nil, "*")
+		if err != nil || got == nil || *got != (ExpectedVersion{Any: true}) {
+			t.Errorf("expected any version, got %v, %v", got, err)
+		}
+		got, err = ResolveVersion(field, &version, "*")
+		if err != nil || got == nil || *got != (ExpectedVersion{Value: 3}) {
+			t.Errorf("expected version 3, got %v, %v", got, err)
+		}
+	})
+}
+
+func TestVersionSelectorsAndUpdate(t *testing.T) {
+	field := &endpoint.DBField{Table: "users", Column: "version"}
+	selectors := database.Selectors{{Column: "id", Predicate: "=", Value: 1}}
+
+	got := versionSelectors(selectors, field, ExpectedVersion{Value: 3})
+	expected := append(selectors, database.Selector{
+		Table: "users", Column: "version", Predicate: "=", Value: int64(3),
+	})
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+	got = versionSelectors(selectors, field, ExpectedVersion{Any: true})
+	if !reflect.DeepEqual(got, selectors) {
+		t.Errorf("expected no version selector, got %v", got)
+	}
+
+	update := versionUpdate(field, ExpectedVersion{Value: 3})
+	if update.Value != int64(4) {
+		t.Errorf("expected version 4, got %v", update.Value)
+	}
+	update = versionUpdate(field, ExpectedVersion{Any: true})
+	if update.Value != (extendeddatabase.Increment{By: 1}) {
+		t.Errorf("expected an increment, got %v", update.Value)
+	}
+}
+
+// countRepo counts the rows of its selectors.
+type countRepo struct {
+	repository.MutatorRepo[database.Mutator]
+	count     int
+	selectors database.Selectors
+}
+
+func (r *countRepo) Count(
+	preparer database.Preparer,
+	tableName string,
+	selectors database.Selectors,
+) (int, error) {
+	r.selectors = selectors
+	return r.count, nil
+}
+
+func TestVersionMismatchError(t *testing.T) {
+	field := &endpoint.DBField{Table: "users", Column: "version"}
+	idSelector := database.Selector{
+		Table: "users", Column: "id", Predicate: "=", Value: 1,
+	}
+	version := ExpectedVersion{Value: 3}
+	selectors := versionSelectors(database.Selectors{idSelector}, field, version)
+
+	repo := &countRepo{count: 1}
+	err := versionMismatchError(nil, "users", selectors, field, version, repo)
+	expectAPIError(t, err, VersionConflictError.ID)
+	if !reflect.DeepEqual(repo.selectors, database.Selectors{idSelector}) {
+		t.Errorf("expected the version selector to be removed, got %v",
+			repo.selectors)
+	}
+
+	repo = &countRepo{count: 0}
+	err = versionMismatchError(nil, "users", selectors, field, version, repo)
+	expectAPIError(t, err, extendeddatabase.NoRowsError.ID)
+
+	err = versionMismatchError(nil, "users", selectors, field, ExpectedVersion{Any: true}, repo)
+	expectAPIError(t, err, extendeddatabase.NoRowsError.ID)
+
+	notCounter := struct {
+		repository.MutatorRepo[database.Mutator]
+	}{}
+	err = versionMismatchError(nil, "users", selectors, field, version, notCounter)
+	expectAPIError(t, err, VersionConflictError.ID)
+}
+
+func TestUpdatedVersion(t *testing.T) {
+	for _, tt := range []struct {
+		name     string
+		version  *ExpectedVersion
+		result   UpdateResult
+		expected int64
+		ok       bool
+	}{
+		{"Updated", &ExpectedVersion{Value: 3}, UpdateResult{Count: 1}, 4, true},
+		{"NotVersioned", nil, UpdateResult{Count: 1}, 0, false},
+		{"AnyVersion", &ExpectedVersion{Any: true}, UpdateResult{Count: 1}, 0, false},
+		{"Inserted", &ExpectedVersion{Value: 3}, UpdateResult{Count: 1, Inserted: true}, 0, false},
+		{"ManyRows", &ExpectedVersion{Value: 3}, UpdateResult{Count: 2}, 0, false},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := updatedVersion(
+				&ParsedUpdateEndpointInput{Version: tt.version}, &tt.result,
+			)
+			if got != tt.expected || ok != tt.ok {
+				t.Errorf("expected %d %v, got %d %v", tt.expected, tt.ok, got, ok)
+			}
+		})
+	}
+}
+
+func expectAPIError(t *testing.T, err error, id string) {
+	t.Helper()
+	var apiErr *core.APIError
+	if !errors.As(err, &apiErr) || apiErr.ID != id {
+		t.Errorf("expected API error %q, got %v", id, err)
+	}
+}
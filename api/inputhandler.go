@@ -1,6 +1,7 @@
 package api
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 
@@ -27,6 +28,7 @@ type MapInputHandler struct {
 	apiFields     types.APIFields
 	conversionMap map[string]func(any) any
 	customRules   map[string]func(any) error
+	fieldPaths    bool
 }
 
 // NewMapInputHandler creates a new MapInputHandler.
@@ -63,6 +65,25 @@ func NewMapInputHandler(
 	return inputHandler
 }
 
+// WithFieldPaths sets the handler to report the path of the invalid field in
+// its validation errors, with array items addressed by index (e.g.
+// "users[2].name"). Otherwise the field of the validation errors is "input".
+//
+// Returns:
+//   - *MapInputHandler: The MapInputHandler.
+func (h *MapInputHandler) WithFieldPaths() *MapInputHandler {
+	h.fieldPaths = true
+	return h
+}
+
+// APIFields returns the APIFields of the input.
+//
+// Returns:
+//   - types.APIFields: The APIFields of the input.
+func (h *MapInputHandler) APIFields() types.APIFields {
+	return h.apiFields
+}
+
 // Handle processes the request input by creating a map presentation from it and
 // validating it.
 //
@@ -80,11 +101,16 @@ func (h *MapInputHandler) Handle(
 	if err != nil {
 		return nil, err
 	}
-	if err := h.validateMap(input, h.apiFields); err != nil {
+	if err := h.validateMap(input, h.apiFields, ""); err != nil {
+		field := "input"
+		var fieldErr *fieldValidationError
+		if h.fieldPaths && errors.As(err, &fieldErr) {
+			field = fieldErr.path
+		}
 		return nil, ValidationError.WithData(ValidationErrorData{
 			Errors: []FieldError{
 				{
-					Field:   "input",
+					Field:   field,
 					Message: err.Error(),
 				},
 			},
@@ -154,17 +180,47 @@ func (h *MapInputHandler) mapFieldConfigFromAPIFields(
 	return cfg, nil
 }
 
+// fieldValidationError is a validation error of the field at the given path.
+type fieldValidationError struct {
+	path string
+	err  error
+}
+
+// Error returns the error message.
+func (e *fieldValidationError) Error() string {
+	return e.err.Error()
+}
+
+// Unwrap returns the underlying error.
+func (e *fieldValidationError) Unwrap() error {
+	return e.err
+}
+
+// fieldPath returns the path of a field within its parent path.
+func fieldPath(parentPath string, field string) string {
+	if parentPath == "" {
+		return field
+	}
+	return parentPath + "." + field
+}
+
 // TODO: Bug if same named fields (in nested fields?).
-// validateMap validates an input map against the provided APIFields.
+// validateMap validates an input map against the provided APIFields. The
+// returned error carries the path of the invalid field, with array items
+// addressed by index (e.g. "users[2].name").
 func (h *MapInputHandler) validateMap(
-	input map[string]any, apiFields types.APIFields,
+	input map[string]any, apiFields types.APIFields, path string,
 ) error {
 	// Ensure required fields are present and validate each value.
 	for _, field := range apiFields {
+		currentPath := fieldPath(path, field.APIName)
 		val, exists := input[field.APIName]
 		if !exists {
 			if field.Required {
-				return fmt.Errorf("field %q is required", field.APIName)
+				return &fieldValidationError{
+					path: currentPath,
+					err:  fmt.Errorf("field %q is required", field.APIName),
+				}
 			}
 			continue
 		}
@@ -172,42 +228,79 @@ func (h *MapInputHandler) validateMap(
 		if field.Nested != nil {
 			switch v := val.(type) {
 			case map[string]any:
-				if err := h.validateMap(v, field.Nested); err != nil {
-					return fmt.Errorf("field %q: %w", field.APIName, err)
+				err := h.validateMap(v, field.Nested, currentPath)
+				if err != nil {
+					return h.wrapNestedError(field.APIName, err)
 				}
 			case []any:
-				for _, item := range v {
-					err := h.validateMap(item.(map[string]any), field.Nested)
+				for i, item := range v {
+					itemPath := fmt.Sprintf("%s[%d]", currentPath, i)
+					itemMap, ok := item.(map[string]any)
+					if !ok {
+						return &fieldValidationError{
+							path: itemPath,
+							err: fmt.Errorf(
+								"field %q item %d is not an object",
+								field.APIName,
+								i,
+							),
+						}
+					}
+					err := h.validateMap(itemMap, field.Nested, itemPath)
 					if err != nil {
-						return fmt.Errorf("field %q: %w", field.APIName, err)
+						return h.wrapNestedError(
+							fmt.Sprintf("%s[%d]", field.APIName, i), err,
+						)
 					}
 				}
 			default:
-				return fmt.Errorf(
-					"field %q is not an object or an array", field.APIName,
-				)
+				return &fieldValidationError{
+					path: currentPath,
+					err: fmt.Errorf(
+						"field %q is not an object or an array", field.APIName,
+					),
+				}
 			}
 		}
 		// For non-object fields, run the validation function.
 		if field.Validate != nil {
 			validate, err := h.getValidator().FromRules(field.Validate)
 			if err != nil {
-				return fmt.Errorf(
-					"validation rule error for field %q: %w",
-					field.APIName,
-					err,
-				)
+				return &fieldValidationError{
+					path: currentPath,
+					err: fmt.Errorf(
+						"validation rule error for field %q: %w",
+						field.APIName,
+						err,
+					),
+				}
 			}
 			if err := validate(val); err != nil {
-				return fmt.Errorf(
-					"validation error for field %q: %w", field.APIName, err,
-				)
+				return &fieldValidationError{
+					path: currentPath,
+					err: fmt.Errorf(
+						"validation error for field %q: %w", field.APIName, err,
+					),
+				}
 			}
 		}
 	}
 	return nil
 }
 
+// wrapNestedError prefixes the message of a nested validation error with the
+// parent field while keeping the path of the invalid field.
+func (h *MapInputHandler) wrapNestedError(
+	field string, err error,
+) error {
+	wrapped := fmt.Errorf("field %q: %w", field, err)
+	var fieldErr *fieldValidationError
+	if errors.As(err, &fieldErr) {
+		return &fieldValidationError{path: fieldErr.path, err: wrapped}
+	}
+	return wrapped
+}
+
 // testValidationRules tests that the validation rules are valid.
 func (h *MapInputHandler) testValidationRules(
 	apiFields types.APIFields,
@@ -0,0 +1,68 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/pakkasys/fluidapi-extended/api/types"
+	"github.com/pakkasys/fluidapi/core"
+)
+
+func TestMapInputHandlerFieldPaths(t *testing.T) {
+	apiFields := types.APIFields{
+		{
+			APIName:  "users",
+			Required: true,
+			Nested: types.APIFields{
+				{
+					APIName:  "name",
+					Validate: []string{"string", "min=1"},
+					Type:     "string",
+					Required: true,
+				},
+			},
+			Validate: []string{"slice", "min=1", "max=2"},
+		},
+	}
+	request := func(body string) *http.Request {
+		r := httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(body))
+		r.Header.Set("Content-Type", "application/json")
+		return r
+	}
+	expectField := func(t *testing.T, err error, expected string) {
+		t.Helper()
+		apiErr, ok := err.(*core.APIError)
+		if !ok || apiErr.ID != ValidationError.ID {
+			t.Fatalf("expected a validation error, got %v", err)
+		}
+		data, ok := apiErr.Data.(ValidationErrorData)
+		if !ok || len(data.Errors) != 1 || data.Errors[0].Field != expected {
+			t.Errorf("expected an error of field %q, got %v", expected, apiErr.Data)
+		}
+	}
+	body := `{"users": [{"name": "a"}, {"name": ""}]}`
+
+	_, err := NewMapInputHandler(apiFields, nil, nil).Handle(nil, request(body))
+	expectField(t, err, "input")
+
+	handler := NewMapInputHandler(apiFields, nil, nil).WithFieldPaths()
+	_, err = handler.Handle(nil, request(body))
+	expectField(t, err, "users[1].name")
+
+	_, err = handler.Handle(
+		nil, request(`{"users": [{"name": "a"}, {"name": "b"}, {"name": "c"}]}`),
+	)
+	expectField(t, err, "users")
+
+	input, err := handler.Handle(nil, request(`{"users": [{"name": "a"}]}`))
+	if err != nil {
+		t.Fatalf("Handle: %v", err)
+	}
+	expected := map[string]any{"users": []any{map[string]any{"name": "a"}}}
+	if !reflect.DeepEqual(input, expected) {
+		t.Errorf("expected %v, got %v", expected, input)
+	}
+}
@@ -18,6 +18,7 @@ var InvalidInputError = core.NewAPIError("INVALID_INPUT")
 // Constants for input sources.
 const (
 	sourceURL     = "url"
+	sourcePath    = "path"
 	sourceBody    = "body"
 	sourceHeader  = "header"
 	sourceHeaders = "headers"
@@ -125,6 +126,10 @@ func (o *ObjectPicker) getValueFromSource(
 		if val, exists := urlData[field]; exists {
 			return val
 		}
+	case sourcePath:
+		if val := r.PathValue(field); val != "" {
+			return val
+		}
 	case sourceBody:
 		if val, exists := bodyData[field]; exists {
 			return val
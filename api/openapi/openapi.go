@@ -0,0 +1,461 @@
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pakkasys/fluidapi-extended/api"
+	"github.com/pakkasys/fluidapi-extended/api/endpoint"
+	"github.com/pakkasys/fluidapi-extended/api/types"
+)
+
+// Version is the OpenAPI version of the generated documents.
+const Version = "3.1.0"
+
+// Input sources of the APIFields.
+const (
+	sourceURL     = "url"
+	sourcePath    = "path"
+	sourceBody    = "body"
+	sourceHeader  = "header"
+	sourceHeaders = "headers"
+	sourceCookie  = "cookie"
+	sourceCookies = "cookies"
+)
+
+// Operation describes a single endpoint of the API.
+type Operation struct {
+	Method  string
+	Path    string
+	Summary string
+	// Input are the input fields of the endpoint.
+	Input types.APIFields
+	// Output are the fields of the payload of a successful response.
+	Output types.APIFields
+	// Errors are the errors the endpoint can return.
+	Errors []api.ExpectedError
+}
+
+// Handler is an endpoint handler that can be described as an operation.
+type Handler interface {
+	URL() string
+	Method() string
+	InputHandler() endpoint.InputHandler
+	ExpectedErrors() []api.ExpectedError
+}
+
+// inputFields is an input handler that knows its input fields.
+type inputFields interface {
+	APIFields() types.APIFields
+}
+
+// HandlerOperation creates an operation from an endpoint handler. The input
+// fields are read from the input handler if it knows its fields.
+//
+// Parameters:
+//   - handler: The endpoint handler.
+//   - summary: The summary of the operation.
+//   - output: The fields of the payload of a successful response.
+//
+// Returns:
+//   - Operation: The new Operation.
+func HandlerOperation(
+	handler Handler, summary string, output types.APIFields,
+) Operation {
+	var input types.APIFields
+	if fields, ok := handler.InputHandler().(inputFields); ok {
+		input = fields.APIFields()
+	}
+	return Operation{
+		Method:  handler.Method(),
+		Path:    handler.URL(),
+		Summary: summary,
+		Input:   input,
+		Output:  output,
+		Errors:  handler.ExpectedErrors(),
+	}
+}
+
+// Document is an OpenAPI document that is built from operations.
+type Document struct {
+	title      string
+	version    string
+	operations []Operation
+}
+
+// NewDocument creates a new Document.
+//
+// Parameters:
+//   - title: The title of the API.
+//   - version: The version of the API.
+//
+// Returns:
+//   - *Document: The new Document.
+func NewDocument(title string, version string) *Document {
+	return &Document{title: title, version: version}
+}
+
+// Add adds operations to the document.
+//
+// Parameters:
+//   - operations: The operations to add.
+//
+// Returns:
+//   - *Document: The document.
+func (d *Document) Add(operations ...Operation) *Document {
+	d.operations = append(d.operations, operations...)
+	return d
+}
+
+// Build builds the document as a map that can be marshaled to JSON or YAML.
+//
+// Returns:
+//   - map[string]any: The document.
+func (d *Document) Build() map[string]any {
+	paths := map[string]any{}
+	for _, operation := range d.operations {
+		path := openAPIPath(operation.Path)
+		item, ok := paths[path].(map[string]any)
+		if !ok {
+			item = map[string]any{}
+			paths[path] = item
+		}
+		item[strings.ToLower(operation.Method)] = buildOperation(operation)
+	}
+	return map[string]any{
+		"openapi": Version,
+		"info": map[string]any{
+			"title":   d.title,
+			"version": d.version,
+		},
+		"paths": paths,
+	}
+}
+
+// JSON returns the document as indented JSON.
+//
+// Returns:
+//   - []byte: The JSON document.
+//   - error: Any error that occurred during marshaling.
+func (d *Document) JSON() ([]byte, error) {
+	return json.MarshalIndent(d.Build(), "", "  ")
+}
+
+// YAML returns the document as YAML.
+//
+// Returns:
+//   - []byte: The YAML document.
+//   - error: Any error that occurred during marshaling.
+func (d *Document) YAML() ([]byte, error) {
+	var builder strings.Builder
+	if err := writeYAML(&builder, d.Build(), 0); err != nil {
+		return nil, err
+	}
+	return []byte(builder.String()), nil
+}
+
+// buildOperation builds the OpenAPI operation object of an operation.
+func buildOperation(operation Operation) map[string]any {
+	object := map[string]any{
+		"responses": buildResponses(operation),
+	}
+	if operation.Summary != "" {
+		object["summary"] = operation.Summary
+	}
+	parameters, body := splitInput(operation)
+	if len(parameters) != 0 {
+		object["parameters"] = parameters
+	}
+	if len(body) != 0 {
+		object["requestBody"] = map[string]any{
+			"required": hasRequired(body),
+			"content":  jsonContent(objectSchema(body)),
+		}
+	}
+	return object
+}
+
+// splitInput splits the input fields of an operation into parameters and body
+// fields by their sources. Fields without a source are read from the query of
+// GET requests and from the body of other requests. The nested fields of query
+// parameters are flattened with the dotted and bracketed syntax of the
+// URLEncoder.
+func splitInput(operation Operation) ([]any, types.APIFields) {
+	defaultSource := sourceBody
+	if operation.Method == http.MethodGet {
+		defaultSource = sourceURL
+	}
+	var parameters []any
+	var body types.APIFields
+	for _, field := range operation.Input {
+		source := field.Source
+		if source == "" {
+			source = defaultSource
+		}
+		switch source {
+		case sourcePath:
+			parameters = append(
+				parameters, parameter(field.APIName, "path", true, field),
+			)
+		case sourceHeader, sourceHeaders:
+			parameters = append(
+				parameters,
+				parameter(field.APIName, "header", field.Required, field),
+			)
+		case sourceCookie, sourceCookies:
+			parameters = append(
+				parameters,
+				parameter(field.APIName, "cookie", field.Required, field),
+			)
+		case sourceURL:
+			parameters = append(
+				parameters, queryParameters(field.APIName, true, field)...,
+			)
+		default:
+			body = append(body, field)
+		}
+	}
+	return parameters, body
+}
+
+// queryParameters flattens a field to query parameters. A parameter is only
+// required if the field and all of its parents are required.
+func queryParameters(
+	name string, parentRequired bool, field types.APIField,
+) []any {
+	required := parentRequired && field.Required
+	if len(field.Nested) == 0 {
+		if isList(field) {
+			name += "[0]"
+			field = types.APIField{APIName: field.APIName}
+		}
+		return []any{parameter(name, "query", required, field)}
+	}
+	prefix := name + "."
+	if isList(field) {
+		prefix = name + "[0]."
+	}
+	var parameters []any
+	for _, nested := range field.Nested {
+		parameters = append(
+			parameters,
+			queryParameters(prefix+nested.APIName, required, nested)...,
+		)
+	}
+	return parameters
+}
+
+// parameter creates an OpenAPI parameter object.
+func parameter(
+	name string, in string, required bool, field types.APIField,
+) map[string]any {
+	return map[string]any{
+		"name":     name,
+		"in":       in,
+		"required": required,
+		"schema":   field.JSONSchema(),
+	}
+}
+
+// buildResponses builds the successful response and a response for each
+// status of the expected errors. The responses use the APIOutput envelope.
+func buildResponses(operation Operation) map[string]any {
+	payload := map[string]any{}
+	if operation.Output != nil {
+		payload = objectSchema(operation.Output)
+	}
+	responses := map[string]any{
+		strconv.Itoa(http.StatusOK): map[string]any{
+			"description": http.StatusText(http.StatusOK),
+			"content": jsonContent(map[string]any{
+				"type":       "object",
+				"properties": map[string]any{"payload": payload},
+				"required":   []any{"payload"},
+			}),
+		},
+	}
+	statusErrors := map[int][]api.ExpectedError{
+		http.StatusInternalServerError: {{
+			ID:     api.InternalServerError.ID,
+			Status: http.StatusInternalServerError,
+		}},
+	}
+	for _, expectedError := range operation.Errors {
+		statusErrors[expectedError.Status] = append(
+			statusErrors[expectedError.Status], expectedError,
+		)
+	}
+	for status, expectedErrors := range statusErrors {
+		responses[strconv.Itoa(status)] = map[string]any{
+			"description": http.StatusText(status),
+			"content": jsonContent(map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"error": errorSchema(expectedErrors),
+				},
+				"required": []any{"error"},
+			}),
+		}
+	}
+	return responses
+}
+
+// errorSchema creates the schema of the API error of the expected errors. The
+// IDs are the masked IDs if set, and the data is only included if it is
+// public for an error.
+func errorSchema(expectedErrors []api.ExpectedError) map[string]any {
+	id := map[string]any{"type": "string"}
+	properties := map[string]any{"id": id}
+	var ids []string
+	publicData := false
+	for _, expectedError := range expectedErrors {
+		errorID := expectedError.ID
+		if expectedError.MaskedID != "" {
+			errorID = expectedError.MaskedID
+		}
+		if !slices.Contains(ids, errorID) {
+			ids = append(ids, errorID)
+		}
+		publicData = publicData || expectedError.PublicData
+	}
+	if len(ids) != 0 {
+		sort.Strings(ids)
+		id["enum"] = stringsToAny(ids)
+	}
+	if publicData {
+		properties["data"] = map[string]any{}
+	}
+	return map[string]any{
+		"type":       "object",
+		"properties": properties,
+		"required":   []any{"id"},
+	}
+}
+
+// objectSchema creates the schema of an object with the fields as properties.
+func objectSchema(fields types.APIFields) map[string]any {
+	return types.APIField{Nested: fields}.JSONSchema()
+}
+
+// fieldType returns the type of a field, or the type of its validation rules
+// if the type is not set.
+func fieldType(field types.APIField) string {
+	if field.Type != "" {
+		return field.Type
+	}
+	if len(field.Validate) != 0 {
+		return strings.ToLower(field.Validate[0])
+	}
+	return ""
+}
+
+// isList returns whether a field is a list.
+func isList(field types.APIField) bool {
+	return fieldType(field) == "slice"
+}
+
+// hasRequired returns whether any of the fields is required.
+func hasRequired(fields types.APIFields) bool {
+	return slices.ContainsFunc(fields, func(field types.APIField) bool {
+		return field.Required
+	})
+}
+
+// jsonContent creates an OpenAPI content object with a JSON schema.
+func jsonContent(schema map[string]any) map[string]any {
+	return map[string]any{
+		"application/json": map[string]any{"schema": schema},
+	}
+}
+
+// openAPIPath converts a URL pattern to an OpenAPI path template by removing
+// the "..." suffix of wildcards.
+//
+// Example:
+//
+//	openAPIPath("/files/{path...}")
+//
+// Output:
+//
+//	"/files/{path}"
+func openAPIPath(url string) string {
+	return strings.ReplaceAll(url, "...}", "}")
+}
+
+// stringsToAny converts a string slice to an any slice.
+func stringsToAny(values []string) []any {
+	result := make([]any, len(values))
+	for i, value := range values {
+		result[i] = value
+	}
+	return result
+}
+
+// writeYAML writes a value built from maps, slices and scalars as YAML. The
+// keys of maps are sorted and strings are written as double-quoted scalars.
+func writeYAML(builder *strings.Builder, value any, indent int) error {
+	padding := strings.Repeat("  ", indent)
+	switch v := value.(type) {
+	case map[string]any:
+		if len(v) == 0 {
+			builder.WriteString(" {}\n")
+			return nil
+		}
+		if indent > 0 {
+			builder.WriteString("\n")
+		}
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			builder.WriteString(padding + yamlKey(key) + ":")
+			if err := writeYAML(builder, v[key], indent+1); err != nil {
+				return err
+			}
+		}
+	case []any:
+		if len(v) == 0 {
+			builder.WriteString(" []\n")
+			return nil
+		}
+		builder.WriteString("\n")
+		for _, item := range v {
+			builder.WriteString(padding + "-")
+			if err := writeYAML(builder, item, indent+1); err != nil {
+				return err
+			}
+		}
+	default:
+		scalar, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Errorf("writeYAML: %w", err)
+		}
+		builder.WriteString(" " + string(scalar) + "\n")
+	}
+	return nil
+}
+
+// yamlKey returns a key as a plain scalar if it only contains letters, digits
+// and underscores, and as a double-quoted scalar otherwise.
+func yamlKey(key string) string {
+	plain := key != ""
+	for _, r := range key {
+		if !(r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' ||
+			r >= '0' && r <= '9') {
+			plain = false
+			break
+		}
+	}
+	if plain && (key[0] < '0' || key[0] > '9') {
+		return key
+	}
+	quoted, _ := json.Marshal(key)
+	return string(quoted)
+}
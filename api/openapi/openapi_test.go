@@ -0,0 +1,157 @@
+package openapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/pakkasys/fluidapi-extended/api"
+	"github.com/pakkasys/fluidapi-extended/api/types"
+)
+
+func testDocument() *Document {
+	return NewDocument("Test API", "1.0.0").Add(
+		Operation{
+			Method:  http.MethodGet,
+			Path:    "/users/{org_id}",
+			Summary: "Get",
+			Input: types.APIFields{
+				{APIName: "org_id", Source: sourcePath, Type: "int64"},
+				{
+					APIName: "selectors",
+					Nested: types.APIFields{
+						{
+							APIName: "name",
+							Nested: types.APIFields{
+								{
+									APIName:  "value",
+									Type:     "string",
+									Validate: []string{"string", "max=10"},
+									Required: true,
+								},
+							},
+						},
+					},
+				},
+				{APIName: "tags", Type: "slice"},
+				{APIName: "If-Match", Source: sourceHeader, Type: "string"},
+			},
+			Output: types.APIFields{
+				{APIName: "count", Type: "int", Required: true},
+			},
+			Errors: []api.ExpectedError{
+				{ID: "B_ERROR", Status: http.StatusBadRequest},
+				{ID: "A_ERROR", Status: http.StatusBadRequest, PublicData: true},
+				{ID: "HIDDEN", MaskedID: "NOT_FOUND", Status: http.StatusNotFound},
+			},
+		},
+		Operation{
+			Method: http.MethodPost,
+			Path:   "/users",
+			Input: types.APIFields{
+				{
+					APIName: "user",
+					Nested: types.APIFields{
+						{
+							APIName:  "role",
+							Type:     "string",
+							Validate: []string{"string", "oneof=admin user"},
+							Default:  "user",
+						},
+					},
+					Required: true,
+				},
+			},
+		},
+	)
+}
+
+func TestDocumentBuild(t *testing.T) {
+	document := testDocument().Build()
+	paths := document["paths"].(map[string]any)
+
+	get := paths["/users/{org_id}"].(map[string]any)["get"].(map[string]any)
+	var names []string
+	for _, p := range get["parameters"].([]any) {
+		parameter := p.(map[string]any)
+		names = append(names, parameter["in"].(string)+":"+parameter["name"].(string))
+	}
+	expectedNames := []string{
+		"path:org_id",
+		"query:selectors.name.value",
+		"query:tags[0]",
+		"header:If-Match",
+	}
+	if !reflect.DeepEqual(names, expectedNames) {
+		t.Errorf("expected parameters %v, got %v", expectedNames, names)
+	}
+	value := get["parameters"].([]any)[1].(map[string]any)
+	if value["required"] != false || value["schema"].(map[string]any)["maxLength"] != int64(10) {
+		t.Errorf("unexpected selector parameter: %v", value)
+	}
+
+	responses := get["responses"].(map[string]any)
+	errorSchema := func(status string) map[string]any {
+		content := responses[status].(map[string]any)["content"].(map[string]any)
+		schema := content["application/json"].(map[string]any)["schema"].(map[string]any)
+		return schema["properties"].(map[string]any)["error"].(map[string]any)
+	}
+	badRequest := errorSchema("400")["properties"].(map[string]any)
+	if !reflect.DeepEqual(
+		badRequest["id"].(map[string]any)["enum"], []any{"A_ERROR", "B_ERROR"},
+	) || badRequest["data"] == nil {
+		t.Errorf("unexpected 400 error schema: %v", badRequest)
+	}
+	notFound := errorSchema("404")["properties"].(map[string]any)
+	if !reflect.DeepEqual(
+		notFound["id"].(map[string]any)["enum"], []any{"NOT_FOUND"},
+	) || notFound["data"] != nil {
+		t.Errorf("unexpected 404 error schema: %v", notFound)
+	}
+	if _, ok := responses["500"]; !ok {
+		t.Errorf("expected internal server error response")
+	}
+
+	post := paths["/users"].(map[string]any)["post"].(map[string]any)
+	body := post["requestBody"].(map[string]any)
+	schema := body["content"].(map[string]any)["application/json"].(map[string]any)["schema"].(map[string]any)
+	role := schema["properties"].(map[string]any)["user"].(map[string]any)["properties"].(map[string]any)["role"].(map[string]any)
+	if body["required"] != true ||
+		!reflect.DeepEqual(role["enum"], []any{"admin", "user"}) ||
+		role["default"] != "user" {
+		t.Errorf("unexpected request body: %v", body)
+	}
+}
+
+func TestDocumentJSONAndYAML(t *testing.T) {
+	document := testDocument()
+
+	jsonDocument, err := document.JSON()
+	if err != nil {
+		t.Fatalf("JSON: %v", err)
+	}
+	var decoded map[string]any
+	if err := json.Unmarshal(jsonDocument, &decoded); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if decoded["openapi"] != Version {
+		t.Errorf("expected version %s, got %v", Version, decoded["openapi"])
+	}
+
+	yamlDocument, err := document.YAML()
+	if err != nil {
+		t.Fatalf("YAML: %v", err)
+	}
+	for _, line := range []string{
+		"openapi: \"3.1.0\"\n",
+		"\n  \"/users/{org_id}\":\n",
+		"\n        \"400\":\n",
+		"- \"A_ERROR\"\n",
+	} {
+		if !strings.Contains(string(yamlDocument), line) {
+			t.Errorf("expected YAML to contain %q", line)
+		}
+	}
+}
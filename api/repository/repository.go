@@ -3,6 +3,7 @@ package repository
 import (
 	"context"
 
+	extendeddatabase "github.com/pakkasys/fluidapi-extended/database"
 	"github.com/pakkasys/fluidapi/database"
 )
 
@@ -37,6 +38,63 @@ type ReaderRepo[Entity database.Getter] interface {
 	) (int, error)
 }
 
+// KeysetReader defines a reader repository that can read the records that
+// come after a keyset.
+type KeysetReader[Entity database.Getter] interface {
+	// GetManyKeyset retrieves the records that come after the keyset.
+	GetManyKeyset(
+		preparer database.Preparer,
+		entityFactoryFn GetterFactoryFn[Entity],
+		getOptions *database.GetOptions,
+		keyset *extendeddatabase.Keyset,
+	) ([]Entity, error)
+}
+
+// FilteredReader defines a reader repository that can read and count the
+// records that match a filter.
+type FilteredReader[Entity database.Getter] interface {
+	// GetManyFiltered retrieves the records that match the filter and come
+	// after the keyset. The keyset can be nil.
+	GetManyFiltered(
+		preparer database.Preparer,
+		entityFactoryFn GetterFactoryFn[Entity],
+		getOptions *database.GetOptions,
+		keyset *extendeddatabase.Keyset,
+		filter *extendeddatabase.Filter,
+	) ([]Entity, error)
+
+	// CountFiltered returns the count of the records that match the filter.
+	CountFiltered(
+		preparer database.Preparer,
+		selectors database.Selectors,
+		page *database.Page,
+		filter *extendeddatabase.Filter,
+		entityFactoryFn GetterFactoryFn[Entity],
+	) (int, error)
+}
+
+// RelationLoader defines a repository that can read related rows.
+type RelationLoader interface {
+	// LoadRelation reads the rows related to the local key values with a
+	// single query and returns them by relation key.
+	LoadRelation(
+		preparer database.Preparer,
+		relation extendeddatabase.Relation,
+		keys []any,
+	) (map[string][]any, error)
+}
+
+// Aggregator defines a repository that can aggregate rows.
+type Aggregator interface {
+	// Aggregate groups the rows and returns the groups and aggregates of each
+	// group by alias.
+	Aggregate(
+		preparer database.Preparer,
+		tableName string,
+		opts *extendeddatabase.AggregateOptions,
+	) ([]map[string]any, error)
+}
+
 // MutatorRepo defines mutation-related operations.
 type MutatorRepo[Entity database.Mutator] interface {
 	Insert(preparer database.Preparer, mutator Entity) (Entity, error)
@@ -54,6 +112,117 @@ type MutatorRepo[Entity database.Mutator] interface {
 	) (int64, error)
 }
 
+// BulkInserter defines a mutator repository that can insert multiple rows
+// with multi-row inserts. The bulk create endpoints check for it with a type
+// assertion and insert the rows one by one if it is not implemented.
+type BulkInserter[Entity database.Mutator] interface {
+	// InsertMany inserts multiple records into the DB using multi-row
+	// inserts.
+	InsertMany(preparer database.Preparer, mutators []Entity) ([]Entity, error)
+}
+
+// Counter defines a mutator repository that can count the rows that match
+// the selectors, e.g. to tell a missing row from a version conflict.
+type Counter interface {
+	// Count returns the count of the rows that match the selectors.
+	Count(
+		preparer database.Preparer,
+		tableName string,
+		selectors database.Selectors,
+	) (int, error)
+}
+
+// InsertIDSetter defines an entity that is given its generated ID, e.g. an
+// auto-increment primary key, when it is inserted with InsertMany. The
+// entities that have their own IDs are not given generated IDs.
+type InsertIDSetter interface {
+	// HasInsertID tells whether the entity has its own ID.
+	HasInsertID() bool
+	// SetInsertID sets the generated ID of the inserted entity.
+	SetInsertID(id int64)
+}
+
+// InsertIDQueryBuilder defines a query builder that tells the generated IDs
+// of a multi-row insert from the last insert ID reported by the database.
+// The databases report either the first or the last ID of the rows.
+type InsertIDQueryBuilder interface {
+	// FirstInsertID returns the generated ID of the first row of a multi-row
+	// insert. The IDs of the other rows follow it.
+	FirstInsertID(lastInsertID int64, rowCount int) int64
+}
+
+// Upserter defines a mutator repository that can upsert rows. The update
+// endpoints check for it with a type assertion when an upsert is requested.
+type Upserter interface {
+	// Upsert inserts a row or updates the update columns of the row that
+	// conflicts with it on the conflict columns. It reports whether the row
+	// was inserted.
+	Upsert(
+		preparer database.Preparer,
+		tableName string,
+		insertedValues database.InsertedValuesFn,
+		conflictColumns []string,
+		updateColumns []string,
+	) (bool, error)
+}
+
+// UpsertQueryBuilder defines a query builder that can build single row upsert
+// queries.
+type UpsertQueryBuilder interface {
+	// Upsert returns the query and values to upsert a row.
+	Upsert(
+		tableName string,
+		insertedValues database.InsertedValuesFn,
+		conflictColumns []string,
+		updateColumns []string,
+	) (string, []any)
+}
+
+// KeysetQueryBuilder defines a query builder that can build keyset paginated
+// get queries.
+type KeysetQueryBuilder interface {
+	// GetKeyset returns the query and values to get the rows after the keyset.
+	// It returns an error if a name of the options or keyset is invalid.
+	GetKeyset(
+		tableName string,
+		opts *database.GetOptions,
+		keyset *extendeddatabase.Keyset,
+	) (string, []any, error)
+}
+
+// FilterQueryBuilder defines a query builder that can build get and count
+// queries with filter expressions.
+type FilterQueryBuilder interface {
+	// GetFiltered returns the query and values to get the rows that match the
+	// filter and come after the keyset. It returns an error if a name of the
+	// options, keyset or filter is invalid.
+	GetFiltered(
+		tableName string,
+		opts *database.GetOptions,
+		keyset *extendeddatabase.Keyset,
+		filter *extendeddatabase.Filter,
+	) (string, []any, error)
+
+	// CountFiltered returns the query and values to count the rows that match
+	// the filter. It returns an error if a name of the options or filter is
+	// invalid.
+	CountFiltered(
+		tableName string,
+		opts *database.CountOptions,
+		filter *extendeddatabase.Filter,
+	) (string, []any, error)
+}
+
+// AggregateQueryBuilder defines a query builder that can build aggregate
+// queries.
+type AggregateQueryBuilder interface {
+	// Aggregate returns the query and values to aggregate the rows. It
+	// returns an error if a name of the options is invalid.
+	Aggregate(
+		tableName string, opts *extendeddatabase.AggregateOptions,
+	) (string, []any, error)
+}
+
 // RawQueryer defines generic methods for executing raw queries and commands.
 type RawQueryer interface {
 	// Exec executes a query using a prepared statement that does not return
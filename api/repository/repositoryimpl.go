@@ -3,7 +3,10 @@ package repository
 import (
 	"context"
 	"fmt"
+	"slices"
+	"strconv"
 
+	extendeddatabase "github.com/pakkasys/fluidapi-extended/database"
 	"github.com/pakkasys/fluidapi/database"
 )
 
@@ -11,13 +14,28 @@ import (
 type DefaultReaderRepo[Entity database.Getter] struct {
 	QueryBuilder database.QueryBuilder
 	ErrorChecker database.ErrorChecker
-	readDBOps    *database.ReadDBOps[Entity]
-	dbOps        *database.DBOps
+	// MaxPlaceholders limits the number of key placeholders in a single
+	// relation query. DefaultMaxPlaceholders is used if it is not set.
+	MaxPlaceholders int
+	readDBOps       *database.ReadDBOps[Entity]
+	dbOps           *database.DBOps
 }
 
 // DefaultReaderRepo implements the ReaderRepo interface.
 var _ ReaderRepo[database.Getter] = (*DefaultReaderRepo[database.Getter])(nil)
 
+// DefaultReaderRepo implements the KeysetReader interface.
+var _ KeysetReader[database.Getter] = (*DefaultReaderRepo[database.Getter])(nil)
+
+// DefaultReaderRepo implements the FilteredReader interface.
+var _ FilteredReader[database.Getter] = (*DefaultReaderRepo[database.Getter])(nil)
+
+// DefaultReaderRepo implements the RelationLoader interface.
+var _ RelationLoader = (*DefaultReaderRepo[database.Getter])(nil)
+
+// DefaultReaderRepo implements the Aggregator interface.
+var _ Aggregator = (*DefaultReaderRepo[database.Getter])(nil)
+
 // NewDefaultReaderRepo returns a new DefaultReaderRepo.
 //
 // Parameters:
@@ -63,7 +81,8 @@ func (r *DefaultReaderRepo[Entity]) GetOne(
 	)
 }
 
-// GetMany retrieves multiple records from the DB.
+// GetMany retrieves multiple records from the DB. If the options have
+// projections, only the projected columns are read into the entities.
 //
 // Parameters:
 //   - preparer: The database connection or transaction to use.
@@ -78,6 +97,14 @@ func (r *DefaultReaderRepo[Entity]) GetMany(
 	entityFactoryFn GetterFactoryFn[Entity],
 	getOptions *database.GetOptions,
 ) ([]Entity, error) {
+	if len(getOptions.Projections) != 0 {
+		query, values := r.QueryBuilder.Get(
+			entityFactoryFn().TableName(), getOptions,
+		)
+		return r.queryProjected(
+			preparer, query, values, getOptions.Projections, entityFactoryFn,
+		)
+	}
 	return r.readDBOps.GetMany(
 		preparer,
 		getOptions,
@@ -87,6 +114,86 @@ func (r *DefaultReaderRepo[Entity]) GetMany(
 	)
 }
 
+// GetManyKeyset retrieves the records that come after the keyset. The query
+// builder must implement KeysetQueryBuilder. If the options have projections,
+// only the projected columns are read into the entities.
+//
+// Parameters:
+//   - preparer: The database connection or transaction to use.
+//   - entityFactoryFn: A function that returns a new instance of T.
+//   - getOptions: Filter and query options for the query.
+//   - keyset: The keyset to retrieve the records after.
+//
+// Returns:
+//   - T: A slice of retrieved entities of type T.
+//   - error: An error if the query fails.
+func (r *DefaultReaderRepo[Entity]) GetManyKeyset(
+	preparer database.Preparer,
+	entityFactoryFn GetterFactoryFn[Entity],
+	getOptions *database.GetOptions,
+	keyset *extendeddatabase.Keyset,
+) ([]Entity, error) {
+	queryBuilder, ok := r.QueryBuilder.(KeysetQueryBuilder)
+	if !ok {
+		return nil, fmt.Errorf(
+			"GetManyKeyset: query builder %T does not support keysets",
+			r.QueryBuilder,
+		)
+	}
+	query, values, err := queryBuilder.GetKeyset(
+		entityFactoryFn().TableName(), getOptions, keyset,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if len(getOptions.Projections) != 0 {
+		return r.queryProjected(
+			preparer, query, values, getOptions.Projections, entityFactoryFn,
+		)
+	}
+	return r.Query(preparer, query, values, entityFactoryFn)
+}
+
+// GetManyFiltered retrieves the records that match the filter and come after
+// the keyset. The query builder must implement FilterQueryBuilder. If the
+// options have projections, only the projected columns are read into the
+// entities.
+//
+// Parameters:
+//   - preparer: The database connection or transaction to use.
+//   - entityFactoryFn: A function that returns a new instance of T.
+//   - getOptions: Filter and query options for the query.
+//   - keyset: The keyset to retrieve the records after, or nil.
+//   - filter: The filter expression the records must match.
+//
+// Returns:
+//   - T: A slice of retrieved entities of type T.
+//   - error: An error if the query fails.
+func (r *DefaultReaderRepo[Entity]) GetManyFiltered(
+	preparer database.Preparer,
+	entityFactoryFn GetterFactoryFn[Entity],
+	getOptions *database.GetOptions,
+	keyset *extendeddatabase.Keyset,
+	filter *extendeddatabase.Filter,
+) ([]Entity, error) {
+	queryBuilder, err := r.filterQueryBuilder()
+	if err != nil {
+		return nil, err
+	}
+	query, values, err := queryBuilder.GetFiltered(
+		entityFactoryFn().TableName(), getOptions, keyset, filter,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if len(getOptions.Projections) != 0 {
+		return r.queryProjected(
+			preparer, query, values, getOptions.Projections, entityFactoryFn,
+		)
+	}
+	return r.Query(preparer, query, values, entityFactoryFn)
+}
+
 // Count returns a record count.
 //
 // Parameters:
@@ -116,6 +223,73 @@ func (r *DefaultReaderRepo[Entity]) Count(
 	)
 }
 
+// CountFiltered returns the count of the records that match the filter. The
+// query builder must implement FilterQueryBuilder.
+//
+// Parameters:
+//   - preparer: The database connection or transaction to use.
+//   - selectors: Filter and query options for the query.
+//   - page: Pagination options.
+//   - filter: The filter expression the records must match.
+//   - entityFactoryFn: A function that returns a new instance of T.
+//
+// Returns:
+//   - int: The count of matching records.
+//   - error: An error if the query fails.
+func (r *DefaultReaderRepo[Entity]) CountFiltered(
+	preparer database.Preparer,
+	selectors database.Selectors,
+	page *database.Page,
+	filter *extendeddatabase.Filter,
+	entityFactoryFn GetterFactoryFn[Entity],
+) (int, error) {
+	queryBuilder, err := r.filterQueryBuilder()
+	if err != nil {
+		return 0, err
+	}
+	query, values, err := queryBuilder.CountFiltered(
+		entityFactoryFn().TableName(),
+		&database.CountOptions{
+			Selectors: selectors,
+			Page:      page,
+		},
+		filter,
+	)
+	if err != nil {
+		return 0, err
+	}
+	rows, stmt, err := r.dbOps.Query(preparer, query, values, r.ErrorChecker)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+	defer stmt.Close()
+	var count int
+	if rows.Next() {
+		if err := rows.Scan(&count); err != nil {
+			return 0, r.ErrorChecker.Check(err)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return 0, r.ErrorChecker.Check(err)
+	}
+	return count, nil
+}
+
+// filterQueryBuilder returns the query builder as a FilterQueryBuilder.
+func (r *DefaultReaderRepo[Entity]) filterQueryBuilder() (
+	FilterQueryBuilder, error,
+) {
+	queryBuilder, ok := r.QueryBuilder.(FilterQueryBuilder)
+	if !ok {
+		return nil, fmt.Errorf(
+			"filterQueryBuilder: query builder %T does not support filters",
+			r.QueryBuilder,
+		)
+	}
+	return queryBuilder, nil
+}
+
 // Query performs a custom SQL query. It returns the results as a slice of
 // entities. It returns an error if the query fails.
 //
@@ -145,18 +319,243 @@ func (r *DefaultReaderRepo[Entity]) Query(
 	return database.RowsToEntities(rows, entityFactoryFn)
 }
 
+// queryProjected performs a query that selects the projected columns and
+// scans them into the matching fields of the entities.
+func (r *DefaultReaderRepo[Entity]) queryProjected(
+	preparer database.Preparer,
+	query string,
+	parameters []any,
+	projections []database.Projection,
+	entityFactoryFn GetterFactoryFn[Entity],
+) ([]Entity, error) {
+	columns := make([]string, len(projections))
+	for i, projection := range projections {
+		columns[i] = projection.Column
+	}
+	rows, stmt, err := r.dbOps.Query(
+		preparer, query, parameters, r.ErrorChecker,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	defer stmt.Close()
+	var entities []Entity
+	for rows.Next() {
+		entity := entityFactoryFn()
+		if err := extendeddatabase.ScanColumns(entity, rows, columns); err != nil {
+			return nil, err
+		}
+		entities = append(entities, entity)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, r.ErrorChecker.Check(err)
+	}
+	return entities, nil
+}
+
+// LoadRelation reads the rows related to the local key values. The keys are
+// read in chunks of at most MaxPlaceholders keys, with a single query per
+// chunk. The rows are returned by the relation key of their foreign value, see
+// extendeddatabase.RelationKey.
+//
+// Parameters:
+//   - preparer: The database connection or transaction to use.
+//   - relation: The relation to read.
+//   - keys: The local key values.
+//
+// Returns:
+//   - map[string][]any: The related entities by relation key.
+//   - error: An error if the query fails.
+func (r *DefaultReaderRepo[Entity]) LoadRelation(
+	preparer database.Preparer,
+	relation extendeddatabase.Relation,
+	keys []any,
+) (map[string][]any, error) {
+	maxPlaceholders := r.MaxPlaceholders
+	if maxPlaceholders <= 0 {
+		maxPlaceholders = DefaultMaxPlaceholders
+	}
+	related := make(map[string][]any)
+	for chunk := range slices.Chunk(keys, maxPlaceholders) {
+		err := r.loadRelationChunk(preparer, relation, chunk, related)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return related, nil
+}
+
+// loadRelationChunk reads the rows related to a chunk of the local key values
+// into related.
+func (r *DefaultReaderRepo[Entity]) loadRelationChunk(
+	preparer database.Preparer,
+	relation extendeddatabase.Relation,
+	keys []any,
+	related map[string][]any,
+) error {
+	query, parameters := r.QueryBuilder.Get(
+		relation.Table, relation.GetOptions(keys),
+	)
+	rows, stmt, err := r.dbOps.Query(
+		preparer, query, parameters, r.ErrorChecker,
+	)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	defer stmt.Close()
+	for rows.Next() {
+		entity := relation.EntityFn()
+		var foreign any
+		err := extendeddatabase.ScanColumns(
+			entity, rows, relation.Columns, &foreign,
+		)
+		if err != nil {
+			return err
+		}
+		key, ok := extendeddatabase.RelationKey(foreign)
+		if !ok {
+			continue
+		}
+		related[key] = append(related[key], entity)
+	}
+	if err := rows.Err(); err != nil {
+		return r.ErrorChecker.Check(err)
+	}
+	return nil
+}
+
+// Aggregate groups the rows and returns the groups and aggregates of each
+// group by alias. Byte slices read by drivers are converted to strings. COUNT
+// aggregates are returned as int64 and the other aggregates as float64. The
+// query builder must implement AggregateQueryBuilder.
+//
+// Parameters:
+//   - preparer: The database connection or transaction to use.
+//   - tableName: The name of the table.
+//   - opts: The options of the aggregate query.
+//
+// Returns:
+//   - []map[string]any: The groups.
+//   - error: An error if the query fails.
+func (r *DefaultReaderRepo[Entity]) Aggregate(
+	preparer database.Preparer,
+	tableName string,
+	opts *extendeddatabase.AggregateOptions,
+) ([]map[string]any, error) {
+	queryBuilder, ok := r.QueryBuilder.(AggregateQueryBuilder)
+	if !ok {
+		return nil, fmt.Errorf(
+			"Aggregate: query builder does not support aggregate queries",
+		)
+	}
+	query, parameters, err := queryBuilder.Aggregate(tableName, opts)
+	if err != nil {
+		return nil, err
+	}
+	rows, stmt, err := r.dbOps.Query(
+		preparer, query, parameters, r.ErrorChecker,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	defer stmt.Close()
+	groups := []map[string]any{}
+	for rows.Next() {
+		values := make([]any, len(opts.GroupBy)+len(opts.Aggregates))
+		pointers := make([]any, len(values))
+		for i := range values {
+			pointers[i] = &values[i]
+		}
+		if err := rows.Scan(pointers...); err != nil {
+			return nil, err
+		}
+		group := make(map[string]any, len(values))
+		for i, groupBy := range opts.GroupBy {
+			group[groupBy.Alias] = groupValue(values[i])
+		}
+		for i, aggregate := range opts.Aggregates {
+			value, err := aggregateValue(
+				aggregate, values[len(opts.GroupBy)+i],
+			)
+			if err != nil {
+				return nil, err
+			}
+			group[aggregate.Alias] = value
+		}
+		groups = append(groups, group)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, r.ErrorChecker.Check(err)
+	}
+	return groups, nil
+}
+
+// groupValue returns the value of a group column. Byte slices are converted
+// to strings.
+func groupValue(value any) any {
+	if bytes, ok := value.([]byte); ok {
+		return string(bytes)
+	}
+	return value
+}
+
+// aggregateValue converts the value of an aggregate to int64 for COUNT and to
+// float64 for the other functions. Nil values are returned as nil.
+func aggregateValue(
+	aggregate extendeddatabase.Aggregate, value any,
+) (any, error) {
+	if value == nil {
+		return nil, nil
+	}
+	text := fmt.Sprint(groupValue(value))
+	if aggregate.Function == extendeddatabase.AggregateCount {
+		count, err := strconv.ParseInt(text, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("aggregateValue: invalid count: %w", err)
+		}
+		return count, nil
+	}
+	number, err := strconv.ParseFloat(text, 64)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"aggregateValue: invalid %s value: %w", aggregate.Function, err,
+		)
+	}
+	return number, nil
+}
+
+// DefaultMaxPlaceholders is the default maximum number of placeholders used in
+// a single multi-row insert or relation query. It is the lowest limit
+// supported by the dialects.
+const DefaultMaxPlaceholders = 999
+
 // DefaultMutatorRepo is the concrete implementation for mutation
 // operations.
 type DefaultMutatorRepo[Entity database.Mutator] struct {
 	QueryBuilder database.QueryBuilder
 	ErrorChecker database.ErrorChecker
-	mutateDBOps  *database.MutateDBOps[Entity]
-	dbOps        *database.DBOps
+	// MaxPlaceholders limits the number of placeholders in a single multi-row
+	// insert query. DefaultMaxPlaceholders is used if it is not set.
+	MaxPlaceholders int
+	mutateDBOps     *database.MutateDBOps[Entity]
+	dbOps           *database.DBOps
 }
 
 // DefaultMutatorRepo implements MutatorRepo[database.Mutator].
 var _ MutatorRepo[database.Mutator] = (*DefaultMutatorRepo[database.Mutator])(nil)
 
+// DefaultMutatorRepo implements BulkInserter.
+var _ BulkInserter[database.Mutator] = (*DefaultMutatorRepo[database.Mutator])(nil)
+
+// DefaultMutatorRepo implements Upserter.
+var _ Upserter = (*DefaultMutatorRepo[database.Mutator])(nil)
+
+// DefaultMutatorRepo implements Counter.
+var _ Counter = (*DefaultMutatorRepo[database.Mutator])(nil)
+
 // NewDefaultMutatorRepo returns a new DefaultMutatorRepo.
 //
 // Parameters:
@@ -199,6 +598,130 @@ func (r *DefaultMutatorRepo[Entity]) Insert(
 	return mutator, nil
 }
 
+// InsertMany inserts multiple records into the DB. The records are inserted
+// with multi-row inserts, chunked so that a single query does not exceed the
+// placeholder limit. All records must be of the same table and have the same
+// columns. If the entities implement InsertIDSetter and the query builder
+// implements InsertIDQueryBuilder, the entities that do not have their own
+// IDs are given their generated IDs. The generated IDs of a multi-row insert
+// follow each other only if none of its rows has its own ID, so the entities
+// that have their own IDs are inserted first with separate queries.
+//
+// Parameters:
+//   - preparer: The database connection or transaction to use.
+//   - mutators: The entities to insert.
+//
+// Returns:
+//   - []Entity: The inserted entities.
+//   - error: An error if the insertion fails.
+func (r *DefaultMutatorRepo[Entity]) InsertMany(
+	preparer database.Preparer, mutators []Entity,
+) ([]Entity, error) {
+	if len(mutators) == 0 {
+		return mutators, nil
+	}
+	var withIDs, withoutIDs []Entity
+	for _, mutator := range mutators {
+		setter, ok := any(mutator).(InsertIDSetter)
+		if ok && setter.HasInsertID() {
+			withIDs = append(withIDs, mutator)
+		} else {
+			withoutIDs = append(withoutIDs, mutator)
+		}
+	}
+	if err := r.insertChunks(preparer, withIDs, false); err != nil {
+		return nil, err
+	}
+	if err := r.insertChunks(preparer, withoutIDs, true); err != nil {
+		return nil, err
+	}
+	return mutators, nil
+}
+
+// insertChunks inserts the entities with multi-row inserts of at most the
+// chunk size and gives them their generated IDs if setIDs is set.
+func (r *DefaultMutatorRepo[Entity]) insertChunks(
+	preparer database.Preparer, mutators []Entity, setIDs bool,
+) error {
+	if len(mutators) == 0 {
+		return nil
+	}
+	columns, _ := mutators[0].InsertedValues()
+	size := r.chunkSize(len(columns))
+	for start := 0; start < len(mutators); start += size {
+		end := min(start+size, len(mutators))
+		insertedValues := make([]database.InsertedValuesFn, end-start)
+		for i, mutator := range mutators[start:end] {
+			insertedValues[i] = mutator.InsertedValues
+		}
+		query, values := r.QueryBuilder.InsertMany(
+			mutators[0].TableName(), insertedValues,
+		)
+		result, err := r.exec(preparer, query, values)
+		if err != nil {
+			return err
+		}
+		if !setIDs {
+			continue
+		}
+		if err := r.setInsertIDs(result, mutators[start:end]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// chunkSize returns the number of rows that fit in a single multi-row insert.
+func (r *DefaultMutatorRepo[Entity]) chunkSize(columnCount int) int {
+	maxPlaceholders := r.MaxPlaceholders
+	if maxPlaceholders <= 0 {
+		maxPlaceholders = DefaultMaxPlaceholders
+	}
+	return max(maxPlaceholders/max(columnCount, 1), 1)
+}
+
+// setInsertIDs gives the entities of a multi-row insert their generated IDs
+// if they implement InsertIDSetter and the query builder implements
+// InsertIDQueryBuilder.
+func (r *DefaultMutatorRepo[Entity]) setInsertIDs(
+	result database.Result, mutators []Entity,
+) error {
+	queryBuilder, ok := r.QueryBuilder.(InsertIDQueryBuilder)
+	if !ok {
+		return nil
+	}
+	if _, ok := any(mutators[0]).(InsertIDSetter); !ok {
+		return nil
+	}
+	lastInsertID, err := result.LastInsertId()
+	if err != nil {
+		return r.ErrorChecker.Check(err)
+	}
+	id := queryBuilder.FirstInsertID(lastInsertID, len(mutators))
+	for i, mutator := range mutators {
+		if setter, ok := any(mutator).(InsertIDSetter); ok {
+			setter.SetInsertID(id + int64(i))
+		}
+	}
+	return nil
+}
+
+// exec prepares and executes a query that does not return rows.
+func (r *DefaultMutatorRepo[Entity]) exec(
+	preparer database.Preparer, query string, values []any,
+) (database.Result, error) {
+	stmt, err := preparer.Prepare(query)
+	if err != nil {
+		return nil, r.ErrorChecker.Check(err)
+	}
+	defer stmt.Close()
+	result, err := stmt.Exec(values...)
+	if err != nil {
+		return nil, r.ErrorChecker.Check(err)
+	}
+	return result, nil
+}
+
 // Update performs the DB update operation.
 //
 // Parameters:
@@ -253,6 +776,349 @@ func (r *DefaultMutatorRepo[Entity]) Delete(
 	)
 }
 
+// Upsert inserts a row or updates the update columns of the row that
+// conflicts with it on the conflict columns. The query builder must implement
+// UpsertQueryBuilder. The row is first inserted with its conflicts ignored, so
+// whether it was inserted is told by the affected rows of the insert. If it
+// conflicts, the row that has the inserted values in the conflict columns is
+// updated. MySQL ignores the conflicts of the insert on any unique key, so if
+// no row has the values in the conflict columns, the row conflicted on
+// another key and DuplicateEntryError is returned. MySQL connections must not
+// set clientFoundRows, which reports the untouched rows as affected.
+//
+// Parameters:
+//   - preparer: The database connection or transaction to use.
+//   - tableName: The name of the table.
+//   - insertedValues: Function used to get the columns and values to insert.
+//   - conflictColumns: The columns that identify a conflicting row.
+//   - updateColumns: The columns to update on conflict.
+//
+// Returns:
+//   - bool: Whether the row was inserted.
+//   - error: An error if the upsert fails.
+func (r *DefaultMutatorRepo[Entity]) Upsert(
+	preparer database.Preparer,
+	tableName string,
+	insertedValues database.InsertedValuesFn,
+	conflictColumns []string,
+	updateColumns []string,
+) (bool, error) {
+	queryBuilder, ok := r.QueryBuilder.(UpsertQueryBuilder)
+	if !ok {
+		return false, fmt.Errorf(
+			"Upsert: query builder %T does not support upsert",
+			r.QueryBuilder,
+		)
+	}
+	selectors, err := insertedSelectors(
+		tableName, insertedValues, conflictColumns,
+	)
+	if err != nil {
+		return false, fmt.Errorf("Upsert: conflict %w", err)
+	}
+	updateSelectors, err := insertedSelectors(
+		tableName, insertedValues, updateColumns,
+	)
+	if err != nil {
+		return false, fmt.Errorf("Upsert: update %w", err)
+	}
+	updates := make(database.Updates, len(updateSelectors))
+	for i, selector := range updateSelectors {
+		updates[i] = database.Update{Field: selector.Column, Value: selector.Value}
+	}
+
+	query, values := queryBuilder.Upsert(
+		tableName, insertedValues, conflictColumns, nil,
+	)
+	// The conflicting row can be deleted between the insert and the update,
+	// in which case the row is inserted again.
+	for range upsertAttempts {
+		result, err := r.exec(preparer, query, values)
+		if err != nil {
+			return false, err
+		}
+		inserted, err := result.RowsAffected()
+		if err != nil {
+			return false, r.ErrorChecker.Check(err)
+		}
+		if inserted != 0 {
+			return true, nil
+		}
+		updated, err := r.updateConflicting(
+			preparer, tableName, selectors, updates,
+		)
+		if err != nil {
+			return false, err
+		}
+		if updated {
+			return false, nil
+		}
+	}
+	return false, extendeddatabase.DuplicateEntryError
+}
+
+// upsertAttempts is the number of times an upsert inserts the row if the
+// conflicting row is not found.
+const upsertAttempts = 2
+
+// updateConflicting updates the row that matches the conflict selectors and
+// tells whether the row exists. MySQL reports the rows whose values did not
+// change as unaffected, so the row is counted if the update affects no rows.
+func (r *DefaultMutatorRepo[Entity]) updateConflicting(
+	preparer database.Preparer,
+	tableName string,
+	selectors database.Selectors,
+	updates database.Updates,
+) (bool, error) {
+	if len(updates) != 0 {
+		query, values := r.QueryBuilder.UpdateQuery(
+			tableName, updates, selectors,
+		)
+		result, err := r.exec(preparer, query, values)
+		if err != nil {
+			return false, err
+		}
+		updated, err := result.RowsAffected()
+		if err != nil {
+			return false, r.ErrorChecker.Check(err)
+		}
+		if updated != 0 {
+			return true, nil
+		}
+	}
+	count, err := r.Count(preparer, tableName, selectors)
+	if err != nil {
+		return false, err
+	}
+	return count != 0, nil
+}
+
+// insertedSelectors returns the equality selectors of the inserted values of
+// the columns.
+func insertedSelectors(
+	tableName string,
+	insertedValues database.InsertedValuesFn,
+	selectedColumns []string,
+) (database.Selectors, error) {
+	columns, values := insertedValues()
+	selectors := make(database.Selectors, 0, len(selectedColumns))
+	for _, column := range selectedColumns {
+		i := slices.Index(columns, column)
+		if i == -1 {
+			return nil, fmt.Errorf("column %q is not inserted", column)
+		}
+		selectors = append(selectors, database.Selector{
+			Table:     tableName,
+			Column:    column,
+			Predicate: "=",
+			Value:     values[i],
+		})
+	}
+	return selectors, nil
+}
+
+// Count returns the count of the rows that match the selectors.
+//
+// Parameters:
+//   - preparer: The database connection or transaction to use.
+//   - tableName: The name of the table.
+//   - selectors: The selectors of the counted rows.
+//
+// Returns:
+//   - int: The count of matching rows.
+//   - error: An error if the query fails.
+func (r *DefaultMutatorRepo[Entity]) Count(
+	preparer database.Preparer,
+	tableName string,
+	selectors database.Selectors,
+) (int, error) {
+	query, values := r.QueryBuilder.Count(
+		tableName, &database.CountOptions{Selectors: selectors},
+	)
+	rows, stmt, err := r.dbOps.Query(preparer, query, values, r.ErrorChecker)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+	defer stmt.Close()
+	var count int
+	if rows.Next() {
+		if err := rows.Scan(&count); err != nil {
+			return 0, r.ErrorChecker.Check(err)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return 0, r.ErrorChecker.Check(err)
+	}
+	return count, nil
+}
+
+// MutatorRepoAdapter adapts a MutatorRepo of a concrete entity type to a
+// MutatorRepo of database.Mutator. The mutators passed to it must be of the
+// concrete entity type.
+type MutatorRepoAdapter[Entity database.Mutator] struct {
+	repo MutatorRepo[Entity]
+}
+
+// MutatorRepoAdapter implements MutatorRepo[database.Mutator].
+var _ MutatorRepo[database.Mutator] = (*MutatorRepoAdapter[database.Mutator])(nil)
+
+// MutatorRepoAdapter implements BulkInserter.
+var _ BulkInserter[database.Mutator] = (*MutatorRepoAdapter[database.Mutator])(nil)
+
+// MutatorRepoAdapter implements Upserter.
+var _ Upserter = (*MutatorRepoAdapter[database.Mutator])(nil)
+
+// MutatorRepoAdapter implements Counter.
+var _ Counter = (*MutatorRepoAdapter[database.Mutator])(nil)
+
+// NewMutatorRepoAdapter returns a new MutatorRepoAdapter.
+//
+// Parameters:
+//   - repo: The entity specific MutatorRepo.
+//
+// Returns:
+//   - *MutatorRepoAdapter: A new MutatorRepoAdapter.
+func NewMutatorRepoAdapter[Entity database.Mutator](
+	repo MutatorRepo[Entity],
+) *MutatorRepoAdapter[Entity] {
+	return &MutatorRepoAdapter[Entity]{
+		repo: repo,
+	}
+}
+
+// Insert inserts a record into the DB.
+func (a *MutatorRepoAdapter[Entity]) Insert(
+	preparer database.Preparer, mutator database.Mutator,
+) (database.Mutator, error) {
+	entity, err := a.entity(mutator)
+	if err != nil {
+		return nil, err
+	}
+	inserted, err := a.repo.Insert(preparer, entity)
+	if err != nil {
+		return nil, err
+	}
+	return inserted, nil
+}
+
+// InsertMany inserts multiple records into the DB. The records are inserted
+// with InsertMany if the adapted repository implements BulkInserter and one by
+// one otherwise.
+func (a *MutatorRepoAdapter[Entity]) InsertMany(
+	preparer database.Preparer, mutators []database.Mutator,
+) ([]database.Mutator, error) {
+	bulkInserter, ok := a.repo.(BulkInserter[Entity])
+	if !ok {
+		result := make([]database.Mutator, len(mutators))
+		for i, mutator := range mutators {
+			inserted, err := a.Insert(preparer, mutator)
+			if err != nil {
+				return nil, err
+			}
+			result[i] = inserted
+		}
+		return result, nil
+	}
+	entities := make([]Entity, len(mutators))
+	for i, mutator := range mutators {
+		entity, err := a.entity(mutator)
+		if err != nil {
+			return nil, err
+		}
+		entities[i] = entity
+	}
+	inserted, err := bulkInserter.InsertMany(preparer, entities)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]database.Mutator, len(inserted))
+	for i := range inserted {
+		result[i] = inserted[i]
+	}
+	return result, nil
+}
+
+// Update performs the DB update operation.
+func (a *MutatorRepoAdapter[Entity]) Update(
+	preparer database.Preparer,
+	updater database.Mutator,
+	selectors database.Selectors,
+	updates database.Updates,
+) (int64, error) {
+	entity, err := a.entity(updater)
+	if err != nil {
+		return 0, err
+	}
+	return a.repo.Update(preparer, entity, selectors, updates)
+}
+
+// Delete performs the DB delete operation.
+func (a *MutatorRepoAdapter[Entity]) Delete(
+	preparer database.Preparer,
+	deleter database.Mutator,
+	selectors database.Selectors,
+	deleteOpts *database.DeleteOptions,
+) (int64, error) {
+	entity, err := a.entity(deleter)
+	if err != nil {
+		return 0, err
+	}
+	return a.repo.Delete(preparer, entity, selectors, deleteOpts)
+}
+
+// Upsert performs the DB upsert operation. The adapted repository must
+// implement Upserter.
+func (a *MutatorRepoAdapter[Entity]) Upsert(
+	preparer database.Preparer,
+	tableName string,
+	insertedValues database.InsertedValuesFn,
+	conflictColumns []string,
+	updateColumns []string,
+) (bool, error) {
+	upserter, ok := a.repo.(Upserter)
+	if !ok {
+		return false, fmt.Errorf(
+			"MutatorRepoAdapter: repository %T does not support upsert",
+			a.repo,
+		)
+	}
+	return upserter.Upsert(
+		preparer, tableName, insertedValues, conflictColumns, updateColumns,
+	)
+}
+
+// Count returns the count of the rows that match the selectors. The adapted
+// repository must implement Counter.
+func (a *MutatorRepoAdapter[Entity]) Count(
+	preparer database.Preparer,
+	tableName string,
+	selectors database.Selectors,
+) (int, error) {
+	counter, ok := a.repo.(Counter)
+	if !ok {
+		return 0, fmt.Errorf(
+			"MutatorRepoAdapter: repository %T does not support count",
+			a.repo,
+		)
+	}
+	return counter.Count(preparer, tableName, selectors)
+}
+
+// entity asserts that the mutator is of the concrete entity type.
+func (a *MutatorRepoAdapter[Entity]) entity(
+	mutator database.Mutator,
+) (Entity, error) {
+	entity, ok := mutator.(Entity)
+	if !ok {
+		var zero Entity
+		return zero, fmt.Errorf(
+			"MutatorRepoAdapter: unexpected mutator type %T", mutator,
+		)
+	}
+	return entity, nil
+}
+
 // DefaultRawQueryer is a concrete implementation of the RawQueryer interface.
 type DefaultRawQueryer struct{}
 
@@ -362,21 +1228,62 @@ func (rq *DefaultRawQueryer) QueryRaw(
 	return rows, nil
 }
 
-// DefaultTxManager is the default transaction manager.
-type DefaultTxManager[Entity any] struct{}
+// DefaultTxManager is the default transaction manager. The transactions that
+// fail with retryable errors are run again as defined by its RetryPolicy.
+// The errors of beginning and committing the transactions are checked by its
+// ErrorChecker, if set, so that e.g. a deadlock on commit is retried.
+type DefaultTxManager[Entity any] struct {
+	RetryPolicy  RetryPolicy
+	ErrorChecker database.ErrorChecker
+}
 
 // DefaultTxManager implements the TxManager interface.
 var _ TxManager[any] = (*DefaultTxManager[any])(nil)
 
-// NewDefaultTxManager returns a new DefaultTxManager.
+// NewDefaultTxManager returns a new DefaultTxManager with the default retry
+// policy.
 //
 // Returns:
 //   - *DefaultTxManager[Entity]: The new DefaultTxManager.
 func NewDefaultTxManager[Entity any]() *DefaultTxManager[Entity] {
-	return &DefaultTxManager[Entity]{}
+	return &DefaultTxManager[Entity]{
+		RetryPolicy: DefaultRetryPolicy(),
+	}
+}
+
+// WithRetryPolicy sets the retry policy of the transaction manager.
+//
+// Parameters:
+//   - policy: The retry policy.
+//
+// Returns:
+//   - *DefaultTxManager[Entity]: The transaction manager.
+func (t *DefaultTxManager[Entity]) WithRetryPolicy(
+	policy RetryPolicy,
+) *DefaultTxManager[Entity] {
+	t.RetryPolicy = policy
+	return t
 }
 
-// WithTransaction wraps a function call in a DB transaction.
+// WithErrorChecker sets the error checker of the errors of beginning and
+// committing the transactions.
+//
+// Parameters:
+//   - errorChecker: The error checker.
+//
+// Returns:
+//   - *DefaultTxManager[Entity]: The transaction manager.
+func (t *DefaultTxManager[Entity]) WithErrorChecker(
+	errorChecker database.ErrorChecker,
+) *DefaultTxManager[Entity] {
+	t.ErrorChecker = errorChecker
+	return t
+}
+
+// WithTransaction wraps a function call in a DB transaction. If the
+// transaction fails with a retryable error, it is rolled back and the whole
+// transaction is run again after a backoff delay. The number of attempts is
+// recorded in the context if it was created by WithTxAttempts.
 //
 // Parameters:
 //   - ctx: The context for the transaction.
@@ -385,7 +1292,8 @@ func NewDefaultTxManager[Entity any]() *DefaultTxManager[Entity] {
 //
 // Returns:
 //   - Entity: The result of the function call.
-//   - error: An error if the transaction fails.
+//   - error: An error if the transaction fails, or the error of the context
+//     if it is done before a retry.
 func (t *DefaultTxManager[Entity]) WithTransaction(
 	ctx context.Context,
 	connFn ConnFn,
@@ -396,10 +1304,111 @@ func (t *DefaultTxManager[Entity]) WithTransaction(
 		var zero Entity
 		return zero, err
 	}
+	for attempt := 1; ; attempt++ {
+		recordTxAttempts(ctx, attempt)
+		entity, err := t.transaction(ctx, conn, callback)
+		if err == nil || !t.RetryPolicy.isRetryable(attempt, err) {
+			return entity, err
+		}
+		if err := t.RetryPolicy.wait(ctx, attempt); err != nil {
+			var zero Entity
+			return zero, err
+		}
+	}
+}
+
+// transaction runs a function call in a DB transaction. The errors of the
+// callback are returned as is, as the repositories have already checked them.
+func (t *DefaultTxManager[Entity]) transaction(
+	ctx context.Context,
+	conn database.DB,
+	callback func(ctx context.Context, tx database.Tx) (Entity, error),
+) (Entity, error) {
 	tx, err := conn.BeginTx(ctx, nil)
 	if err != nil {
 		var zero Entity
+		return zero, t.checkError(err)
+	}
+	var callbackFailed bool
+	entity, err := database.Transaction(
+		ctx,
+		tx,
+		func(ctx context.Context, tx database.Tx) (Entity, error) {
+			entity, err := callback(ctx, tx)
+			callbackFailed = err != nil
+			return entity, err
+		},
+	)
+	if err != nil && !callbackFailed {
+		return entity, t.checkError(err)
+	}
+	return entity, err
+}
+
+// checkError checks an error of the transaction with the error checker, if
+// set.
+func (t *DefaultTxManager[Entity]) checkError(err error) error {
+	if t.ErrorChecker == nil {
+		return err
+	}
+	return t.ErrorChecker.Check(err)
+}
+
+// TxManagerAdapter adapts a TxManager of one result type to a TxManager of
+// another result type, so a single configured transaction manager can run the
+// transactions of all endpoints.
+type TxManagerAdapter[From any, To any] struct {
+	txManager TxManager[From]
+}
+
+// TxManagerAdapter implements the TxManager interface.
+var _ TxManager[any] = (*TxManagerAdapter[int, any])(nil)
+
+// NewTxManagerAdapter returns a new TxManagerAdapter.
+//
+// Parameters:
+//   - txManager: The adapted transaction manager.
+//
+// Returns:
+//   - *TxManagerAdapter[From, To]: The new TxManagerAdapter.
+func NewTxManagerAdapter[From any, To any](
+	txManager TxManager[From],
+) *TxManagerAdapter[From, To] {
+	return &TxManagerAdapter[From, To]{
+		txManager: txManager,
+	}
+}
+
+// WithTransaction wraps a function call in a DB transaction of the adapted
+// transaction manager.
+//
+// Parameters:
+//   - ctx: The context for the transaction.
+//   - connFn: A function that returns a DB connection.
+//   - callback: The function to execute within the transaction.
+//
+// Returns:
+//   - To: The result of the function call.
+//   - error: An error if the transaction fails.
+func (a *TxManagerAdapter[From, To]) WithTransaction(
+	ctx context.Context,
+	connFn ConnFn,
+	callback func(ctx context.Context, tx database.Tx) (To, error),
+) (To, error) {
+	var result To
+	_, err := a.txManager.WithTransaction(
+		ctx,
+		connFn,
+		func(ctx context.Context, tx database.Tx) (From, error) {
+			var zero From
+			var err error
+			result, err = callback(ctx, tx)
+			return zero, err
+		},
+	)
+	if err != nil {
+		var zero To
 		return zero, err
 	}
-	return database.Transaction(ctx, tx, callback)
+	return result, nil
 }
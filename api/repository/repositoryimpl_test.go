@@ -0,0 +1,465 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"reflect"
+	"testing"
+
+	extendeddatabase "github.com/pakkasys/fluidapi-extended/database"
+	"github.com/pakkasys/fluidapi-extended/sqlite"
+	"github.com/pakkasys/fluidapi/database"
+)
+
+// fakePreparer records the prepared queries. Its queries return count as the
+// single row of a single column, or no rows if noRows is set, and its
+// executions report the last insert IDs and the affected rows in order. An
+// execution affects 1 row if no affected rows are left.
+type fakePreparer struct {
+	count         int
+	noRows        bool
+	lastInsertIDs []int64
+	rowsAffected  []int64
+	queries       []string
+}
+
+func (p *fakePreparer) Prepare(query string) (database.Stmt, error) {
+	p.queries = append(p.queries, query)
+	return &fakeStmt{preparer: p}, nil
+}
+
+type fakeStmt struct {
+	preparer *fakePreparer
+}
+
+func (s *fakeStmt) Exec(args ...any) (database.Result, error) {
+	result := fakeResult{rowsAffected: 1}
+	if len(s.preparer.lastInsertIDs) != 0 {
+		result.lastInsertID = s.preparer.lastInsertIDs[0]
+		s.preparer.lastInsertIDs = s.preparer.lastInsertIDs[1:]
+	}
+	if len(s.preparer.rowsAffected) != 0 {
+		result.rowsAffected = s.preparer.rowsAffected[0]
+		s.preparer.rowsAffected = s.preparer.rowsAffected[1:]
+	}
+	return result, nil
+}
+
+func (s *fakeStmt) Query(args ...any) (database.Rows, error) {
+	if s.preparer.noRows {
+		return &fakeRows{}, nil
+	}
+	return &fakeRows{values: []int{s.preparer.count}}, nil
+}
+
+func (s *fakeStmt) QueryRow(args ...any) database.Row {
+	return nil
+}
+
+func (s *fakeStmt) Close() error {
+	return nil
+}
+
+type fakeResult struct {
+	lastInsertID int64
+	rowsAffected int64
+}
+
+func (r fakeResult) LastInsertId() (int64, error) { return r.lastInsertID, nil }
+func (r fakeResult) RowsAffected() (int64, error) { return r.rowsAffected, nil }
+
+type fakeRows struct {
+	values []int
+	next   int
+}
+
+func (r *fakeRows) Next() bool {
+	r.next++
+	return r.next <= len(r.values)
+}
+
+func (r *fakeRows) Scan(dest ...any) error {
+	*dest[0].(*int) = r.values[r.next-1]
+	return nil
+}
+
+func (r *fakeRows) Err() error                 { return nil }
+func (r *fakeRows) Close() error               { return nil }
+func (r *fakeRows) Columns() ([]string, error) { return []string{"count"}, nil }
+
+type passErrorChecker struct{}
+
+func (passErrorChecker) Check(err error) error { return err }
+
+// item is an entity with a generated ID unless it has its own ID.
+type item struct {
+	id   int64
+	name string
+}
+
+func (i *item) TableName() string { return "items" }
+
+func (i *item) InsertedValues() ([]string, []any) {
+	if i.id != 0 {
+		return []string{"id", "name"}, []any{i.id, i.name}
+	}
+	return []string{"name"}, []any{i.name}
+}
+
+func (i *item) HasInsertID() bool    { return i.id != 0 }
+func (i *item) SetInsertID(id int64) { i.id = id }
+
+func (i *item) ScanRow(row database.Row) error {
+	return row.Scan(&i.id, &i.name)
+}
+
+func TestDefaultMutatorRepoInsertMany(t *testing.T) {
+	repo := NewDefaultMutatorRepo[*item](&sqlite.Query{}, passErrorChecker{})
+	repo.MaxPlaceholders = 2
+	items := []*item{{name: "a"}, {name: "b"}, {name: "c"}}
+	// SQLite reports the rowid of the last row of each insert.
+	preparer := &fakePreparer{lastInsertIDs: []int64{11, 12}}
+
+	inserted, err := repo.InsertMany(preparer, items)
+	if err != nil {
+		t.Fatalf("InsertMany: %v", err)
+	}
+	expectedQueries := []string{
+		`INSERT INTO "items" ("name") VALUES (?), (?)`,
+		`INSERT INTO "items" ("name") VALUES (?)`,
+	}
+	if !reflect.DeepEqual(preparer.queries, expectedQueries) {
+		t.Errorf("expected queries %q, got %q", expectedQueries, preparer.queries)
+	}
+	for i, expected := range []int64{10, 11, 12} {
+		if inserted[i].id != expected {
+			t.Errorf("expected item %d to have ID %d, got %d",
+				i, expected, inserted[i].id)
+		}
+	}
+}
+
+func TestDefaultMutatorRepoInsertManyOwnIDs(t *testing.T) {
+	repo := NewDefaultMutatorRepo[*item](&sqlite.Query{}, passErrorChecker{})
+	items := []*item{{name: "a"}, {id: 5, name: "b"}, {name: "c"}}
+	preparer := &fakePreparer{lastInsertIDs: []int64{5, 7}}
+
+	inserted, err := repo.InsertMany(preparer, items)
+	if err != nil {
+		t.Fatalf("InsertMany: %v", err)
+	}
+	expectedQueries := []string{
+		`INSERT INTO "items" ("id", "name") VALUES (?, ?)`,
+		`INSERT INTO "items" ("name") VALUES (?), (?)`,
+	}
+	if !reflect.DeepEqual(preparer.queries, expectedQueries) {
+		t.Errorf("expected queries %q, got %q", expectedQueries, preparer.queries)
+	}
+	for i, expected := range []int64{6, 5, 7} {
+		if inserted[i].id != expected {
+			t.Errorf("expected item %d to have ID %d, got %d",
+				i, expected, inserted[i].id)
+		}
+	}
+}
+
+func TestDefaultReaderRepoLoadRelation(t *testing.T) {
+	repo := NewDefaultReaderRepo[database.Getter](
+		&sqlite.Query{}, passErrorChecker{},
+	)
+	repo.MaxPlaceholders = 2
+	relation := extendeddatabase.Relation{
+		Name:          "posts",
+		Kind:          extendeddatabase.RelationHasMany,
+		Table:         "posts",
+		Columns:       []string{"title"},
+		ForeignColumn: "user_id",
+		EntityFn:      func() any { return &struct{}{} },
+	}
+	preparer := &fakePreparer{noRows: true}
+
+	related, err := repo.LoadRelation(preparer, relation, []any{1, 2, 3})
+	if err != nil {
+		t.Fatalf("LoadRelation: %v", err)
+	}
+	if len(related) != 0 {
+		t.Errorf("expected no related rows, got %v", related)
+	}
+	expectedQueries := []string{
+		`SELECT "posts"."title","posts"."user_id" FROM "posts"` +
+			` WHERE "posts"."user_id" IN (?,?)`,
+		`SELECT "posts"."title","posts"."user_id" FROM "posts"` +
+			` WHERE "posts"."user_id" IN (?)`,
+	}
+	if !reflect.DeepEqual(preparer.queries, expectedQueries) {
+		t.Errorf("expected queries %q, got %q", expectedQueries, preparer.queries)
+	}
+
+	preparer = &fakePreparer{noRows: true}
+	if _, err := repo.LoadRelation(preparer, relation, nil); err != nil ||
+		len(preparer.queries) != 0 {
+		t.Errorf("expected no queries without keys, got %q %v",
+			preparer.queries, err)
+	}
+}
+
+func TestDefaultReaderRepoInvalidName(t *testing.T) {
+	repo := NewDefaultReaderRepo[*item](&sqlite.Query{}, passErrorChecker{})
+	entityFactoryFn := func() *item { return &item{} }
+	preparer := &fakePreparer{}
+
+	_, err := repo.GetManyKeyset(
+		preparer,
+		entityFactoryFn,
+		&database.GetOptions{
+			Orders: []database.Order{{Field: "name\x00", Direction: "ASC"}},
+		},
+		nil,
+	)
+	if err == nil {
+		t.Errorf("expected an error for an invalid order field")
+	}
+	_, err = repo.CountFiltered(
+		preparer,
+		database.Selectors{{Column: "", Predicate: "=", Value: 1}},
+		nil,
+		nil,
+		entityFactoryFn,
+	)
+	if err == nil {
+		t.Errorf("expected an error for an invalid selector column")
+	}
+	if len(preparer.queries) != 0 {
+		t.Errorf("expected no queries, got %q", preparer.queries)
+	}
+}
+
+func TestDefaultMutatorRepoUpsert(t *testing.T) {
+	repo := NewDefaultMutatorRepo[database.Mutator](
+		&sqlite.Query{}, passErrorChecker{},
+	)
+	insertedValues := func() ([]string, []any) {
+		return []string{"code", "name"}, []any{"a", "name a"}
+	}
+	const (
+		insert = `INSERT INTO "items" ("code", "name") VALUES (?, ?)` +
+			` ON CONFLICT("code") DO NOTHING`
+		update = `UPDATE "items" SET "name" = ? WHERE "items"."code" = ?`
+		count  = `SELECT COUNT(*) FROM "items" WHERE "items"."code" = ?`
+	)
+
+	for _, tt := range []struct {
+		name         string
+		rowsAffected []int64
+		count        int
+		inserted     bool
+		queries      []string
+	}{
+		{"Inserted", []int64{1}, 0, true, []string{insert}},
+		{"Updated", []int64{0, 1}, 0, false, []string{insert, update}},
+		// MySQL reports the rows whose values did not change as unaffected.
+		{"Unchanged", []int64{0, 0}, 1, false, []string{insert, update, count}},
+		{
+			"DeletedBeforeUpdate", []int64{0, 0, 1}, 0, true,
+			[]string{insert, update, count, insert},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			preparer := &fakePreparer{
+				count: tt.count, rowsAffected: tt.rowsAffected,
+			}
+			inserted, err := repo.Upsert(
+				preparer,
+				"items",
+				insertedValues,
+				[]string{"code"},
+				[]string{"name"},
+			)
+			if err != nil {
+				t.Fatalf("Upsert: %v", err)
+			}
+			if inserted != tt.inserted {
+				t.Errorf("expected inserted %v, got %v", tt.inserted, inserted)
+			}
+			if !reflect.DeepEqual(preparer.queries, tt.queries) {
+				t.Errorf("expected queries %q, got %q", tt.queries, preparer.queries)
+			}
+		})
+	}
+
+	// MySQL ignores the conflicts on any unique key, so the row is not found
+	// by the conflict columns if it conflicted on another key.
+	t.Run("OtherKey", func(t *testing.T) {
+		_, err := repo.Upsert(
+			&fakePreparer{rowsAffected: []int64{0, 0, 0, 0}},
+			"items",
+			insertedValues,
+			[]string{"code"},
+			[]string{"name"},
+		)
+		if !errors.Is(err, extendeddatabase.DuplicateEntryError) {
+			t.Errorf("expected a duplicate entry error, got %v", err)
+		}
+	})
+
+	t.Run("MissingColumn", func(t *testing.T) {
+		for _, columns := range [][]string{{"id"}, nil} {
+			_, err := repo.Upsert(
+				&fakePreparer{},
+				"items",
+				insertedValues,
+				append([]string{"code"}, columns...),
+				[]string{"id"},
+			)
+			if err == nil {
+				t.Errorf("expected an error for columns %v", columns)
+			}
+		}
+	})
+}
+
+func TestMutatorRepoAdapterUpsert(t *testing.T) {
+	adapter := NewMutatorRepoAdapter[database.Mutator](
+		NewDefaultMutatorRepo[database.Mutator](
+			&sqlite.Query{}, passErrorChecker{},
+		),
+	)
+	inserted, err := adapter.Upsert(
+		&fakePreparer{},
+		"items",
+		func() ([]string, []any) { return []string{"code"}, []any{"a"} },
+		[]string{"code"},
+		nil,
+	)
+	if err != nil || !inserted {
+		t.Errorf("expected an insert, got %v %v", inserted, err)
+	}
+
+	adapter = NewMutatorRepoAdapter[database.Mutator](mutatorRepo{})
+	if _, err := adapter.Upsert(&fakePreparer{}, "items", nil, nil, nil); err == nil {
+		t.Errorf("expected an error without an Upserter")
+	}
+}
+
+// callbackTxManager runs the callback without a transaction.
+type callbackTxManager struct{}
+
+func (callbackTxManager) WithTransaction(
+	ctx context.Context,
+	connFn ConnFn,
+	callback func(ctx context.Context, tx database.Tx) (int, error),
+) (int, error) {
+	return callback(ctx, nil)
+}
+
+func TestTxManagerAdapter(t *testing.T) {
+	adapter := NewTxManagerAdapter[int, string](callbackTxManager{})
+	result, err := adapter.WithTransaction(
+		context.Background(),
+		nil,
+		func(ctx context.Context, tx database.Tx) (string, error) {
+			return "result", nil
+		},
+	)
+	if result != "result" || err != nil {
+		t.Errorf("expected the result, got %q %v", result, err)
+	}
+
+	callbackErr := errors.New("callback")
+	result, err = adapter.WithTransaction(
+		context.Background(),
+		nil,
+		func(ctx context.Context, tx database.Tx) (string, error) {
+			return "partial", callbackErr
+		},
+	)
+	if result != "" || err != callbackErr {
+		t.Errorf("expected the callback error, got %q %v", result, err)
+	}
+}
+
+// txDB begins transactions whose commits fail with the errors of commitErrs
+// in order.
+type txDB struct {
+	database.DB
+	commitErrs []error
+	commits    int
+}
+
+func (db *txDB) BeginTx(
+	ctx context.Context, opts *sql.TxOptions,
+) (database.Tx, error) {
+	return &commitTx{db: db}, nil
+}
+
+type commitTx struct {
+	database.Tx
+	db *txDB
+}
+
+func (tx *commitTx) Commit() error {
+	tx.db.commits++
+	if len(tx.db.commitErrs) == 0 {
+		return nil
+	}
+	err := tx.db.commitErrs[0]
+	tx.db.commitErrs = tx.db.commitErrs[1:]
+	return err
+}
+
+func (tx *commitTx) Rollback() error { return nil }
+
+// deadlockErrorChecker checks deadlockErr as a DeadlockError.
+type deadlockErrorChecker struct{}
+
+var deadlockErr = errors.New("deadlock found when trying to get lock")
+
+func (deadlockErrorChecker) Check(err error) error {
+	if err == deadlockErr {
+		return extendeddatabase.DeadlockError.WithData(err)
+	}
+	return err
+}
+
+func TestDefaultTxManagerCommitError(t *testing.T) {
+	txManager := NewDefaultTxManager[int]().
+		WithRetryPolicy(RetryPolicy{MaxAttempts: 2}).
+		WithErrorChecker(deadlockErrorChecker{})
+	db := &txDB{commitErrs: []error{deadlockErr}}
+	ctx := WithTxAttempts(context.Background())
+
+	result, err := txManager.WithTransaction(
+		ctx,
+		func() (database.DB, error) { return db, nil },
+		func(ctx context.Context, tx database.Tx) (int, error) {
+			return 1, nil
+		},
+	)
+	if result != 1 || err != nil {
+		t.Fatalf("expected the retried transaction to succeed, got %d %v",
+			result, err)
+	}
+	if db.commits != 2 || TxAttempts(ctx) != 2 {
+		t.Errorf("expected 2 commits in 2 attempts, got %d in %d",
+			db.commits, TxAttempts(ctx))
+	}
+
+	// The callback errors are returned as is.
+	db = &txDB{}
+	_, err = txManager.WithTransaction(
+		ctx,
+		func() (database.DB, error) { return db, nil },
+		func(ctx context.Context, tx database.Tx) (int, error) {
+			return 0, deadlockErr
+		},
+	)
+	if err != deadlockErr || db.commits != 0 {
+		t.Errorf("expected the callback error, got %v", err)
+	}
+}
+
+// mutatorRepo is a MutatorRepo that does not implement Upserter.
+type mutatorRepo struct {
+	MutatorRepo[database.Mutator]
+}
@@ -0,0 +1,154 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"time"
+
+	extendeddatabase "github.com/pakkasys/fluidapi-extended/database"
+	"github.com/pakkasys/fluidapi/core"
+)
+
+// Defaults of the retry policy.
+const (
+	DefaultMaxAttempts = 3
+	DefaultBaseDelay   = 10 * time.Millisecond
+	DefaultMaxDelay    = 500 * time.Millisecond
+)
+
+// RetryPolicy defines how a transaction is retried. The whole transaction is
+// run again, so the retried callback must only change the database.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of attempts. The transaction is run
+	// once if it is less than 2.
+	MaxAttempts int
+	// BaseDelay is the delay before the first retry. The delay is doubled
+	// for each retry and a random part of up to half of it is subtracted.
+	BaseDelay time.Duration
+	// MaxDelay is the maximum delay before a retry. If it is zero, the delay
+	// is not limited.
+	MaxDelay time.Duration
+	// IsRetryable tells whether a failed transaction is retried. If it is nil,
+	// IsTransientError is used.
+	IsRetryable func(err error) bool
+}
+
+// DefaultRetryPolicy returns the default retry policy. The transactions that
+// fail with transient errors are attempted up to three times.
+//
+// Returns:
+//   - RetryPolicy: The default retry policy.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts: DefaultMaxAttempts,
+		BaseDelay:   DefaultBaseDelay,
+		MaxDelay:    DefaultMaxDelay,
+		IsRetryable: IsTransientError,
+	}
+}
+
+// IsTransientError tells whether an error is a deadlock, a lock timeout or a
+// busy database, e.g. a MySQL error 1213 or 1205 or SQLITE_BUSY, as returned
+// by the error checkers. Other errors, such as the database errors that are
+// not checked, are not transient.
+//
+// Parameters:
+//   - err: The error.
+//
+// Returns:
+//   - bool: Whether the error is transient.
+func IsTransientError(err error) bool {
+	var apiErr *core.APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	switch apiErr.ID {
+	case extendeddatabase.DeadlockError.ID,
+		extendeddatabase.LockTimeoutError.ID,
+		extendeddatabase.DatabaseBusyError.ID:
+		return true
+	default:
+		return false
+	}
+}
+
+// isRetryable tells whether a failed attempt is retried.
+func (p RetryPolicy) isRetryable(attempt int, err error) bool {
+	if attempt >= p.MaxAttempts {
+		return false
+	}
+	if p.IsRetryable == nil {
+		return IsTransientError(err)
+	}
+	return p.IsRetryable(err)
+}
+
+// delay returns the jittered delay before the retry that follows the given
+// attempt.
+func (p RetryPolicy) delay(attempt int) time.Duration {
+	delay := p.BaseDelay
+	for i := 1; i < attempt && (p.MaxDelay == 0 || delay < p.MaxDelay); i++ {
+		delay *= 2
+	}
+	if p.MaxDelay > 0 && delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	if delay <= 0 {
+		return 0
+	}
+	return delay - rand.N(delay/2+1)
+}
+
+// wait waits for the delay before the retry that follows the given attempt.
+// It returns the error of the context if the context is done first.
+func (p RetryPolicy) wait(ctx context.Context, attempt int) error {
+	timer := time.NewTimer(p.delay(attempt))
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// txAttemptsKey is the context key of the recorded transaction attempts.
+type txAttemptsKey struct{}
+
+// WithTxAttempts returns a context that records the number of attempts of the
+// transactions that are run with it. The attempts are read with TxAttempts,
+// e.g. to log them.
+//
+// Parameters:
+//   - ctx: The parent context.
+//
+// Returns:
+//   - context.Context: The context that records the attempts.
+func WithTxAttempts(ctx context.Context) context.Context {
+	return context.WithValue(ctx, txAttemptsKey{}, new(int))
+}
+
+// TxAttempts returns the number of attempts of the last transaction that was
+// run with the context.
+//
+// Parameters:
+//   - ctx: The context created by WithTxAttempts.
+//
+// Returns:
+//   - int: The number of attempts, or zero if none were recorded.
+func TxAttempts(ctx context.Context) int {
+	attempts, ok := ctx.Value(txAttemptsKey{}).(*int)
+	if !ok {
+		return 0
+	}
+	return *attempts
+}
+
+// recordTxAttempts records the number of attempts of a transaction in the
+// context if it was created by WithTxAttempts.
+func recordTxAttempts(ctx context.Context, count int) {
+	if attempts, ok := ctx.Value(txAttemptsKey{}).(*int); ok {
+		*attempts = count
+	}
+}
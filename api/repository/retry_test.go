@@ -0,0 +1,96 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	extendeddatabase "github.com/pakkasys/fluidapi-extended/database"
+)
+
+func TestIsTransientError(t *testing.T) {
+	for _, err := range []error{
+		extendeddatabase.DeadlockError,
+		extendeddatabase.LockTimeoutError.WithOrigin("db"),
+		fmt.Errorf("insert: %w", extendeddatabase.DatabaseBusyError),
+	} {
+		if !IsTransientError(err) {
+			t.Errorf("expected %v to be transient", err)
+		}
+	}
+	for _, err := range []error{
+		extendeddatabase.DuplicateEntryError,
+		extendeddatabase.ReadOnlyDatabaseError,
+		errors.New("other"),
+	} {
+		if IsTransientError(err) {
+			t.Errorf("expected %v not to be transient", err)
+		}
+	}
+}
+
+func TestRetryPolicyIsRetryable(t *testing.T) {
+	policy := DefaultRetryPolicy()
+	if !policy.isRetryable(1, extendeddatabase.DeadlockError) {
+		t.Errorf("expected the first attempt to be retried")
+	}
+	if policy.isRetryable(policy.MaxAttempts, extendeddatabase.DeadlockError) {
+		t.Errorf("expected the last attempt not to be retried")
+	}
+	if (RetryPolicy{}).isRetryable(1, extendeddatabase.DeadlockError) {
+		t.Errorf("expected no retries without attempts")
+	}
+	policy.IsRetryable = func(err error) bool { return false }
+	if policy.isRetryable(1, extendeddatabase.DeadlockError) {
+		t.Errorf("expected the classifier to be used")
+	}
+}
+
+func TestRetryPolicyDelay(t *testing.T) {
+	policy := RetryPolicy{BaseDelay: 10 * time.Millisecond, MaxDelay: 50 * time.Millisecond}
+	for _, tt := range []struct {
+		attempt int
+		max     time.Duration
+	}{
+		{1, 10 * time.Millisecond},
+		{2, 20 * time.Millisecond},
+		{3, 40 * time.Millisecond},
+		{4, 50 * time.Millisecond},
+		{100, 50 * time.Millisecond},
+	} {
+		for range 100 {
+			delay := policy.delay(tt.attempt)
+			if delay < tt.max/2 || delay > tt.max {
+				t.Fatalf("attempt %d: expected delay in [%v, %v], got %v",
+					tt.attempt, tt.max/2, tt.max, delay)
+			}
+		}
+	}
+	if delay := (RetryPolicy{}).delay(1); delay != 0 {
+		t.Errorf("expected no delay, got %v", delay)
+	}
+}
+
+func TestRetryPolicyWait(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	policy := RetryPolicy{BaseDelay: time.Hour}
+	if err := policy.wait(ctx, 1); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected the context error, got %v", err)
+	}
+}
+
+func TestTxAttempts(t *testing.T) {
+	ctx := context.Background()
+	recordTxAttempts(ctx, 2)
+	if attempts := TxAttempts(ctx); attempts != 0 {
+		t.Errorf("expected no attempts, got %d", attempts)
+	}
+	ctx = WithTxAttempts(ctx)
+	recordTxAttempts(ctx, 2)
+	if attempts := TxAttempts(ctx); attempts != 2 {
+		t.Errorf("expected 2 attempts, got %d", attempts)
+	}
+}
@@ -0,0 +1,389 @@
+package types
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// JSONSchemaDialect is the JSON Schema dialect of the exported schemas.
+const JSONSchemaDialect = "https://json-schema.org/draft/2020-12/schema"
+
+// JSONSchemaDBColumnKeyword is the schema keyword that sets the DB column of a
+// field when APIFields are built from a JSON Schema.
+const JSONSchemaDBColumnKeyword = "x-db-column"
+
+// unsupportedKeywords are the constraint keywords that have no matching
+// validation rule.
+var unsupportedKeywords = []string{
+	"$ref", "$dynamicRef", "allOf", "anyOf", "oneOf", "not", "if", "const",
+	"pattern", "multipleOf", "exclusiveMinimum", "exclusiveMaximum",
+	"uniqueItems", "contains", "prefixItems", "minProperties",
+	"maxProperties", "patternProperties", "dependentRequired",
+}
+
+// JSONSchema returns the JSON Schema document of an object with the fields as
+// properties.
+//
+// Returns:
+//   - map[string]any: The JSON Schema document.
+func (a APIFields) JSONSchema() map[string]any {
+	schema := objectSchema(a)
+	schema["$schema"] = JSONSchemaDialect
+	return schema
+}
+
+// JSONSchema returns the JSON Schema of the field. Nested fields are objects,
+// or lists of objects if the type of the field is "slice". The validation
+// rules of the field are translated to schema keywords and custom rules are
+// ignored.
+//
+// Returns:
+//   - map[string]any: The JSON Schema of the field.
+func (field APIField) JSONSchema() map[string]any {
+	var schema map[string]any
+	if len(field.Nested) != 0 {
+		schema = objectSchema(field.Nested)
+		if field.schemaType() == "slice" {
+			schema = map[string]any{"type": "array", "items": schema}
+		}
+	} else {
+		schema = typeSchema(field.schemaType())
+	}
+	addRuleKeywords(schema, field.Validate)
+	if field.Default != nil {
+		schema["default"] = field.Default
+	}
+	return schema
+}
+
+// APIFieldsFromJSONSchema builds APIFields from the JSON Schema document of an
+// object. The properties are sorted by name and their DB columns are set from
+// the "x-db-column" keyword, or from the property name if it is not set.
+//
+// Parameters:
+//   - document: The JSON Schema document.
+//
+// Returns:
+//   - APIFields: The APIFields of the properties.
+//   - error: An error if the schema can not be expressed as APIFields.
+func APIFieldsFromJSONSchema(document []byte) (APIFields, error) {
+	var schema map[string]any
+	if err := json.Unmarshal(document, &schema); err != nil {
+		return nil, fmt.Errorf("APIFieldsFromJSONSchema: %w", err)
+	}
+	fields, err := objectFields("", schema)
+	if err != nil {
+		return nil, fmt.Errorf("APIFieldsFromJSONSchema: %w", err)
+	}
+	return fields, nil
+}
+
+// schemaType returns the type of the field, or the type of its validation
+// rules if the type is not set.
+func (field APIField) schemaType() string {
+	if field.Type != "" {
+		return field.Type
+	}
+	if len(field.Validate) != 0 {
+		return strings.ToLower(field.Validate[0])
+	}
+	return ""
+}
+
+// objectSchema creates the schema of an object with the fields as properties.
+func objectSchema(fields APIFields) map[string]any {
+	properties := map[string]any{}
+	var required []any
+	for _, field := range fields {
+		properties[field.APIName] = field.JSONSchema()
+		if field.Required {
+			required = append(required, field.APIName)
+		}
+	}
+	schema := map[string]any{
+		"type":       "object",
+		"properties": properties,
+	}
+	if len(required) != 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+// typeSchema creates the schema of a field type.
+func typeSchema(fieldType string) map[string]any {
+	switch fieldType {
+	case "string":
+		return map[string]any{"type": "string"}
+	case "int":
+		return map[string]any{"type": "integer", "format": "int32"}
+	case "int64":
+		return map[string]any{"type": "integer", "format": "int64"}
+	case "float64":
+		return map[string]any{"type": "number", "format": "double"}
+	case "bool":
+		return map[string]any{"type": "boolean"}
+	case "slice":
+		return map[string]any{"type": "array"}
+	default:
+		return map[string]any{}
+	}
+}
+
+// addRuleKeywords adds the schema keywords of validation rules to a schema.
+// Unknown rules are ignored.
+func addRuleKeywords(schema map[string]any, rules []string) {
+	if len(rules) == 0 {
+		return
+	}
+	ruleType := strings.ToLower(rules[0])
+	for _, rule := range rules[1:] {
+		key, param, _ := strings.Cut(rule, "=")
+		key = strings.ToLower(key)
+		if ruleType == "string" && key == "oneof" {
+			var enum []any
+			for _, value := range strings.Fields(param) {
+				enum = append(enum, value)
+			}
+			schema["enum"] = enum
+			continue
+		}
+		if ruleType == "string" && key == "email" {
+			schema["format"] = "email"
+			continue
+		}
+		n, err := strconv.ParseInt(param, 10, 64)
+		if err != nil {
+			continue
+		}
+		var keywords []string
+		switch ruleType {
+		case "string":
+			keywords = rangeKeywords(key, "minLength", "maxLength")
+		case "slice":
+			keywords = rangeKeywords(key, "minItems", "maxItems")
+		case "int", "int64":
+			if key != "len" {
+				keywords = rangeKeywords(key, "minimum", "maximum")
+			}
+		}
+		for _, keyword := range keywords {
+			schema[keyword] = n
+		}
+	}
+}
+
+// rangeKeywords returns the schema keywords of a len, min or max rule.
+func rangeKeywords(key string, minKeyword string, maxKeyword string) []string {
+	switch key {
+	case "len":
+		return []string{minKeyword, maxKeyword}
+	case "min":
+		return []string{minKeyword}
+	case "max":
+		return []string{maxKeyword}
+	default:
+		return nil
+	}
+}
+
+// objectFields builds the APIFields of the properties of an object schema.
+func objectFields(path string, schema map[string]any) (APIFields, error) {
+	if schemaType, _ := schema["type"].(string); schemaType != "object" {
+		return nil, fmt.Errorf("%s: expected an object schema", schemaPath(path))
+	}
+	properties, _ := schema["properties"].(map[string]any)
+	if len(properties) == 0 {
+		return nil, fmt.Errorf("%s: object has no properties", schemaPath(path))
+	}
+	required := map[string]bool{}
+	requiredList, _ := schema["required"].([]any)
+	for _, name := range requiredList {
+		if name, ok := name.(string); ok {
+			required[name] = true
+		}
+	}
+	names := make([]string, 0, len(properties))
+	for name := range properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	fields := make(APIFields, 0, len(names))
+	for _, name := range names {
+		property, ok := properties[name].(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf(
+				"%s: property is not a schema",
+				schemaPath(path+"/properties/"+name),
+			)
+		}
+		field, err := propertyField(
+			path+"/properties/"+name, name, property,
+		)
+		if err != nil {
+			return nil, err
+		}
+		field.Required = required[name]
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
+
+// propertyField builds the APIField of an object property.
+func propertyField(
+	path string, name string, schema map[string]any,
+) (APIField, error) {
+	for _, keyword := range unsupportedKeywords {
+		if _, ok := schema[keyword]; ok {
+			return APIField{}, fmt.Errorf(
+				"%s: unsupported keyword %q", schemaPath(path), keyword,
+			)
+		}
+	}
+	field := APIField{
+		APIName:  name,
+		DBColumn: name,
+		Default:  schema["default"],
+	}
+	if column, ok := schema[JSONSchemaDBColumnKeyword].(string); ok {
+		field.DBColumn = column
+	}
+	var err error
+	schemaType, _ := schema["type"].(string)
+	switch schemaType {
+	case "string":
+		field.Type = "string"
+		field.Validate, err = stringRules(schema)
+	case "integer":
+		field.Type = "int64"
+		if schema["format"] == "int32" {
+			field.Type = "int"
+		}
+		field.Validate, err = rangeRules(
+			field.Type, schema, "minimum", "maximum",
+		)
+	case "number":
+		field.Type = "float64"
+		err = noKeywords(schema, "minimum", "maximum")
+	case "boolean":
+		field.Type = "bool"
+		field.Validate = []string{"bool"}
+	case "array":
+		field.Type = "slice"
+		field.Validate, err = rangeRules(
+			"slice", schema, "minItems", "maxItems",
+		)
+	case "object":
+	default:
+		return APIField{}, fmt.Errorf(
+			"%s: unsupported type %v", schemaPath(path), schema["type"],
+		)
+	}
+	if err != nil {
+		return APIField{}, fmt.Errorf("%s: %w", schemaPath(path), err)
+	}
+	if number, ok := field.Default.(float64); ok {
+		switch field.Type {
+		case "int":
+			field.Default = int(number)
+		case "int64":
+			field.Default = int64(number)
+		}
+	}
+	switch items, _ := schema["items"].(map[string]any); {
+	case schemaType == "object":
+		field.Nested, err = objectFields(path, schema)
+	case schemaType == "array" && items["type"] == "object":
+		field.Nested, err = objectFields(path+"/items", items)
+	}
+	if err != nil {
+		return APIField{}, err
+	}
+	return field, nil
+}
+
+// stringRules builds the validation rules of a string schema.
+func stringRules(schema map[string]any) ([]string, error) {
+	rules, err := rangeRules("string", schema, "minLength", "maxLength")
+	if err != nil {
+		return nil, err
+	}
+	if schema["format"] == "email" {
+		rules = append(rules, "email")
+	}
+	if enum, ok := schema["enum"].([]any); ok {
+		values := make([]string, len(enum))
+		for i, value := range enum {
+			value, ok := value.(string)
+			if !ok || value == "" || strings.ContainsAny(value, " \t\n") {
+				return nil, fmt.Errorf(
+					"enum value %v can not be validated", enum[i],
+				)
+			}
+			values[i] = value
+		}
+		rules = append(rules, "oneof="+strings.Join(values, " "))
+	}
+	return rules, nil
+}
+
+// rangeRules builds the validation rules of a schema with minimum and maximum
+// keywords. Equal string and array bounds become a len rule.
+func rangeRules(
+	ruleType string, schema map[string]any, minKeyword string, maxKeyword string,
+) ([]string, error) {
+	rules := []string{ruleType}
+	minimum, hasMin, err := integerKeyword(schema, minKeyword)
+	if err != nil {
+		return nil, err
+	}
+	maximum, hasMax, err := integerKeyword(schema, maxKeyword)
+	if err != nil {
+		return nil, err
+	}
+	if hasMin && hasMax && minimum == maximum &&
+		(ruleType == "string" || ruleType == "slice") {
+		return append(rules, fmt.Sprintf("len=%d", minimum)), nil
+	}
+	if hasMin {
+		rules = append(rules, fmt.Sprintf("min=%d", minimum))
+	}
+	if hasMax {
+		rules = append(rules, fmt.Sprintf("max=%d", maximum))
+	}
+	return rules, nil
+}
+
+// integerKeyword returns the integer value of a schema keyword.
+func integerKeyword(
+	schema map[string]any, keyword string,
+) (int64, bool, error) {
+	value, ok := schema[keyword]
+	if !ok {
+		return 0, false, nil
+	}
+	number, ok := value.(float64)
+	if !ok || number != math.Trunc(number) {
+		return 0, false, fmt.Errorf("%s must be an integer", keyword)
+	}
+	return int64(number), true, nil
+}
+
+// noKeywords returns an error if the schema has any of the keywords.
+func noKeywords(schema map[string]any, keywords ...string) error {
+	for _, keyword := range keywords {
+		if _, ok := schema[keyword]; ok {
+			return fmt.Errorf("unsupported keyword %q", keyword)
+		}
+	}
+	return nil
+}
+
+// schemaPath returns the JSON pointer of a schema location.
+func schemaPath(path string) string {
+	return "#" + path
+}
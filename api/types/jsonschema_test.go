@@ -0,0 +1,202 @@
+package types
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestAPIFieldsJSONSchema(t *testing.T) {
+	fields := APIFields{
+		{
+			APIName:  "name",
+			Type:     "string",
+			Validate: []string{"string", "min=2", "max=50"},
+			Required: true,
+		},
+		{
+			APIName:  "code",
+			Type:     "string",
+			Validate: []string{"string", "len=3"},
+		},
+		{
+			APIName:  "role",
+			Type:     "string",
+			Validate: []string{"string", "oneof=admin user"},
+			Default:  "user",
+		},
+		{APIName: "email", Type: "string", Validate: []string{"string", "email"}},
+		{APIName: "age", Type: "int64", Validate: []string{"int64", "min=0", "max=150"}},
+		{APIName: "tags", Type: "slice", Validate: []string{"slice", "max=5"}},
+		{APIName: "custom", Validate: []string{"my_rule"}},
+		{
+			APIName: "address",
+			Nested: APIFields{
+				{APIName: "city", Type: "string", Required: true},
+			},
+		},
+		{
+			APIName: "items",
+			Type:    "slice",
+			Nested: APIFields{
+				{APIName: "id", Type: "int"},
+			},
+		},
+	}
+
+	expected := map[string]any{
+		"$schema": JSONSchemaDialect,
+		"type":    "object",
+		"properties": map[string]any{
+			"name": map[string]any{
+				"type": "string", "minLength": int64(2), "maxLength": int64(50),
+			},
+			"code": map[string]any{
+				"type": "string", "minLength": int64(3), "maxLength": int64(3),
+			},
+			"role": map[string]any{
+				"type": "string", "enum": []any{"admin", "user"}, "default": "user",
+			},
+			"email": map[string]any{"type": "string", "format": "email"},
+			"age": map[string]any{
+				"type":    "integer",
+				"format":  "int64",
+				"minimum": int64(0),
+				"maximum": int64(150),
+			},
+			"tags":   map[string]any{"type": "array", "maxItems": int64(5)},
+			"custom": map[string]any{},
+			"address": map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"city": map[string]any{"type": "string"},
+				},
+				"required": []any{"city"},
+			},
+			"items": map[string]any{
+				"type": "array",
+				"items": map[string]any{
+					"type": "object",
+					"properties": map[string]any{
+						"id": map[string]any{"type": "integer", "format": "int32"},
+					},
+				},
+			},
+		},
+		"required": []any{"name"},
+	}
+
+	schema := fields.JSONSchema()
+	if !reflect.DeepEqual(schema, expected) {
+		t.Errorf("expected %v, got %v", expected, schema)
+	}
+}
+
+func TestAPIFieldsFromJSONSchema(t *testing.T) {
+	t.Run("RoundTrip", func(t *testing.T) {
+		fields := APIFields{
+			{
+				APIName:  "address",
+				DBColumn: "address",
+				Nested: APIFields{
+					{
+						APIName:  "city",
+						DBColumn: "city",
+						Type:     "string",
+						Validate: []string{"string", "len=3"},
+						Required: true,
+					},
+				},
+			},
+			{
+				APIName:  "age",
+				DBColumn: "age",
+				Type:     "int",
+				Validate: []string{"int", "min=0"},
+				Default:  18,
+			},
+			{
+				APIName:  "role",
+				DBColumn: "role",
+				Type:     "string",
+				Validate: []string{"string", "email", "oneof=a b"},
+			},
+			{
+				APIName:  "tags",
+				DBColumn: "tags",
+				Type:     "slice",
+				Validate: []string{"slice", "min=1", "max=5"},
+				Required: true,
+			},
+		}
+		document, err := json.Marshal(fields.JSONSchema())
+		if err != nil {
+			t.Fatalf("marshal: %v", err)
+		}
+		parsed, err := APIFieldsFromJSONSchema(document)
+		if err != nil {
+			t.Fatalf("APIFieldsFromJSONSchema: %v", err)
+		}
+		if !reflect.DeepEqual(parsed, fields) {
+			t.Errorf("expected %+v, got %+v", fields, parsed)
+		}
+	})
+
+	t.Run("DBColumn", func(t *testing.T) {
+		fields, err := APIFieldsFromJSONSchema([]byte(`{
+			"type": "object",
+			"properties": {
+				"active": {"type": "boolean", "x-db-column": "is_active"}
+			}
+		}`))
+		if err != nil {
+			t.Fatalf("APIFieldsFromJSONSchema: %v", err)
+		}
+		expected := APIFields{{
+			APIName:  "active",
+			DBColumn: "is_active",
+			Type:     "bool",
+			Validate: []string{"bool"},
+		}}
+		if !reflect.DeepEqual(fields, expected) {
+			t.Errorf("expected %+v, got %+v", expected, fields)
+		}
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		tests := []struct {
+			document string
+			message  string
+		}{
+			{`{"type": "array"}`, "#: expected an object schema"},
+			{`{"type": "object"}`, "#: object has no properties"},
+			{
+				`{"type": "object", "properties": {"a": {"type": "null"}}}`,
+				"#/properties/a: unsupported type",
+			},
+			{
+				`{"type": "object", "properties": {"a": {"type": "string", "pattern": "x"}}}`,
+				`#/properties/a: unsupported keyword "pattern"`,
+			},
+			{
+				`{"type": "object", "properties": {"a": {"type": "string", "enum": ["a b"]}}}`,
+				"#/properties/a: enum value a b can not be validated",
+			},
+			{
+				`{"type": "object", "properties": {"a": {"type": "integer", "minimum": 1.5}}}`,
+				"#/properties/a: minimum must be an integer",
+			},
+			{
+				`{"type": "object", "properties": {"a": {"type": "array", "items": {"type": "object"}}}}`,
+				"#/properties/a/items: object has no properties",
+			},
+		}
+		for _, tt := range tests {
+			_, err := APIFieldsFromJSONSchema([]byte(tt.document))
+			if err == nil || !strings.Contains(err.Error(), tt.message) {
+				t.Errorf("expected error %q, got %v", tt.message, err)
+			}
+		}
+	})
+}
@@ -12,6 +12,7 @@ const (
 	jsonTag   = "json"
 
 	tagURL     = "url"
+	tagPath    = "path"
 	tagBody    = "body"
 	tagHeader  = "header"
 	tagHeaders = "headers"
@@ -20,8 +21,10 @@ const (
 )
 
 // ParseInput parses the input struct and returns the parsed data. It will
-// populate the URL parameters, headers, cookies, and body based on the
-// struct tags. E.g. the struct field `source: url` will be placed in the URL.
+// populate the URL parameters, path parameters, headers, cookies, and body
+// based on the struct tags. E.g. the struct field `source: url` will be placed
+// in the URL and `source: path` will replace the matching "{field}" segment of
+// the URL path.
 //
 // Example:
 //
@@ -70,19 +73,21 @@ func ParseInput(method string, input any) (*RequestData, error) {
 
 // RequestData represents request data.
 type RequestData struct {
-	URLParameters map[string]any
-	Headers       map[string]string
-	Cookies       []http.Cookie
-	Body          map[string]any
+	URLParameters  map[string]any
+	PathParameters map[string]any
+	Headers        map[string]string
+	Cookies        []http.Cookie
+	Body           map[string]any
 }
 
 // NewRequestData returns a new instance of RequestData.
 func NewRequestData() *RequestData {
 	return &RequestData{
-		URLParameters: make(map[string]any),
-		Headers:       make(map[string]string),
-		Cookies:       make([]http.Cookie, 0),
-		Body:          make(map[string]any),
+		URLParameters:  make(map[string]any),
+		PathParameters: make(map[string]any),
+		Headers:        make(map[string]string),
+		Cookies:        make([]http.Cookie, 0),
+		Body:           make(map[string]any),
 	}
 }
 
@@ -164,6 +169,8 @@ func (d *RequestData) placeFieldValue(
 	switch placement {
 	case tagURL:
 		d.URLParameters[field] = value
+	case tagPath:
+		d.PathParameters[field] = value
 	case tagBody:
 		d.Body[field] = value
 	case tagHeader, tagHeaders:
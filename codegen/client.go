@@ -0,0 +1,450 @@
+package codegen
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/pakkasys/fluidapi-extended/api/openapi"
+	"github.com/pakkasys/fluidapi-extended/api/types"
+)
+
+// fieldSelectors is the input field of the selectors of an operation.
+const fieldSelectors = "selectors"
+
+// Input sources of the APIFields that are set as struct tags.
+var tagSources = []string{"path", "header", "headers", "cookie", "cookies"}
+
+// initialisms are the words that are written in upper case in Go names.
+var initialisms = []string{
+	"api", "id", "ip", "json", "sql", "url", "uri", "uuid", "http", "html",
+}
+
+// predicateMethods maps the predicates of selectors to the suffixes of the
+// selector builder methods.
+var predicateMethods = map[string]string{
+	"=":        "Eq",
+	"eq":       "Eq",
+	"!=":       "NotEq",
+	"ne":       "NotEq",
+	">":        "Gt",
+	"gt":       "Gt",
+	">=":       "Gte",
+	"ge":       "Gte",
+	"gte":      "Gte",
+	"<":        "Lt",
+	"lt":       "Lt",
+	"<=":       "Lte",
+	"le":       "Lte",
+	"lte":      "Lte",
+	"in":       "In",
+	"not_in":   "NotIn",
+	"not in":   "NotIn",
+	"like":     "Like",
+	"not_like": "NotLike",
+	"not like": "NotLike",
+}
+
+// listPredicates are the predicates whose values are lists.
+var listPredicates = []string{"In", "NotIn"}
+
+// clientMethod is a method of a generated client.
+type clientMethod struct {
+	name      string
+	summary   string
+	operation *openapi.Operation
+}
+
+// clientGenerator generates the client of a resource.
+type clientGenerator struct {
+	resource   Resource
+	imports    map[string]string
+	typeBodies map[string]string
+	typeDecls  []string
+	selectors  string
+	body       bytes.Buffer
+}
+
+// GenerateClient generates the Go source of a typed client of a resource. The
+// client has a method for each operation of the resource and the input and
+// output structs of the operations. The selectors of the operations are built
+// with a typed selector builder.
+//
+// Parameters:
+//   - packageName: The package of the generated source.
+//   - resource: The resource.
+//
+// Returns:
+//   - []byte: The formatted Go source.
+//   - error: An error if the source can not be generated.
+func GenerateClient(packageName string, resource Resource) ([]byte, error) {
+	if !isIdentifier(resource.Name) || !unicode.IsUpper([]rune(resource.Name)[0]) {
+		return nil, fmt.Errorf(
+			"GenerateClient: resource name %q is not an exported identifier",
+			resource.Name,
+		)
+	}
+	g := &clientGenerator{
+		resource:   resource,
+		imports:    map[string]string{},
+		typeBodies: map[string]string{},
+	}
+	methods := []clientMethod{
+		{"List", "lists the entities", resource.List},
+		{"Create", "creates an entity", resource.Create},
+		{"Update", "updates the selected entities", resource.Update},
+		{"Delete", "deletes the selected entities", resource.Delete},
+	}
+	g.writeClient()
+	for _, method := range methods {
+		if method.operation == nil {
+			continue
+		}
+		if err := g.writeMethod(method); err != nil {
+			return nil, fmt.Errorf("GenerateClient: %s: %w", method.name, err)
+		}
+	}
+
+	var body bytes.Buffer
+	body.Write(g.body.Bytes())
+	for _, decl := range g.typeDecls {
+		body.WriteString(decl)
+	}
+	source, err := formatSource(
+		"// Code generated by fluidgen. DO NOT EDIT.", packageName, g.imports,
+		body.Bytes(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("GenerateClient: %w", err)
+	}
+	return source, nil
+}
+
+// writeClient writes the client struct and its constructor.
+func (g *clientGenerator) writeClient() {
+	g.imports["time"] = ""
+	name := g.resource.Name + "Client"
+	fmt.Fprintf(&g.body, `// %s is the client of the %s resource.
+type %s struct {
+	baseURL string
+	timeout time.Duration
+}
+
+// New%s creates a new %s.
+// If the timeout is zero or negative, a default timeout is used.
+func New%s(baseURL string, timeout time.Duration) *%s {
+	return &%s{baseURL: baseURL, timeout: timeout}
+}
+
+`, name, g.resource.Name, name, name, name, name, name, name)
+}
+
+// writeMethod writes a client method and the input and output structs of its
+// operation.
+func (g *clientGenerator) writeMethod(method clientMethod) error {
+	operation := method.operation
+	clientName := g.resource.Name + "Client"
+	inputName, err := g.structType(
+		[]string{g.resource.Name + method.name + "Input"},
+		fmt.Sprintf("is the input of %s.%s.", clientName, method.name),
+		operation.Input,
+		method.name,
+		true,
+	)
+	if err != nil {
+		return err
+	}
+	outputName, err := g.structType(
+		[]string{g.resource.Name + method.name + "Output"},
+		fmt.Sprintf("is the output of %s.%s.", clientName, method.name),
+		operation.Output,
+		method.name,
+		false,
+	)
+	if err != nil {
+		return err
+	}
+	g.imports["context"] = ""
+	g.imports["github.com/pakkasys/fluidapi-extended/api"] = ""
+	g.imports["github.com/pakkasys/fluidapi-extended/api/endpoint"] = ""
+	g.imports["github.com/pakkasys/fluidapi-extended/client"] = ""
+	methodName := methodConstant(operation.Method)
+	if strings.HasPrefix(methodName, "http.") {
+		g.imports["net/http"] = ""
+	}
+	fmt.Fprintf(&g.body, `// %s %s.
+func (c *%sClient) %s(
+	ctx context.Context, input *%s,
+) (*%s, error) {
+	return endpoint.SendRequestPayload(
+		ctx,
+		client.NewJSONClient[api.APIOutput[%s]](c.baseURL, c.timeout),
+		%q,
+		%s,
+		input,
+	)
+}
+
+`,
+		method.name, method.summary, g.resource.Name, method.name, inputName,
+		outputName, outputName, operation.Path, methodName,
+	)
+	return nil
+}
+
+// structType declares a struct type for the fields and returns its name. The
+// type gets the first of the names that is not declared with other fields and
+// its doc comment is the name followed by the description. Required fields
+// are values and optional fields are pointers. The fields of request structs
+// get the source tags of their input sources.
+func (g *clientGenerator) structType(
+	names []string,
+	description string,
+	fields types.APIFields,
+	operation string,
+	request bool,
+) (string, error) {
+	var body strings.Builder
+	var used []string
+	for _, field := range fields {
+		fieldName := goName(field.APIName)
+		if fieldName == "" || slices.Contains(used, fieldName) {
+			return "", fmt.Errorf(
+				"field %q has no unique Go name", field.APIName,
+			)
+		}
+		used = append(used, fieldName)
+		source := ""
+		if request && slices.Contains(tagSources, field.Source) {
+			source = field.Source
+		}
+		fieldType, err := g.fieldType(
+			names[0], field, operation, request, source,
+		)
+		if err != nil {
+			return "", err
+		}
+		tag := fmt.Sprintf("json:%q", field.APIName)
+		if source != "" {
+			tag += fmt.Sprintf(" source:%q", source)
+		}
+		fmt.Fprintf(&body, "\t%s %s `%s`\n", fieldName, fieldType, tag)
+	}
+	for _, name := range names {
+		declared, ok := g.typeBodies[name]
+		if ok && declared != body.String() {
+			continue
+		}
+		if !ok {
+			g.typeBodies[name] = body.String()
+			g.typeDecls = append(g.typeDecls, fmt.Sprintf(
+				"// %s %s\ntype %s struct {\n%s}\n\n",
+				name, description, name, body.String(),
+			))
+		}
+		return name, nil
+	}
+	return "", fmt.Errorf("type %s is declared twice", names[0])
+}
+
+// fieldType returns the Go type of a field and declares the types of its
+// nested fields.
+func (g *clientGenerator) fieldType(
+	parent string,
+	field types.APIField,
+	operation string,
+	request bool,
+	source string,
+) (string, error) {
+	if request && field.APIName == fieldSelectors && len(field.Nested) != 0 {
+		return g.selectorsType(field.Nested)
+	}
+	fieldType := field.Type
+	if fieldType == "" && len(field.Validate) != 0 {
+		fieldType = strings.ToLower(field.Validate[0])
+	}
+	var goType string
+	if len(field.Nested) != 0 {
+		names, description := g.nestedType(parent, field, operation, request)
+		name, err := g.structType(
+			names, description, field.Nested, operation, request,
+		)
+		if err != nil {
+			return "", err
+		}
+		if fieldType == "slice" {
+			return "[]" + name, nil
+		}
+		goType = name
+	} else {
+		goType = scalarType(fieldType)
+	}
+	if source != "" || field.Required || strings.HasPrefix(goType, "[]") ||
+		goType == "any" {
+		return goType, nil
+	}
+	return "*" + goType, nil
+}
+
+// nestedType returns the names and description of the type of a nested
+// field. The entities of the resource are named after the resource if the
+// name is not taken by other fields.
+func (g *clientGenerator) nestedType(
+	parent string, field types.APIField, operation string, request bool,
+) ([]string, string) {
+	name := parent + goName(field.APIName)
+	if !slices.Contains(g.resource.EntityKeys, field.APIName) {
+		return []string{name}, fmt.Sprintf(
+			"is the %s field of %s.", field.APIName, parent,
+		)
+	}
+	if request {
+		return []string{g.resource.Name + operation, name}, fmt.Sprintf(
+			"is the %s input of a %s entity.", operation, g.resource.Name,
+		)
+	}
+	return []string{g.resource.Name, name}, fmt.Sprintf(
+		"is an entity of the %s resource.", g.resource.Name,
+	)
+}
+
+// selectorsType declares the selectors type of the resource and its builder
+// methods. Each field gets a method for each of its predicates.
+func (g *clientGenerator) selectorsType(fields types.APIFields) (string, error) {
+	name := g.resource.Name + "Selectors"
+	if g.selectors != "" {
+		return name, nil
+	}
+	selector := g.resource.Name + "Selector"
+	var methods strings.Builder
+	fmt.Fprintf(&methods, `// New%s creates new %s.
+func New%s() %s {
+	return %s{}
+}
+`, name, name, name, name, name)
+	for _, field := range fields {
+		value, _ := field.Nested.GetAPIField("value")
+		predicate, _ := field.Nested.GetAPIField("predicate")
+		valueType := scalarType(value.Type)
+		fieldName := goName(field.APIName)
+		for _, allowed := range oneOfValues(predicate.Validate) {
+			suffix, ok := predicateMethods[strings.ToLower(allowed)]
+			if !ok {
+				continue
+			}
+			parameter := "value " + valueType
+			if slices.Contains(listPredicates, suffix) {
+				parameter = "values ..." + valueType
+			}
+			argument := strings.Fields(parameter)[0]
+			fmt.Fprintf(&methods, `
+// %s%s selects the entities whose %s matches the %s predicate.
+func (s %s) %s%s(%s) %s {
+	s[%q] = %s{Value: %s, Predicate: %q}
+	return s
+}
+`,
+				fieldName, suffix, field.APIName, allowed,
+				name, fieldName, suffix, parameter, name,
+				field.APIName, selector, argument, allowed,
+			)
+		}
+	}
+	g.selectors = fmt.Sprintf(`// %s is a selector of the %s resource.
+type %s struct {
+	Value     any    `+"`json:\"value\"`"+`
+	Predicate string `+"`json:\"predicate\"`"+`
+}
+
+// %s selects the entities of the %s resource by field.
+type %s map[string]%s
+
+%s
+`,
+		selector, g.resource.Name, selector,
+		name, g.resource.Name, name, selector,
+		methods.String(),
+	)
+	g.body.WriteString(g.selectors)
+	return name, nil
+}
+
+// scalarType returns the Go type of a field type.
+func scalarType(fieldType string) string {
+	switch fieldType {
+	case "string", "int", "int64", "float64", "bool":
+		return fieldType
+	case "slice":
+		return "[]any"
+	default:
+		return "any"
+	}
+}
+
+// oneOfValues returns the values of the oneof rule of validation rules.
+func oneOfValues(rules []string) []string {
+	for _, rule := range rules {
+		key, param, _ := strings.Cut(rule, "=")
+		if strings.ToLower(key) == "oneof" {
+			return strings.Fields(param)
+		}
+	}
+	return nil
+}
+
+// methodConstant returns the net/http constant of an HTTP method.
+func methodConstant(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodDelete:
+		return "http.Method" + goName(strings.ToLower(method))
+	default:
+		return fmt.Sprintf("%q", method)
+	}
+}
+
+// goName converts an API name to an exported Go name. The words of the name
+// are separated by characters that are not letters or digits.
+//
+// Example:
+//
+//	goName("user_id")
+//
+// Output:
+//
+//	"UserID"
+func goName(apiName string) string {
+	words := strings.FieldsFunc(apiName, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	var builder strings.Builder
+	for _, word := range words {
+		if slices.Contains(initialisms, strings.ToLower(word)) {
+			builder.WriteString(strings.ToUpper(word))
+			continue
+		}
+		runes := []rune(word)
+		builder.WriteString(string(unicode.ToUpper(runes[0])) + string(runes[1:]))
+	}
+	name := builder.String()
+	if name != "" && unicode.IsDigit([]rune(name)[0]) {
+		name = "F" + name
+	}
+	return name
+}
+
+// isIdentifier returns whether a name is a Go identifier.
+func isIdentifier(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i, r := range name {
+		if !unicode.IsLetter(r) && r != '_' && (i == 0 || !unicode.IsDigit(r)) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,163 @@
+package codegen
+
+import (
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	gotypes "go/types"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/pakkasys/fluidapi-extended/api/openapi"
+	"github.com/pakkasys/fluidapi-extended/api/types"
+)
+
+// userResource returns a resource with create, list and delete operations.
+func userResource() Resource {
+	entity := types.APIFields{
+		{APIName: "id", Type: "int64", Required: true},
+		{APIName: "email", Type: "string"},
+	}
+	selectors := types.APIField{
+		APIName: "selectors",
+		Nested: types.APIFields{{
+			APIName: "email",
+			Nested: types.APIFields{
+				{APIName: "value", Type: "string", Required: true},
+				{
+					APIName:  "predicate",
+					Type:     "string",
+					Validate: []string{"string", "oneof== IN"},
+					Required: true,
+				},
+			},
+		}},
+	}
+	return Resource{
+		Name:       "User",
+		EntityKeys: []string{"user", "users"},
+		Create: &openapi.Operation{
+			Method: http.MethodPost,
+			Path:   "/users",
+			Input: types.APIFields{
+				{APIName: "user", Nested: entity[1:], Required: true},
+			},
+			Output: types.APIFields{{APIName: "user", Nested: entity}},
+		},
+		List: &openapi.Operation{
+			Method: http.MethodGet,
+			Path:   "/users",
+			Input:  types.APIFields{selectors},
+			Output: types.APIFields{
+				{APIName: "users", Type: "slice", Nested: entity},
+			},
+		},
+		Delete: &openapi.Operation{
+			Method: http.MethodDelete,
+			Path:   "/users",
+			Input: types.APIFields{
+				selectors,
+				{APIName: "If-Match", Source: "header", Type: "string"},
+			},
+			Output: types.APIFields{
+				{APIName: "count", Type: "int64", Required: true},
+			},
+		},
+	}
+}
+
+func TestGenerateClient(t *testing.T) {
+	source, err := GenerateClient("userclient", userResource())
+	if err != nil {
+		t.Fatalf("GenerateClient: %v", err)
+	}
+	for _, expected := range []string{
+		"package userclient",
+		"func NewUserClient(baseURL string, timeout time.Duration) *UserClient {",
+		"func (s UserSelectors) EmailEq(value string) UserSelectors {",
+		"func (s UserSelectors) EmailIn(values ...string) UserSelectors {",
+		"func (c *UserClient) List(",
+		"func (c *UserClient) Create(",
+		"func (c *UserClient) Delete(",
+		"http.MethodDelete,",
+		"type User struct {",
+		"Users []User `json:\"users\"`",
+		"User UserCreate `json:\"user\"`",
+		"Email *string `json:\"email\"`",
+		"IfMatch   string        `json:\"If-Match\" source:\"header\"`",
+	} {
+		if !strings.Contains(string(source), expected) {
+			t.Errorf("expected source to contain %q:\n%s", expected, source)
+		}
+	}
+	if strings.Contains(string(source), "func (c *UserClient) Update(") {
+		t.Errorf("expected no Update method")
+	}
+
+	_, err = GenerateClient("userclient", Resource{Name: "user"})
+	if err == nil {
+		t.Errorf("expected error for unexported resource name")
+	}
+}
+
+func TestGenerateClientTypeChecks(t *testing.T) {
+	source, err := GenerateClient("userclient", userResource())
+	if err != nil {
+		t.Fatalf("GenerateClient: %v", err)
+	}
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "user_client.go", source, 0)
+	if err != nil {
+		t.Fatalf("parse: %v\n%s", err, source)
+	}
+	config := gotypes.Config{Importer: importer.ForCompiler(fset, "source", nil)}
+	pkg, err := config.Check("userclient", fset, []*ast.File{file}, nil)
+	if err != nil {
+		t.Fatalf("type check: %v\n%s", err, source)
+	}
+
+	selectors := pkg.Scope().Lookup("UserSelectors")
+	if selectors == nil {
+		t.Fatalf("expected the UserSelectors type")
+	}
+	method, _, _ := gotypes.LookupFieldOrMethod(
+		selectors.Type(), false, pkg, "EmailIn",
+	)
+	if method == nil {
+		t.Fatalf("expected the EmailIn method")
+	}
+	signature := method.Type().(*gotypes.Signature)
+	if !signature.Variadic() ||
+		signature.Params().At(0).Type().String() != "[]string" {
+		t.Errorf("expected EmailIn to take ...string, got %s", signature)
+	}
+}
+
+func TestGoName(t *testing.T) {
+	tests := map[string]string{
+		"user_id":     "UserID",
+		"If-Match":    "IfMatch",
+		"next_cursor": "NextCursor",
+		"2fa":         "F2fa",
+	}
+	for apiName, expected := range tests {
+		if name := goName(apiName); name != expected {
+			t.Errorf("goName(%q): expected %q, got %q", apiName, expected, name)
+		}
+	}
+}
+
+func TestFileName(t *testing.T) {
+	tests := map[string]string{
+		"User":      "user",
+		"UserGroup": "user_group",
+		"APIKey":    "api_key",
+	}
+	for name, expected := range tests {
+		if file := fileName(name); file != expected {
+			t.Errorf("fileName(%q): expected %q, got %q", name, expected, file)
+		}
+	}
+}
@@ -0,0 +1,316 @@
+package codegen
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"go/parser"
+	"go/token"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+
+	"github.com/pakkasys/fluidapi-extended/api/crud"
+	"github.com/pakkasys/fluidapi-extended/api/openapi"
+	"github.com/pakkasys/fluidapi/database"
+)
+
+// Resource is a resource of the API that a client is generated for.
+type Resource struct {
+	// Name is the Go name of the resource, e.g. "User".
+	Name string
+	// EntityKeys are the input and output fields that hold the entities of
+	// the resource. Their types are named after the resource.
+	EntityKeys []string
+	Create     *openapi.Operation
+	List       *openapi.Operation
+	Update     *openapi.Operation
+	Delete     *openapi.Operation
+}
+
+// CRUDResource creates a resource from built CRUD endpoints.
+//
+// Parameters:
+//   - name: The Go name of the resource.
+//   - endpoints: The CRUD endpoints of the resource.
+//
+// Returns:
+//   - Resource: The new Resource.
+func CRUDResource[Entity database.CRUDEntity, CreateInput any, CreateOutput any, GetOutput any](
+	name string,
+	endpoints *crud.CRUDEndpoints[Entity, CreateInput, CreateOutput, GetOutput],
+) Resource {
+	resource := Resource{Name: name}
+	if endpoints.Create != nil {
+		operation := endpoints.Create.Operation()
+		resource.Create = &operation
+		resource.EntityKeys = append(
+			resource.EntityKeys,
+			endpoints.Create.InputKey,
+			endpoints.Create.OutputKey,
+		)
+	}
+	if endpoints.Get != nil {
+		operation := endpoints.Get.Operation()
+		resource.List = &operation
+		resource.EntityKeys = append(
+			resource.EntityKeys, endpoints.Get.OutputKey,
+		)
+	}
+	if endpoints.Update != nil {
+		operation := endpoints.Update.Operation()
+		resource.Update = &operation
+	}
+	if endpoints.Delete != nil {
+		operation := endpoints.Delete.Operation()
+		resource.Delete = &operation
+	}
+	return resource
+}
+
+// Registry holds the resources that clients are generated for.
+type Registry struct {
+	resources []Resource
+}
+
+// NewRegistry creates a new Registry.
+//
+// Returns:
+//   - *Registry: The new Registry.
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// Add adds resources to the registry.
+//
+// Parameters:
+//   - resources: The resources to add.
+//
+// Returns:
+//   - *Registry: The registry.
+func (r *Registry) Add(resources ...Resource) *Registry {
+	r.resources = append(r.resources, resources...)
+	return r
+}
+
+// Main runs the fluidgen command with the resources of the registry and exits
+// with a non-zero status on errors. Main is a library entry point, there is no
+// fluidgen binary: the clients are generated from the resources of a service,
+// so the service registers its resources in its own command, e.g.
+// cmd/fluidgen, and runs it with "go run ./cmd/fluidgen client". The command
+// must import the SQL driver of the dialect to scaffold from tables.
+//
+// Example:
+//
+//	import _ "github.com/mattn/go-sqlite3"
+//
+//	func main() {
+//	    codegen.Main(codegen.NewRegistry().Add(
+//	        codegen.CRUDResource("User", userBuilder.BuildCRUDEndpoints("")),
+//	    ))
+//	}
+//
+// Parameters:
+//   - registry: The registry of the resources.
+func Main(registry *Registry) {
+	if err := Run(registry, os.Args[1:], os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// Run runs a fluidgen command. The client command writes a client file for
+// each resource of the registry. The scaffold command writes the boilerplate
+// of a CRUD resource for an entity struct or an existing table. Scaffolding
+// from a table requires the command to import the SQL driver of the dialect.
+//
+// Usage:
+//
+//	client [-out dir] [-package name]
+//	scaffold -struct file -type name [-dialect d] [-out file] [-package name] [-force]
+//	scaffold -table name -dsn dsn [-dialect d] [-out file] [-package name] [-force]
+//
+// Parameters:
+//   - registry: The registry of the resources.
+//   - args: The command line arguments.
+//   - stdout: The writer of the written file names.
+//
+// Returns:
+//   - error: An error if the command fails.
+func Run(registry *Registry, args []string, stdout io.Writer) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: fluidgen client|scaffold [flags]")
+	}
+	switch args[0] {
+	case "client":
+		return runClient(registry, args[1:], stdout)
+	case "scaffold":
+		return runScaffold(args[1:], stdout)
+	default:
+		return fmt.Errorf("fluidgen: unknown command %q", args[0])
+	}
+}
+
+// runClient runs the client command.
+func runClient(registry *Registry, args []string, stdout io.Writer) error {
+	flags := flag.NewFlagSet("client", flag.ContinueOnError)
+	out := flags.String("out", "client", "output directory")
+	packageName := flags.String("package", "", "package name, default: base name of the output directory")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *packageName == "" {
+		*packageName = filepath.Base(*out)
+	}
+	if !isIdentifier(*packageName) {
+		return fmt.Errorf("fluidgen client: invalid package name %q", *packageName)
+	}
+	if len(registry.resources) == 0 {
+		return fmt.Errorf("fluidgen client: no resources registered")
+	}
+	if err := os.MkdirAll(*out, 0o755); err != nil {
+		return fmt.Errorf("fluidgen client: %w", err)
+	}
+	for _, resource := range registry.resources {
+		source, err := GenerateClient(*packageName, resource)
+		if err != nil {
+			return fmt.Errorf("fluidgen client: %s: %w", resource.Name, err)
+		}
+		path := filepath.Join(*out, fileName(resource.Name)+"_client.go")
+		if err := os.WriteFile(path, source, 0o644); err != nil {
+			return fmt.Errorf("fluidgen client: %w", err)
+		}
+		fmt.Fprintln(stdout, path)
+	}
+	return nil
+}
+
+// runScaffold runs the scaffold command. The package defaults to the package
+// of the struct file, or to the name of the output directory.
+func runScaffold(args []string, stdout io.Writer) error {
+	flags := flag.NewFlagSet("scaffold", flag.ContinueOnError)
+	structFile := flags.String("struct", "", "Go file of the entity struct")
+	typeName := flags.String("type", "", "name of the entity struct")
+	table := flags.String("table", "", "name of an existing table")
+	dsn := flags.String("dsn", "", "data source name of the database of the table")
+	dialect := flags.String("dialect", DialectMySQL, "SQL dialect: mysql or sqlite")
+	name := flags.String("name", "", "Go name of the entity, default: derived from the table name")
+	out := flags.String("out", "", "output file, default: <entity>_scaffold.go")
+	packageName := flags.String("package", "", "package name")
+	force := flags.Bool("force", false, "overwrite an existing output file")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	var entity *Entity
+	var err error
+	switch {
+	case *structFile != "" && *table == "":
+		if *typeName == "" {
+			return fmt.Errorf("fluidgen scaffold: -type is required with -struct")
+		}
+		entity, err = scaffoldStruct(*structFile, *typeName, *dialect, packageName)
+		if *out == "" {
+			*out = filepath.Join(filepath.Dir(*structFile), fileName(*typeName)+"_scaffold.go")
+		}
+	case *table != "" && *structFile == "":
+		entity, err = scaffoldTable(*table, *dsn, *dialect)
+	default:
+		return fmt.Errorf("fluidgen scaffold: either -struct or -table is required")
+	}
+	if err != nil {
+		return fmt.Errorf("fluidgen scaffold: %w", err)
+	}
+	if *name != "" {
+		entity.Name = *name
+	}
+	if *out == "" {
+		*out = fileName(entity.Name) + "_scaffold.go"
+	}
+	if *packageName == "" {
+		dir, err := filepath.Abs(filepath.Dir(*out))
+		if err != nil {
+			return fmt.Errorf("fluidgen scaffold: %w", err)
+		}
+		*packageName = filepath.Base(dir)
+	}
+	if !isIdentifier(*packageName) {
+		return fmt.Errorf("fluidgen scaffold: invalid package name %q", *packageName)
+	}
+	source, err := GenerateScaffold(*packageName, entity)
+	if err != nil {
+		return fmt.Errorf("fluidgen scaffold: %w", err)
+	}
+	if _, err := os.Stat(*out); err == nil && !*force {
+		return fmt.Errorf("fluidgen scaffold: %s exists, use -force to overwrite it", *out)
+	}
+	if err := os.WriteFile(*out, source, 0o644); err != nil {
+		return fmt.Errorf("fluidgen scaffold: %w", err)
+	}
+	fmt.Fprintln(stdout, *out)
+	return nil
+}
+
+// scaffoldStruct reads the entity of a struct file. The package name is set
+// to the package of the file if it is not set.
+func scaffoldStruct(
+	path string, typeName string, dialect string, packageName *string,
+) (*Entity, error) {
+	source, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if *packageName == "" {
+		file, err := parser.ParseFile(
+			token.NewFileSet(), path, source, parser.PackageClauseOnly,
+		)
+		if err != nil {
+			return nil, err
+		}
+		*packageName = file.Name.Name
+	}
+	return EntityFromSource(source, typeName, dialect)
+}
+
+// scaffoldTable reads the entity of an existing table.
+func scaffoldTable(table string, dsn string, dialect string) (*Entity, error) {
+	if dsn == "" {
+		return nil, fmt.Errorf("-dsn is required with -table")
+	}
+	driverName := dialect
+	if dialect == DialectSQLite {
+		driverName = "sqlite3"
+	}
+	db, err := sql.Open(driverName, dsn)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+	return EntityFromTable(context.Background(), db, dialect, table)
+}
+
+// fileName converts a Go name to a snake case file name.
+//
+// Example:
+//
+//	fileName("UserGroup")
+//
+// Output:
+//
+//	"user_group"
+func fileName(name string) string {
+	var builder strings.Builder
+	runes := []rune(name)
+	for i, r := range runes {
+		if unicode.IsUpper(r) && i > 0 &&
+			(unicode.IsLower(runes[i-1]) ||
+				i+1 < len(runes) && unicode.IsLower(runes[i+1])) {
+			builder.WriteRune('_')
+		}
+		builder.WriteRune(unicode.ToLower(r))
+	}
+	return builder.String()
+}
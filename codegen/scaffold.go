@@ -0,0 +1,898 @@
+package codegen
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/pakkasys/fluidapi-extended/api/types"
+	"github.com/pakkasys/fluidapi-extended/mysql"
+	"github.com/pakkasys/fluidapi-extended/sqlite"
+)
+
+// Dialects of the scaffolded tables.
+const (
+	DialectMySQL  = "mysql"
+	DialectSQLite = "sqlite"
+)
+
+// scaffoldedMethods are the methods of the entity that are scaffolded if the
+// entity does not declare them.
+var scaffoldedMethods = []string{"TableName", "ScanRow", "InsertedValues"}
+
+// lengthPattern matches the length of a character column type.
+var lengthPattern = regexp.MustCompile(`CHAR\((\d+)\)`)
+
+// Column is a column of a scaffolded entity.
+type Column struct {
+	// Name is the name of the DB column.
+	Name string
+	// APIName is the API name of the column. The column name is used if it
+	// is not set.
+	APIName string
+	// GoType is the Go type of the entity field. The fields of nullable
+	// columns are pointers.
+	GoType        string
+	SQLType       string
+	NotNull       bool
+	Default       *string
+	PrimaryKey    bool
+	AutoIncrement bool
+	Unique        bool
+}
+
+// Entity is an entity that the boilerplate of a resource is scaffolded for.
+type Entity struct {
+	// Name is the Go name of the entity, e.g. "User".
+	Name      string
+	TableName string
+	Dialect   string
+	Columns   []Column
+	// Declared are the names of the entity type, its methods and its
+	// constructor that are already declared and are not scaffolded.
+	Declared []string
+	// Imports map the package names of the column types to their import
+	// paths. The package name is used as the path if it is not mapped.
+	Imports map[string]string
+}
+
+// EntityFromSource reads an entity from the Go source of its struct. The
+// columns are the fields with `db` tags and their API names are taken from
+// the `json` tags. The table name is returned by the TableName method of the
+// entity, or derived from the name of the entity if it is not declared. The
+// "id" column is the primary key.
+//
+// Parameters:
+//   - source: The Go source that declares the entity struct.
+//   - typeName: The name of the entity struct.
+//   - dialect: The dialect of the table.
+//
+// Returns:
+//   - *Entity: The entity.
+//   - error: An error if the struct can not be read.
+func EntityFromSource(
+	source []byte, typeName string, dialect string,
+) (*Entity, error) {
+	if err := checkDialect(dialect); err != nil {
+		return nil, fmt.Errorf("EntityFromSource: %w", err)
+	}
+	file, err := parser.ParseFile(token.NewFileSet(), "", source, 0)
+	if err != nil {
+		return nil, fmt.Errorf("EntityFromSource: %w", err)
+	}
+	entity := &Entity{
+		Name:    typeName,
+		Dialect: dialect,
+		Imports: map[string]string{},
+	}
+	for _, spec := range file.Imports {
+		path, err := strconv.Unquote(spec.Path.Value)
+		if err != nil {
+			return nil, fmt.Errorf("EntityFromSource: %w", err)
+		}
+		name := path[strings.LastIndex(path, "/")+1:]
+		if spec.Name != nil {
+			name = spec.Name.Name
+		}
+		entity.Imports[name] = path
+	}
+	var structType *ast.StructType
+	for _, decl := range file.Decls {
+		switch decl := decl.(type) {
+		case *ast.GenDecl:
+			for _, spec := range decl.Specs {
+				spec, ok := spec.(*ast.TypeSpec)
+				if ok && spec.Name.Name == typeName {
+					structType, _ = spec.Type.(*ast.StructType)
+				}
+			}
+		case *ast.FuncDecl:
+			if decl.Recv == nil {
+				if decl.Name.Name == "New"+typeName {
+					entity.Declared = append(entity.Declared, decl.Name.Name)
+				}
+				continue
+			}
+			if receiverName(decl.Recv) != typeName {
+				continue
+			}
+			entity.Declared = append(entity.Declared, decl.Name.Name)
+			if decl.Name.Name == "TableName" {
+				entity.TableName = returnedString(decl.Body)
+			}
+		}
+	}
+	if structType == nil {
+		return nil, fmt.Errorf(
+			"EntityFromSource: struct type %q not found", typeName,
+		)
+	}
+	entity.Declared = append(entity.Declared, typeName)
+	for _, field := range structType.Fields.List {
+		if field.Tag == nil {
+			continue
+		}
+		tag, err := strconv.Unquote(field.Tag.Value)
+		if err != nil {
+			return nil, fmt.Errorf("EntityFromSource: %w", err)
+		}
+		name := reflect.StructTag(tag).Get("db")
+		if name == "" || name == "-" {
+			continue
+		}
+		column := Column{
+			Name:    name,
+			GoType:  exprString(field.Type),
+			NotNull: true,
+		}
+		if _, ok := field.Type.(*ast.StarExpr); ok {
+			column.NotNull = false
+		}
+		jsonName := strings.Split(reflect.StructTag(tag).Get("json"), ",")[0]
+		if jsonName != "" && jsonName != "-" {
+			column.APIName = jsonName
+		}
+		column.SQLType = sqlType(dialect, column.baseType())
+		if name == "id" {
+			column.PrimaryKey = true
+			column.AutoIncrement = isIntegerType(column.baseType())
+		}
+		entity.Columns = append(entity.Columns, column)
+	}
+	if len(entity.Columns) == 0 {
+		return nil, fmt.Errorf(
+			"EntityFromSource: struct type %q has no db tags", typeName,
+		)
+	}
+	if entity.TableName == "" {
+		entity.TableName = plural(fileName(typeName))
+	}
+	return entity, nil
+}
+
+// EntityFromTable reads an entity from an existing table. The columns are
+// read from information_schema with MySQL and with the table_info pragma with
+// SQLite. The name of the entity is derived from the table name.
+//
+// Parameters:
+//   - ctx: The context of the queries.
+//   - db: The database connection.
+//   - dialect: The dialect of the database.
+//   - tableName: The name of the table.
+//
+// Returns:
+//   - *Entity: The entity.
+//   - error: An error if the table can not be read.
+func EntityFromTable(
+	ctx context.Context, db *sql.DB, dialect string, tableName string,
+) (*Entity, error) {
+	if err := checkDialect(dialect); err != nil {
+		return nil, fmt.Errorf("EntityFromTable: %w", err)
+	}
+	var columns []Column
+	var err error
+	if dialect == DialectMySQL {
+		columns, err = mysqlTableColumns(ctx, db, tableName)
+	} else {
+		columns, err = sqliteTableColumns(ctx, db, tableName)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("EntityFromTable: %w", err)
+	}
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("EntityFromTable: table %q not found", tableName)
+	}
+	return &Entity{
+		Name:      goName(singular(tableName)),
+		TableName: tableName,
+		Dialect:   dialect,
+		Columns:   columns,
+	}, nil
+}
+
+// GenerateScaffold generates the Go source of the boilerplate of a CRUD
+// resource for an entity. The source has the APIFields master list, the
+// predicates and the orderable fields, the create and get input and output
+// structs, a CRUDConfig constructor, the column definitions and a query that
+// creates the table. The entity struct, its CRUDEntity methods and its
+// constructor are generated if they are not declared.
+//
+// Parameters:
+//   - packageName: The package of the generated source.
+//   - entity: The entity.
+//
+// Returns:
+//   - []byte: The formatted Go source.
+//   - error: An error if the source can not be generated.
+func GenerateScaffold(packageName string, entity *Entity) ([]byte, error) {
+	if !isIdentifier(entity.Name) || !unicode.IsUpper([]rune(entity.Name)[0]) {
+		return nil, fmt.Errorf(
+			"GenerateScaffold: entity name %q is not an exported identifier",
+			entity.Name,
+		)
+	}
+	if err := checkDialect(entity.Dialect); err != nil {
+		return nil, fmt.Errorf("GenerateScaffold: %w", err)
+	}
+	if len(entity.Columns) == 0 {
+		return nil, fmt.Errorf("GenerateScaffold: entity has no columns")
+	}
+	g := &scaffoldGenerator{entity: entity, imports: map[string]string{}}
+	g.writeEntity()
+	g.writeAPIFields()
+	g.writeStructs()
+	g.writeCRUDConfig()
+	g.writeMigration()
+	source, err := formatSource(
+		fmt.Sprintf(
+			"// Scaffolded by fluidgen from the %s table.", entity.TableName,
+		),
+		packageName,
+		g.imports,
+		g.body.Bytes(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("GenerateScaffold: %w", err)
+	}
+	return source, nil
+}
+
+// scaffoldGenerator generates the scaffold of an entity.
+type scaffoldGenerator struct {
+	entity  *Entity
+	imports map[string]string
+	body    bytes.Buffer
+}
+
+// writeEntity writes the entity struct, its methods and its constructor if
+// they are not declared.
+func (g *scaffoldGenerator) writeEntity() {
+	e := g.entity
+	fmt.Fprintf(&g.body, "// %sTableName is the table of the %s entity.\n", e.Name, e.Name)
+	fmt.Fprintf(&g.body, "const %sTableName = %q\n\n", e.Name, e.TableName)
+	if !g.declared(e.Name) {
+		fmt.Fprintf(&g.body, "// %s is an entity of the %s table.\n", e.Name, e.TableName)
+		fmt.Fprintf(&g.body, "type %s struct {\n", e.Name)
+		for _, column := range e.Columns {
+			g.importType(column.GoType)
+			fmt.Fprintf(&g.body, "\t%s %s `db:%q`\n", goName(column.Name), column.GoType, column.Name)
+		}
+		g.body.WriteString("}\n\n")
+	}
+	methods := map[string]string{
+		"TableName": `// TableName returns the table name of the entity.
+func (e *%[1]s) TableName() string {
+	return %[1]sTableName
+}
+
+`,
+		"ScanRow": `// ScanRow scans a row into the entity.
+func (e *%[1]s) ScanRow(row database.Row) error {
+	return extendeddatabase.ScanRow(e, row)
+}
+
+`,
+		"InsertedValues": `// InsertedValues returns the columns and values of the entity to insert.
+func (e *%[1]s) InsertedValues() ([]string, []any) {
+	return extendeddatabase.InsertedValues(e)
+}
+
+`,
+	}
+	for _, method := range scaffoldedMethods {
+		if g.declared(method) {
+			continue
+		}
+		if method != "TableName" {
+			g.imports["github.com/pakkasys/fluidapi/database"] = ""
+			g.imports["github.com/pakkasys/fluidapi-extended/database"] = "extendeddatabase"
+		}
+		fmt.Fprintf(&g.body, methods[method], e.Name)
+	}
+	if !g.declared("New" + e.Name) {
+		g.imports["github.com/pakkasys/fluidapi-extended/database"] = "extendeddatabase"
+		fmt.Fprintf(&g.body, `// New%[1]s creates a new %[1]s with the options applied.
+func New%[1]s(opts ...extendeddatabase.EntityOption[*%[1]s]) *%[1]s {
+	entity := &%[1]s{}
+	for _, opt := range opts {
+		opt(entity)
+	}
+	return entity
+}
+
+`, e.Name)
+	}
+}
+
+// writeAPIFields writes the APIFields master list, the predicates of the
+// fields and the orderable fields.
+func (g *scaffoldGenerator) writeAPIFields() {
+	e := g.entity
+	g.imports["github.com/pakkasys/fluidapi-extended/api/types"] = ""
+	g.imports["github.com/pakkasys/fluidapi/endpoint"] = ""
+	fmt.Fprintf(&g.body, "// %sAPIFields is the master list of the API fields of the %s entity.\n", e.Name, e.Name)
+	fmt.Fprintf(&g.body, "var %sAPIFields = types.APIFields{\n", e.Name)
+	for _, column := range e.Columns {
+		field := column.apiField()
+		fmt.Fprintf(&g.body, "\t{\n\t\tAPIName: %q,\n\t\tDBColumn: %q,\n", field.APIName, field.DBColumn)
+		if field.Type != "" {
+			fmt.Fprintf(&g.body, "\t\tType: %q,\n", field.Type)
+		}
+		if len(field.Validate) != 0 {
+			fmt.Fprintf(&g.body, "\t\tValidate: %s,\n", stringSlice(field.Validate))
+		}
+		g.body.WriteString("\t},\n")
+	}
+	g.body.WriteString("}\n\n")
+
+	fmt.Fprintf(&g.body, "// %sPredicates are the predicates of the selectable fields.\n", e.Name)
+	fmt.Fprintf(&g.body, "var %sPredicates = map[string]endpoint.Predicates{\n", e.Name)
+	for _, column := range e.Columns {
+		fmt.Fprintf(&g.body, "\t%q: {%s},\n", column.apiName(), quotedList(column.predicates()))
+	}
+	g.body.WriteString("}\n\n")
+
+	var orderable []string
+	for _, column := range e.Columns {
+		if column.baseType() != "[]byte" {
+			orderable = append(orderable, column.apiName())
+		}
+	}
+	fmt.Fprintf(&g.body, "// %sOrderable are the fields that the entities can be ordered by.\n", e.Name)
+	fmt.Fprintf(&g.body, "var %sOrderable = %s\n\n", e.Name, stringSlice(orderable))
+}
+
+// writeStructs writes the input and output structs of the create and get
+// endpoints. The required fields of the create input are the non-null
+// columns without defaults.
+func (g *scaffoldGenerator) writeStructs() {
+	e := g.entity
+	key := fileName(e.Name)
+	pluralKey := plural(key)
+
+	fmt.Fprintf(&g.body, "// %sOutput is the %s entity in the outputs.\n", e.Name, e.Name)
+	fmt.Fprintf(&g.body, "type %sOutput struct {\n", e.Name)
+	for _, column := range e.Columns {
+		g.importType(column.GoType)
+		fmt.Fprintf(&g.body, "\t%s %s `json:%q`\n", goName(column.apiName()), column.GoType, column.apiName())
+	}
+	g.body.WriteString("}\n\n")
+
+	fmt.Fprintf(&g.body, "// %sCreate is the %s entity in the create input.\n", e.Name, e.Name)
+	fmt.Fprintf(&g.body, "type %sCreate struct {\n", e.Name)
+	for _, column := range e.Columns {
+		if column.AutoIncrement {
+			continue
+		}
+		if column.NotNull && column.Default == nil {
+			fmt.Fprintf(&g.body, "\t%s %s `json:%q required:\"true\"`\n", goName(column.apiName()), column.GoType, column.apiName())
+			continue
+		}
+		goType := column.GoType
+		if column.NotNull && goType != "[]byte" {
+			goType = "*" + goType
+		}
+		fmt.Fprintf(&g.body, "\t%s %s `json:%q`\n", goName(column.apiName()), goType, column.apiName())
+	}
+	g.body.WriteString("}\n\n")
+
+	fmt.Fprintf(&g.body, `// %[1]sCreateInput is the input of the create endpoint.
+type %[1]sCreateInput struct {
+	%[1]s %[1]sCreate `+"`json:%[2]q`"+`
+}
+
+// %[1]sCreateOutput is the output of the create endpoint.
+type %[1]sCreateOutput struct {
+	%[1]s %[1]sOutput `+"`json:%[2]q`"+`
+}
+
+// %[1]sGetOutput is the output of the get endpoint.
+type %[1]sGetOutput struct {
+	%[3]s []%[1]sOutput `+"`json:%[4]q`"+`
+	Count int `+"`json:\"count\"`"+`
+}
+
+`, e.Name, key, goName(pluralKey), pluralKey)
+}
+
+// writeCRUDConfig writes the constructor of the CRUDConfig of the entity.
+// The key fields are the primary key columns and the updatable fields are
+// the other columns.
+func (g *scaffoldGenerator) writeCRUDConfig() {
+	e := g.entity
+	g.imports["github.com/pakkasys/fluidapi-extended/api/crud"] = ""
+	g.imports["github.com/pakkasys/fluidapi-extended/api/repository"] = ""
+	var keyFields, updatable []string
+	for _, column := range e.Columns {
+		if column.PrimaryKey {
+			keyFields = append(keyFields, column.apiName())
+		} else if !column.AutoIncrement {
+			updatable = append(updatable, column.apiName())
+		}
+	}
+	configType := fmt.Sprintf(
+		"crud.CRUDConfig[*%[1]s, %[1]sCreateInput, %[1]sCreateOutput, %[1]sGetOutput]",
+		e.Name,
+	)
+	fmt.Fprintf(&g.body, `// New%[1]sCRUDConfig returns the CRUD config of the %[1]s entity. The
+// repositories, callbacks and other options are set by the caller.
+func New%[1]sCRUDConfig(url string, connFn repository.ConnFn) %[2]s {
+	return %[2]s{
+		URL: url,
+		TableName: %[1]sTableName,
+		EntityFn: New%[1]s,
+		Predicates: %[1]sPredicates,
+		Orderable: %[1]sOrderable,
+`, e.Name, configType)
+	if len(keyFields) != 0 {
+		fmt.Fprintf(&g.body, "\t\tKeyFields: %s,\n", stringSlice(keyFields))
+	}
+	fmt.Fprintf(&g.body, "\t\tConnFn: connFn,\n\t\tAllAPIFields: %sAPIFields,\n", e.Name)
+	if len(updatable) != 0 {
+		fmt.Fprintf(&g.body, "\t\tUpdateAPIFields: %sAPIFields.MustGetAPIFields(%s),\n", e.Name, stringSlice(updatable))
+	}
+	fmt.Fprintf(&g.body, "\t\tEntityName: %q,\n\t\tEntityNamePlural: %q,\n\t}\n}\n\n", fileName(e.Name), plural(fileName(e.Name)))
+}
+
+// writeMigration writes the column definitions of the table and the query
+// that creates it. A composite primary key is added as a table constraint.
+func (g *scaffoldGenerator) writeMigration() {
+	e := g.entity
+	queryPackage := "github.com/pakkasys/fluidapi-extended/" + e.Dialect
+	g.imports[queryPackage] = ""
+	g.imports["github.com/pakkasys/fluidapi/database"] = ""
+	var primaryKey []string
+	for _, column := range e.Columns {
+		if column.PrimaryKey {
+			primaryKey = append(primaryKey, fmt.Sprintf("`%s`", column.Name))
+		}
+	}
+	compositeKey := len(primaryKey) > 1
+
+	fmt.Fprintf(&g.body, "// %sColumns are the column definitions of the %s table.\n", e.Name, e.TableName)
+	fmt.Fprintf(&g.body, "var %sColumns = []database.ColumnDefinition{\n", e.Name)
+	hasDefault := false
+	for _, column := range e.Columns {
+		fmt.Fprintf(&g.body, "\t{\n\t\tName: %q,\n\t\tType: %q,\n", column.Name, column.SQLType)
+		if column.NotNull {
+			g.body.WriteString("\t\tNotNull: true,\n")
+		}
+		if column.Default != nil {
+			hasDefault = true
+			fmt.Fprintf(&g.body, "\t\tDefault: %sDefault(%q),\n", lowerFirst(e.Name), *column.Default)
+		}
+		if column.AutoIncrement {
+			g.body.WriteString("\t\tAutoIncrement: true,\n")
+		}
+		if column.PrimaryKey && !compositeKey {
+			g.body.WriteString("\t\tPrimaryKey: true,\n")
+		}
+		if column.Unique {
+			g.body.WriteString("\t\tUnique: true,\n")
+		}
+		g.body.WriteString("\t},\n")
+	}
+	g.body.WriteString("}\n\n")
+
+	constraints := "nil"
+	if compositeKey {
+		constraints = stringSlice([]string{
+			"PRIMARY KEY (" + strings.Join(primaryKey, ", ") + ")",
+		})
+	}
+	fmt.Fprintf(&g.body, `// Create%[1]sTableQuery returns the query that creates the %[2]s table.
+func Create%[1]sTableQuery() (string, []any, error) {
+	query := &%[3]s.Query{}
+	return query.CreateTableQuery(
+		%[1]sTableName, true, %[1]sColumns, %[4]s, database.TableOptions{},
+	)
+}
+
+`, e.Name, e.TableName, e.Dialect, constraints)
+	if hasDefault {
+		fmt.Fprintf(&g.body, `// %[1]sDefault returns a pointer to a column default.
+func %[1]sDefault(value string) *string {
+	return &value
+}
+`, lowerFirst(e.Name))
+	}
+}
+
+// importType imports the package of a qualified type.
+func (g *scaffoldGenerator) importType(goType string) {
+	name, _, ok := strings.Cut(strings.TrimLeft(goType, "*[]"), ".")
+	if !ok {
+		return
+	}
+	path := name
+	if imported, ok := g.entity.Imports[name]; ok {
+		path = imported
+	}
+	if path[strings.LastIndex(path, "/")+1:] == name {
+		g.imports[path] = ""
+	} else {
+		g.imports[path] = name
+	}
+}
+
+// declared returns whether a name is declared by the entity source.
+func (g *scaffoldGenerator) declared(name string) bool {
+	return slices.Contains(g.entity.Declared, name)
+}
+
+// apiName returns the API name of the column.
+func (c Column) apiName() string {
+	if c.APIName != "" {
+		return c.APIName
+	}
+	return c.Name
+}
+
+// baseType returns the Go type of the column without the pointer.
+func (c Column) baseType() string {
+	return strings.TrimPrefix(c.GoType, "*")
+}
+
+// apiField returns the APIField of the column. The validation rules of
+// string columns limit the length to the length of the column type.
+func (c Column) apiField() types.APIField {
+	field := types.APIField{APIName: c.apiName(), DBColumn: c.Name}
+	switch baseType := c.baseType(); baseType {
+	case "string":
+		field.Type = baseType
+		field.Validate = []string{"string"}
+		match := lengthPattern.FindStringSubmatch(strings.ToUpper(c.SQLType))
+		if match != nil {
+			field.Validate = append(field.Validate, "max="+match[1])
+		}
+	case "int", "int64", "bool":
+		field.Type = baseType
+		field.Validate = []string{baseType}
+	case "float64":
+		field.Type = baseType
+	}
+	return field
+}
+
+// predicates returns the selector predicates of the column. Numbers and
+// times can be compared.
+func (c Column) predicates() []string {
+	switch baseType := c.baseType(); {
+	case baseType == "bool" || baseType == "[]byte":
+		return []string{"=", "!="}
+	case isIntegerType(baseType) || strings.HasPrefix(baseType, "float") ||
+		baseType == "time.Time":
+		return []string{"=", "!=", ">", ">=", "<", "<=", "in", "not_in"}
+	default:
+		return []string{"=", "!=", "in", "not_in"}
+	}
+}
+
+// mysqlTableColumns reads the columns of a MySQL table.
+func mysqlTableColumns(
+	ctx context.Context, db *sql.DB, tableName string,
+) ([]Column, error) {
+	rows, err := db.QueryContext(ctx, `SELECT COLUMN_NAME, COLUMN_TYPE,
+IS_NULLABLE, COLUMN_DEFAULT, COLUMN_KEY, EXTRA
+FROM information_schema.COLUMNS
+WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ?
+ORDER BY ORDINAL_POSITION`, tableName)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var columns []Column
+	for rows.Next() {
+		var name, columnType, nullable, key, extra string
+		var defaultValue sql.NullString
+		if err := rows.Scan(
+			&name, &columnType, &nullable, &defaultValue, &key, &extra,
+		); err != nil {
+			return nil, err
+		}
+		column := Column{
+			Name:          name,
+			SQLType:       strings.ToUpper(columnType),
+			NotNull:       nullable == "NO",
+			PrimaryKey:    key == "PRI",
+			AutoIncrement: strings.Contains(strings.ToLower(extra), "auto_increment"),
+			Unique:        key == "UNI",
+		}
+		if defaultValue.Valid {
+			column.Default = &defaultValue.String
+		}
+		column.GoType = goType(DialectMySQL, column.SQLType, column.NotNull)
+		columns = append(columns, column)
+	}
+	return columns, rows.Err()
+}
+
+// sqliteTableColumns reads the columns of a SQLite table. The primary key
+// auto-increments if the table is created with AUTOINCREMENT and the columns
+// are unique if they have a single column unique constraint.
+func sqliteTableColumns(
+	ctx context.Context, db *sql.DB, tableName string,
+) ([]Column, error) {
+	var tableSQL string
+	err := db.QueryRowContext(
+		ctx,
+		"SELECT sql FROM sqlite_master WHERE type = 'table' AND name = ?",
+		tableName,
+	).Scan(&tableSQL)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	autoIncrement := strings.Contains(strings.ToUpper(tableSQL), "AUTOINCREMENT")
+	unique, err := sqliteUniqueColumns(ctx, db, tableName)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := db.QueryContext(ctx, `SELECT name, type, "notnull",
+dflt_value, pk
+FROM pragma_table_info(?)
+ORDER BY cid`, tableName)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var columns []Column
+	for rows.Next() {
+		var name, columnType string
+		var notNull, primaryKey int
+		var defaultValue sql.NullString
+		if err := rows.Scan(
+			&name, &columnType, &notNull, &defaultValue, &primaryKey,
+		); err != nil {
+			return nil, err
+		}
+		column := Column{
+			Name:          name,
+			SQLType:       strings.ToUpper(columnType),
+			NotNull:       notNull == 1 || primaryKey > 0,
+			PrimaryKey:    primaryKey > 0,
+			AutoIncrement: primaryKey > 0 && autoIncrement,
+			Unique:        unique[name],
+		}
+		if defaultValue.Valid && strings.ToUpper(defaultValue.String) != "NULL" {
+			value := defaultValue.String
+			if len(value) >= 2 && value[0] == '\'' && value[len(value)-1] == '\'' {
+				value = strings.ReplaceAll(value[1:len(value)-1], "''", "'")
+			}
+			column.Default = &value
+		}
+		column.GoType = goType(DialectSQLite, column.SQLType, column.NotNull)
+		columns = append(columns, column)
+	}
+	return columns, rows.Err()
+}
+
+// sqliteUniqueColumns returns the columns of a SQLite table that have a
+// single column unique constraint.
+func sqliteUniqueColumns(
+	ctx context.Context, db *sql.DB, tableName string,
+) (map[string]bool, error) {
+	rows, err := db.QueryContext(ctx, `SELECT MIN(ii.name)
+FROM pragma_index_list(?) AS il, pragma_index_info(il.name) AS ii
+WHERE il."unique" = 1 AND il.origin = 'u'
+GROUP BY il.name
+HAVING COUNT(*) = 1`, tableName)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	unique := map[string]bool{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		unique[name] = true
+	}
+	return unique, rows.Err()
+}
+
+// goType returns the Go type of a column type. Nullable columns are
+// pointers, except for byte slices.
+func goType(dialect string, sqlType string, notNull bool) string {
+	var goType string
+	switch sqlType = strings.ToUpper(sqlType); {
+	case strings.HasPrefix(sqlType, "TINYINT(1)") ||
+		strings.Contains(sqlType, "BOOL"):
+		goType = "bool"
+	case strings.Contains(sqlType, "CHAR") || strings.Contains(sqlType, "TEXT") ||
+		strings.Contains(sqlType, "CLOB"):
+		goType = "string"
+	case strings.Contains(sqlType, "BIGINT"):
+		goType = "int64"
+	case strings.Contains(sqlType, "INT"):
+		goType = "int"
+		if dialect == DialectSQLite {
+			goType = "int64"
+		}
+	case strings.Contains(sqlType, "REAL") || strings.Contains(sqlType, "FLOA") ||
+		strings.Contains(sqlType, "DOUB") || strings.Contains(sqlType, "DEC") ||
+		strings.Contains(sqlType, "NUMERIC"):
+		goType = "float64"
+	case strings.Contains(sqlType, "DATE") || strings.Contains(sqlType, "TIME"):
+		goType = "time.Time"
+	case strings.Contains(sqlType, "BLOB") || strings.Contains(sqlType, "BINARY") ||
+		sqlType == "" && dialect == DialectSQLite:
+		return "[]byte"
+	default:
+		goType = "string"
+	}
+	if !notNull {
+		return "*" + goType
+	}
+	return goType
+}
+
+// sqlType returns the column type of a Go type. Types without a column type
+// of the dialect are stored as text.
+func sqlType(dialect string, goType string) string {
+	columnType := mysql.ColumnType
+	if dialect == DialectSQLite {
+		columnType = sqlite.ColumnType
+	}
+	if sqlType, ok := columnType(goType); ok {
+		return sqlType
+	}
+	return "TEXT"
+}
+
+// isIntegerType returns whether a Go type is an integer type.
+func isIntegerType(goType string) bool {
+	return strings.HasPrefix(goType, "int") || strings.HasPrefix(goType, "uint")
+}
+
+// checkDialect returns an error if the dialect is not supported.
+func checkDialect(dialect string) error {
+	if dialect != DialectMySQL && dialect != DialectSQLite {
+		return fmt.Errorf("unsupported dialect %q", dialect)
+	}
+	return nil
+}
+
+// receiverName returns the type name of a method receiver.
+func receiverName(receiver *ast.FieldList) string {
+	if len(receiver.List) == 0 {
+		return ""
+	}
+	expr := receiver.List[0].Type
+	if star, ok := expr.(*ast.StarExpr); ok {
+		expr = star.X
+	}
+	if ident, ok := expr.(*ast.Ident); ok {
+		return ident.Name
+	}
+	return ""
+}
+
+// returnedString returns the string literal that a function body returns, or
+// an empty string if the body does not return a single string literal.
+func returnedString(body *ast.BlockStmt) string {
+	if body == nil || len(body.List) != 1 {
+		return ""
+	}
+	ret, ok := body.List[0].(*ast.ReturnStmt)
+	if !ok || len(ret.Results) != 1 {
+		return ""
+	}
+	literal, ok := ret.Results[0].(*ast.BasicLit)
+	if !ok || literal.Kind != token.STRING {
+		return ""
+	}
+	value, err := strconv.Unquote(literal.Value)
+	if err != nil {
+		return ""
+	}
+	return value
+}
+
+// exprString returns the source of an expression.
+func exprString(expr ast.Expr) string {
+	var buffer bytes.Buffer
+	printer.Fprint(&buffer, token.NewFileSet(), expr)
+	return buffer.String()
+}
+
+// stringSlice returns the Go source of a string slice.
+func stringSlice(values []string) string {
+	return "[]string{" + quotedList(values) + "}"
+}
+
+// quotedList returns the Go source of a comma separated list of strings.
+func quotedList(values []string) string {
+	quoted := make([]string, len(values))
+	for i, value := range values {
+		quoted[i] = strconv.Quote(value)
+	}
+	return strings.Join(quoted, ", ")
+}
+
+// lowerFirst returns the name with its first letter in lower case.
+func lowerFirst(name string) string {
+	runes := []rune(name)
+	runes[0] = unicode.ToLower(runes[0])
+	return string(runes)
+}
+
+// plural returns the plural of a snake case name.
+//
+// Example:
+//
+//	plural("category")
+//
+// Output:
+//
+//	"categories"
+func plural(name string) string {
+	switch {
+	case strings.HasSuffix(name, "y") && len(name) > 1 &&
+		!strings.ContainsRune("aeiou", rune(name[len(name)-2])):
+		return name[:len(name)-1] + "ies"
+	case strings.HasSuffix(name, "s") || strings.HasSuffix(name, "x") ||
+		strings.HasSuffix(name, "ch") || strings.HasSuffix(name, "sh"):
+		return name + "es"
+	default:
+		return name + "s"
+	}
+}
+
+// singular returns the singular of a plural snake case name.
+//
+// Example:
+//
+//	singular("categories")
+//
+// Output:
+//
+//	"category"
+func singular(name string) string {
+	switch {
+	case strings.HasSuffix(name, "ies"):
+		return name[:len(name)-3] + "y"
+	case strings.HasSuffix(name, "sses") || strings.HasSuffix(name, "xes") ||
+		strings.HasSuffix(name, "ches") || strings.HasSuffix(name, "shes"):
+		return name[:len(name)-2]
+	case strings.HasSuffix(name, "s") && !strings.HasSuffix(name, "ss"):
+		return name[:len(name)-1]
+	default:
+		return name
+	}
+}
@@ -0,0 +1,159 @@
+package codegen
+
+import (
+	"context"
+	"database/sql"
+	"go/parser"
+	"go/token"
+	"reflect"
+	"strings"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+const testEntitySource = `package user
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type User struct {
+	ID        int64     ` + "`db:\"id\"`" + `
+	Email     string    ` + "`db:\"email\"`" + `
+	Nickname  *string   ` + "`db:\"nickname\" json:\"nick\"`" + `
+	Token     uuid.UUID ` + "`db:\"token\"`" + `
+	CreatedAt time.Time ` + "`db:\"created_at\"`" + `
+	internal  string
+}
+
+func (u *User) TableName() string {
+	return "app_users"
+}
+`
+
+func TestEntityFromSource(t *testing.T) {
+	entity, err := EntityFromSource([]byte(testEntitySource), "User", DialectSQLite)
+	if err != nil {
+		t.Fatalf("EntityFromSource: %v", err)
+	}
+	if entity.TableName != "app_users" {
+		t.Errorf("expected table app_users, got %s", entity.TableName)
+	}
+	if !reflect.DeepEqual(entity.Declared, []string{"TableName", "User"}) {
+		t.Errorf("unexpected declared names: %v", entity.Declared)
+	}
+	expected := []Column{
+		{Name: "id", GoType: "int64", SQLType: "INTEGER", NotNull: true, PrimaryKey: true, AutoIncrement: true},
+		{Name: "email", GoType: "string", SQLType: "TEXT", NotNull: true},
+		{Name: "nickname", APIName: "nick", GoType: "*string", SQLType: "TEXT"},
+		{Name: "token", GoType: "uuid.UUID", SQLType: "TEXT", NotNull: true},
+		{Name: "created_at", GoType: "time.Time", SQLType: "INTEGER", NotNull: true},
+	}
+	if !reflect.DeepEqual(entity.Columns, expected) {
+		t.Errorf("expected columns %+v, got %+v", expected, entity.Columns)
+	}
+
+	if _, err := EntityFromSource([]byte(testEntitySource), "Group", DialectSQLite); err == nil {
+		t.Errorf("expected error for a missing struct")
+	}
+	if _, err := EntityFromSource([]byte(testEntitySource), "User", "oracle"); err == nil {
+		t.Errorf("expected error for an unsupported dialect")
+	}
+}
+
+func TestEntityFromTable(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer db.Close()
+	if _, err := db.Exec(`CREATE TABLE categories (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		name VARCHAR(50) NOT NULL UNIQUE,
+		label TEXT DEFAULT 'it''s',
+		weight REAL,
+		active BOOLEAN NOT NULL DEFAULT 1
+	)`); err != nil {
+		t.Fatalf("create table: %v", err)
+	}
+
+	entity, err := EntityFromTable(context.Background(), db, DialectSQLite, "categories")
+	if err != nil {
+		t.Fatalf("EntityFromTable: %v", err)
+	}
+	if entity.Name != "Category" {
+		t.Errorf("expected name Category, got %s", entity.Name)
+	}
+	label, active := "it's", "1"
+	expected := []Column{
+		{Name: "id", GoType: "int64", SQLType: "INTEGER", NotNull: true, PrimaryKey: true, AutoIncrement: true},
+		{Name: "name", GoType: "string", SQLType: "VARCHAR(50)", NotNull: true, Unique: true},
+		{Name: "label", GoType: "*string", SQLType: "TEXT", Default: &label},
+		{Name: "weight", GoType: "*float64", SQLType: "REAL"},
+		{Name: "active", GoType: "bool", SQLType: "BOOLEAN", NotNull: true, Default: &active},
+	}
+	if !reflect.DeepEqual(entity.Columns, expected) {
+		t.Errorf("expected columns %+v, got %+v", expected, entity.Columns)
+	}
+
+	if _, err := EntityFromTable(context.Background(), db, DialectSQLite, "missing"); err == nil {
+		t.Errorf("expected error for a missing table")
+	}
+}
+
+func TestGenerateScaffold(t *testing.T) {
+	entity, err := EntityFromSource([]byte(testEntitySource), "User", DialectMySQL)
+	if err != nil {
+		t.Fatalf("EntityFromSource: %v", err)
+	}
+	source, err := GenerateScaffold("user", entity)
+	if err != nil {
+		t.Fatalf("GenerateScaffold: %v", err)
+	}
+	if _, err := parser.ParseFile(token.NewFileSet(), "", source, 0); err != nil {
+		t.Fatalf("invalid source: %v\n%s", err, source)
+	}
+	for _, fragment := range []string{
+		`"github.com/google/uuid"`,
+		`"github.com/pakkasys/fluidapi-extended/mysql"`,
+		"func (e *User) ScanRow(row database.Row) error {",
+		"func NewUser(opts ...extendeddatabase.EntityOption[*User]) *User {",
+		`APIName:  "nick",`,
+		`"email":      {"=", "!=", "in", "not_in"},`,
+		`"created_at": {"=", "!=", ">", ">=", "<", "<=", "in", "not_in"},`,
+		`Validate: []string{"string", "max=255"},`,
+		"Email     string    `json:\"email\" required:\"true\"`",
+		"Nick      *string   `json:\"nick\"`",
+		"Users []UserOutput `json:\"users\"`",
+		`KeyFields:        []string{"id"},`,
+		`UpdateAPIFields:  UserAPIFields.MustGetAPIFields([]string{"email", "nick", "token", "created_at"}),`,
+		`Type:    "VARCHAR(255)",`,
+	} {
+		if !strings.Contains(string(source), fragment) {
+			t.Errorf("expected source to contain %q\n%s", fragment, source)
+		}
+	}
+	if strings.Contains(string(source), "func (e *User) TableName()") {
+		t.Errorf("expected the declared TableName method not to be generated")
+	}
+}
+
+func TestPlural(t *testing.T) {
+	for name, expected := range map[string]string{
+		"user":     "users",
+		"category": "categories",
+		"key":      "keys",
+		"address":  "addresses",
+		"box":      "boxes",
+	} {
+		if got := plural(name); got != expected {
+			t.Errorf("plural(%q): expected %q, got %q", name, expected, got)
+		}
+		if got := singular(expected); got != name {
+			t.Errorf("singular(%q): expected %q, got %q", expected, name, got)
+		}
+	}
+}
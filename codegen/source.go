@@ -0,0 +1,47 @@
+package codegen
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"sort"
+	"strings"
+)
+
+// formatSource assembles and formats a Go source file. The imports map the
+// import paths to their names, and the standard library imports are grouped
+// before the other imports.
+func formatSource(
+	header string, packageName string, imports map[string]string, body []byte,
+) ([]byte, error) {
+	var source bytes.Buffer
+	fmt.Fprintf(&source, "%s\n\npackage %s\n\n", header, packageName)
+	var stdImports, moduleImports []string
+	for path := range imports {
+		if strings.Contains(strings.Split(path, "/")[0], ".") {
+			moduleImports = append(moduleImports, path)
+		} else {
+			stdImports = append(stdImports, path)
+		}
+	}
+	sort.Strings(stdImports)
+	sort.Strings(moduleImports)
+	source.WriteString("import (\n")
+	for _, group := range [][]string{stdImports, moduleImports} {
+		for _, path := range group {
+			if name := imports[path]; name != "" {
+				fmt.Fprintf(&source, "\t%s %q\n", name, path)
+			} else {
+				fmt.Fprintf(&source, "\t%q\n", path)
+			}
+		}
+		source.WriteString("\n")
+	}
+	source.WriteString(")\n\n")
+	source.Write(body)
+	formatted, err := format.Source(source.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("format: %w", err)
+	}
+	return formatted, nil
+}
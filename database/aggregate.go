@@ -0,0 +1,67 @@
+package database
+
+import (
+	"github.com/pakkasys/fluidapi/database"
+)
+
+// AggregateFunction is an SQL aggregate function.
+type AggregateFunction string
+
+// Aggregate functions.
+const (
+	AggregateCount AggregateFunction = "COUNT"
+	AggregateSum   AggregateFunction = "SUM"
+	AggregateAvg   AggregateFunction = "AVG"
+	AggregateMin   AggregateFunction = "MIN"
+	AggregateMax   AggregateFunction = "MAX"
+)
+
+// DateBucket truncates a time value to a period. Time values are stored as
+// Unix nanoseconds, and they are bucketed in UTC.
+type DateBucket string
+
+// Date buckets. The buckets are formatted as strings, for example "2024-05"
+// for a month and "2024-W19" for a week.
+const (
+	BucketHour  DateBucket = "hour"
+	BucketDay   DateBucket = "day"
+	BucketWeek  DateBucket = "week"
+	BucketMonth DateBucket = "month"
+	BucketYear  DateBucket = "year"
+)
+
+// GroupBy groups the rows by the value of a column, or by the date bucket of
+// the value if the bucket is set.
+type GroupBy struct {
+	Table  string
+	Column string
+	Bucket DateBucket
+	Alias  string
+}
+
+// Aggregate is an aggregate expression of a column. COUNT without a column
+// counts the rows.
+type Aggregate struct {
+	Function AggregateFunction
+	Table    string
+	Column   string
+	Alias    string
+}
+
+// Having selects the groups by the value of an aggregate.
+type Having struct {
+	Aggregate Aggregate
+	Predicate database.Predicate
+	Value     any
+}
+
+// AggregateOptions holds the options of an aggregate query.
+type AggregateOptions struct {
+	Selectors  []database.Selector
+	GroupBy    []GroupBy
+	Aggregates []Aggregate
+	Having     []Having
+	// Orders order the groups by the aliases of the groups and aggregates.
+	Orders []database.Order
+	Page   *database.Page
+}
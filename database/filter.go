@@ -0,0 +1,105 @@
+package database
+
+import (
+	"github.com/pakkasys/fluidapi/database"
+)
+
+// FilterOperator combines the filters of a filter group.
+type FilterOperator string
+
+// Filter operators.
+const (
+	FilterAnd FilterOperator = "AND"
+	FilterOr  FilterOperator = "OR"
+	FilterNot FilterOperator = "NOT"
+)
+
+// Filter is a boolean filter expression. A filter is either a term that holds
+// a selector or a search, or a group that combines its filters with the
+// operator. A NOT group negates its only filter.
+type Filter struct {
+	Operator FilterOperator
+	Filters  []Filter
+	Selector *database.Selector
+	Search   *Search
+}
+
+// NewFilterTerm creates a filter term from a selector.
+//
+// Parameters:
+//   - selector: The selector of the term.
+//
+// Returns:
+//   - *Filter: The new Filter.
+func NewFilterTerm(selector database.Selector) *Filter {
+	return &Filter{Selector: &selector}
+}
+
+// NewFilterSearch creates a filter term from a full-text search.
+//
+// Parameters:
+//   - search: The search of the term.
+//
+// Returns:
+//   - *Filter: The new Filter.
+func NewFilterSearch(search Search) *Filter {
+	return &Filter{Search: &search}
+}
+
+// NewFilterGroup creates a filter group.
+//
+// Parameters:
+//   - operator: The operator that combines the filters.
+//   - filters: The filters of the group.
+//
+// Returns:
+//   - *Filter: The new Filter.
+func NewFilterGroup(operator FilterOperator, filters ...Filter) *Filter {
+	return &Filter{Operator: operator, Filters: filters}
+}
+
+// AndFilters combines the filters that are not nil with AND.
+//
+// Parameters:
+//   - filters: The filters to combine.
+//
+// Returns:
+//   - *Filter: The combined filter, the filter itself if only one filter is
+//     set or nil if none is set.
+func AndFilters(filters ...*Filter) *Filter {
+	var set []Filter
+	for _, filter := range filters {
+		if filter != nil {
+			set = append(set, *filter)
+		}
+	}
+	switch len(set) {
+	case 0:
+		return nil
+	case 1:
+		return &set[0]
+	default:
+		return NewFilterGroup(FilterAnd, set...)
+	}
+}
+
+// RelevanceSearch returns the first search term of the filter that orders the
+// rows by relevance. Only the terms that all rows must match are considered,
+// so the terms of OR and NOT groups are ignored.
+//
+// Returns:
+//   - *Search: The search, or nil if there is none.
+func (f Filter) RelevanceSearch() *Search {
+	if f.Search != nil && f.Search.Relevance {
+		return f.Search
+	}
+	if f.Operator != FilterAnd {
+		return nil
+	}
+	for _, child := range f.Filters {
+		if search := child.RelevanceSearch(); search != nil {
+			return search
+		}
+	}
+	return nil
+}
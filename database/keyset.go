@@ -0,0 +1,135 @@
+package database
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pakkasys/fluidapi/database"
+)
+
+// KeysetColumn is a column of a keyset pagination key.
+type KeysetColumn struct {
+	Table      string
+	Column     string
+	Descending bool
+}
+
+// Keyset selects the rows that come after the given values when the rows are
+// ordered by the keyset columns. The keyset columns should not be nullable
+// since rows with NULL values never compare as being after the values.
+type Keyset struct {
+	Columns []KeysetColumn
+	Values  []any
+}
+
+// NewKeyset creates a keyset from the orders of a query and the order values
+// of the row after which the rows are selected.
+//
+// Parameters:
+//   - orders: The orders of the query.
+//   - values: The values of the ordered columns.
+//
+// Returns:
+//   - *Keyset: The new Keyset.
+func NewKeyset(orders []database.Order, values []any) *Keyset {
+	columns := make([]KeysetColumn, len(orders))
+	for i, order := range orders {
+		columns[i] = KeysetColumn{
+			Table:      order.Table,
+			Column:     order.Field,
+			Descending: IsDescending(string(order.Direction)),
+		}
+	}
+	return &Keyset{
+		Columns: columns,
+		Values:  values,
+	}
+}
+
+// IsDescending returns true if the order direction is descending. Only DESC
+// and DESCENDING, in any case, are descending.
+//
+// Parameters:
+//   - direction: The order direction.
+//
+// Returns:
+//   - bool: True if the direction is descending.
+func IsDescending(direction string) bool {
+	return strings.EqualFold(direction, "DESC") ||
+		strings.EqualFold(direction, "DESCENDING")
+}
+
+// SQLDirection returns the SQL keyword of an order direction: DESC if the
+// direction is descending and ASC otherwise. The query builders write it
+// instead of the direction itself, so the direction is never written into
+// the query text.
+//
+// Parameters:
+//   - direction: The order direction.
+//
+// Returns:
+//   - string: ASC or DESC.
+func SQLDirection(direction database.OrderDirection) string {
+	if IsDescending(string(direction)) {
+		return "DESC"
+	}
+	return "ASC"
+}
+
+// Condition returns the condition that selects the rows after the keyset
+// values. If all columns are ordered in the same direction, a row value
+// comparison is used. Otherwise the comparison is expanded column by column.
+//
+// Parameters:
+//   - columnFn: Function that returns the quoted name of a keyset column.
+//
+// Returns:
+//   - string: The condition with ? placeholders.
+//   - []any: The values of the placeholders.
+func (k *Keyset) Condition(
+	columnFn func(column KeysetColumn) string,
+) (string, []any) {
+	columns := make([]string, len(k.Columns))
+	sameDirection := true
+	for i, column := range k.Columns {
+		columns[i] = columnFn(column)
+		if column.Descending != k.Columns[0].Descending {
+			sameDirection = false
+		}
+	}
+
+	if sameDirection {
+		return fmt.Sprintf(
+			"(%s) %s (%s)",
+			strings.Join(columns, ", "),
+			keysetOperator(k.Columns[0]),
+			strings.TrimSuffix(strings.Repeat("?, ", len(columns)), ", "),
+		), k.Values
+	}
+
+	var conditions []string
+	var values []any
+	for i, column := range k.Columns {
+		var parts []string
+		for j := 0; j < i; j++ {
+			parts = append(parts, fmt.Sprintf("%s = ?", columns[j]))
+			values = append(values, k.Values[j])
+		}
+		parts = append(parts, fmt.Sprintf(
+			"%s %s ?", columns[i], keysetOperator(column),
+		))
+		values = append(values, k.Values[i])
+		conditions = append(
+			conditions, "("+strings.Join(parts, " AND ")+")",
+		)
+	}
+	return "(" + strings.Join(conditions, " OR ") + ")", values
+}
+
+// keysetOperator returns the comparison operator for a keyset column.
+func keysetOperator(column KeysetColumn) string {
+	if column.Descending {
+		return "<"
+	}
+	return ">"
+}
@@ -0,0 +1,49 @@
+package database
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestKeysetCondition(t *testing.T) {
+	columnFn := func(column KeysetColumn) string {
+		return `"` + column.Column + `"`
+	}
+	tests := []struct {
+		name           string
+		columns        []KeysetColumn
+		expected       string
+		expectedValues []any
+	}{
+		{
+			"SameDirection",
+			[]KeysetColumn{
+				{Column: "created", Descending: true},
+				{Column: "id", Descending: true},
+			},
+			`("created", "id") < (?, ?)`,
+			[]any{100, 5},
+		},
+		{
+			"MixedDirections",
+			[]KeysetColumn{
+				{Column: "created", Descending: true},
+				{Column: "id"},
+			},
+			`(("created" < ?) OR ("created" = ? AND "id" > ?))`,
+			[]any{100, 100, 5},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keyset := &Keyset{Columns: tt.columns, Values: []any{100, 5}}
+			condition, values := keyset.Condition(columnFn)
+			if condition != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, condition)
+			}
+			if !reflect.DeepEqual(values, tt.expectedValues) {
+				t.Errorf("expected values %v, got %v", tt.expectedValues, values)
+			}
+		})
+	}
+}
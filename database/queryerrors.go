@@ -9,4 +9,50 @@ var (
 	DuplicateEntryError    = core.NewAPIError("DUPLICATE_ENTRY")
 	ForeignConstraintError = core.NewAPIError("FOREIGN_CONSTRAINT_ERROR")
 	NoRowsError            = core.NewAPIError("NO_ROWS")
+	InvalidIdentifierError = core.NewAPIError("INVALID_IDENTIFIER")
 )
+
+// Errors of query parts that are written into the query text and are not
+// one of the supported values.
+var (
+	InvalidPredicateError         = core.NewAPIError("INVALID_PREDICATE")
+	InvalidOrderDirectionError    = core.NewAPIError("INVALID_ORDER_DIRECTION")
+	InvalidAggregateFunctionError = core.NewAPIError("INVALID_AGGREGATE_FUNCTION")
+)
+
+// Errors of values that violate the constraints of a table.
+var (
+	NotNullViolationError = core.NewAPIError("NOT_NULL_VIOLATION")
+	CheckConstraintError  = core.NewAPIError("CHECK_CONSTRAINT_ERROR")
+	DataTooLongError      = core.NewAPIError("DATA_TOO_LONG")
+	OutOfRangeError       = core.NewAPIError("OUT_OF_RANGE")
+)
+
+// Errors of queries that could not be run at the time and may succeed when
+// they are retried.
+var (
+	DeadlockError         = core.NewAPIError("DEADLOCK")
+	LockTimeoutError      = core.NewAPIError("LOCK_TIMEOUT")
+	DatabaseBusyError     = core.NewAPIError("DATABASE_BUSY")
+	ReadOnlyDatabaseError = core.NewAPIError("READ_ONLY_DATABASE")
+)
+
+// ConstraintViolation is the data of DuplicateEntryError and
+// ForeignConstraintError. It describes the violated key as far as the database
+// reports it and wraps the database error.
+type ConstraintViolation struct {
+	Table   string   // The table of the key, if reported.
+	Key     string   // The name of the key or constraint, if reported.
+	Columns []string // The columns of the key, if reported.
+	Err     error    // The database error.
+}
+
+// Error returns the message of the database error.
+func (v *ConstraintViolation) Error() string {
+	return v.Err.Error()
+}
+
+// Unwrap returns the database error.
+func (v *ConstraintViolation) Unwrap() error {
+	return v.Err
+}
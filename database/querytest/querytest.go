@@ -0,0 +1,533 @@
+// Package querytest implements a conformance suite for the query builders of
+// the SQL dialects. Every case is checked against the golden query of the
+// dialect, and if a database is given, the queries are also executed against
+// a fixture to check that the dialects behave the same.
+package querytest
+
+import (
+	"database/sql"
+	"reflect"
+	"slices"
+	"sort"
+	"testing"
+
+	extendeddatabase "github.com/pakkasys/fluidapi-extended/database"
+	"github.com/pakkasys/fluidapi/database"
+)
+
+// QueryBuilder is the query builder surface that the suite checks.
+type QueryBuilder interface {
+	Insert(
+		tableName string, insertedValues database.InsertedValuesFn,
+	) (string, []any)
+	InsertMany(
+		tableName string, insertedValues []database.InsertedValuesFn,
+	) (string, []any)
+	Upsert(
+		tableName string,
+		insertedValues database.InsertedValuesFn,
+		conflictColumns []string,
+		updateColumns []string,
+	) (string, []any)
+	UpsertMany(
+		tableName string,
+		insertedValues []database.InsertedValuesFn,
+		updateProjections []database.Projection,
+	) (string, []any)
+	UpsertWithOptions(
+		tableName string,
+		insertedValues []database.InsertedValuesFn,
+		opts extendeddatabase.UpsertOptions,
+	) (string, []any, error)
+	Get(tableName string, opts *database.GetOptions) (string, []any)
+	Count(tableName string, opts *database.CountOptions) (string, []any)
+	UpdateQuery(
+		tableName string,
+		updates []database.Update,
+		selectors []database.Selector,
+	) (string, []any)
+	Delete(
+		tableName string,
+		selectors []database.Selector,
+		opts *database.DeleteOptions,
+	) (string, []any)
+}
+
+// Golden holds the expected queries of a dialect by case name.
+type Golden map[string]string
+
+// Options holds the options of the suite.
+type Options struct {
+	// Golden holds the expected queries of the dialect. Every case must have
+	// a golden query.
+	Golden Golden
+	// OpenDB opens an empty database that the queries of a case are executed
+	// against. If it is nil, only the golden queries are checked.
+	OpenDB func(t *testing.T) *sql.DB
+	// SkipBehavior names the cases that are not executed against the
+	// database, e.g. because the database is not compiled with the features
+	// that the query needs. Their golden queries are still checked.
+	SkipBehavior []string
+}
+
+// Case is a conformance case. A case either selects rows, which must equal
+// the rows of the case, or changes the users table, which must then equal the
+// state of the case.
+type Case struct {
+	Name   string
+	Build  func(builder QueryBuilder) (string, []any)
+	Values []any
+	Rows   [][]any
+	State  [][]any
+}
+
+// fixture creates the tables of the cases and their rows.
+var fixture = []string{
+	"CREATE TABLE groups (id INTEGER PRIMARY KEY, name VARCHAR(64) NOT NULL)",
+	"CREATE TABLE users (id INTEGER PRIMARY KEY, name VARCHAR(64) NOT NULL UNIQUE," +
+		" age INTEGER NULL, group_id INTEGER NULL)",
+	"INSERT INTO groups (id, name) VALUES (1, 'admins'), (2, 'staff')",
+	"INSERT INTO users (id, name, age, group_id) VALUES" +
+		" (1, 'a', 30, 1), (2, 'b', NULL, 2), (3, 'c', 20, NULL)",
+}
+
+// stateQuery selects the state of the users table.
+const stateQuery = "SELECT id, name, age, group_id FROM users ORDER BY id"
+
+// fixtureUsers are the rows of the users table in the fixture.
+var fixtureUsers = [][]any{
+	{int64(1), "a", int64(30), int64(1)},
+	{int64(2), "b", nil, int64(2)},
+	{int64(3), "c", int64(20), nil},
+}
+
+// userValues returns the function used to get the columns and values of a
+// user row.
+func userValues(columns []string, values ...any) database.InsertedValuesFn {
+	return func() ([]string, []any) {
+		return columns, values
+	}
+}
+
+// userColumns are the columns of the users table.
+var userColumns = []string{"id", "name", "age", "group_id"}
+
+// withUsers returns the rows of the fixture users with the rows replaced or
+// added by id and the rows of the deleted ids removed.
+func withUsers(rows [][]any, deleted ...int64) [][]any {
+	byID := map[int64][]any{}
+	for _, row := range fixtureUsers {
+		byID[row[0].(int64)] = row
+	}
+	for _, row := range rows {
+		byID[row[0].(int64)] = row
+	}
+	for _, id := range deleted {
+		delete(byID, id)
+	}
+	state := make([][]any, 0, len(byID))
+	for _, row := range byID {
+		state = append(state, row)
+	}
+	sort.Slice(state, func(i, j int) bool {
+		return state[i][0].(int64) < state[j][0].(int64)
+	})
+	return state
+}
+
+// queryOrError returns the query and values of a builder that can fail. If
+// it fails, the error is returned as the query so that it is reported as a
+// mismatch with the golden query.
+func queryOrError(query string, values []any, err error) (string, []any) {
+	if err != nil {
+		return err.Error(), nil
+	}
+	return query, values
+}
+
+// Cases returns the conformance cases.
+//
+// Returns:
+//   - []Case: The cases.
+func Cases() []Case {
+	return []Case{
+		{
+			Name: "insert",
+			Build: func(b QueryBuilder) (string, []any) {
+				return b.Insert("users", userValues(userColumns, 4, "d", 40, nil))
+			},
+			Values: []any{4, "d", 40, nil},
+			State:  withUsers([][]any{{int64(4), "d", int64(40), nil}}),
+		},
+		{
+			Name: "insert_many",
+			Build: func(b QueryBuilder) (string, []any) {
+				return b.InsertMany("users", []database.InsertedValuesFn{
+					userValues(userColumns, 4, "d", 40, 1),
+					userValues(userColumns, 5, "e", nil, nil),
+				})
+			},
+			Values: []any{4, "d", 40, 1, 5, "e", nil, nil},
+			State: withUsers([][]any{
+				{int64(4), "d", int64(40), int64(1)},
+				{int64(5), "e", nil, nil},
+			}),
+		},
+		{
+			Name: "upsert",
+			Build: func(b QueryBuilder) (string, []any) {
+				return b.Upsert(
+					"users",
+					userValues([]string{"id", "name", "age"}, 4, "a", 99),
+					[]string{"name"},
+					[]string{"age"},
+				)
+			},
+			Values: []any{4, "a", 99},
+			State:  withUsers([][]any{{int64(1), "a", int64(99), int64(1)}}),
+		},
+		{
+			Name: "upsert/do_nothing",
+			Build: func(b QueryBuilder) (string, []any) {
+				return b.Upsert(
+					"users",
+					userValues([]string{"id", "name", "age"}, 1, "z", 99),
+					[]string{"id"},
+					nil,
+				)
+			},
+			Values: []any{1, "z", 99},
+			State:  withUsers(nil),
+		},
+		{
+			Name: "upsert/options",
+			Build: func(b QueryBuilder) (string, []any) {
+				return queryOrError(b.UpsertWithOptions(
+					"users",
+					[]database.InsertedValuesFn{
+						userValues(userColumns, 1, "a", 77, 2),
+						userValues(userColumns, 4, "d", 40, nil),
+					},
+					// Without conflict columns the conflicts are resolved
+					// on any unique key.
+					extendeddatabase.UpsertOptions{
+						ExcludeColumns: []string{"id", "group_id"},
+					},
+				))
+			},
+			Values: []any{1, "a", 77, 2, 4, "d", 40, nil},
+			State: withUsers([][]any{
+				{int64(1), "a", int64(77), int64(1)},
+				{int64(4), "d", int64(40), nil},
+			}),
+		},
+		{
+			// PostgreSQL resolves the conflicts of UpsertMany on the primary
+			// key and updates the projected columns, while MySQL and SQLite
+			// resolve them on the projected columns, so the conflicting row
+			// is upserted with its current values.
+			Name: "upsert_many",
+			Build: func(b QueryBuilder) (string, []any) {
+				columns := []string{"id", "name", "age"}
+				return b.UpsertMany(
+					"users",
+					[]database.InsertedValuesFn{
+						userValues(columns, 1, "a", 30),
+						userValues(columns, 4, "d", 40),
+					},
+					[]database.Projection{{Column: "name"}},
+				)
+			},
+			Values: []any{1, "a", 30, 4, "d", 40},
+			State: withUsers([][]any{
+				{int64(4), "d", int64(40), nil},
+			}),
+		},
+		{
+			Name: "get/selectors",
+			Build: func(b QueryBuilder) (string, []any) {
+				return b.Get("users", &database.GetOptions{
+					Selectors: []database.Selector{
+						{Table: "users", Column: "age", Predicate: ">=", Value: 20},
+						{Column: "group_id", Predicate: "=", Value: 1},
+					},
+				})
+			},
+			Values: []any{20, 1},
+			Rows:   [][]any{{int64(1), "a", int64(30), int64(1)}},
+		},
+		{
+			Name: "get/null",
+			Build: func(b QueryBuilder) (string, []any) {
+				return b.Get("users", &database.GetOptions{
+					Selectors: []database.Selector{
+						{Table: "users", Column: "age", Predicate: "=", Value: nil},
+					},
+					Projections: []database.Projection{{Column: "id"}},
+				})
+			},
+			Rows: [][]any{{int64(2)}},
+		},
+		{
+			Name: "get/not_null",
+			Build: func(b QueryBuilder) (string, []any) {
+				return b.Get("users", &database.GetOptions{
+					Selectors: []database.Selector{
+						{Column: "age", Predicate: "!=", Value: nil},
+					},
+					Projections: []database.Projection{{Column: "id"}},
+					Orders:      []database.Order{{Field: "id", Direction: "ASC"}},
+				})
+			},
+			Rows: [][]any{{int64(1)}, {int64(3)}},
+		},
+		{
+			Name: "get/in",
+			Build: func(b QueryBuilder) (string, []any) {
+				return b.Get("users", &database.GetOptions{
+					Selectors: []database.Selector{
+						{Table: "users", Column: "id", Predicate: "IN", Value: []int{1, 3}},
+						{Column: "name", Predicate: "NOT IN", Value: []string{"c"}},
+						{Column: "group_id", Predicate: "IN", Value: 1},
+					},
+					Projections: []database.Projection{{Table: "users", Column: "id"}},
+				})
+			},
+			Values: []any{1, 3, "c", 1},
+			Rows:   [][]any{{int64(1)}},
+		},
+		{
+			Name: "get/join",
+			Build: func(b QueryBuilder) (string, []any) {
+				return b.Get("users", &database.GetOptions{
+					Projections: []database.Projection{
+						{Table: "users", Column: "name"},
+						{Table: "groups", Column: "name", Alias: "group_name"},
+					},
+					Joins: []database.Join{{
+						Type:    "INNER",
+						Table:   "groups",
+						OnLeft:  database.ColumnSelector{Table: "groups", Column: "id"},
+						OnRight: database.ColumnSelector{Table: "users", Column: "group_id"},
+					}},
+					Orders: []database.Order{
+						{Table: "users", Field: "id", Direction: "ASC"},
+					},
+				})
+			},
+			Rows: [][]any{{"a", "admins"}, {"b", "staff"}},
+		},
+		{
+			Name: "get/orders_page",
+			Build: func(b QueryBuilder) (string, []any) {
+				return b.Get("users", &database.GetOptions{
+					Projections: []database.Projection{{Column: "name"}},
+					Orders:      []database.Order{{Field: "name", Direction: "DESC"}},
+					Page:        &database.Page{Offset: 1, Limit: 2},
+				})
+			},
+			Rows: [][]any{{"b"}, {"a"}},
+		},
+		{
+			Name: "get/lock",
+			Build: func(b QueryBuilder) (string, []any) {
+				return b.Get("users", &database.GetOptions{
+					Selectors: []database.Selector{
+						{Column: "id", Predicate: "=", Value: 2},
+					},
+					Projections: []database.Projection{{Column: "name"}},
+					Lock:        true,
+				})
+			},
+			Values: []any{2},
+			Rows:   [][]any{{"b"}},
+		},
+		{
+			Name: "count",
+			Build: func(b QueryBuilder) (string, []any) {
+				return b.Count("users", &database.CountOptions{
+					Selectors: []database.Selector{
+						{Table: "users", Column: "age", Predicate: ">", Value: 10},
+					},
+				})
+			},
+			Values: []any{10},
+			Rows:   [][]any{{int64(2)}},
+		},
+		{
+			Name: "count/page",
+			Build: func(b QueryBuilder) (string, []any) {
+				return b.Count("users", &database.CountOptions{
+					Page: &database.Page{Offset: 1, Limit: 5},
+				})
+			},
+			Rows: [][]any{{int64(2)}},
+		},
+		{
+			Name: "update",
+			Build: func(b QueryBuilder) (string, []any) {
+				return b.UpdateQuery(
+					"users",
+					[]database.Update{{Field: "age", Value: 31}},
+					[]database.Selector{
+						{Table: "users", Column: "id", Predicate: "=", Value: 1},
+					},
+				)
+			},
+			Values: []any{31, 1},
+			State:  withUsers([][]any{{int64(1), "a", int64(31), int64(1)}}),
+		},
+		{
+			Name: "delete",
+			Build: func(b QueryBuilder) (string, []any) {
+				return b.Delete(
+					"users",
+					[]database.Selector{
+						{Table: "users", Column: "age", Predicate: "<", Value: 25},
+					},
+					nil,
+				)
+			},
+			Values: []any{25},
+			State:  withUsers(nil, 3),
+		},
+		{
+			Name: "delete/limit",
+			Build: func(b QueryBuilder) (string, []any) {
+				return b.Delete(
+					"users",
+					[]database.Selector{
+						{Column: "id", Predicate: ">", Value: 0},
+					},
+					&database.DeleteOptions{
+						Limit:  1,
+						Orders: []database.Order{{Field: "id", Direction: "ASC"}},
+					},
+				)
+			},
+			Values: []any{0},
+			State:  withUsers(nil, 1),
+		},
+	}
+}
+
+// Run runs the conformance suite against a query builder.
+//
+// Example:
+//
+//	func TestConformance(t *testing.T) {
+//	    querytest.Run(t, &Query{}, querytest.Options{Golden: golden})
+//	}
+//
+// Parameters:
+//   - t: The test.
+//   - builder: The query builder.
+//   - options: The options of the suite.
+func Run(t *testing.T, builder QueryBuilder, options Options) {
+	names := map[string]bool{}
+	for _, c := range Cases() {
+		names[c.Name] = true
+		t.Run(c.Name, func(t *testing.T) {
+			query, values := c.Build(builder)
+			expected, ok := options.Golden[c.Name]
+			if !ok {
+				t.Errorf("no golden query, got:\n%s", query)
+			} else if query != expected {
+				t.Errorf("expected query:\n%s\ngot:\n%s", expected, query)
+			}
+			if !reflect.DeepEqual(values, c.Values) {
+				t.Errorf("expected values %v, got %v", c.Values, values)
+			}
+			if options.OpenDB != nil &&
+				!slices.Contains(options.SkipBehavior, c.Name) {
+				checkBehavior(t, options.OpenDB(t), c, query, values)
+			}
+		})
+	}
+	for name := range options.Golden {
+		if !names[name] {
+			t.Errorf("golden query of unknown case %q", name)
+		}
+	}
+}
+
+// checkBehavior executes the query of a case against the fixture and checks
+// the selected rows or the state of the users table.
+func checkBehavior(
+	t *testing.T, db *sql.DB, c Case, query string, values []any,
+) {
+	t.Helper()
+	for _, statement := range fixture {
+		if _, err := db.Exec(statement); err != nil {
+			t.Fatalf("fixture %q: %v", statement, err)
+		}
+	}
+	if c.State == nil {
+		rows, err := selectRows(db, query, values)
+		if err != nil {
+			t.Fatalf("query: %v", err)
+		}
+		if !reflect.DeepEqual(rows, c.Rows) {
+			t.Errorf("expected rows %v, got %v", c.Rows, rows)
+		}
+		return
+	}
+	if _, err := db.Exec(query, values...); err != nil {
+		t.Fatalf("exec: %v", err)
+	}
+	state, err := selectRows(db, stateQuery, nil)
+	if err != nil {
+		t.Fatalf("state: %v", err)
+	}
+	if !reflect.DeepEqual(state, c.State) {
+		t.Errorf("expected users %v, got %v", c.State, state)
+	}
+}
+
+// selectRows returns the rows of a query with the values normalized so that
+// the rows of different drivers can be compared.
+func selectRows(db *sql.DB, query string, values []any) ([][]any, error) {
+	rows, err := db.Query(query, values...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	result := [][]any{}
+	for rows.Next() {
+		row := make([]any, len(columns))
+		pointers := make([]any, len(columns))
+		for i := range row {
+			pointers[i] = &row[i]
+		}
+		if err := rows.Scan(pointers...); err != nil {
+			return nil, err
+		}
+		for i, value := range row {
+			row[i] = normalize(value)
+		}
+		result = append(result, row)
+	}
+	return result, rows.Err()
+}
+
+// normalize converts the integers of a scanned value to int64 and the byte
+// slices to strings.
+func normalize(value any) any {
+	if bytes, ok := value.([]byte); ok {
+		return string(bytes)
+	}
+	reflected := reflect.ValueOf(value)
+	switch reflected.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32:
+		return reflected.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32,
+		reflect.Uint64:
+		return int64(reflected.Uint())
+	default:
+		return value
+	}
+}
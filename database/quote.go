@@ -0,0 +1,183 @@
+package database
+
+import (
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// Quoter quotes the identifiers and string literals of an SQL dialect. The
+// quote characters in identifiers and literals are doubled, so a quoted value
+// is always read back as the same value and can not end the quoting early.
+type Quoter struct {
+	// IdentifierQuote is the character that identifiers are quoted with, e.g.
+	// '`' in MySQL and '"' in SQLite and PostgreSQL.
+	IdentifierQuote rune
+	// MaxIdentifierLength is the maximum length of an identifier in bytes. If
+	// it is zero, the length is not limited.
+	MaxIdentifierLength int
+	// BackslashEscapes is set if backslashes are escape characters in string
+	// literals, as in MySQL by default. The backslashes are doubled.
+	BackslashEscapes bool
+	// EscapeStrings is set if the literals with backslashes are written as
+	// escape strings, E'...', as in PostgreSQL. The literals are then read the
+	// same regardless of the standard_conforming_strings setting.
+	EscapeStrings bool
+}
+
+// ValidateIdentifier checks that a name can be used as an identifier. The
+// name must not be empty, must be valid UTF-8 without NUL characters and must
+// fit the maximum identifier length.
+//
+// Parameters:
+//   - name: The identifier.
+//
+// Returns:
+//   - error: InvalidIdentifierError if the identifier is invalid.
+func (q Quoter) ValidateIdentifier(name string) error {
+	if name == "" ||
+		!utf8.ValidString(name) ||
+		strings.ContainsRune(name, 0) ||
+		(q.MaxIdentifierLength > 0 && len(name) > q.MaxIdentifierLength) {
+		return InvalidIdentifierError.WithData(name)
+	}
+	return nil
+}
+
+// ValidateIdentifiers checks that names can be used as identifiers.
+//
+// Parameters:
+//   - names: The identifiers.
+//
+// Returns:
+//   - error: InvalidIdentifierError if an identifier is invalid.
+func (q Quoter) ValidateIdentifiers(names ...string) error {
+	for _, name := range names {
+		if err := q.ValidateIdentifier(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Identifier validates and quotes an identifier.
+//
+// Example:
+//
+//	Quoter{IdentifierQuote: '"'}.Identifier(`my "table"`)
+//
+// Output:
+//
+//	"my ""table"""
+//
+// Parameters:
+//   - name: The identifier.
+//
+// Returns:
+//   - string: The quoted identifier.
+//   - error: InvalidIdentifierError if the identifier is invalid.
+func (q Quoter) Identifier(name string) (string, error) {
+	if err := q.ValidateIdentifier(name); err != nil {
+		return "", err
+	}
+	return q.QuoteIdentifier(name), nil
+}
+
+// Identifiers validates and quotes identifiers and joins them with commas.
+//
+// Parameters:
+//   - names: The identifiers.
+//
+// Returns:
+//   - string: The comma separated quoted identifiers.
+//   - error: InvalidIdentifierError if an identifier is invalid.
+func (q Quoter) Identifiers(names []string) (string, error) {
+	quoted := make([]string, len(names))
+	for i, name := range names {
+		identifier, err := q.Identifier(name)
+		if err != nil {
+			return "", err
+		}
+		quoted[i] = identifier
+	}
+	return strings.Join(quoted, ", "), nil
+}
+
+// QuoteIdentifier quotes an identifier without validating it. It is used by
+// the builders that can not return errors. An invalid identifier can not
+// break out of the quotes, but the database rejects it.
+//
+// Parameters:
+//   - name: The identifier.
+//
+// Returns:
+//   - string: The quoted identifier.
+func (q Quoter) QuoteIdentifier(name string) string {
+	quote := string(q.IdentifierQuote)
+	return quote + strings.ReplaceAll(name, quote, quote+quote) + quote
+}
+
+// QuoteQualified quotes a column that is qualified with its table. If the
+// table is empty, only the column is quoted.
+//
+// Parameters:
+//   - table: The table of the column.
+//   - column: The column.
+//
+// Returns:
+//   - string: The quoted column.
+func (q Quoter) QuoteQualified(table string, column string) string {
+	if table == "" {
+		return q.QuoteIdentifier(column)
+	}
+	return q.QuoteIdentifier(table) + "." + q.QuoteIdentifier(column)
+}
+
+// Literal quotes a string literal.
+//
+// Example:
+//
+//	Quoter{BackslashEscapes: true}.Literal(`it's \n`)
+//
+// Output:
+//
+//	'it''s \\n'
+//
+// Parameters:
+//   - value: The value of the literal.
+//
+// Returns:
+//   - string: The quoted literal.
+func (q Quoter) Literal(value string) string {
+	escapeString := q.EscapeStrings && strings.ContainsRune(value, '\\')
+	if q.BackslashEscapes || escapeString {
+		value = strings.ReplaceAll(value, `\`, `\\`)
+	}
+	literal := "'" + strings.ReplaceAll(value, "'", "''") + "'"
+	if escapeString {
+		return "E" + literal
+	}
+	return literal
+}
+
+// namePattern matches the names that are written without quotes, e.g. the
+// session variables "time_zone" and "@@session.sql_mode" or the character set
+// "utf8mb4".
+var namePattern = regexp.MustCompile(`^@{0,2}[A-Za-z_][A-Za-z0-9_.$]*$`)
+
+// ValidateName checks that a name can be written without quotes. It is used
+// for the names that can not be quoted, such as session variables, character
+// sets, collations and storage engines. Only letters, digits, underscores,
+// dots and dollar signs with an optional @ or @@ prefix are allowed.
+//
+// Parameters:
+//   - name: The name.
+//
+// Returns:
+//   - error: InvalidIdentifierError if the name is invalid.
+func ValidateName(name string) error {
+	if !namePattern.MatchString(name) {
+		return InvalidIdentifierError.WithData(name)
+	}
+	return nil
+}
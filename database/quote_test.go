@@ -0,0 +1,228 @@
+package database
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/pakkasys/fluidapi/core"
+	"github.com/pakkasys/fluidapi/database"
+)
+
+var testQuoters = map[string]Quoter{
+	"mysql":    {IdentifierQuote: '`', MaxIdentifierLength: 64, BackslashEscapes: true},
+	"sqlite":   {IdentifierQuote: '"'},
+	"postgres": {IdentifierQuote: '"', MaxIdentifierLength: 63, EscapeStrings: true},
+}
+
+// readQuoted reads a quoted value from the start of s the way the database
+// does. It returns the value and the rest of s after the closing quote.
+func readQuoted(s string, quote byte, backslashEscapes bool) (string, string, bool) {
+	if len(s) == 0 || s[0] != quote {
+		return "", "", false
+	}
+	var value strings.Builder
+	for i := 1; i < len(s); i++ {
+		switch {
+		case backslashEscapes && s[i] == '\\':
+			if i+1 == len(s) {
+				return "", "", false
+			}
+			i++
+			value.WriteByte(s[i])
+		case s[i] == quote && i+1 < len(s) && s[i+1] == quote:
+			i++
+			value.WriteByte(quote)
+		case s[i] == quote:
+			return value.String(), s[i+1:], true
+		default:
+			value.WriteByte(s[i])
+		}
+	}
+	return "", "", false
+}
+
+func TestIdentifier(t *testing.T) {
+	quoter := testQuoters["mysql"]
+	quoted, err := quoter.Identifier("my`table")
+	if err != nil || quoted != "`my``table`" {
+		t.Errorf("expected `my``table`, got %s (%v)", quoted, err)
+	}
+	quoted, err = quoter.Identifiers([]string{"a", "b"})
+	if err != nil || quoted != "`a`, `b`" {
+		t.Errorf("expected `a`, `b`, got %s (%v)", quoted, err)
+	}
+
+	for _, name := range []string{
+		"", "a\x00b", "\xff", strings.Repeat("a", 65),
+	} {
+		_, err := quoter.Identifier(name)
+		var apiErr *core.APIError
+		if !errors.As(err, &apiErr) || apiErr.ID != InvalidIdentifierError.ID {
+			t.Errorf("expected invalid identifier error for %q, got %v", name, err)
+		}
+	}
+}
+
+func TestQuoteQualified(t *testing.T) {
+	quoter := testQuoters["sqlite"]
+	if quoted := quoter.QuoteQualified("", "id"); quoted != `"id"` {
+		t.Errorf(`expected "id", got %s`, quoted)
+	}
+	if quoted := quoter.QuoteQualified("users", "id"); quoted != `"users"."id"` {
+		t.Errorf(`expected "users"."id", got %s`, quoted)
+	}
+}
+
+func TestLiteral(t *testing.T) {
+	tests := []struct {
+		quoter   string
+		value    string
+		expected string
+	}{
+		{"sqlite", `it's \n`, `'it''s \n'`},
+		{"mysql", `it's \n`, `'it''s \\n'`},
+		{"postgres", "it's", `'it''s'`},
+		{"postgres", `it's \n`, `E'it''s \\n'`},
+	}
+	for _, tt := range tests {
+		literal := testQuoters[tt.quoter].Literal(tt.value)
+		if literal != tt.expected {
+			t.Errorf("%s: expected %s, got %s", tt.quoter, tt.expected, literal)
+		}
+	}
+}
+
+func TestValidateName(t *testing.T) {
+	for _, name := range []string{
+		"time_zone", "@@session.sql_mode", "@var", "utf8mb4_unicode_ci",
+	} {
+		if err := ValidateName(name); err != nil {
+			t.Errorf("expected %q to be valid, got %v", name, err)
+		}
+	}
+	for _, name := range []string{
+		"", "time_zone = 'UTC'; DROP TABLE users", "1a", "a b", "@@@a",
+	} {
+		if err := ValidateName(name); err == nil {
+			t.Errorf("expected %q to be invalid", name)
+		}
+	}
+}
+
+func FuzzQuoteIdentifier(f *testing.F) {
+	for _, seed := range []string{
+		"users", "", "`", `"`, "a`; DROP TABLE users; --", `a"."b`, "\\`",
+	} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, name string) {
+		for dialect, quoter := range testQuoters {
+			quoted := quoter.QuoteIdentifier(name)
+			value, rest, ok := readQuoted(quoted, byte(quoter.IdentifierQuote), false)
+			if !ok || rest != "" || value != name {
+				t.Fatalf(
+					"%s: %q quoted as %s is read as %q with %q left",
+					dialect, name, quoted, value, rest,
+				)
+			}
+		}
+	})
+}
+
+func FuzzLiteral(f *testing.F) {
+	for _, seed := range []string{
+		"value", "", "'", `\`, `\'`, "'; DROP TABLE users; --", `a\\'b''`,
+	} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, value string) {
+		for dialect, quoter := range testQuoters {
+			literal := quoter.Literal(value)
+			backslashEscapes := quoter.BackslashEscapes
+			if strings.HasPrefix(literal, "E") {
+				literal = literal[1:]
+				backslashEscapes = true
+			}
+			read, rest, ok := readQuoted(literal, '\'', backslashEscapes)
+			if !ok || rest != "" || read != value {
+				t.Fatalf(
+					"%s: %q quoted as %s is read as %q with %q left",
+					dialect, value, quoter.Literal(value), read, rest,
+				)
+			}
+		}
+	})
+}
+
+// withoutIdentifiers returns query with the quoted identifiers removed. It
+// returns false if a quoted identifier is not closed.
+func withoutIdentifiers(query string, quote byte) (string, bool) {
+	var rest strings.Builder
+	for len(query) > 0 {
+		if query[0] != quote {
+			rest.WriteByte(query[0])
+			query = query[1:]
+			continue
+		}
+		_, after, ok := readQuoted(query, quote, false)
+		if !ok {
+			return "", false
+		}
+		query = after
+	}
+	return rest.String(), true
+}
+
+// FuzzKeysetCondition checks that the keyset condition of orders that pass
+// the validation has no other text than the quoted identifiers, the
+// placeholders and the comparison of the keyset.
+func FuzzKeysetCondition(f *testing.F) {
+	for _, seed := range [][3]string{
+		{"", "created", "DESC"},
+		{"users", "id", "descending"},
+		{"", "id", "DESC, (SELECT 1)"},
+		{"a`.`b", `a"; DROP TABLE users; --`, "asc"},
+	} {
+		f.Add(seed[0], seed[1], seed[2])
+	}
+	f.Fuzz(func(t *testing.T, table string, column string, direction string) {
+		orders := []database.Order{
+			{
+				Table:     table,
+				Field:     column,
+				Direction: database.OrderDirection(direction),
+			},
+			{Field: "id", Direction: "ASC"},
+		}
+		for dialect, quoter := range testQuoters {
+			if err := quoter.ValidateOrders(orders); err != nil {
+				continue
+			}
+			condition, values := NewKeyset(orders, []any{1, 2}).Condition(
+				func(column KeysetColumn) string {
+					return quoter.QuoteQualified(column.Table, column.Column)
+				},
+			)
+			rest, ok := withoutIdentifiers(
+				condition, byte(quoter.IdentifierQuote),
+			)
+			if !ok {
+				t.Fatalf("%s: unclosed identifier in %s", dialect, condition)
+			}
+			words := strings.FieldsFunc(rest, func(r rune) bool {
+				return strings.ContainsRune(" ().,?<>=", r)
+			})
+			for _, word := range words {
+				if word != "AND" && word != "OR" {
+					t.Fatalf("%s: unexpected %q in %s", dialect, word, condition)
+				}
+			}
+			if strings.Count(rest, "?") != len(values) {
+				t.Fatalf(
+					"%s: %d values for %s", dialect, len(values), condition,
+				)
+			}
+		}
+	})
+}
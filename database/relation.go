@@ -0,0 +1,135 @@
+package database
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/pakkasys/fluidapi/database"
+)
+
+// RelationKind is the kind of a relation.
+type RelationKind string
+
+// Relation kinds.
+const (
+	RelationBelongsTo  RelationKind = "belongs_to"
+	RelationHasMany    RelationKind = "has_many"
+	RelationManyToMany RelationKind = "many_to_many"
+)
+
+// Relation declares the rows of a table that are related to the rows of
+// another table. The related rows are read with a single query for all the
+// rows of a page.
+type Relation struct {
+	// Name is the name of the relation.
+	Name string
+	Kind RelationKind
+	// Table is the related table.
+	Table string
+	// Columns are the read columns of the related table.
+	Columns []string
+	// LocalTable is the table of the rows the related rows are read for.
+	LocalTable string
+	// LocalColumn is the column of the rows whose value identifies the
+	// related rows. For belongs-to relations it is the foreign key, otherwise
+	// usually the primary key.
+	LocalColumn string
+	// ForeignColumn is the column of the related table that matches the local
+	// column, or the join table for many-to-many relations.
+	ForeignColumn string
+	// JoinTable is the join table of many-to-many relations.
+	JoinTable string
+	// JoinLocalColumn is the column of the join table that matches the local
+	// column.
+	JoinLocalColumn string
+	// JoinForeignColumn is the column of the join table that matches the
+	// foreign column.
+	JoinForeignColumn string
+	// EntityFn returns a new related entity. It must be a pointer to a struct
+	// whose `db` tags match the columns.
+	EntityFn func() any
+}
+
+// GetOptions returns the get options that read the rows related to the local
+// key values. The key of each related row is projected after the columns.
+// The rows of many-to-many relations are joined with the join table.
+//
+// Parameters:
+//   - keys: The local key values.
+//
+// Returns:
+//   - *database.GetOptions: The get options.
+func (r Relation) GetOptions(keys []any) *database.GetOptions {
+	projections := make([]database.Projection, 0, len(r.Columns)+1)
+	for _, column := range r.Columns {
+		projections = append(projections, database.Projection{
+			Table:  r.Table,
+			Column: column,
+		})
+	}
+	if r.Kind == RelationManyToMany {
+		return &database.GetOptions{
+			Selectors: []database.Selector{{
+				Table:     r.JoinTable,
+				Column:    r.JoinLocalColumn,
+				Predicate: "IN",
+				Value:     keys,
+			}},
+			Projections: append(projections, database.Projection{
+				Table:  r.JoinTable,
+				Column: r.JoinLocalColumn,
+			}),
+			Joins: []database.Join{{
+				Type:  "INNER",
+				Table: r.JoinTable,
+				OnLeft: database.ColumnSelector{
+					Table:  r.JoinTable,
+					Column: r.JoinForeignColumn,
+				},
+				OnRight: database.ColumnSelector{
+					Table:  r.Table,
+					Column: r.ForeignColumn,
+				},
+			}},
+		}
+	}
+	return &database.GetOptions{
+		Selectors: []database.Selector{{
+			Table:     r.Table,
+			Column:    r.ForeignColumn,
+			Predicate: "IN",
+			Value:     keys,
+		}},
+		Projections: append(projections, database.Projection{
+			Table:  r.Table,
+			Column: r.ForeignColumn,
+		}),
+	}
+}
+
+// RelationKey returns the key that matches the local and foreign values of a
+// relation. Pointers are dereferenced and byte slices read by drivers are
+// converted to strings.
+//
+// Parameters:
+//   - value: The local or foreign value.
+//
+// Returns:
+//   - string: The key.
+//   - bool: False if the value is nil.
+func RelationKey(value any) (string, bool) {
+	v := reflect.ValueOf(value)
+	for v.IsValid() && v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return "", false
+		}
+		v = v.Elem()
+	}
+	if !v.IsValid() {
+		return "", false
+	}
+	if bytes, ok := v.Interface().([]byte); ok {
+		return string(bytes), true
+	}
+	return fmt.Sprint(v.Interface()), true
+}
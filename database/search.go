@@ -0,0 +1,74 @@
+package database
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Search is a full-text search of the columns of a table.
+type Search struct {
+	Table   string
+	Columns []string
+	Query   string
+	// Relevance orders the rows by relevance before the other orders.
+	Relevance bool
+	// FTSTable is the full-text table of dialects that keep the index in a
+	// separate table, such as the FTS5 virtual tables of SQLite. The table
+	// name with the "_fts" suffix is used if it is not set.
+	FTSTable string
+	// FTSKey is the column of the table that matches the rowid of the
+	// full-text table. The rowid is used if it is not set.
+	FTSKey string
+}
+
+// FTSTableName returns the name of the full-text table of the search.
+//
+// Returns:
+//   - string: The name of the full-text table.
+func (s Search) FTSTableName() string {
+	if s.FTSTable != "" {
+		return s.FTSTable
+	}
+	return s.Table + "_fts"
+}
+
+// FTSKeyColumn returns the column of the table that matches the rowid of the
+// full-text table.
+//
+// Returns:
+//   - string: The key column.
+func (s Search) FTSKeyColumn() string {
+	if s.FTSKey != "" {
+		return s.FTSKey
+	}
+	return "rowid"
+}
+
+// FTS5Query returns the search query as an FTS5 query. Each word of the query
+// is quoted so that it is matched as is, and all words must match one of the
+// searched columns.
+//
+// Example:
+//
+//	Search{Columns: []string{"title", "body"}, Query: `go "sql`}.FTS5Query()
+//
+// Output:
+//
+//	{title body} : "go" AND {title body} : """sql"
+//
+// Returns:
+//   - string: The FTS5 query.
+func (s Search) FTS5Query() string {
+	columnFilter := ""
+	if len(s.Columns) != 0 {
+		columnFilter = fmt.Sprintf("{%s} : ", strings.Join(s.Columns, " "))
+	}
+	words := strings.Fields(s.Query)
+	terms := make([]string, len(words))
+	for i, word := range words {
+		terms[i] = fmt.Sprintf(
+			`%s"%s"`, columnFilter, strings.ReplaceAll(word, `"`, `""`),
+		)
+	}
+	return strings.Join(terms, " AND ")
+}
@@ -0,0 +1,44 @@
+package database
+
+import "testing"
+
+func TestFTS5Query(t *testing.T) {
+	tests := []struct {
+		name     string
+		search   Search
+		expected string
+	}{
+		{
+			"Columns",
+			Search{Columns: []string{"title", "body"}, Query: "go  sql"},
+			`{title body} : "go" AND {title body} : "sql"`,
+		},
+		{
+			"NoColumns",
+			Search{Query: "go"},
+			`"go"`,
+		},
+		{
+			"Quotes",
+			Search{Query: `"go" a"b`},
+			`"""go""" AND "a""b"`,
+		},
+		{
+			"Operators",
+			Search{Query: "go OR NOT sql* ^a -b"},
+			`"go" AND "OR" AND "NOT" AND "sql*" AND "^a" AND "-b"`,
+		},
+		{
+			"Empty",
+			Search{Query: "  "},
+			"",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if query := tt.search.FTS5Query(); query != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, query)
+			}
+		})
+	}
+}
@@ -0,0 +1,398 @@
+package database
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/pakkasys/fluidapi/database"
+)
+
+// ColumnTypeFn returns the column type of a Go type, e.g. "int64",
+// "time.Time" or "[]byte". It returns false if the type has no column type.
+type ColumnTypeFn func(goType string) (string, bool)
+
+// Index is an index of a table.
+type Index struct {
+	Name    string
+	Columns []string
+	Unique  bool
+}
+
+// TableDefinition is the definition of a table. The constraints hold the
+// composite primary key and the foreign keys, and the indexes are created
+// after the table.
+type TableDefinition struct {
+	Columns     []database.ColumnDefinition
+	Constraints []string
+	Indexes     []Index
+}
+
+// constraintQuoter quotes the identifiers of the constraints. Backticks are
+// understood by both MySQL and SQLite.
+var constraintQuoter = Quoter{IdentifierQuote: '`'}
+
+// foreignKeyActions are the referential actions of foreign keys.
+var foreignKeyActions = []string{
+	"CASCADE", "SET NULL", "SET DEFAULT", "RESTRICT", "NO ACTION",
+}
+
+// StructTableDefinition builds the definition of a table from the `db` and
+// `dbdef` tags of an entity struct. The column types are derived from the
+// field types, and the fields of pointer types are nullable. The `dbdef` tag
+// is a comma separated list of options:
+//   - type=T: The column type.
+//   - notnull, null: Whether the column is nullable.
+//   - default=V: The default value.
+//   - pk: The column is part of the primary key.
+//   - autoinc: The column is auto-incremented.
+//   - unique: The column is unique.
+//   - index, index=name: The column is part of an index. The columns of an
+//     index name form a composite index in the order of the fields.
+//   - unique_index, unique_index=name: The column is part of a unique index.
+//   - fk=table.column: The column references a column of another table.
+//   - ondelete=A, onupdate=A: The referential actions of the foreign key.
+//
+// Example:
+//
+//	type Order struct {
+//	    ID     int64     `db:"id" dbdef:"pk,autoinc"`
+//	    UserID int64     `db:"user_id" dbdef:"fk=users.id,ondelete=CASCADE,index"`
+//	    Code   string    `db:"code" dbdef:"type=CHAR(8),unique"`
+//	    Note   *string   `db:"note"`
+//	    Placed time.Time `db:"placed_at" dbdef:"index=idx_orders_placed"`
+//	}
+//
+// Parameters:
+//   - tableName: The name of the table.
+//   - entity: The entity struct or a pointer to it.
+//   - columnType: The column types of the dialect.
+//
+// Returns:
+//   - *TableDefinition: The table definition.
+//   - error: An error if a tag is invalid or a type has no column type.
+func StructTableDefinition(
+	tableName string, entity any, columnType ColumnTypeFn,
+) (*TableDefinition, error) {
+	typ := reflect.TypeOf(entity)
+	if typ != nil && typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	if typ == nil || typ.Kind() != reflect.Struct {
+		return nil, fmt.Errorf(
+			"StructTableDefinition: expected a struct, got %T", entity,
+		)
+	}
+
+	definition := &TableDefinition{}
+	var primaryKey []string
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		name := field.Tag.Get("db")
+		if name == "" || name == "-" {
+			continue
+		}
+		column, options, err := fieldColumn(field, name, columnType)
+		if err != nil {
+			return nil, fmt.Errorf("StructTableDefinition: field %s: %w", field.Name, err)
+		}
+		if column.PrimaryKey {
+			primaryKey = append(primaryKey, name)
+		}
+		for _, option := range options {
+			key, value, _ := strings.Cut(option, "=")
+			switch key {
+			case "index", "unique_index":
+				unique := key == "unique_index"
+				if value == "" {
+					value = indexName(tableName, name, unique)
+				}
+				definition.addIndex(value, name, unique)
+			}
+		}
+		constraint, err := foreignKey(tableName, name, options)
+		if err != nil {
+			return nil, fmt.Errorf("StructTableDefinition: field %s: %w", field.Name, err)
+		}
+		if constraint != "" {
+			definition.Constraints = append(definition.Constraints, constraint)
+		}
+		definition.Columns = append(definition.Columns, column)
+	}
+	if len(definition.Columns) == 0 {
+		return nil, fmt.Errorf(
+			"StructTableDefinition: type %s has no db tags", typ.Name(),
+		)
+	}
+	if len(primaryKey) > 1 {
+		for i := range definition.Columns {
+			definition.Columns[i].PrimaryKey = false
+		}
+		definition.Constraints = append(
+			[]string{"PRIMARY KEY (" + quoteColumns(primaryKey) + ")"},
+			definition.Constraints...,
+		)
+	}
+	return definition, nil
+}
+
+// fieldColumn builds the column definition of a struct field and returns the
+// index and foreign key options of its `dbdef` tag.
+func fieldColumn(
+	field reflect.StructField, name string, columnType ColumnTypeFn,
+) (database.ColumnDefinition, []string, error) {
+	column := database.ColumnDefinition{
+		Name:    name,
+		NotNull: field.Type.Kind() != reflect.Ptr,
+	}
+	var options []string
+	for _, option := range splitTagOptions(field.Tag.Get("dbdef")) {
+		key, value, hasValue := strings.Cut(option, "=")
+		switch {
+		case key == "type" && hasValue:
+			column.Type = value
+		case key == "default" && hasValue:
+			if len(value) >= 2 && value[0] == '\'' && value[len(value)-1] == '\'' {
+				value = value[1 : len(value)-1]
+			}
+			column.Default = &value
+		case key == "notnull" && !hasValue:
+			column.NotNull = true
+		case key == "null" && !hasValue:
+			column.NotNull = false
+		case key == "pk" && !hasValue:
+			column.PrimaryKey = true
+		case key == "autoinc" && !hasValue:
+			column.AutoIncrement = true
+		case key == "unique" && !hasValue:
+			column.Unique = true
+		case key == "index" || key == "unique_index" ||
+			(key == "fk" || key == "ondelete" || key == "onupdate") && hasValue:
+			options = append(options, option)
+		default:
+			return column, nil, fmt.Errorf("invalid dbdef option %q", option)
+		}
+	}
+	if column.Type == "" {
+		goType := goTypeName(field.Type)
+		sqlType, ok := columnType(goType)
+		if !ok {
+			return column, nil, fmt.Errorf("no column type for %s", goType)
+		}
+		column.Type = sqlType
+	}
+	return column, options, nil
+}
+
+// foreignKey returns the foreign key constraint of the fk option of a
+// column, or an empty string if it has none.
+func foreignKey(
+	tableName string, column string, options []string,
+) (string, error) {
+	var reference, onDelete, onUpdate string
+	for _, option := range options {
+		key, value, _ := strings.Cut(option, "=")
+		switch key {
+		case "fk":
+			reference = value
+		case "ondelete":
+			onDelete = strings.ToUpper(value)
+		case "onupdate":
+			onUpdate = strings.ToUpper(value)
+		}
+	}
+	if reference == "" {
+		if onDelete != "" || onUpdate != "" {
+			return "", fmt.Errorf("referential action without fk")
+		}
+		return "", nil
+	}
+	table, foreignColumn, ok := strings.Cut(reference, ".")
+	if !ok || table == "" || foreignColumn == "" {
+		return "", fmt.Errorf("fk %q must be of the form table.column", reference)
+	}
+	constraint := fmt.Sprintf(
+		"CONSTRAINT %s FOREIGN KEY (%s) REFERENCES %s (%s)",
+		constraintQuoter.QuoteIdentifier("fk_"+tableName+"_"+column),
+		constraintQuoter.QuoteIdentifier(column),
+		constraintQuoter.QuoteIdentifier(table),
+		constraintQuoter.QuoteIdentifier(foreignColumn),
+	)
+	for _, action := range []struct{ clause, value string }{
+		{"ON DELETE", onDelete},
+		{"ON UPDATE", onUpdate},
+	} {
+		if action.value == "" {
+			continue
+		}
+		if !slices.Contains(foreignKeyActions, action.value) {
+			return "", fmt.Errorf("invalid referential action %q", action.value)
+		}
+		constraint += " " + action.clause + " " + action.value
+	}
+	return constraint, nil
+}
+
+// UniqueKeyColumns returns the columns of a unique key of the table by the
+// name of the key. The unique indexes are named by their index names and the
+// keys of the UNIQUE columns by their columns.
+//
+// Parameters:
+//   - key: The name of the key.
+//
+// Returns:
+//   - []string: The columns of the key.
+//   - bool: Whether the table has a unique key of the name.
+func (d *TableDefinition) UniqueKeyColumns(key string) ([]string, bool) {
+	for _, index := range d.Indexes {
+		if index.Unique && index.Name == key {
+			return index.Columns, true
+		}
+	}
+	for _, column := range d.Columns {
+		if column.Unique && column.Name == key {
+			return []string{column.Name}, true
+		}
+	}
+	return nil, false
+}
+
+// PrimaryKeyColumns returns the columns of the primary key of the table. The
+// primary key is either made of the primary key columns or defined by a
+// PRIMARY KEY constraint, which StructTableDefinition adds for a composite
+// primary key.
+//
+// Returns:
+//   - []string: The columns of the primary key.
+//   - bool: Whether the table has a primary key.
+func (d *TableDefinition) PrimaryKeyColumns() ([]string, bool) {
+	var columns []string
+	for _, column := range d.Columns {
+		if column.PrimaryKey {
+			columns = append(columns, column.Name)
+		}
+	}
+	if len(columns) != 0 {
+		return columns, true
+	}
+	for _, constraint := range d.Constraints {
+		const prefix = "PRIMARY KEY ("
+		if len(constraint) <= len(prefix) ||
+			!strings.EqualFold(constraint[:len(prefix)], prefix) ||
+			!strings.HasSuffix(constraint, ")") {
+			continue
+		}
+		list := constraint[len(prefix) : len(constraint)-1]
+		return constraintColumns(list), true
+	}
+	return nil, false
+}
+
+// constraintColumns returns the columns of a comma separated column list of a
+// constraint. The columns may be quoted with backticks.
+func constraintColumns(list string) []string {
+	var columns []string
+	var column strings.Builder
+	quoted := false
+	for i := 0; i < len(list); i++ {
+		switch {
+		case list[i] == '`' && quoted && i+1 < len(list) && list[i+1] == '`':
+			column.WriteByte('`')
+			i++
+		case list[i] == '`':
+			quoted = !quoted
+		case list[i] == ',' && !quoted:
+			columns = append(columns, strings.TrimSpace(column.String()))
+			column.Reset()
+		case list[i] == ' ' && !quoted:
+		default:
+			column.WriteByte(list[i])
+		}
+	}
+	return append(columns, strings.TrimSpace(column.String()))
+}
+
+// addIndex adds a column to an index, creating the index if needed.
+func (d *TableDefinition) addIndex(name string, column string, unique bool) {
+	for i := range d.Indexes {
+		if d.Indexes[i].Name == name {
+			d.Indexes[i].Columns = append(d.Indexes[i].Columns, column)
+			d.Indexes[i].Unique = d.Indexes[i].Unique || unique
+			return
+		}
+	}
+	d.Indexes = append(d.Indexes, Index{
+		Name:    name,
+		Columns: []string{column},
+		Unique:  unique,
+	})
+}
+
+// indexName returns the default name of a single column index.
+func indexName(tableName string, column string, unique bool) string {
+	if unique {
+		return fmt.Sprintf("uq_%s_%s", tableName, column)
+	}
+	return fmt.Sprintf("idx_%s_%s", tableName, column)
+}
+
+// goTypeName returns the name of a Go type that column types are looked up
+// with. Pointers are dereferenced and named types are named by their kind,
+// except for time.Time and byte slices.
+func goTypeName(typ reflect.Type) string {
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	switch {
+	case typ == reflect.TypeOf(time.Time{}):
+		return "time.Time"
+	case typ.Kind() == reflect.Slice && typ.Elem().Kind() == reflect.Uint8:
+		return "[]byte"
+	default:
+		return typ.Kind().String()
+	}
+}
+
+// splitTagOptions splits the options of a tag at the commas that are not in
+// parentheses or single quotes.
+//
+// Example:
+//
+//	splitTagOptions("type=DECIMAL(10,2),default='a,b'")
+//
+// Output:
+//
+//	[]string{"type=DECIMAL(10,2)", "default='a,b'"}
+func splitTagOptions(tag string) []string {
+	var options []string
+	depth, quoted, start := 0, false, 0
+	for i, r := range tag {
+		switch {
+		case r == '\'':
+			quoted = !quoted
+		case quoted:
+		case r == '(':
+			depth++
+		case r == ')':
+			depth--
+		case r == ',' && depth == 0:
+			options = append(options, strings.TrimSpace(tag[start:i]))
+			start = i + 1
+		}
+	}
+	if last := strings.TrimSpace(tag[start:]); last != "" {
+		options = append(options, last)
+	}
+	return options
+}
+
+// quoteColumns returns a comma separated list of quoted columns.
+func quoteColumns(columns []string) string {
+	quoted := make([]string, len(columns))
+	for i, column := range columns {
+		quoted[i] = constraintQuoter.QuoteIdentifier(column)
+	}
+	return strings.Join(quoted, ", ")
+}
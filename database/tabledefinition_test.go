@@ -0,0 +1,167 @@
+package database
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pakkasys/fluidapi/database"
+)
+
+func testColumnType(goType string) (string, bool) {
+	types := map[string]string{
+		"int64":     "BIGINT",
+		"string":    "VARCHAR(255)",
+		"time.Time": "BIGINT",
+		"[]byte":    "BLOB",
+	}
+	columnType, ok := types[goType]
+	return columnType, ok
+}
+
+type testStatus string
+
+type testOrder struct {
+	ID       int64      `db:"id" dbdef:"pk,autoinc"`
+	UserID   int64      `db:"user_id" dbdef:"fk=users.id,ondelete=cascade,index"`
+	Code     string     `db:"code" dbdef:"type=DECIMAL(10,2),unique,default='a,b'"`
+	Status   testStatus `db:"status" dbdef:"index=idx_status_placed"`
+	PlacedAt *time.Time `db:"placed_at" dbdef:"index=idx_status_placed"`
+	Data     []byte     `db:"data" dbdef:"null"`
+	Ignored  string
+}
+
+func TestStructTableDefinition(t *testing.T) {
+	definition, err := StructTableDefinition("orders", &testOrder{}, testColumnType)
+	if err != nil {
+		t.Fatalf("StructTableDefinition: %v", err)
+	}
+	defaultValue := "a,b"
+	expected := &TableDefinition{
+		Columns: []database.ColumnDefinition{
+			{Name: "id", Type: "BIGINT", NotNull: true, PrimaryKey: true, AutoIncrement: true},
+			{Name: "user_id", Type: "BIGINT", NotNull: true},
+			{Name: "code", Type: "DECIMAL(10,2)", NotNull: true, Unique: true, Default: &defaultValue},
+			{Name: "status", Type: "VARCHAR(255)", NotNull: true},
+			{Name: "placed_at", Type: "BIGINT"},
+			{Name: "data", Type: "BLOB"},
+		},
+		Constraints: []string{
+			"CONSTRAINT `fk_orders_user_id` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`) ON DELETE CASCADE",
+		},
+		Indexes: []Index{
+			{Name: "idx_orders_user_id", Columns: []string{"user_id"}},
+			{Name: "idx_status_placed", Columns: []string{"status", "placed_at"}},
+		},
+	}
+	if !reflect.DeepEqual(definition, expected) {
+		t.Errorf("expected %+v, got %+v", expected, definition)
+	}
+}
+
+func TestStructTableDefinitionCompositeKey(t *testing.T) {
+	type membership struct {
+		UserID  int64 `db:"user_id" dbdef:"pk"`
+		GroupID int64 `db:"group_id" dbdef:"pk,unique_index=uq_group"`
+	}
+	definition, err := StructTableDefinition("memberships", membership{}, testColumnType)
+	if err != nil {
+		t.Fatalf("StructTableDefinition: %v", err)
+	}
+	if definition.Columns[0].PrimaryKey || definition.Columns[1].PrimaryKey {
+		t.Errorf("expected no column primary keys: %+v", definition.Columns)
+	}
+	if !reflect.DeepEqual(definition.Constraints, []string{"PRIMARY KEY (`user_id`, `group_id`)"}) {
+		t.Errorf("unexpected constraints: %v", definition.Constraints)
+	}
+	if !reflect.DeepEqual(definition.Indexes, []Index{
+		{Name: "uq_group", Columns: []string{"group_id"}, Unique: true},
+	}) {
+		t.Errorf("unexpected indexes: %v", definition.Indexes)
+	}
+}
+
+func TestPrimaryKeyColumns(t *testing.T) {
+	tests := []struct {
+		name       string
+		definition TableDefinition
+		expected   []string
+		ok         bool
+	}{
+		{
+			name: "Column",
+			definition: TableDefinition{Columns: []database.ColumnDefinition{
+				{Name: "id", PrimaryKey: true}, {Name: "name"},
+			}},
+			expected: []string{"id"},
+			ok:       true,
+		},
+		{
+			name: "Constraint",
+			definition: TableDefinition{Constraints: []string{
+				"CONSTRAINT `fk` FOREIGN KEY (`a`) REFERENCES `b` (`id`)",
+				"PRIMARY KEY (`user_id`, `group``id`)",
+			}},
+			expected: []string{"user_id", "group`id"},
+			ok:       true,
+		},
+		{
+			name: "UnquotedConstraint",
+			definition: TableDefinition{Constraints: []string{
+				"primary key (a, b)",
+			}},
+			expected: []string{"a", "b"},
+			ok:       true,
+		},
+		{
+			name: "None",
+			definition: TableDefinition{Columns: []database.ColumnDefinition{
+				{Name: "id"},
+			}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			columns, ok := tt.definition.PrimaryKeyColumns()
+			if ok != tt.ok || !reflect.DeepEqual(columns, tt.expected) {
+				t.Errorf(
+					"expected %v (%t), got %v (%t)",
+					tt.expected, tt.ok, columns, ok,
+				)
+			}
+		})
+	}
+}
+
+func TestStructTableDefinitionErrors(t *testing.T) {
+	type unknownOption struct {
+		ID int64 `db:"id" dbdef:"primary"`
+	}
+	type unsupportedType struct {
+		Tags map[string]string `db:"tags"`
+	}
+	type invalidReference struct {
+		UserID int64 `db:"user_id" dbdef:"fk=users"`
+	}
+	type invalidAction struct {
+		UserID int64 `db:"user_id" dbdef:"fk=users.id,ondelete=drop"`
+	}
+	tests := []struct {
+		entity  any
+		message string
+	}{
+		{1, "expected a struct"},
+		{struct{ A int64 }{}, "has no db tags"},
+		{unknownOption{}, `invalid dbdef option "primary"`},
+		{unsupportedType{}, "no column type for map"},
+		{invalidReference{}, "must be of the form table.column"},
+		{invalidAction{}, `invalid referential action "DROP"`},
+	}
+	for _, tt := range tests {
+		_, err := StructTableDefinition("t", tt.entity, testColumnType)
+		if err == nil || !strings.Contains(err.Error(), tt.message) {
+			t.Errorf("expected error %q, got %v", tt.message, err)
+		}
+	}
+}
@@ -0,0 +1,16 @@
+package database
+
+// Increment is an update value that increments the column by the given
+// amount instead of setting it, e.g. to increment a version column without
+// knowing its value.
+//
+// Example:
+//
+//	database.Update{Field: "version", Value: Increment{By: 1}}
+//
+// Output:
+//
+//	"version" = "version" + ?
+type Increment struct {
+	By int64
+}
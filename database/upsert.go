@@ -0,0 +1,65 @@
+package database
+
+import (
+	"slices"
+)
+
+// UpsertOptions are the options of an upsert query. They have the same
+// meaning in every dialect:
+//   - ConflictColumns: The columns of the unique key that identifies a
+//     conflicting row. If empty, a conflict on any unique key of the table is
+//     resolved. MySQL resolves a conflict on any unique key, so it only
+//     updates a conflicting row whose conflict columns have the inserted
+//     values and leaves the row untouched on a conflict on another key.
+//   - UpdateColumns: The columns to update on conflict. If empty, the inserted
+//     columns that are not conflict columns are updated.
+//   - ExcludeColumns: The columns that are never updated, e.g. the primary key
+//     or the creation time.
+//   - DoNothing: Whether to leave the conflicting rows untouched. The
+//     conflicting rows are also left untouched if no columns are updated.
+//   - WhereCondition: The condition that the conflicting row must match to be
+//     updated. The columns of the condition refer to the conflicting row.
+type UpsertOptions struct {
+	ConflictColumns []string
+	UpdateColumns   []string
+	ExcludeColumns  []string
+	DoNothing       bool
+	WhereCondition  *Filter
+}
+
+// UpdatedColumns returns the columns that are updated on conflict.
+//
+// Parameters:
+//   - insertedColumns: The inserted columns.
+//
+// Returns:
+//   - []string: The updated columns. It is empty if nothing is updated.
+func (o *UpsertOptions) UpdatedColumns(insertedColumns []string) []string {
+	if o.DoNothing {
+		return nil
+	}
+	candidates := o.UpdateColumns
+	if len(candidates) == 0 {
+		candidates = insertedColumns
+	}
+	var columns []string
+	for _, column := range candidates {
+		if len(o.UpdateColumns) == 0 &&
+			slices.Contains(o.ConflictColumns, column) {
+			continue
+		}
+		if slices.Contains(o.ExcludeColumns, column) {
+			continue
+		}
+		columns = append(columns, column)
+	}
+	return columns
+}
+
+// Identifiers returns the column identifiers of the options.
+//
+// Returns:
+//   - []string: The conflict, update and exclude columns.
+func (o *UpsertOptions) Identifiers() []string {
+	return slices.Concat(o.ConflictColumns, o.UpdateColumns, o.ExcludeColumns)
+}
@@ -0,0 +1,39 @@
+package database
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestUpdatedColumns(t *testing.T) {
+	inserted := []string{"id", "email", "name", "created"}
+	tests := []struct {
+		opts     UpsertOptions
+		expected []string
+	}{
+		{UpsertOptions{}, inserted},
+		{
+			UpsertOptions{
+				ConflictColumns: []string{"email"},
+				ExcludeColumns:  []string{"id", "created"},
+			},
+			[]string{"name"},
+		},
+		{
+			UpsertOptions{
+				ConflictColumns: []string{"email"},
+				UpdateColumns:   []string{"email", "name", "created"},
+				ExcludeColumns:  []string{"created"},
+			},
+			[]string{"email", "name"},
+		},
+		{UpsertOptions{UpdateColumns: []string{"name"}, DoNothing: true}, nil},
+		{UpsertOptions{ExcludeColumns: inserted}, nil},
+	}
+	for _, tt := range tests {
+		columns := tt.opts.UpdatedColumns(inserted)
+		if !reflect.DeepEqual(columns, tt.expected) {
+			t.Errorf("expected %v for %+v, got %v", tt.expected, tt.opts, columns)
+		}
+	}
+}
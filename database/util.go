@@ -3,6 +3,7 @@ package database
 import (
 	"fmt"
 	"reflect"
+	"strings"
 	"time"
 
 	"github.com/pakkasys/fluidapi/database"
@@ -153,7 +154,65 @@ func ScanRow[T any](t *T, row database.Row) error {
 	return nil
 }
 
+// ScanColumns uses reflection to scan a row that contains only the given
+// columns into the object fields with matching `db` tags. The other fields are
+// left untouched. The extra destinations are scanned after the columns.
+func ScanColumns(
+	t any, row database.Row, columns []string, extra ...any,
+) error {
+	v := reflect.ValueOf(t)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("ScanColumns: expected a pointer to a struct, got %T", t)
+	}
+	v = v.Elem()
+	typ := v.Type()
+	fields := make(map[string]reflect.Value)
+	for i := 0; i < v.NumField(); i++ {
+		if tag := typ.Field(i).Tag.Get("db"); tag != "" {
+			fields[tag] = v.Field(i)
+		}
+	}
+	pointers := make([]any, len(columns), len(columns)+len(extra))
+	for i, column := range columns {
+		field, ok := fields[column]
+		if !ok {
+			return fmt.Errorf("ScanColumns: unknown column %q", column)
+		}
+		pointers[i] = field.Addr().Interface()
+	}
+	if err := row.Scan(append(pointers, extra...)...); err != nil {
+		return fmt.Errorf("ScanColumns: failed to scan row: %w", err)
+	}
+	return nil
+}
+
+// ColumnValues uses reflection to return the values of the object fields with
+// the given `db` tags.
+func ColumnValues(t any, columns []string) ([]any, error) {
+	v := reflect.Indirect(reflect.ValueOf(t))
+	if v.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("ColumnValues: expected a struct, got %T", t)
+	}
+	typ := v.Type()
+	fields := make(map[string]reflect.Value)
+	for i := 0; i < v.NumField(); i++ {
+		if tag := typ.Field(i).Tag.Get("db"); tag != "" {
+			fields[tag] = v.Field(i)
+		}
+	}
+	values := make([]any, len(columns))
+	for i, column := range columns {
+		field, ok := fields[column]
+		if !ok {
+			return nil, fmt.Errorf("ColumnValues: unknown column %q", column)
+		}
+		values[i] = field.Interface()
+	}
+	return values, nil
+}
+
 // InsertedValues uses reflection to generate slices of column names and values.
+// Times are inserted as Unix nanoseconds and nil time pointers as NULL.
 func InsertedValues[T any](t *T) ([]string, []any) {
 	val := reflect.ValueOf(*t)
 	typ := reflect.TypeOf(*t)
@@ -165,10 +224,17 @@ func InsertedValues[T any](t *T) ([]string, []any) {
 			continue
 		}
 		cols = append(cols, col)
-		if typ.Field(i).Type == reflect.TypeOf(time.Time{}) {
-			vals = append(vals, val.Field(i).Interface().(time.Time).UnixNano())
-		} else {
-			vals = append(vals, val.Field(i).Interface())
+		switch value := val.Field(i).Interface().(type) {
+		case time.Time:
+			vals = append(vals, value.UnixNano())
+		case *time.Time:
+			if value == nil {
+				vals = append(vals, nil)
+			} else {
+				vals = append(vals, value.UnixNano())
+			}
+		default:
+			vals = append(vals, value)
 		}
 	}
 	return cols, vals
@@ -241,3 +307,29 @@ func mustBeAssignable(value any, fieldName string, expectedType reflect.Type) {
 		value,
 	))
 }
+
+// JoinClauses joins the clauses of a query with single spaces. The empty
+// clauses are skipped.
+//
+// Example:
+//
+//	JoinClauses("SELECT COUNT(*) FROM `users`", "", "WHERE `age` > ?")
+//
+// Output:
+//
+//	SELECT COUNT(*) FROM `users` WHERE `age` > ?
+//
+// Parameters:
+//   - clauses: The clauses of the query.
+//
+// Returns:
+//   - string: The query.
+func JoinClauses(clauses ...string) string {
+	var nonEmpty []string
+	for _, clause := range clauses {
+		if clause != "" {
+			nonEmpty = append(nonEmpty, clause)
+		}
+	}
+	return strings.Join(nonEmpty, " ")
+}
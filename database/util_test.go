@@ -0,0 +1,36 @@
+package database
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestInsertedValuesTimes(t *testing.T) {
+	type event struct {
+		ID        int        `db:"id"`
+		Created   time.Time  `db:"created"`
+		DeletedAt *time.Time `db:"deleted_at"`
+		Note      string
+	}
+	created := time.Unix(10, 20)
+	deleted := time.Unix(30, 40)
+
+	columns, values := InsertedValues(&event{ID: 1, Created: created})
+	expectedColumns := []string{"id", "created", "deleted_at"}
+	if !reflect.DeepEqual(columns, expectedColumns) {
+		t.Errorf("expected columns %v, got %v", expectedColumns, columns)
+	}
+	expected := []any{1, created.UnixNano(), nil}
+	if !reflect.DeepEqual(values, expected) {
+		t.Errorf("expected values %v, got %v", expected, values)
+	}
+
+	_, values = InsertedValues(
+		&event{ID: 1, Created: created, DeletedAt: &deleted},
+	)
+	expected = []any{1, created.UnixNano(), deleted.UnixNano()}
+	if !reflect.DeepEqual(values, expected) {
+		t.Errorf("expected values %v, got %v", expected, values)
+	}
+}
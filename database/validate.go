@@ -0,0 +1,340 @@
+package database
+
+import (
+	"strings"
+
+	"github.com/pakkasys/fluidapi/database"
+)
+
+// predicates are the selector predicates that the query builders support.
+var predicates = map[database.Predicate]bool{
+	"=":      true,
+	"!=":     true,
+	"<":      true,
+	"<=":     true,
+	">":      true,
+	">=":     true,
+	"IN":     true,
+	"NOT IN": true,
+}
+
+// aggregateFunctions are the aggregate functions that the query builders
+// support.
+var aggregateFunctions = map[AggregateFunction]bool{
+	AggregateCount: true,
+	AggregateSum:   true,
+	AggregateAvg:   true,
+	AggregateMin:   true,
+	AggregateMax:   true,
+}
+
+// ValidatePredicate checks that a predicate is one of the predicates that the
+// query builders support: =, !=, <, <=, >, >=, IN and NOT IN.
+//
+// Parameters:
+//   - predicate: The predicate.
+//
+// Returns:
+//   - error: InvalidPredicateError if the predicate is not supported.
+func ValidatePredicate(predicate database.Predicate) error {
+	if !predicates[predicate] {
+		return InvalidPredicateError.WithData(predicate)
+	}
+	return nil
+}
+
+// ValidateOrderDirection checks that an order direction is empty or one of
+// ASC, ASCENDING, DESC and DESCENDING in any case.
+//
+// Parameters:
+//   - direction: The order direction.
+//
+// Returns:
+//   - error: InvalidOrderDirectionError if the direction is not supported.
+func ValidateOrderDirection(direction database.OrderDirection) error {
+	switch strings.ToUpper(string(direction)) {
+	case "", "ASC", "ASCENDING", "DESC", "DESCENDING":
+		return nil
+	}
+	return InvalidOrderDirectionError.WithData(direction)
+}
+
+// ValidateQualified checks that a column and its optional table can be used
+// as identifiers. The table may be empty.
+//
+// Parameters:
+//   - table: The table of the column.
+//   - column: The column.
+//
+// Returns:
+//   - error: InvalidIdentifierError if the table or column is invalid.
+func (q Quoter) ValidateQualified(table string, column string) error {
+	if table != "" {
+		if err := q.ValidateIdentifier(table); err != nil {
+			return err
+		}
+	}
+	return q.ValidateIdentifier(column)
+}
+
+// ValidateGetOptions checks the selectors, orders and projections of get
+// options, which often come from the request of a client.
+//
+// Parameters:
+//   - opts: The get options. It can be nil.
+//
+// Returns:
+//   - error: InvalidIdentifierError if a name is invalid,
+//     InvalidPredicateError if a predicate is not supported or
+//     InvalidOrderDirectionError if a direction is not supported.
+func (q Quoter) ValidateGetOptions(opts *database.GetOptions) error {
+	if opts == nil {
+		return nil
+	}
+	if err := q.ValidateSelectors(opts.Selectors); err != nil {
+		return err
+	}
+	if err := q.ValidateOrders(opts.Orders); err != nil {
+		return err
+	}
+	for _, projection := range opts.Projections {
+		if err := q.ValidateQualified(
+			projection.Table, projection.Column,
+		); err != nil {
+			return err
+		}
+		if projection.Alias != "" {
+			if err := q.ValidateIdentifier(projection.Alias); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// ValidateSelectors checks the tables, columns and predicates of selectors.
+//
+// Parameters:
+//   - selectors: The selectors.
+//
+// Returns:
+//   - error: InvalidIdentifierError if a name is invalid or
+//     InvalidPredicateError if a predicate is not supported.
+func (q Quoter) ValidateSelectors(selectors []database.Selector) error {
+	for _, selector := range selectors {
+		if err := q.ValidateQualified(
+			selector.Table, selector.Column,
+		); err != nil {
+			return err
+		}
+		if err := ValidatePredicate(selector.Predicate); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ValidateOrders checks the tables, fields and directions of orders.
+//
+// Parameters:
+//   - orders: The orders.
+//
+// Returns:
+//   - error: InvalidIdentifierError if a name is invalid or
+//     InvalidOrderDirectionError if a direction is not supported.
+func (q Quoter) ValidateOrders(orders []database.Order) error {
+	for _, order := range orders {
+		if err := q.ValidateQualified(order.Table, order.Field); err != nil {
+			return err
+		}
+		if err := ValidateOrderDirection(order.Direction); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ValidateKeyset checks the tables and columns of a keyset.
+//
+// Parameters:
+//   - keyset: The keyset. It can be nil.
+//
+// Returns:
+//   - error: InvalidIdentifierError if a name is invalid.
+func (q Quoter) ValidateKeyset(keyset *Keyset) error {
+	if keyset == nil {
+		return nil
+	}
+	for _, column := range keyset.Columns {
+		if err := q.ValidateQualified(column.Table, column.Column); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ValidateFilter checks the selectors and searches of a filter expression.
+//
+// Parameters:
+//   - filter: The filter expression. It can be nil.
+//
+// Returns:
+//   - error: InvalidIdentifierError if a name is invalid or
+//     InvalidPredicateError if a predicate is not supported.
+func (q Quoter) ValidateFilter(filter *Filter) error {
+	if filter == nil {
+		return nil
+	}
+	if filter.Selector != nil {
+		if err := q.ValidateSelectors(
+			[]database.Selector{*filter.Selector},
+		); err != nil {
+			return err
+		}
+	}
+	if filter.Search != nil {
+		if err := q.ValidateSearch(*filter.Search); err != nil {
+			return err
+		}
+	}
+	for i := range filter.Filters {
+		if err := q.ValidateFilter(&filter.Filters[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ValidateSearch checks the table, columns and full-text table of a search.
+// The table and the full-text table and key may be empty.
+//
+// Parameters:
+//   - search: The search.
+//
+// Returns:
+//   - error: InvalidIdentifierError if a name is invalid.
+func (q Quoter) ValidateSearch(search Search) error {
+	for _, column := range search.Columns {
+		if err := q.ValidateQualified(search.Table, column); err != nil {
+			return err
+		}
+	}
+	if search.FTSTable != "" {
+		if err := q.ValidateIdentifier(search.FTSTable); err != nil {
+			return err
+		}
+	}
+	return q.ValidateQualified(search.Table, search.FTSKeyColumn())
+}
+
+// ValidateAggregateOptions checks the selectors, groups, aggregates, HAVING
+// conditions and orders of aggregate options.
+//
+// Parameters:
+//   - opts: The aggregate options.
+//
+// Returns:
+//   - error: InvalidIdentifierError if a name is invalid,
+//     InvalidAggregateFunctionError if a function is not supported,
+//     InvalidPredicateError if a predicate is not supported or
+//     InvalidOrderDirectionError if a direction is not supported.
+func (q Quoter) ValidateAggregateOptions(opts *AggregateOptions) error {
+	if err := q.ValidateSelectors(opts.Selectors); err != nil {
+		return err
+	}
+	for _, groupBy := range opts.GroupBy {
+		if err := q.ValidateQualified(groupBy.Table, groupBy.Column); err != nil {
+			return err
+		}
+		if err := q.ValidateIdentifier(groupBy.Alias); err != nil {
+			return err
+		}
+	}
+	for _, aggregate := range opts.Aggregates {
+		if err := q.validateAggregate(aggregate); err != nil {
+			return err
+		}
+		if err := q.ValidateIdentifier(aggregate.Alias); err != nil {
+			return err
+		}
+	}
+	// The aggregates of the HAVING conditions are not selected, so they have
+	// no aliases.
+	for _, having := range opts.Having {
+		if err := q.validateAggregate(having.Aggregate); err != nil {
+			return err
+		}
+		if err := ValidatePredicate(having.Predicate); err != nil {
+			return err
+		}
+	}
+	return q.ValidateOrders(opts.Orders)
+}
+
+// validateAggregate checks the function and column of an aggregate. COUNT
+// without a column has no names to check.
+func (q Quoter) validateAggregate(aggregate Aggregate) error {
+	if !aggregateFunctions[aggregate.Function] {
+		return InvalidAggregateFunctionError.WithData(aggregate.Function)
+	}
+	if aggregate.Column == "" {
+		return nil
+	}
+	return q.ValidateQualified(aggregate.Table, aggregate.Column)
+}
+
+// ValidateGetQuery checks the names, predicates and order directions of a
+// filtered get query.
+//
+// Parameters:
+//   - tableName: The name of the table.
+//   - opts: The get options. It can be nil.
+//   - keyset: The keyset. It can be nil.
+//   - filter: The filter expression. It can be nil.
+//
+// Returns:
+//   - error: InvalidIdentifierError if a name is invalid,
+//     InvalidPredicateError if a predicate is not supported or
+//     InvalidOrderDirectionError if a direction is not supported.
+func (q Quoter) ValidateGetQuery(
+	tableName string,
+	opts *database.GetOptions,
+	keyset *Keyset,
+	filter *Filter,
+) error {
+	if err := q.ValidateIdentifier(tableName); err != nil {
+		return err
+	}
+	if err := q.ValidateGetOptions(opts); err != nil {
+		return err
+	}
+	if err := q.ValidateKeyset(keyset); err != nil {
+		return err
+	}
+	return q.ValidateFilter(filter)
+}
+
+// ValidateCountQuery checks the names and predicates of a filtered count
+// query.
+//
+// Parameters:
+//   - tableName: The name of the table.
+//   - opts: The count options.
+//   - filter: The filter expression. It can be nil.
+//
+// Returns:
+//   - error: InvalidIdentifierError if a name is invalid or
+//     InvalidPredicateError if a predicate is not supported.
+func (q Quoter) ValidateCountQuery(
+	tableName string,
+	opts *database.CountOptions,
+	filter *Filter,
+) error {
+	if err := q.ValidateIdentifier(tableName); err != nil {
+		return err
+	}
+	if err := q.ValidateSelectors(opts.Selectors); err != nil {
+		return err
+	}
+	return q.ValidateFilter(filter)
+}
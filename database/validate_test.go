@@ -0,0 +1,195 @@
+package database
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/pakkasys/fluidapi/core"
+	"github.com/pakkasys/fluidapi/database"
+)
+
+func TestValidateGetQuery(t *testing.T) {
+	quoter := testQuoters["sqlite"]
+	search := func(column string) *Filter {
+		return NewFilterSearch(Search{Table: "posts", Columns: []string{column}})
+	}
+
+	valid := NewFilterGroup(
+		FilterAnd,
+		*NewFilterTerm(database.Selector{
+			Table: "posts", Column: "status", Predicate: "=",
+		}),
+		*search("title"),
+	)
+	err := quoter.ValidateGetQuery(
+		"posts",
+		&database.GetOptions{
+			Orders: []database.Order{{Field: "id", Direction: "DESC"}},
+		},
+		NewKeyset(
+			[]database.Order{{Field: "id", Direction: "DESC"}}, []any{1},
+		),
+		valid,
+	)
+	if err != nil {
+		t.Fatalf("expected the query to be valid, got %v", err)
+	}
+
+	for name, tt := range map[string]struct {
+		opts   *database.GetOptions
+		keyset *Keyset
+		filter *Filter
+	}{
+		"Order": {
+			opts: &database.GetOptions{
+				Orders: []database.Order{{Field: "id\x00"}},
+			},
+		},
+		"OrderTable": {
+			opts: &database.GetOptions{
+				Orders: []database.Order{{Table: "\xff", Field: "id"}},
+			},
+		},
+		"Selector": {
+			opts: &database.GetOptions{
+				Selectors: []database.Selector{{Column: ""}},
+			},
+		},
+		"Projection": {
+			opts: &database.GetOptions{
+				Projections: []database.Projection{{Column: "a", Alias: "\x00"}},
+			},
+		},
+		"Keyset": {
+			keyset: &Keyset{Columns: []KeysetColumn{{Column: ""}}},
+		},
+		"Filter": {
+			filter: NewFilterGroup(
+				FilterNot,
+				*NewFilterTerm(database.Selector{Column: "a\x00"}),
+			),
+		},
+		"Search": {filter: search("")},
+	} {
+		err := quoter.ValidateGetQuery("posts", tt.opts, tt.keyset, tt.filter)
+		var apiErr *core.APIError
+		if !errors.As(err, &apiErr) || apiErr.ID != InvalidIdentifierError.ID {
+			t.Errorf("%s: expected invalid identifier error, got %v", name, err)
+		}
+	}
+}
+
+func TestValidateGetQueryPredicatesAndDirections(t *testing.T) {
+	quoter := testQuoters["sqlite"]
+	for _, tt := range []struct {
+		name   string
+		opts   *database.GetOptions
+		filter *Filter
+		errID  string
+	}{
+		{
+			name: "Valid",
+			opts: &database.GetOptions{
+				Selectors: []database.Selector{
+					{Column: "a", Predicate: "NOT IN", Value: []int{1}},
+				},
+				Orders: []database.Order{
+					{Field: "a", Direction: "descending"},
+					{Field: "b", Direction: "Asc"},
+					{Field: "c"},
+				},
+			},
+		},
+		{
+			name: "Predicate",
+			opts: &database.GetOptions{
+				Selectors: []database.Selector{
+					{Column: "a", Predicate: "= 1 OR 1 ="},
+				},
+			},
+			errID: InvalidPredicateError.ID,
+		},
+		{
+			name: "LowerCasePredicate",
+			opts: &database.GetOptions{
+				Selectors: []database.Selector{{Column: "a", Predicate: "in"}},
+			},
+			errID: InvalidPredicateError.ID,
+		},
+		{
+			name: "FilterPredicate",
+			filter: NewFilterTerm(database.Selector{
+				Column: "a", Predicate: "LIKE",
+			}),
+			errID: InvalidPredicateError.ID,
+		},
+		{
+			name: "Direction",
+			opts: &database.GetOptions{
+				Orders: []database.Order{
+					{Field: "a", Direction: "DESC, (SELECT 1)"},
+				},
+			},
+			errID: InvalidOrderDirectionError.ID,
+		},
+		{
+			name: "DirectionPrefix",
+			opts: &database.GetOptions{
+				Orders: []database.Order{{Field: "a", Direction: "DESCX"}},
+			},
+			errID: InvalidOrderDirectionError.ID,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			err := quoter.ValidateGetQuery("posts", tt.opts, nil, tt.filter)
+			if tt.errID == "" {
+				if err != nil {
+					t.Fatalf("expected the query to be valid, got %v", err)
+				}
+				return
+			}
+			var apiErr *core.APIError
+			if !errors.As(err, &apiErr) || apiErr.ID != tt.errID {
+				t.Errorf("expected %s, got %v", tt.errID, err)
+			}
+		})
+	}
+}
+
+func TestValidateAggregateOptions(t *testing.T) {
+	quoter := testQuoters["sqlite"]
+	count := Aggregate{Function: AggregateCount, Alias: "count"}
+	if err := quoter.ValidateAggregateOptions(&AggregateOptions{
+		GroupBy:    []GroupBy{{Column: "status", Alias: "status"}},
+		Aggregates: []Aggregate{count},
+		Having: []Having{{
+			Aggregate: Aggregate{Function: AggregateCount}, Predicate: ">",
+		}},
+	}); err != nil {
+		t.Fatalf("expected the options to be valid, got %v", err)
+	}
+
+	for name, opts := range map[string]*AggregateOptions{
+		"GroupBy":      {GroupBy: []GroupBy{{Column: "a\x00", Alias: "a"}}},
+		"GroupByAlias": {GroupBy: []GroupBy{{Column: "a"}}},
+		"Aggregate": {Aggregates: []Aggregate{
+			{Function: AggregateSum, Column: "\xff", Alias: "sum"},
+		}},
+		"AggregateAlias": {Aggregates: []Aggregate{{Function: AggregateCount}}},
+		"Having": {Having: []Having{{
+			Aggregate: Aggregate{Function: AggregateSum, Column: "a\x00"},
+		}}},
+		"Function": {Aggregates: []Aggregate{
+			{Function: "SLEEP", Column: "a", Alias: "sleep"},
+		}},
+		"HavingPredicate": {Having: []Having{{
+			Aggregate: Aggregate{Function: AggregateCount}, Predicate: "<>",
+		}}},
+		"Order":          {Orders: []database.Order{{Field: ""}}},
+		"OrderDirection": {Orders: []database.Order{{Field: "a", Direction: "UP"}}},
+	} {
+		if err := quoter.ValidateAggregateOptions(opts); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"math"
+	"time"
+
+	"github.com/pakkasys/fluidapi/database"
+)
+
+// lockPollInterval is the interval of the attempts to take the lock.
+const lockPollInterval = 100 * time.Millisecond
+
+// lock takes the migration lock on the connection and returns the function
+// that releases it. The advisory lock of the dialect is used if it has one.
+// The advisory lock is polled until the lock timeout, so dialects whose lock
+// query does not wait, such as PostgreSQL, are also bounded by the timeout.
+func (m *Migrator) lock(
+	ctx context.Context, conn *sql.Conn,
+) (func() error, error) {
+	timeout := int(math.Ceil(m.options.LockTimeout.Seconds()))
+	query, values, err := m.dialect.AdvisoryLock(m.options.LockName, timeout)
+	if err != nil {
+		return nil, err
+	}
+	if query == "" {
+		return m.lockTable(ctx, conn)
+	}
+	deadline := time.Now().Add(m.options.LockTimeout)
+	for {
+		var locked sql.NullInt64
+		err := conn.QueryRowContext(ctx, query, values...).Scan(&locked)
+		if err != nil {
+			return nil, err
+		}
+		if locked.Valid && locked.Int64 == 1 {
+			break
+		}
+		if !locked.Valid || time.Now().After(deadline) {
+			return nil, LockTimeoutError.WithData(m.options.LockName)
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(lockPollInterval):
+		}
+	}
+	return func() error {
+		query, values, err := m.dialect.AdvisoryUnlock(m.options.LockName)
+		if err != nil {
+			return err
+		}
+		// The context of the migrations might be done, but the lock must
+		// still be released.
+		_, err = conn.ExecContext(context.Background(), query, values...)
+		return err
+	}, nil
+}
+
+// lockTable emulates the migration lock with a lock table. The lock is held
+// while the table has a row. While the lock is held by another process, the
+// lock is polled until the lock timeout. The other errors are returned
+// immediately. A lock that is left behind by a crashed process must be
+// released by deleting the row.
+func (m *Migrator) lockTable(
+	ctx context.Context, conn *sql.Conn,
+) (func() error, error) {
+	table := m.options.Table + "_lock"
+	query, values, err := m.dialect.CreateTableQuery(
+		table,
+		true,
+		[]database.ColumnDefinition{
+			{Name: "id", Type: "INTEGER", NotNull: true, PrimaryKey: true},
+			{Name: "locked_at", Type: "BIGINT", NotNull: true},
+		},
+		nil,
+		database.TableOptions{},
+	)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := conn.ExecContext(ctx, query, values...); err != nil {
+		return nil, err
+	}
+
+	deadline := time.Now().Add(m.options.LockTimeout)
+	for {
+		// The row is inserted only if the lock is free, so a held lock is
+		// not an error and the errors of the insert are returned as is.
+		query, values := m.dialect.Upsert(
+			table,
+			func() ([]string, []any) {
+				return []string{"id", "locked_at"},
+					[]any{1, time.Now().UnixNano()}
+			},
+			[]string{"id"},
+			nil,
+		)
+		result, err := conn.ExecContext(ctx, query, values...)
+		if err != nil {
+			return nil, err
+		}
+		inserted, err := result.RowsAffected()
+		if err != nil {
+			return nil, err
+		}
+		if inserted == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			return nil, LockTimeoutError.WithData(m.options.LockName)
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(lockPollInterval):
+		}
+	}
+	return func() error {
+		query, values := m.dialect.Delete(
+			table,
+			[]database.Selector{{Column: "id", Predicate: "=", Value: 1}},
+			nil,
+		)
+		_, err := conn.ExecContext(context.Background(), query, values...)
+		return err
+	}, nil
+}
@@ -0,0 +1,522 @@
+package migrate
+
+import (
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"slices"
+	"time"
+
+	"github.com/pakkasys/fluidapi/core"
+	"github.com/pakkasys/fluidapi/database"
+)
+
+// Defaults of the migrator options.
+const (
+	DefaultTable       = "schema_migrations"
+	DefaultLockTimeout = time.Minute
+)
+
+// Migration errors.
+var (
+	ChecksumMismatchError      = core.NewAPIError("MIGRATION_CHECKSUM_MISMATCH")
+	UnknownMigrationError      = core.NewAPIError("UNKNOWN_MIGRATION")
+	OutOfOrderMigrationError   = core.NewAPIError("OUT_OF_ORDER_MIGRATION")
+	IrreversibleMigrationError = core.NewAPIError("IRREVERSIBLE_MIGRATION")
+	LockTimeoutError           = core.NewAPIError("MIGRATION_LOCK_TIMEOUT")
+)
+
+// Dialect builds the dialect specific queries of the migrator, so that their
+// identifiers and placeholders follow the dialect. mysql.Query, postgres.Query
+// and sqlite.Query implement it. If the dialect has no advisory locks, the
+// lock is emulated with a lock table. The advisory lock query must return 1 if
+// the lock is taken and 0 if it is not, in which case it is tried again until
+// the lock timeout. An upsert without update columns must not insert or
+// change a conflicting row.
+type Dialect interface {
+	Insert(
+		tableName string, insertedValues database.InsertedValuesFn,
+	) (string, []any)
+	Upsert(
+		tableName string,
+		insertedValues database.InsertedValuesFn,
+		conflictColumns []string,
+		updateColumns []string,
+	) (string, []any)
+	Get(tableName string, opts *database.GetOptions) (string, []any)
+	Delete(
+		tableName string,
+		selectors []database.Selector,
+		opts *database.DeleteOptions,
+	) (string, []any)
+	CreateTableQuery(
+		tableName string,
+		ifNotExists bool,
+		columns []database.ColumnDefinition,
+		constraints []string,
+		options database.TableOptions,
+	) (string, []any, error)
+	AdvisoryLock(lockName string, timeout int) (string, []any, error)
+	AdvisoryUnlock(lockName string) (string, []any, error)
+}
+
+// Migration is a versioned schema migration. It is either an SQL migration
+// with statements or a Go migration with functions.
+type Migration struct {
+	Version int64
+	Name    string
+	// UpSQL and DownSQL are the statements of an SQL migration, separated by
+	// semicolons.
+	UpSQL   string
+	DownSQL string
+	// Up and Down are the functions of a Go migration. They are run in the
+	// transaction of the migration.
+	Up   func(ctx context.Context, tx *sql.Tx) error
+	Down func(ctx context.Context, tx *sql.Tx) error
+	// Checksum identifies the content of a Go migration. The checksum of an
+	// SQL migration is computed from its up statements.
+	Checksum string
+}
+
+// checksum returns the checksum of the migration.
+func (m Migration) checksum() string {
+	if m.UpSQL == "" {
+		return m.Checksum
+	}
+	sum := sha256.Sum256([]byte(m.UpSQL))
+	return hex.EncodeToString(sum[:])
+}
+
+// Options are the options of a Migrator.
+type Options struct {
+	// Table is the table of the applied migrations. DefaultTable is used if
+	// it is not set.
+	Table string
+	// LockName is the name of the advisory lock. The table name is used if
+	// it is not set.
+	LockName string
+	// LockTimeout is the time to wait for the lock. DefaultLockTimeout is
+	// used if it is not set.
+	LockTimeout time.Duration
+	// DryRun writes the statements of the migrations to Output instead of
+	// running them. The database is not modified and the lock is not taken.
+	DryRun bool
+	Output io.Writer
+}
+
+// Status is the status of a migration.
+type Status struct {
+	Migration Migration
+	Applied   bool
+	AppliedAt time.Time
+}
+
+// Migrator applies and rolls back migrations. Each migration is run in its
+// own transaction together with its record in the migrations table. Note
+// that MySQL commits DDL statements implicitly.
+type Migrator struct {
+	db         *sql.DB
+	dialect    Dialect
+	migrations []Migration
+	options    Options
+}
+
+// appliedMigration is a record of the migrations table.
+type appliedMigration struct {
+	version   int64
+	name      string
+	checksum  string
+	appliedAt time.Time
+}
+
+// NewMigrator creates a new Migrator. The migrations are sorted by version.
+//
+// Parameters:
+//   - db: The database.
+//   - dialect: The dialect of the database.
+//   - migrations: The migrations.
+//   - options: The options of the migrator.
+//
+// Returns:
+//   - *Migrator: The new Migrator.
+//   - error: An error if the migrations are invalid.
+func NewMigrator(
+	db *sql.DB, dialect Dialect, migrations []Migration, options Options,
+) (*Migrator, error) {
+	sorted := slices.Clone(migrations)
+	sortMigrations(sorted)
+	for i, migration := range sorted {
+		if migration.Version <= 0 {
+			return nil, fmt.Errorf(
+				"NewMigrator: migration %q has no positive version",
+				migration.Name,
+			)
+		}
+		if i > 0 && sorted[i-1].Version == migration.Version {
+			return nil, fmt.Errorf(
+				"NewMigrator: duplicate migration version %d",
+				migration.Version,
+			)
+		}
+		if (migration.UpSQL == "") == (migration.Up == nil) {
+			return nil, fmt.Errorf(
+				"NewMigrator: migration %d must have either up SQL or an up function",
+				migration.Version,
+			)
+		}
+	}
+	if options.Table == "" {
+		options.Table = DefaultTable
+	}
+	if options.LockName == "" {
+		options.LockName = options.Table
+	}
+	if options.LockTimeout == 0 {
+		options.LockTimeout = DefaultLockTimeout
+	}
+	if options.DryRun && options.Output == nil {
+		options.Output = io.Discard
+	}
+	return &Migrator{
+		db:         db,
+		dialect:    dialect,
+		migrations: sorted,
+		options:    options,
+	}, nil
+}
+
+// Up applies the pending migrations in order. The checksums of the applied
+// migrations are checked before anything is applied.
+//
+// Parameters:
+//   - ctx: The context of the migrations.
+//
+// Returns:
+//   - []Migration: The applied migrations, or the migrations that would be
+//     applied in a dry run.
+//   - error: An error if a migration fails.
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	var applied []Migration
+	err := m.run(ctx, func(conn *sql.Conn, records []appliedMigration) error {
+		pending, err := m.pending(records)
+		if err != nil {
+			return err
+		}
+		for _, migration := range pending {
+			if err := m.apply(ctx, conn, migration, true); err != nil {
+				return err
+			}
+			applied = append(applied, migration)
+		}
+		return nil
+	})
+	if err != nil {
+		return applied, fmt.Errorf("Up: %w", err)
+	}
+	return applied, nil
+}
+
+// Down rolls back the latest applied migrations in reverse order.
+//
+// Parameters:
+//   - ctx: The context of the migrations.
+//   - steps: The number of migrations to roll back.
+//
+// Returns:
+//   - []Migration: The rolled back migrations, or the migrations that would
+//     be rolled back in a dry run.
+//   - error: An error if a migration fails.
+func (m *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	var rolledBack []Migration
+	err := m.run(ctx, func(conn *sql.Conn, records []appliedMigration) error {
+		for i := len(records) - 1; i >= 0 && len(rolledBack) < steps; i-- {
+			migration, _ := m.migration(records[i].version)
+			if migration.DownSQL == "" && migration.Down == nil {
+				return IrreversibleMigrationError.WithData(migration.Version)
+			}
+			if err := m.apply(ctx, conn, migration, false); err != nil {
+				return err
+			}
+			rolledBack = append(rolledBack, migration)
+		}
+		return nil
+	})
+	if err != nil {
+		return rolledBack, fmt.Errorf("Down: %w", err)
+	}
+	return rolledBack, nil
+}
+
+// Status returns the status of the migrations.
+//
+// Parameters:
+//   - ctx: The context of the queries.
+//
+// Returns:
+//   - []Status: The status of each migration in order.
+//   - error: An error if the applied migrations can not be read.
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("Status: %w", err)
+	}
+	defer conn.Close()
+	records, err := m.appliedMigrations(ctx, conn)
+	if err != nil {
+		return nil, fmt.Errorf("Status: %w", err)
+	}
+	statuses := make([]Status, len(m.migrations))
+	for i, migration := range m.migrations {
+		statuses[i].Migration = migration
+		for _, record := range records {
+			if record.version == migration.Version {
+				statuses[i].Applied = true
+				statuses[i].AppliedAt = record.appliedAt
+			}
+		}
+	}
+	return statuses, nil
+}
+
+// run runs a function with the verified applied migrations on a connection
+// that holds the migration lock. In a dry run the lock is not taken and the
+// tables are not created.
+func (m *Migrator) run(
+	ctx context.Context,
+	fn func(conn *sql.Conn, records []appliedMigration) error,
+) (err error) {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if !m.options.DryRun {
+		unlock, err := m.lock(ctx, conn)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if unlockErr := unlock(); unlockErr != nil && err == nil {
+				err = unlockErr
+			}
+		}()
+		if err := m.createTable(ctx, conn); err != nil {
+			return err
+		}
+	}
+	records, err := m.appliedMigrations(ctx, conn)
+	if err != nil {
+		return err
+	}
+	if err := m.verify(records); err != nil {
+		return err
+	}
+	return fn(conn, records)
+}
+
+// verify checks that the applied migrations are known and that their
+// checksums match.
+func (m *Migrator) verify(records []appliedMigration) error {
+	for _, record := range records {
+		migration, ok := m.migration(record.version)
+		if !ok {
+			return UnknownMigrationError.WithData(record.version)
+		}
+		if migration.checksum() != record.checksum {
+			return ChecksumMismatchError.WithData(record.version)
+		}
+	}
+	return nil
+}
+
+// pending returns the migrations that are not applied. A pending migration
+// that is older than an applied migration is an error.
+func (m *Migrator) pending(records []appliedMigration) ([]Migration, error) {
+	applied := map[int64]bool{}
+	var latest int64
+	for _, record := range records {
+		applied[record.version] = true
+		latest = max(latest, record.version)
+	}
+	var pending []Migration
+	for _, migration := range m.migrations {
+		if applied[migration.Version] {
+			continue
+		}
+		if migration.Version < latest {
+			return nil, OutOfOrderMigrationError.WithData(migration.Version)
+		}
+		pending = append(pending, migration)
+	}
+	return pending, nil
+}
+
+// migration returns the migration of a version.
+func (m *Migrator) migration(version int64) (Migration, bool) {
+	for _, migration := range m.migrations {
+		if migration.Version == version {
+			return migration, true
+		}
+	}
+	return Migration{}, false
+}
+
+// apply applies or rolls back a migration and records it in a transaction.
+// In a dry run the statements are written to the output.
+func (m *Migrator) apply(
+	ctx context.Context, conn *sql.Conn, migration Migration, up bool,
+) error {
+	statements := splitStatements(migration.UpSQL)
+	fn := migration.Up
+	if !up {
+		statements = splitStatements(migration.DownSQL)
+		fn = migration.Down
+	}
+	if m.options.DryRun {
+		direction := "up"
+		if !up {
+			direction = "down"
+		}
+		fmt.Fprintf(
+			m.options.Output, "-- %d %s (%s)\n",
+			migration.Version, migration.Name, direction,
+		)
+		if fn != nil {
+			fmt.Fprintln(m.options.Output, "-- Go migration")
+		}
+		for _, statement := range statements {
+			fmt.Fprintf(m.options.Output, "%s;\n", statement)
+		}
+		return nil
+	}
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	err = func() error {
+		for _, statement := range statements {
+			if _, err := tx.ExecContext(ctx, statement); err != nil {
+				return err
+			}
+		}
+		if fn != nil {
+			if err := fn(ctx, tx); err != nil {
+				return err
+			}
+		}
+		var query string
+		var values []any
+		if up {
+			query, values = m.dialect.Insert(
+				m.options.Table,
+				func() ([]string, []any) {
+					return []string{"version", "name", "checksum", "applied_at"},
+						[]any{
+							migration.Version,
+							migration.Name,
+							migration.checksum(),
+							time.Now().UnixNano(),
+						}
+				},
+			)
+		} else {
+			query, values = m.dialect.Delete(
+				m.options.Table,
+				[]database.Selector{{
+					Column:    "version",
+					Predicate: "=",
+					Value:     migration.Version,
+				}},
+				nil,
+			)
+		}
+		_, err := tx.ExecContext(ctx, query, values...)
+		return err
+	}()
+	if err != nil {
+		if rollbackErr := tx.Rollback(); rollbackErr != nil {
+			return fmt.Errorf(
+				"migration %d: %w; additionally, rollback error: %v",
+				migration.Version,
+				err,
+				rollbackErr,
+			)
+		}
+		return fmt.Errorf("migration %d: %w", migration.Version, err)
+	}
+	return tx.Commit()
+}
+
+// sortMigrations sorts migrations by version.
+func sortMigrations(migrations []Migration) {
+	slices.SortFunc(migrations, func(a, b Migration) int {
+		return cmp.Compare(a.Version, b.Version)
+	})
+}
+
+// createTable creates the migrations table if it does not exist.
+func (m *Migrator) createTable(ctx context.Context, conn *sql.Conn) error {
+	query, values, err := m.dialect.CreateTableQuery(
+		m.options.Table,
+		true,
+		[]database.ColumnDefinition{
+			{Name: "version", Type: "BIGINT", NotNull: true, PrimaryKey: true},
+			{Name: "name", Type: "VARCHAR(255)", NotNull: true},
+			{Name: "checksum", Type: "VARCHAR(64)", NotNull: true},
+			{Name: "applied_at", Type: "BIGINT", NotNull: true},
+		},
+		nil,
+		database.TableOptions{},
+	)
+	if err != nil {
+		return err
+	}
+	_, err = conn.ExecContext(ctx, query, values...)
+	return err
+}
+
+// appliedMigrations returns the applied migrations in order. In a dry run
+// the migrations table might not exist yet, and no migrations are applied
+// if it can not be read.
+func (m *Migrator) appliedMigrations(
+	ctx context.Context, conn *sql.Conn,
+) ([]appliedMigration, error) {
+	var projections []database.Projection
+	for _, column := range []string{"version", "name", "checksum", "applied_at"} {
+		projections = append(projections, database.Projection{
+			Table: m.options.Table, Column: column,
+		})
+	}
+	query, values := m.dialect.Get(m.options.Table, &database.GetOptions{
+		Orders: []database.Order{{
+			Table: m.options.Table, Field: "version", Direction: "ASC",
+		}},
+		Projections: projections,
+	})
+	rows, err := conn.QueryContext(ctx, query, values...)
+	if err != nil {
+		if m.options.DryRun {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer rows.Close()
+	var records []appliedMigration
+	for rows.Next() {
+		var record appliedMigration
+		var appliedAt int64
+		if err := rows.Scan(
+			&record.version, &record.name, &record.checksum, &appliedAt,
+		); err != nil {
+			return nil, err
+		}
+		record.appliedAt = time.Unix(0, appliedAt)
+		records = append(records, record)
+	}
+	return records, rows.Err()
+}
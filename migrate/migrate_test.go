@@ -0,0 +1,358 @@
+package migrate
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pakkasys/fluidapi-extended/postgres"
+	"github.com/pakkasys/fluidapi-extended/sqlite"
+	"github.com/pakkasys/fluidapi/core"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func testDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func testMigrations() []Migration {
+	return []Migration{
+		{
+			Version: 2,
+			Name:    "add_admin",
+			Up: func(ctx context.Context, tx *sql.Tx) error {
+				_, err := tx.ExecContext(ctx, "INSERT INTO users (name) VALUES ('admin')")
+				return err
+			},
+			Down: func(ctx context.Context, tx *sql.Tx) error {
+				_, err := tx.ExecContext(ctx, "DELETE FROM users WHERE name = 'admin'")
+				return err
+			},
+			Checksum: "v1",
+		},
+		{
+			Version: 1,
+			Name:    "create_users",
+			UpSQL:   "CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT); -- users\n",
+			DownSQL: "DROP TABLE users;",
+		},
+	}
+}
+
+func userCount(t *testing.T, db *sql.DB) int {
+	t.Helper()
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM users").Scan(&count); err != nil {
+		t.Fatalf("count users: %v", err)
+	}
+	return count
+}
+
+func expectAPIError(t *testing.T, err error, id string) {
+	t.Helper()
+	var apiErr *core.APIError
+	if !errors.As(err, &apiErr) || apiErr.ID != id {
+		t.Errorf("expected API error %q, got %v", id, err)
+	}
+}
+
+func TestMigratorUpAndDown(t *testing.T) {
+	ctx := context.Background()
+	db := testDB(t)
+	migrator, err := NewMigrator(db, &sqlite.Query{}, testMigrations(), Options{})
+	if err != nil {
+		t.Fatalf("NewMigrator: %v", err)
+	}
+
+	applied, err := migrator.Up(ctx)
+	if err != nil {
+		t.Fatalf("Up: %v", err)
+	}
+	if len(applied) != 2 || applied[0].Version != 1 || applied[1].Version != 2 {
+		t.Errorf("unexpected applied migrations: %+v", applied)
+	}
+	if count := userCount(t, db); count != 1 {
+		t.Errorf("expected 1 user, got %d", count)
+	}
+	applied, err = migrator.Up(ctx)
+	if err != nil || len(applied) != 0 {
+		t.Errorf("expected no pending migrations, got %+v, %v", applied, err)
+	}
+
+	statuses, err := migrator.Status(ctx)
+	if err != nil {
+		t.Fatalf("Status: %v", err)
+	}
+	for _, status := range statuses {
+		if !status.Applied || status.AppliedAt.IsZero() {
+			t.Errorf("expected migration %d to be applied", status.Migration.Version)
+		}
+	}
+
+	rolledBack, err := migrator.Down(ctx, 1)
+	if err != nil {
+		t.Fatalf("Down: %v", err)
+	}
+	if len(rolledBack) != 1 || rolledBack[0].Version != 2 {
+		t.Errorf("unexpected rolled back migrations: %+v", rolledBack)
+	}
+	if count := userCount(t, db); count != 0 {
+		t.Errorf("expected 0 users, got %d", count)
+	}
+	var locks int
+	if err := db.QueryRow("SELECT COUNT(*) FROM schema_migrations_lock").Scan(&locks); err != nil || locks != 0 {
+		t.Errorf("expected the lock to be released, got %d, %v", locks, err)
+	}
+}
+
+func TestMigratorChecksum(t *testing.T) {
+	ctx := context.Background()
+	db := testDB(t)
+	migrator, _ := NewMigrator(db, &sqlite.Query{}, testMigrations(), Options{})
+	if _, err := migrator.Up(ctx); err != nil {
+		t.Fatalf("Up: %v", err)
+	}
+
+	changed := testMigrations()
+	changed[1].UpSQL = "CREATE TABLE users (id INTEGER PRIMARY KEY);"
+	migrator, _ = NewMigrator(db, &sqlite.Query{}, changed, Options{})
+	_, err := migrator.Up(ctx)
+	expectAPIError(t, err, ChecksumMismatchError.ID)
+
+	migrator, _ = NewMigrator(db, &sqlite.Query{}, testMigrations()[1:], Options{})
+	_, err = migrator.Up(ctx)
+	expectAPIError(t, err, UnknownMigrationError.ID)
+}
+
+func TestMigratorDryRun(t *testing.T) {
+	ctx := context.Background()
+	db := testDB(t)
+	var output bytes.Buffer
+	migrator, _ := NewMigrator(
+		db, &sqlite.Query{}, testMigrations(), Options{DryRun: true, Output: &output},
+	)
+	applied, err := migrator.Up(ctx)
+	if err != nil {
+		t.Fatalf("Up: %v", err)
+	}
+	if len(applied) != 2 {
+		t.Errorf("expected 2 planned migrations, got %d", len(applied))
+	}
+	expected := "-- 1 create_users (up)\n" +
+		"CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT);\n" +
+		"-- 2 add_admin (up)\n" +
+		"-- Go migration\n"
+	if output.String() != expected {
+		t.Errorf("expected output %q, got %q", expected, output.String())
+	}
+	var tables int
+	if err := db.QueryRow("SELECT COUNT(*) FROM sqlite_master").Scan(&tables); err != nil || tables != 0 {
+		t.Errorf("expected no tables, got %d, %v", tables, err)
+	}
+}
+
+func TestMigratorLock(t *testing.T) {
+	db := testDB(t)
+	if _, err := db.Exec(
+		"CREATE TABLE schema_migrations_lock (id INTEGER PRIMARY KEY, locked_at BIGINT)",
+	); err != nil {
+		t.Fatalf("create lock table: %v", err)
+	}
+	if _, err := db.Exec("INSERT INTO schema_migrations_lock VALUES (1, 0)"); err != nil {
+		t.Fatalf("lock: %v", err)
+	}
+	migrator, _ := NewMigrator(
+		db, &sqlite.Query{}, testMigrations(), Options{LockTimeout: 150 * time.Millisecond},
+	)
+	_, err := migrator.Up(context.Background())
+	expectAPIError(t, err, LockTimeoutError.ID)
+}
+
+// advisoryDialect is a dialect whose advisory lock is taken on the given
+// attempt. The attempts are counted in the lock_attempts table. The lock is
+// never taken if the attempt is 0.
+type advisoryDialect struct {
+	*sqlite.Query
+	lockedAttempt int
+}
+
+func (d *advisoryDialect) AdvisoryLock(
+	lockName string, timeout int,
+) (string, []any, error) {
+	return "UPDATE lock_attempts SET n = n + 1" +
+		" RETURNING ? != 0 AND n >= ?", []any{d.lockedAttempt, d.lockedAttempt}, nil
+}
+
+func (d *advisoryDialect) AdvisoryUnlock(lockName string) (string, []any, error) {
+	return "SELECT 1", nil, nil
+}
+
+// advisoryDB returns a database with the lock_attempts table of
+// advisoryDialect and a function that returns the number of attempts.
+func advisoryDB(t *testing.T) (*sql.DB, func() int) {
+	t.Helper()
+	db := testDB(t)
+	for _, statement := range []string{
+		"CREATE TABLE lock_attempts (n INTEGER)",
+		"INSERT INTO lock_attempts VALUES (0)",
+	} {
+		if _, err := db.Exec(statement); err != nil {
+			t.Fatalf("exec %q: %v", statement, err)
+		}
+	}
+	return db, func() int {
+		var attempts int
+		err := db.QueryRow("SELECT n FROM lock_attempts").Scan(&attempts)
+		if err != nil {
+			t.Fatalf("count attempts: %v", err)
+		}
+		return attempts
+	}
+}
+
+func TestMigratorAdvisoryLock(t *testing.T) {
+	db, attempts := advisoryDB(t)
+	migrator, _ := NewMigrator(
+		db,
+		&advisoryDialect{Query: &sqlite.Query{}, lockedAttempt: 3},
+		testMigrations(),
+		Options{LockTimeout: 10 * time.Second},
+	)
+	if _, err := migrator.Up(context.Background()); err != nil {
+		t.Fatalf("Up: %v", err)
+	}
+	if attempts() != 3 {
+		t.Errorf("expected the lock to be polled 3 times, got %d", attempts())
+	}
+
+	db, attempts = advisoryDB(t)
+	migrator, _ = NewMigrator(
+		db,
+		&advisoryDialect{Query: &sqlite.Query{}},
+		testMigrations(),
+		Options{LockTimeout: 150 * time.Millisecond},
+	)
+	_, err := migrator.Up(context.Background())
+	expectAPIError(t, err, LockTimeoutError.ID)
+	if attempts() < 2 {
+		t.Errorf("expected the lock to be polled, got %d attempts", attempts())
+	}
+}
+
+// tableLockDialect is the PostgreSQL dialect without advisory locks. SQLite
+// accepts its double-quoted identifiers and $1 placeholders, so the queries
+// of the migrator are checked to follow the dialect.
+type tableLockDialect struct {
+	*postgres.Query
+}
+
+func (d *tableLockDialect) AdvisoryLock(
+	lockName string, timeout int,
+) (string, []any, error) {
+	return "", nil, nil
+}
+
+func (d *tableLockDialect) AdvisoryUnlock(lockName string) (string, []any, error) {
+	return "", nil, nil
+}
+
+func TestMigratorDialectQueries(t *testing.T) {
+	ctx := context.Background()
+	db := testDB(t)
+	migrator, err := NewMigrator(
+		db,
+		&tableLockDialect{Query: &postgres.Query{}},
+		[]Migration{{
+			Version: 1,
+			Name:    "create_users",
+			UpSQL: "CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT);" +
+				" CREATE TABLE audit (name TEXT);" +
+				" CREATE TRIGGER users_audit AFTER INSERT ON users BEGIN" +
+				" INSERT INTO audit VALUES (NEW.name); END;",
+			DownSQL: "DROP TABLE users; DROP TABLE audit;",
+		}},
+		Options{},
+	)
+	if err != nil {
+		t.Fatalf("NewMigrator: %v", err)
+	}
+	if _, err := migrator.Up(ctx); err != nil {
+		t.Fatalf("Up: %v", err)
+	}
+	if _, err := db.Exec("INSERT INTO users (name) VALUES ('a')"); err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+	var audited int
+	if err := db.QueryRow("SELECT COUNT(*) FROM audit").Scan(&audited); err != nil ||
+		audited != 1 {
+		t.Errorf("expected the trigger to audit the insert, got %d %v", audited, err)
+	}
+	statuses, err := migrator.Status(ctx)
+	if err != nil || len(statuses) != 1 || !statuses[0].Applied {
+		t.Errorf("expected the migration to be applied, got %v %v", statuses, err)
+	}
+	rolledBack, err := migrator.Down(ctx, 1)
+	if err != nil || len(rolledBack) != 1 {
+		t.Fatalf("expected the migration to be rolled back, got %v %v",
+			rolledBack, err)
+	}
+	var locks int
+	if err := db.QueryRow(
+		"SELECT COUNT(*) FROM schema_migrations_lock",
+	).Scan(&locks); err != nil || locks != 0 {
+		t.Errorf("expected the lock to be released, got %d %v", locks, err)
+	}
+}
+
+func TestMigratorLockError(t *testing.T) {
+	db := testDB(t)
+	if _, err := db.Exec(
+		"CREATE TABLE schema_migrations_lock" +
+			" (id INTEGER PRIMARY KEY, locked_at BIGINT, owner TEXT NOT NULL)",
+	); err != nil {
+		t.Fatalf("create lock table: %v", err)
+	}
+	migrator, _ := NewMigrator(
+		db, &sqlite.Query{}, testMigrations(), Options{LockTimeout: 10 * time.Second},
+	)
+	start := time.Now()
+	_, err := migrator.Up(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "NOT NULL") {
+		t.Errorf("expected the insert error, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("expected the error without retries, took %v", elapsed)
+	}
+}
+
+func TestNewMigrator(t *testing.T) {
+	tests := []struct {
+		migrations []Migration
+		message    string
+	}{
+		{[]Migration{{Name: "a", UpSQL: "SELECT 1"}}, "no positive version"},
+		{
+			[]Migration{{Version: 1, UpSQL: "SELECT 1"}, {Version: 1, UpSQL: "SELECT 2"}},
+			"duplicate migration version 1",
+		},
+		{[]Migration{{Version: 1}}, "either up SQL or an up function"},
+	}
+	for _, tt := range tests {
+		_, err := NewMigrator(nil, &sqlite.Query{}, tt.migrations, Options{})
+		if err == nil || !strings.Contains(err.Error(), tt.message) {
+			t.Errorf("expected error %q, got %v", tt.message, err)
+		}
+	}
+}
@@ -0,0 +1,227 @@
+package migrate
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// fileNamePattern matches the names of migration files, e.g.
+// "0001_create_users.up.sql".
+var fileNamePattern = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// FromFS reads SQL migrations from a directory of a file system, such as an
+// embedded one. The files are named "<version>_<name>.up.sql" and
+// "<version>_<name>.down.sql", and the down file is optional. Other than .sql
+// files are ignored.
+//
+// Example:
+//
+//	//go:embed migrations/*.sql
+//	var migrationFiles embed.FS
+//
+//	migrations, err := migrate.FromFS(migrationFiles, "migrations")
+//
+// Parameters:
+//   - fsys: The file system.
+//   - dir: The directory of the migration files.
+//
+// Returns:
+//   - []Migration: The migrations ordered by version.
+//   - error: An error if the files can not be read or are not named properly.
+func FromFS(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, fmt.Errorf("FromFS: %w", err)
+	}
+	byVersion := map[int64]*Migration{}
+	var versions []int64
+	for _, entry := range entries {
+		if entry.IsDir() || path.Ext(entry.Name()) != ".sql" {
+			continue
+		}
+		match := fileNamePattern.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf(
+				"FromFS: invalid migration file name %q", entry.Name(),
+			)
+		}
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("FromFS: %w", err)
+		}
+		content, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("FromFS: %w", err)
+		}
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = migration
+			versions = append(versions, version)
+		}
+		if migration.Name != match[2] {
+			return nil, fmt.Errorf(
+				"FromFS: migration %d has the names %q and %q",
+				version,
+				migration.Name,
+				match[2],
+			)
+		}
+		if match[3] == "up" {
+			migration.UpSQL = string(content)
+		} else {
+			migration.DownSQL = string(content)
+		}
+	}
+	// The entries are sorted by file name, which might not be the version
+	// order if the versions are not zero padded.
+	migrations := make([]Migration, 0, len(versions))
+	for _, version := range versions {
+		migration := byVersion[version]
+		if strings.TrimSpace(migration.UpSQL) == "" {
+			return nil, fmt.Errorf(
+				"FromFS: migration %d has no up statements", version,
+			)
+		}
+		migrations = append(migrations, *migration)
+	}
+	sortMigrations(migrations)
+	return migrations, nil
+}
+
+// dollarQuotePattern matches the opening tag of a PostgreSQL dollar-quoted
+// string, e.g. "$$" or "$body$".
+var dollarQuotePattern = regexp.MustCompile(`^\$([A-Za-z_][A-Za-z0-9_]*)?\$`)
+
+// splitStatements splits SQL into statements at the semicolons that are not
+// in quotes, comments or blocks. The comments are removed and empty
+// statements are skipped. A block is a BEGIN ... END body, such as the body of
+// a trigger, or a CASE ... END expression, and blocks can be nested. The
+// bodies of PostgreSQL functions are kept whole if they are dollar-quoted.
+func splitStatements(sql string) []string {
+	var statements []string
+	var statement strings.Builder
+	depth := 0
+	previous := ""
+	flush := func() {
+		if text := strings.TrimSpace(statement.String()); text != "" {
+			statements = append(statements, text)
+		}
+		statement.Reset()
+		depth = 0
+		previous = ""
+	}
+	for i := 0; i < len(sql); i++ {
+		c := sql[i]
+		switch {
+		case c == '\'' || c == '"' || c == '`':
+			end := i + 1
+			for end < len(sql) && sql[end] != c {
+				if sql[end] == '\\' && c != '`' {
+					end++
+				}
+				end++
+			}
+			end = min(end, len(sql)-1)
+			statement.WriteString(sql[i : end+1])
+			i = end
+		case c == '$' && (i == 0 || !isWordByte(sql[i-1])) &&
+			dollarQuotePattern.MatchString(sql[i:]):
+			tag := dollarQuotePattern.FindString(sql[i:])
+			end := strings.Index(sql[i+len(tag):], tag)
+			if end < 0 {
+				end = len(sql)
+			} else {
+				end += i + 2*len(tag)
+			}
+			statement.WriteString(sql[i:end])
+			i = end - 1
+		case c == '-' && strings.HasPrefix(sql[i:], "--"):
+			end := strings.IndexByte(sql[i:], '\n')
+			if end < 0 {
+				i = len(sql)
+			} else {
+				i += end - 1
+			}
+		case c == '/' && strings.HasPrefix(sql[i:], "/*"):
+			end := strings.Index(sql[i+2:], "*/")
+			if end < 0 {
+				i = len(sql)
+			} else {
+				i += end + 3
+			}
+			statement.WriteByte(' ')
+		case isWordByte(c) && (i == 0 || !isWordByte(sql[i-1])):
+			end := i + 1
+			for end < len(sql) && isWordByte(sql[end]) {
+				end++
+			}
+			word := strings.ToUpper(sql[i:end])
+			depth = blockDepth(depth, previous, word, sql[end:])
+			previous = word
+			statement.WriteString(sql[i:end])
+			i = end - 1
+		case c == ';' && depth == 0:
+			flush()
+		default:
+			statement.WriteByte(c)
+		}
+	}
+	flush()
+	return statements
+}
+
+// blockDepth returns the block depth after a word. BEGIN starts a block
+// unless it starts a transaction, CASE starts a block unless it ends an END
+// CASE statement, and END ends a block. END IF, END LOOP, END WHILE and END
+// REPEAT end statements that do not start blocks, so they do not end one.
+//
+// Parameters:
+//   - depth: The block depth before the word.
+//   - previous: The previous word in upper case.
+//   - word: The word in upper case.
+//   - rest: The SQL after the word.
+//
+// Returns:
+//   - int: The block depth after the word.
+func blockDepth(depth int, previous string, word string, rest string) int {
+	switch word {
+	case "BEGIN":
+		next := strings.TrimSpace(rest)
+		for _, prefix := range []string{
+			";", "TRANSACTION", "WORK", "DEFERRED", "IMMEDIATE", "EXCLUSIVE",
+			"ISOLATION", "READ",
+		} {
+			if len(next) >= len(prefix) &&
+				strings.EqualFold(next[:len(prefix)], prefix) {
+				return depth
+			}
+		}
+		if next == "" {
+			return depth
+		}
+		return depth + 1
+	case "CASE":
+		if previous == "END" {
+			return depth
+		}
+		return depth + 1
+	case "END":
+		return max(depth-1, 0)
+	case "IF", "LOOP", "WHILE", "REPEAT":
+		if previous == "END" {
+			return depth + 1
+		}
+	}
+	return depth
+}
+
+// isWordByte reports whether a byte is part of an unquoted word.
+func isWordByte(c byte) bool {
+	return c == '_' || c == '$' || c >= '0' && c <= '9' ||
+		c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= 0x80
+}
@@ -0,0 +1,93 @@
+package migrate
+
+import (
+	"reflect"
+	"testing"
+	"testing/fstest"
+)
+
+func TestFromFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"migrations/10_add_index.up.sql":     {Data: []byte("CREATE INDEX a ON users (name);")},
+		"migrations/2_create_users.up.sql":   {Data: []byte("CREATE TABLE users (name TEXT);")},
+		"migrations/2_create_users.down.sql": {Data: []byte("DROP TABLE users;")},
+		"migrations/README.md":               {Data: []byte("docs")},
+		"migrations/subdir/3_ignored.up.sql": {Data: []byte("SELECT 1;")},
+	}
+	migrations, err := FromFS(fsys, "migrations")
+	if err != nil {
+		t.Fatalf("FromFS: %v", err)
+	}
+	expected := []Migration{
+		{
+			Version: 2,
+			Name:    "create_users",
+			UpSQL:   "CREATE TABLE users (name TEXT);",
+			DownSQL: "DROP TABLE users;",
+		},
+		{Version: 10, Name: "add_index", UpSQL: "CREATE INDEX a ON users (name);"},
+	}
+	if !reflect.DeepEqual(migrations, expected) {
+		t.Errorf("expected %+v, got %+v", expected, migrations)
+	}
+
+	for name, fsys := range map[string]fstest.MapFS{
+		"invalid name": {"m/create.up.sql": {Data: []byte("SELECT 1;")}},
+		"missing up":   {"m/1_a.down.sql": {Data: []byte("SELECT 1;")}},
+		"name mismatch": {
+			"m/1_a.up.sql":   {Data: []byte("SELECT 1;")},
+			"m/1_b.down.sql": {Data: []byte("SELECT 1;")},
+		},
+	} {
+		if _, err := FromFS(fsys, "m"); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestSplitStatements(t *testing.T) {
+	sql := `-- create the table
+CREATE TABLE a (b TEXT DEFAULT 'x;y', c TEXT DEFAULT 'it''s; \'ok\'');
+/* a; comment */ INSERT INTO a (b) VALUES ("1;2");
+INSERT INTO ` + "`a;b`" + ` VALUES (1) -- trailing; comment
+;
+;`
+	expected := []string{
+		`CREATE TABLE a (b TEXT DEFAULT 'x;y', c TEXT DEFAULT 'it''s; \'ok\'')`,
+		`INSERT INTO a (b) VALUES ("1;2")`,
+		"INSERT INTO `a;b` VALUES (1)",
+	}
+	if statements := splitStatements(sql); !reflect.DeepEqual(statements, expected) {
+		t.Errorf("expected %q, got %q", expected, statements)
+	}
+}
+
+func TestSplitStatementsBlocks(t *testing.T) {
+	sql := `CREATE TRIGGER a_insert AFTER INSERT ON a
+BEGIN
+  UPDATE b SET n = CASE WHEN n > 0 THEN n + 1 ELSE 1 END;
+  INSERT INTO c VALUES ('end;');
+END;
+BEGIN TRANSACTION;
+CREATE PROCEDURE p() BEGIN IF 1 THEN SELECT 1; END IF; END;
+CREATE FUNCTION f() RETURNS trigger AS $body$
+BEGIN
+  NEW.n := $1;
+  RETURN NEW;
+END;
+$body$ LANGUAGE plpgsql;
+SELECT CASE WHEN 1 THEN 2 END;`
+	expected := []string{
+		"CREATE TRIGGER a_insert AFTER INSERT ON a\nBEGIN\n" +
+			"  UPDATE b SET n = CASE WHEN n > 0 THEN n + 1 ELSE 1 END;\n" +
+			"  INSERT INTO c VALUES ('end;');\nEND",
+		"BEGIN TRANSACTION",
+		"CREATE PROCEDURE p() BEGIN IF 1 THEN SELECT 1; END IF; END",
+		"CREATE FUNCTION f() RETURNS trigger AS $body$\nBEGIN\n" +
+			"  NEW.n := $1;\n  RETURN NEW;\nEND;\n$body$ LANGUAGE plpgsql",
+		"SELECT CASE WHEN 1 THEN 2 END",
+	}
+	if statements := splitStatements(sql); !reflect.DeepEqual(statements, expected) {
+		t.Errorf("expected %q, got %q", expected, statements)
+	}
+}
@@ -0,0 +1,54 @@
+package mysql
+
+import (
+	"testing"
+
+	"github.com/pakkasys/fluidapi-extended/database/querytest"
+)
+
+// golden holds the expected queries of the conformance cases. The queries are
+// not executed since the tests have no MySQL server, so only their SQL text
+// is checked.
+var golden = querytest.Golden{
+	"insert": "INSERT INTO `users` (`id`, `name`, `age`, `group_id`)" +
+		" VALUES (?, ?, ?, ?)",
+	"insert_many": "INSERT INTO `users` (`id`, `name`, `age`, `group_id`)" +
+		" VALUES (?, ?, ?, ?), (?, ?, ?, ?)",
+	"upsert": "INSERT INTO `users` (`id`, `name`, `age`)" +
+		" VALUES (?, ?, ?) ON DUPLICATE KEY UPDATE" +
+		" `age` = IF(`name` <=> VALUES(`name`), VALUES(`age`), `age`)",
+	"upsert/do_nothing": "INSERT INTO `users` (`id`, `name`, `age`)" +
+		" VALUES (?, ?, ?) ON DUPLICATE KEY UPDATE `id` = `id`",
+	"upsert/options": "INSERT INTO `users` (`id`, `name`, `age`, `group_id`)" +
+		" VALUES (?, ?, ?, ?), (?, ?, ?, ?)" +
+		" ON DUPLICATE KEY UPDATE `name` = VALUES(`name`), `age` = VALUES(`age`)",
+	"upsert_many": "INSERT INTO `users` (`id`, `name`, `age`)" +
+		" VALUES (?, ?, ?), (?, ?, ?) ON DUPLICATE KEY UPDATE" +
+		" `age` = IF(`name` <=> VALUES(`name`), VALUES(`age`), `age`)",
+	"get/selectors": "SELECT * FROM `users`" +
+		" WHERE `users`.`age` >= ? AND `group_id` = ?",
+	"get/null": "SELECT `id` FROM `users` WHERE `users`.`age` IS NULL",
+	"get/not_null": "SELECT `id` FROM `users`" +
+		" WHERE `age` IS NOT NULL" +
+		" ORDER BY `id` ASC",
+	"get/in": "SELECT `users`.`id` FROM `users`" +
+		" WHERE `users`.`id` IN (?,?) AND `name` NOT IN (?) AND `group_id` IN (?)",
+	"get/join": "SELECT `users`.`name`,`groups`.`name` AS `group_name` FROM `users`" +
+		" INNER JOIN `groups` ON `groups`.`id` = `users`.`group_id`" +
+		" ORDER BY `users`.`id` ASC",
+	"get/orders_page": "SELECT `name` FROM `users`" +
+		" ORDER BY `name` DESC" +
+		" LIMIT 2 OFFSET 1",
+	"get/lock":     "SELECT `name` FROM `users` WHERE `id` = ? FOR UPDATE",
+	"count":        "SELECT COUNT(*) FROM `users` WHERE `users`.`age` > ?",
+	"count/page":   "SELECT COUNT(*) FROM (SELECT * FROM `users` LIMIT 5 OFFSET 1) AS limited_result",
+	"update":       "UPDATE `users` SET `age` = ? WHERE `users`.`id` = ?",
+	"delete":       "DELETE FROM `users` WHERE `users`.`age` < ?",
+	"delete/limit": "DELETE FROM `users` WHERE `id` > ? ORDER BY `id` ASC LIMIT 1",
+}
+
+// TestConformanceSQLText checks the SQL text of the conformance cases. Unlike
+// the SQLite cases, the queries are not executed.
+func TestConformanceSQLText(t *testing.T) {
+	querytest.Run(t, &Query{}, querytest.Options{Golden: golden})
+}
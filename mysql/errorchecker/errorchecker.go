@@ -3,9 +3,13 @@ package errorchecker
 import (
 	"database/sql"
 	"errors"
+	"regexp"
+	"slices"
+	"strings"
 
 	"github.com/go-sql-driver/mysql"
 	"github.com/pakkasys/fluidapi-extended/database"
+	"github.com/pakkasys/fluidapi/core"
 )
 
 // MySQLErrorCode represents a MySQL error code.
@@ -13,19 +17,86 @@ type MySQLErrorCode uint16
 
 // Constants for MySQL error codes.
 const (
-	ForeignConstraintErrorCode MySQLErrorCode = 1452
-	DuplicateEntryErrorCode    MySQLErrorCode = 1062
+	ForeignConstraintErrorCode       MySQLErrorCode = 1452
+	DuplicateEntryErrorCode          MySQLErrorCode = 1062
+	BadNullErrorCode                 MySQLErrorCode = 1048
+	NoDefaultForFieldErrorCode       MySQLErrorCode = 1364
+	CheckConstraintErrorCode         MySQLErrorCode = 3819
+	DataTooLongErrorCode             MySQLErrorCode = 1406
+	WarnDataOutOfRangeErrorCode      MySQLErrorCode = 1264
+	DataOutOfRangeErrorCode          MySQLErrorCode = 1690
+	DeadlockErrorCode                MySQLErrorCode = 1213
+	LockWaitTimeoutErrorCode         MySQLErrorCode = 1205
+	OptionPreventsStatementErrorCode MySQLErrorCode = 1290
+	ReadOnlyTransactionErrorCode     MySQLErrorCode = 1792
+	ReadOnlyModeErrorCode            MySQLErrorCode = 1836
 )
 
+// errorsByCode are the API errors of the MySQL error codes. Error 1290 is
+// classified by its message, see apiError.
+var errorsByCode = map[MySQLErrorCode]*core.APIError{
+	DuplicateEntryErrorCode:      database.DuplicateEntryError,
+	ForeignConstraintErrorCode:   database.ForeignConstraintError,
+	BadNullErrorCode:             database.NotNullViolationError,
+	NoDefaultForFieldErrorCode:   database.NotNullViolationError,
+	CheckConstraintErrorCode:     database.CheckConstraintError,
+	DataTooLongErrorCode:         database.DataTooLongError,
+	WarnDataOutOfRangeErrorCode:  database.OutOfRangeError,
+	DataOutOfRangeErrorCode:      database.OutOfRangeError,
+	DeadlockErrorCode:            database.DeadlockError,
+	LockWaitTimeoutErrorCode:     database.LockTimeoutError,
+	ReadOnlyTransactionErrorCode: database.ReadOnlyDatabaseError,
+	ReadOnlyModeErrorCode:        database.ReadOnlyDatabaseError,
+}
+
 // ErrorChecker is a MySQL error checker.
-type ErrorChecker struct{}
+type ErrorChecker struct {
+	systemId string
+	tables   map[string]*database.TableDefinition
+}
 
 // NewErrorChecker returns a new ErrorChecker.
 func NewErrorChecker() *ErrorChecker {
 	return &ErrorChecker{}
 }
 
-// Check is a function used to check if an error is a MySQL error.
+// WithOrigin sets the origin of the checked errors.
+//
+// Parameters:
+//   - systemId: The origin of the checked errors.
+//
+// Returns:
+//   - *ErrorChecker: The ErrorChecker.
+func (c *ErrorChecker) WithOrigin(systemId string) *ErrorChecker {
+	c.systemId = systemId
+	return c
+}
+
+// WithTableDefinition adds the definition of a table whose unique keys,
+// including the primary key, are mapped to their columns in the duplicate
+// entry errors. MySQL only reports the name of the violated key, so the
+// columns of the keys of the other tables are not known.
+//
+// Parameters:
+//   - tableName: The name of the table.
+//   - definition: The definition of the table.
+//
+// Returns:
+//   - *ErrorChecker: The ErrorChecker.
+func (c *ErrorChecker) WithTableDefinition(
+	tableName string, definition *database.TableDefinition,
+) *ErrorChecker {
+	if c.tables == nil {
+		c.tables = map[string]*database.TableDefinition{}
+	}
+	c.tables[tableName] = definition
+	return c
+}
+
+// Check is a function used to check if an error is a MySQL error. The known
+// MySQL errors are returned as API errors with the MySQL error as data. The
+// data of the duplicate entry and foreign key errors is a ConstraintViolation
+// that wraps the MySQL error.
 //
 // Parameters:
 //   - err: The error to check.
@@ -33,22 +104,135 @@ func NewErrorChecker() *ErrorChecker {
 // Returns:
 //   - error: The checked error.
 func (c *ErrorChecker) Check(err error) error {
-	if isMySQLErrorCode(err, DuplicateEntryErrorCode) {
-		return database.DuplicateEntryError.WithData(err)
-	} else if isMySQLErrorCode(err, ForeignConstraintErrorCode) {
-		return database.ForeignConstraintError.WithData(err)
-	} else if err == sql.ErrNoRows {
-		return database.NoRowsError
+	if err == nil {
+		return nil
+	}
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		if apiErr, ok := apiError(mysqlErr); ok {
+			return apiErr.WithData(c.errorData(mysqlErr, err)).
+				WithOrigin(c.systemId)
+		}
+	} else if errors.Is(err, sql.ErrNoRows) {
+		return database.NoRowsError.WithOrigin(c.systemId)
 	}
 	return err
 }
 
-// isMySQLErrorCode checks whether 'err' is a mysql.MySQLError and if its Number
-// matches the desired code.
-func isMySQLErrorCode(err error, code MySQLErrorCode) bool {
-	var mysqlErr *mysql.MySQLError
-	if errors.As(err, &mysqlErr) {
-		return mysqlErr.Number == uint16(code)
+// apiError returns the API error of a MySQL error. Error 1290 is returned for
+// any server option that prevents a statement, e.g. --secure-file-priv, so it
+// is a read-only error only if the option is --read-only or --super-read-only.
+func apiError(mysqlErr *mysql.MySQLError) (*core.APIError, bool) {
+	code := MySQLErrorCode(mysqlErr.Number)
+	if code == OptionPreventsStatementErrorCode {
+		readOnly := strings.Contains(mysqlErr.Message, "--read-only") ||
+			strings.Contains(mysqlErr.Message, "--super-read-only")
+		return database.ReadOnlyDatabaseError, readOnly
+	}
+	apiErr, ok := errorsByCode[code]
+	return apiErr, ok
+}
+
+// foreignKeyPattern matches the table, the constraint and the columns of a
+// foreign key in the message of a foreign key error, e.g. "... (`db`.`orders`,
+// CONSTRAINT `fk_orders_user_id` FOREIGN KEY (`user_id`) REFERENCES ...".
+var foreignKeyPattern = regexp.MustCompile(
+	"`([^`]*)`, CONSTRAINT `([^`]*)` FOREIGN KEY \\(([^)]*)\\)",
+)
+
+// errorData returns the data of the API error of a MySQL error. The
+// constraint errors are described by a ConstraintViolation and the other
+// errors are returned as is.
+func (c *ErrorChecker) errorData(mysqlErr *mysql.MySQLError, err error) any {
+	switch MySQLErrorCode(mysqlErr.Number) {
+	case DuplicateEntryErrorCode:
+		return c.duplicateEntryViolation(mysqlErr.Message, err)
+	case ForeignConstraintErrorCode:
+		return foreignKeyViolation(mysqlErr.Message, err)
+	default:
+		return err
+	}
+}
+
+// duplicateEntryViolation parses a duplicate entry message, e.g. "Duplicate
+// entry 'a' for key 'users.uq_users_email'". MySQL only reports the name of
+// the key and, since 8.0.19, its table, so the columns are known only for the
+// keys of the table definitions added with WithTableDefinition.
+func (c *ErrorChecker) duplicateEntryViolation(
+	message string, err error,
+) *database.ConstraintViolation {
+	violation := &database.ConstraintViolation{Err: err}
+	i := strings.LastIndex(message, " for key '")
+	if i < 0 {
+		return violation
+	}
+	key := strings.TrimSuffix(message[i+len(" for key '"):], "'")
+	if table, name, ok := strings.Cut(key, "."); ok {
+		violation.Table = table
+		key = name
+	}
+	violation.Key = key
+	if violation.Table != "" {
+		if definition, ok := c.tables[violation.Table]; ok {
+			violation.Columns, _ = keyColumns(definition, key)
+		}
+		return violation
+	}
+	violation.Table, violation.Columns = c.findKey(key)
+	return violation
+}
+
+// findKey finds a key by its name alone in the table definitions, since MySQL
+// reports the table of the key only since 8.0.19. The table is returned if
+// only one table has a key of the name, and the columns if all the keys of
+// the name have the same columns, e.g. the primary keys named "id".
+func (c *ErrorChecker) findKey(key string) (string, []string) {
+	var tables []string
+	var columns []string
+	for table, definition := range c.tables {
+		keyColumns, ok := keyColumns(definition, key)
+		if !ok {
+			continue
+		}
+		if len(tables) != 0 && !slices.Equal(columns, keyColumns) {
+			return "", nil
+		}
+		tables = append(tables, table)
+		columns = keyColumns
+	}
+	if len(tables) != 1 {
+		return "", columns
+	}
+	return tables[0], columns
+}
+
+// keyColumns returns the columns of a unique key of a table. MySQL names the
+// primary key PRIMARY.
+func keyColumns(
+	definition *database.TableDefinition, key string,
+) ([]string, bool) {
+	if key == "PRIMARY" {
+		return definition.PrimaryKeyColumns()
+	}
+	return definition.UniqueKeyColumns(key)
+}
+
+// foreignKeyViolation parses a foreign key error message, which includes the
+// definition of the violated foreign key.
+func foreignKeyViolation(
+	message string, err error,
+) *database.ConstraintViolation {
+	violation := &database.ConstraintViolation{Err: err}
+	match := foreignKeyPattern.FindStringSubmatch(message)
+	if match == nil {
+		return violation
+	}
+	violation.Table = match[1]
+	violation.Key = match[2]
+	for _, column := range strings.Split(match[3], ", ") {
+		violation.Columns = append(
+			violation.Columns, strings.Trim(column, "`"),
+		)
 	}
-	return false
+	return violation
 }
@@ -0,0 +1,157 @@
+package errorchecker
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/pakkasys/fluidapi-extended/database"
+	"github.com/pakkasys/fluidapi/core"
+)
+
+func TestCheck(t *testing.T) {
+	checker := NewErrorChecker().WithOrigin("db")
+	tests := []struct {
+		err      error
+		expected *core.APIError
+	}{
+		{&mysql.MySQLError{Number: 1062}, database.DuplicateEntryError},
+		{fmt.Errorf("insert: %w", &mysql.MySQLError{Number: 1452}), database.ForeignConstraintError},
+		{&mysql.MySQLError{Number: 1048}, database.NotNullViolationError},
+		{&mysql.MySQLError{Number: 1364}, database.NotNullViolationError},
+		{&mysql.MySQLError{Number: 3819}, database.CheckConstraintError},
+		{&mysql.MySQLError{Number: 1406}, database.DataTooLongError},
+		{&mysql.MySQLError{Number: 1264}, database.OutOfRangeError},
+		{&mysql.MySQLError{Number: 1213}, database.DeadlockError},
+		{&mysql.MySQLError{Number: 1205}, database.LockTimeoutError},
+		{&mysql.MySQLError{Number: 1836}, database.ReadOnlyDatabaseError},
+		{&mysql.MySQLError{Number: 1290, Message: "The MySQL server is running with the --read-only option so it cannot execute this statement"}, database.ReadOnlyDatabaseError},
+		{&mysql.MySQLError{Number: 1290, Message: "The MySQL server is running with the --super-read-only option so it cannot execute this statement"}, database.ReadOnlyDatabaseError},
+		{sql.ErrNoRows, database.NoRowsError},
+		{fmt.Errorf("get: %w", sql.ErrNoRows), database.NoRowsError},
+	}
+	for _, tt := range tests {
+		var apiErr *core.APIError
+		if !errors.As(checker.Check(tt.err), &apiErr) ||
+			apiErr.ID != tt.expected.ID || apiErr.Origin != "db" {
+			t.Errorf("expected %s for %v, got %v", tt.expected.ID, tt.err, apiErr)
+		}
+	}
+
+	for _, err := range []error{
+		&mysql.MySQLError{Number: 1146},
+		&mysql.MySQLError{Number: 1290, Message: "The MySQL server is running with the --secure-file-priv option so it cannot execute this statement"},
+		errors.New("other"),
+	} {
+		if checked := checker.Check(err); checked != err {
+			t.Errorf("expected %v to be returned as is, got %v", err, checked)
+		}
+	}
+	if checker.Check(nil) != nil {
+		t.Errorf("expected nil")
+	}
+
+	var apiErr *core.APIError
+	err := NewErrorChecker().Check(&mysql.MySQLError{Number: 1062})
+	if !errors.As(err, &apiErr) || apiErr.Origin != "" {
+		t.Errorf("expected an error without an origin, got %v", err)
+	}
+}
+
+func TestCheckConstraintViolation(t *testing.T) {
+	users, err := database.StructTableDefinition(
+		"users",
+		struct {
+			ID    int64  `db:"id" dbdef:"pk"`
+			Code  string `db:"code" dbdef:"unique"`
+			Email string `db:"email" dbdef:"unique_index"`
+			Name  string `db:"name" dbdef:"index"`
+		}{},
+		func(goType string) (string, bool) { return "VARCHAR(255)", true },
+	)
+	if err != nil {
+		t.Fatalf("StructTableDefinition: %v", err)
+	}
+	memberships, err := database.StructTableDefinition(
+		"memberships",
+		struct {
+			UserID  int64 `db:"user_id" dbdef:"pk"`
+			GroupID int64 `db:"group_id" dbdef:"pk"`
+		}{},
+		func(goType string) (string, bool) { return "BIGINT", true },
+	)
+	if err != nil {
+		t.Fatalf("StructTableDefinition: %v", err)
+	}
+	checker := NewErrorChecker().WithOrigin("db").
+		WithTableDefinition("users", users).
+		WithTableDefinition("memberships", memberships)
+	tests := []struct {
+		err      *mysql.MySQLError
+		expected database.ConstraintViolation
+	}{
+		{
+			&mysql.MySQLError{Number: 1062, Message: "Duplicate entry 'a' for key 'users.uq_users_email'"},
+			database.ConstraintViolation{Table: "users", Key: "uq_users_email", Columns: []string{"email"}},
+		},
+		{
+			&mysql.MySQLError{Number: 1062, Message: "Duplicate entry 'a' for key 'users.code'"},
+			database.ConstraintViolation{Table: "users", Key: "code", Columns: []string{"code"}},
+		},
+		{
+			&mysql.MySQLError{Number: 1062, Message: "Duplicate entry 'a' for key 'users.idx_users_name'"},
+			database.ConstraintViolation{Table: "users", Key: "idx_users_name"},
+		},
+		{
+			&mysql.MySQLError{Number: 1062, Message: "Duplicate entry 'a' for key 'orders.uq_orders_code'"},
+			database.ConstraintViolation{Table: "orders", Key: "uq_orders_code"},
+		},
+		{
+			&mysql.MySQLError{Number: 1062, Message: "Duplicate entry 'a' for key 'uq_users_email'"},
+			database.ConstraintViolation{Table: "users", Key: "uq_users_email", Columns: []string{"email"}},
+		},
+		{
+			&mysql.MySQLError{Number: 1062, Message: "Duplicate entry 'a' for key 'uq_orders_code'"},
+			database.ConstraintViolation{Key: "uq_orders_code"},
+		},
+		{
+			&mysql.MySQLError{Number: 1062, Message: "Duplicate entry '1' for key 'users.PRIMARY'"},
+			database.ConstraintViolation{Table: "users", Key: "PRIMARY", Columns: []string{"id"}},
+		},
+		{
+			&mysql.MySQLError{Number: 1062, Message: "Duplicate entry '1-2' for key 'memberships.PRIMARY'"},
+			database.ConstraintViolation{Table: "memberships", Key: "PRIMARY", Columns: []string{"user_id", "group_id"}},
+		},
+		{
+			// Before MySQL 8.0.19 the primary keys of the tables are only
+			// known by their name, and the tables have different ones.
+			&mysql.MySQLError{Number: 1062, Message: "Duplicate entry '1' for key 'PRIMARY'"},
+			database.ConstraintViolation{Key: "PRIMARY"},
+		},
+		{
+			&mysql.MySQLError{Number: 1452, Message: "Cannot add or update a child row: a foreign key constraint fails " +
+				"(`db`.`orders`, CONSTRAINT `fk_orders_user_id` FOREIGN KEY (`user_id`, `tenant_id`) REFERENCES `users` (`id`, `tenant_id`))"},
+			database.ConstraintViolation{Table: "orders", Key: "fk_orders_user_id", Columns: []string{"user_id", "tenant_id"}},
+		},
+	}
+	for _, tt := range tests {
+		var apiErr *core.APIError
+		if !errors.As(checker.Check(tt.err), &apiErr) {
+			t.Fatalf("expected an API error for %v", tt.err)
+		}
+		violation, ok := apiErr.Data.(*database.ConstraintViolation)
+		if !ok {
+			t.Fatalf("expected a constraint violation for %v, got %T", tt.err, apiErr.Data)
+		}
+		if violation.Table != tt.expected.Table || violation.Key != tt.expected.Key ||
+			!slices.Equal(violation.Columns, tt.expected.Columns) {
+			t.Errorf("expected %+v, got %+v", tt.expected, *violation)
+		}
+		if !errors.Is(violation, tt.err) {
+			t.Errorf("expected the violation to wrap %v", tt.err)
+		}
+	}
+}
@@ -5,17 +5,26 @@ import (
 	"reflect"
 	"strings"
 
+	extendeddatabase "github.com/pakkasys/fluidapi-extended/database"
 	"github.com/pakkasys/fluidapi/database"
 )
 
 // Constants for comparison operators.
 const (
 	in    = "IN"
+	notIn = "NOT IN"
 	is    = "IS"
 	isNot = "IS NOT"
 	null  = "NULL"
 )
 
+// quoter quotes the identifiers and literals of MySQL queries.
+var quoter = extendeddatabase.Quoter{
+	IdentifierQuote:     '`',
+	MaxIdentifierLength: 64,
+	BackslashEscapes:    true,
+}
+
 // Query is a query builder for MySQL.
 type Query struct{}
 
@@ -40,8 +49,8 @@ func (q *Query) Insert(
 	)
 
 	query := fmt.Sprintf(
-		"INSERT INTO `%s` (%s) VALUES (%s)",
-		tableName,
+		"INSERT INTO %s (%s) VALUES (%s)",
+		quoteIdentifier(tableName),
 		columnames,
 		valuePlaceholders,
 	)
@@ -81,8 +90,8 @@ func (q *Query) InsertMany(
 	}
 
 	query := fmt.Sprintf(
-		"INSERT INTO `%s` (%s) VALUES %s",
-		tableName,
+		"INSERT INTO %s (%s) VALUES %s",
+		quoteIdentifier(tableName),
 		columnames,
 		strings.Join(valuePlaceholders, ", "),
 	)
@@ -90,12 +99,32 @@ func (q *Query) InsertMany(
 	return query, allValues
 }
 
-// UpsertMany creates an upsert query for a list of entities.
+// FirstInsertID returns the generated ID of the first row of a multi-row
+// insert. MySQL reports the ID of the first row as the last insert ID, and
+// the IDs of the rows of a single insert are consecutive unless
+// auto_increment_increment is changed.
+//
+// Parameters:
+//   - lastInsertID: The last insert ID reported by the database.
+//   - rowCount: The number of inserted rows.
+//
+// Returns:
+//   - int64: The generated ID of the first row.
+func (q *Query) FirstInsertID(lastInsertID int64, rowCount int) int64 {
+	return lastInsertID
+}
+
+// UpsertMany creates an upsert query for a list of entities. The conflicts
+// are resolved on the projected columns and all the inserted columns except
+// "id" and "created" are updated. MySQL resolves a conflict on any unique key
+// of the table, so a conflicting row is only updated if its projected columns
+// have the inserted values. If no projections are given, the conflicts are
+// resolved on any unique key of the table.
 //
 // Parameters:
 //   - tableName: The name of the database table.
 //   - insertedValue: The function used to get the columns and values to insert.
-//   - updateProjections: The projections of the entities to update.
+//   - updateProjections: The projections of the conflict columns.
 //
 // Returns:
 //   - string: The upsert query.
@@ -105,33 +134,163 @@ func (q *Query) UpsertMany(
 	insertedValues []database.InsertedValuesFn,
 	updateProjections []database.Projection,
 ) (string, []any) {
-	if len(insertedValues) == 0 {
-		return "", nil
+	conflictColumns := make([]string, len(updateProjections))
+	for i, proj := range updateProjections {
+		conflictColumns[i] = proj.Column
 	}
 
-	updateParts := make([]string, len(updateProjections))
-	for i, proj := range updateProjections {
-		updateParts[i] = fmt.Sprintf(
-			"`%s` = VALUES(`%s`)",
-			proj.Column,
-			proj.Column,
+	// The conflict columns are not updated since they match the existing row.
+	return q.upsert(tableName, insertedValues, &extendeddatabase.UpsertOptions{
+		ConflictColumns: conflictColumns,
+		ExcludeColumns:  []string{"id", "created"},
+	})
+}
+
+// Upsert creates an upsert query for a single entity. MySQL resolves a
+// conflict on any unique key of the table, so a conflicting row is only
+// updated if its conflict columns have the inserted values. If no update
+// columns are given, conflicting rows are left untouched.
+//
+// Parameters:
+//   - tableName: The name of the database table.
+//   - insertedValues: The function used to get the columns and values to insert.
+//   - conflictColumns: The columns that identify a conflicting row.
+//   - updateColumns: The columns to update on conflict.
+//
+// Returns:
+//   - string: The upsert query.
+//   - []any: The values.
+func (q *Query) Upsert(
+	tableName string,
+	insertedValues database.InsertedValuesFn,
+	conflictColumns []string,
+	updateColumns []string,
+) (string, []any) {
+	return q.upsert(
+		tableName,
+		[]database.InsertedValuesFn{insertedValues},
+		&extendeddatabase.UpsertOptions{
+			ConflictColumns: conflictColumns,
+			UpdateColumns:   updateColumns,
+			DoNothing:       len(updateColumns) == 0,
+		},
+	)
+}
+
+// UpsertWithOptions creates an upsert query for a list of entities with the
+// update policy of the options. MySQL resolves a conflict on any unique key
+// of the table, so with conflict columns a conflicting row is only updated if
+// its conflict columns have the inserted values, and a conflict on another
+// key leaves the row untouched. If nothing is updated, the first inserted column, usually the primary key, is set to
+// itself, which leaves the conflicting rows untouched. Unlike INSERT IGNORE,
+// this does not turn other errors, such as foreign key errors, into warnings.
+//
+// Example:
+//
+//	q.UpsertWithOptions("users", values, extendeddatabase.UpsertOptions{
+//	    ExcludeColumns: []string{"id", "email", "created"},
+//	})
+//
+// Output:
+//
+//	INSERT INTO `users` (`id`, `email`, `name`, `created`) VALUES (?, ?, ?, ?)
+//	ON DUPLICATE KEY UPDATE `name` = VALUES(`name`)
+//
+// Parameters:
+//   - tableName: The name of the database table.
+//   - insertedValues: The functions used to get the columns and values to
+//     insert.
+//   - opts: The upsert options.
+//
+// Returns:
+//   - string: The upsert query.
+//   - []any: The values.
+//   - error: InvalidIdentifierError if an identifier is invalid, or an error
+//     if the options have a where condition, which MySQL does not support.
+func (q *Query) UpsertWithOptions(
+	tableName string,
+	insertedValues []database.InsertedValuesFn,
+	opts extendeddatabase.UpsertOptions,
+) (string, []any, error) {
+	err := quoter.ValidateIdentifiers(append(
+		[]string{tableName}, opts.Identifiers()...,
+	)...)
+	if err != nil {
+		return "", nil, err
+	}
+	// The assignments of ON DUPLICATE KEY UPDATE are evaluated from left to
+	// right, so a condition repeated in each of them would see the columns
+	// that the previous assignments updated.
+	if opts.WhereCondition != nil && !opts.DoNothing {
+		return "", nil, fmt.Errorf(
+			"UpsertWithOptions: MySQL does not support a where condition",
 		)
 	}
+	query, values := q.upsert(tableName, insertedValues, &opts)
+	return query, values, nil
+}
+
+// upsert creates an upsert query for a list of entities. The where condition
+// of the options is not supported and is ignored.
+func (q *Query) upsert(
+	tableName string,
+	insertedValues []database.InsertedValuesFn,
+	opts *extendeddatabase.UpsertOptions,
+) (string, []any) {
+	if len(insertedValues) == 0 {
+		return "", nil
+	}
 
-	insertQueryPart, allValues := q.InsertMany(tableName, insertedValues)
+	insertQuery, values := q.InsertMany(tableName, insertedValues)
 
-	builder := strings.Builder{}
-	builder.WriteString(insertQueryPart)
-	if len(updateParts) != 0 {
-		builder.WriteString(" ON DUPLICATE KEY UPDATE ")
-		builder.WriteString(strings.Join(updateParts, ", "))
+	columns, _ := insertedValues[0]()
+	updateColumns := opts.UpdatedColumns(columns)
+	if len(updateColumns) == 0 {
+		noop := quoteIdentifier(columns[0])
+		return fmt.Sprintf(
+			"%s ON DUPLICATE KEY UPDATE %s = %s", insertQuery, noop, noop,
+		), values
+	}
+
+	updateParts := make([]string, len(updateColumns))
+	for i, column := range updateColumns {
+		updateParts[i] = updateAssignment(column, opts.ConflictColumns)
 	}
-	upsertQuery := builder.String()
 
-	return upsertQuery, allValues
+	return fmt.Sprintf(
+		"%s ON DUPLICATE KEY UPDATE %s",
+		insertQuery,
+		strings.Join(updateParts, ", "),
+	), values
+}
+
+// updateAssignment returns the ON DUPLICATE KEY UPDATE assignment of a column.
+// With conflict columns the column is only set to the inserted value if the
+// conflict columns of the row have the inserted values. An updated conflict
+// column is set to the value it already has, so the later assignments see the
+// same conflict columns.
+func updateAssignment(column string, conflictColumns []string) string {
+	quoted := quoteIdentifier(column)
+	value := fmt.Sprintf("VALUES(%s)", quoted)
+	if len(conflictColumns) == 0 {
+		return fmt.Sprintf("%s = %s", quoted, value)
+	}
+	conditions := make([]string, len(conflictColumns))
+	for i, conflictColumn := range conflictColumns {
+		conditions[i] = fmt.Sprintf(
+			"%s <=> VALUES(%s)",
+			quoteIdentifier(conflictColumn),
+			quoteIdentifier(conflictColumn),
+		)
+	}
+	return fmt.Sprintf(
+		"%s = IF(%s, %s, %s)",
+		quoted, strings.Join(conditions, " AND "), value, quoted,
+	)
 }
 
-// Get returns a get query.
+// Get returns a get query. The names are quoted but not validated since the
+// method can not return an error. GetFiltered validates them.
 //
 //   - tableName: The name of the database table.
 //   - dbOptions: The options for the query.
@@ -142,22 +301,92 @@ func (q *Query) UpsertMany(
 func (q *Query) Get(
 	tableName string, opts *database.GetOptions,
 ) (string, []any) {
-	whereClause, whereValues := whereClause(opts.Selectors)
+	return q.getFiltered(tableName, opts, nil, nil)
+}
+
+// GetKeyset returns a get query that selects the rows after the keyset. The
+// keyset condition is added to the selectors of the options. The orders of
+// the options should match the keyset columns. If the keyset is nil, the
+// query is a regular get query.
+//
+// Parameters:
+//   - tableName: The name of the table.
+//   - opts: The options for the query.
+//   - keyset: The keyset to select the rows after.
+//
+// Returns:
+//   - string: The query.
+//   - []any: The values.
+//   - error: InvalidIdentifierError if a name is invalid.
+func (q *Query) GetKeyset(
+	tableName string, opts *database.GetOptions,
+	keyset *extendeddatabase.Keyset,
+) (string, []any, error) {
+	return q.GetFiltered(tableName, opts, keyset, nil)
+}
+
+// GetFiltered returns a get query that selects the rows that match the filter
+// and come after the keyset. The conditions are added to the selectors of the
+// options. If the filter or keyset is nil, the condition is not added.
+//
+// Parameters:
+//   - tableName: The name of the table.
+//   - opts: The options for the query.
+//   - keyset: The keyset to select the rows after.
+//   - filter: The filter expression the rows must match.
+//
+// Returns:
+//   - string: The query.
+//   - []any: The values.
+//   - error: InvalidIdentifierError if a name is invalid.
+func (q *Query) GetFiltered(
+	tableName string, opts *database.GetOptions,
+	keyset *extendeddatabase.Keyset,
+	filter *extendeddatabase.Filter,
+) (string, []any, error) {
+	err := quoter.ValidateGetQuery(tableName, opts, keyset, filter)
+	if err != nil {
+		return "", nil, err
+	}
+	query, values := q.getFiltered(tableName, opts, keyset, filter)
+	return query, values, nil
+}
+
+// getFiltered builds the get query of GetFiltered without validating the
+// names.
+func (q *Query) getFiltered(
+	tableName string, opts *database.GetOptions,
+	keyset *extendeddatabase.Keyset,
+	filter *extendeddatabase.Filter,
+) (string, []any) {
+	whereColumns, whereValues := processSelectors(opts.Selectors)
+	if filter != nil {
+		condition, values := filterCondition(*filter)
+		whereColumns = append(whereColumns, condition)
+		whereValues = append(whereValues, values...)
+	}
+	if keyset != nil && len(keyset.Columns) != 0 {
+		condition, values := keyset.Condition(keysetColumnToString)
+		whereColumns = append(whereColumns, condition)
+		whereValues = append(whereValues, values...)
+	}
+	whereClause := getWhereClause(whereColumns)
 
 	builder := strings.Builder{}
 	builder.WriteString(fmt.Sprintf(
 		"SELECT %s",
 		strings.Join(projectionsToStrings(opts.Projections), ","),
 	))
-	builder.WriteString(fmt.Sprintf(" FROM `%s`", tableName))
+	builder.WriteString(fmt.Sprintf(" FROM %s", quoteIdentifier(tableName)))
 	if len(opts.Joins) != 0 {
 		builder.WriteString(" " + joinClause(opts.Joins))
 	}
 	if whereClause != "" {
 		builder.WriteString(" " + whereClause)
 	}
-	if len(opts.Orders) != 0 {
-		builder.WriteString(" " + getOrderClauseFromOrders(opts.Orders))
+	orderClause, orderValues := filteredOrderClause(opts.Orders, filter)
+	if orderClause != "" {
+		builder.WriteString(" " + orderClause)
 	}
 	if opts.Page != nil {
 		builder.WriteString(" " + getLimitOffsetClauseFromPage(opts.Page))
@@ -166,10 +395,11 @@ func (q *Query) Get(
 		builder.WriteString(" FOR UPDATE")
 	}
 
-	return builder.String(), whereValues
+	return builder.String(), append(whereValues, orderValues...)
 }
 
-// Count returns a count query.
+// Count returns a count query. The names are quoted but not validated since the
+// method can not return an error. CountFiltered validates them.
 //
 // Parameters:
 //   - tableName: The name of the database table.
@@ -181,18 +411,51 @@ func (q *Query) Get(
 func (q *Query) Count(
 	tableName string, opts *database.CountOptions,
 ) (string, []any) {
-	whereClause, whereValues := whereClause(opts.Selectors)
+	return q.countFiltered(tableName, opts, nil)
+}
+
+// CountFiltered returns a count query that counts the rows that match the
+// filter. If the filter is nil, the query is a regular count query.
+//
+// Parameters:
+//   - tableName: The name of the database table.
+//   - opts: The options for the query.
+//   - filter: The filter expression the rows must match.
+//
+// Returns:
+//   - string: The query.
+//   - []any: The values.
+//   - error: InvalidIdentifierError if a name is invalid.
+func (q *Query) CountFiltered(
+	tableName string,
+	opts *database.CountOptions,
+	filter *extendeddatabase.Filter,
+) (string, []any, error) {
+	if err := quoter.ValidateCountQuery(tableName, opts, filter); err != nil {
+		return "", nil, err
+	}
+	query, values := q.countFiltered(tableName, opts, filter)
+	return query, values, nil
+}
+
+// countFiltered builds the count query of CountFiltered without validating
+// the names.
+func (q *Query) countFiltered(
+	tableName string,
+	opts *database.CountOptions,
+	filter *extendeddatabase.Filter,
+) (string, []any) {
+	whereClause, whereValues := whereClause(opts.Selectors, filter)
 	joinStmt := joinClause(opts.Joins)
 
 	if opts.Page != nil {
 		// Build the inner query with pagination.
-		innerQuery := strings.Trim(fmt.Sprintf(
-			"SELECT * FROM `%s` %s %s %s",
-			tableName,
+		innerQuery := extendeddatabase.JoinClauses(
+			"SELECT * FROM "+quoteIdentifier(tableName),
 			joinStmt,
 			whereClause,
 			getLimitOffsetClauseFromPage(opts.Page),
-		), " ")
+		)
 
 		// Wrap the inner query in an outer COUNT(*) query.
 		query := fmt.Sprintf(
@@ -203,16 +466,98 @@ func (q *Query) Count(
 	}
 
 	// Otherwise, build a simple COUNT query without pagination.
-	query := strings.Trim(fmt.Sprintf(
-		"SELECT COUNT(*) FROM `%s` %s %s",
-		tableName,
+	query := extendeddatabase.JoinClauses(
+		"SELECT COUNT(*) FROM "+quoteIdentifier(tableName),
 		joinStmt,
 		whereClause,
-	), " ")
+	)
 
 	return query, whereValues
 }
 
+// Aggregate returns a query that groups the rows that match the selectors and
+// computes the aggregates of each group. The groups and aggregates are
+// selected in order with their aliases.
+//
+// Parameters:
+//   - tableName: The name of the table.
+//   - opts: The options for the query.
+//
+// Returns:
+//   - string: The query.
+//   - []any: The values.
+//   - error: InvalidIdentifierError if a name is invalid.
+func (q *Query) Aggregate(
+	tableName string, opts *extendeddatabase.AggregateOptions,
+) (string, []any, error) {
+	if err := quoter.ValidateIdentifier(tableName); err != nil {
+		return "", nil, err
+	}
+	if err := quoter.ValidateAggregateOptions(opts); err != nil {
+		return "", nil, err
+	}
+	query, values := q.aggregate(tableName, opts)
+	return query, values, nil
+}
+
+// aggregate builds the aggregate query of Aggregate without validating the
+// names.
+func (q *Query) aggregate(
+	tableName string, opts *extendeddatabase.AggregateOptions,
+) (string, []any) {
+	var columns []string
+	var groupColumns []string
+	for _, groupBy := range opts.GroupBy {
+		expression := groupByExpression(groupBy)
+		columns = append(
+			columns, fmt.Sprintf(
+				"%s AS %s", expression, quoteIdentifier(groupBy.Alias),
+			),
+		)
+		groupColumns = append(groupColumns, expression)
+	}
+	for _, aggregate := range opts.Aggregates {
+		columns = append(columns, fmt.Sprintf(
+			"%s AS %s",
+			aggregateExpression(aggregate),
+			quoteIdentifier(aggregate.Alias),
+		))
+	}
+	whereColumns, values := processSelectors(opts.Selectors)
+
+	builder := strings.Builder{}
+	builder.WriteString(fmt.Sprintf(
+		"SELECT %s FROM %s",
+		strings.Join(columns, ","),
+		quoteIdentifier(tableName),
+	))
+	if whereClause := getWhereClause(whereColumns); whereClause != "" {
+		builder.WriteString(" " + whereClause)
+	}
+	if len(groupColumns) != 0 {
+		builder.WriteString(" GROUP BY " + strings.Join(groupColumns, ","))
+	}
+	if len(opts.Having) != 0 {
+		conditions := make([]string, len(opts.Having))
+		for i, having := range opts.Having {
+			conditions[i] = fmt.Sprintf(
+				"%s %s ?",
+				aggregateExpression(having.Aggregate),
+				having.Predicate,
+			)
+			values = append(values, having.Value)
+		}
+		builder.WriteString(" HAVING " + strings.Join(conditions, " AND "))
+	}
+	if len(opts.Orders) != 0 {
+		builder.WriteString(" " + getOrderClauseFromOrders(opts.Orders))
+	}
+	if opts.Page != nil {
+		builder.WriteString(" " + getLimitOffsetClauseFromPage(opts.Page))
+	}
+	return builder.String(), values
+}
+
 // UpdateQuery returns the SQL query and values for an update query.
 //
 // Parameters:
@@ -233,8 +578,8 @@ func (q *Query) UpdateQuery(
 
 	builder := strings.Builder{}
 	builder.WriteString(fmt.Sprintf(
-		"UPDATE `%s` SET %s",
-		tableName,
+		"UPDATE %s SET %s",
+		quoteIdentifier(tableName),
 		setClause,
 	))
 	if len(whereColumns) != 0 {
@@ -268,7 +613,9 @@ func (q *Query) Delete(
 
 	builder := strings.Builder{}
 	builder.WriteString(
-		fmt.Sprintf("DELETE FROM `%s` %s", tableName, whereClause),
+		fmt.Sprintf(
+			"DELETE FROM %s %s", quoteIdentifier(tableName), whereClause,
+		),
 	)
 
 	if opts != nil {
@@ -295,12 +642,20 @@ func (q *Query) Delete(
 func (q *Query) CreateDatabaseQuery(
 	dbName string, ifNotExists bool, charset string, collate string,
 ) (string, []any, error) {
+	quotedName, err := quoter.Identifier(dbName)
+	if err != nil {
+		return "", nil, err
+	}
+	if err := validateNames(charset, collate); err != nil {
+		return "", nil, err
+	}
+
 	var builder strings.Builder
 	builder.WriteString("CREATE DATABASE ")
 	if ifNotExists {
 		builder.WriteString("IF NOT EXISTS ")
 	}
-	builder.WriteString(fmt.Sprintf("`%s`", dbName))
+	builder.WriteString(quotedName)
 	if charset != "" {
 		builder.WriteString(fmt.Sprintf(" DEFAULT CHARACTER SET %s", charset))
 	}
@@ -325,18 +680,26 @@ func (q *Query) CreateTableQuery(
 	constraints []string,
 	options database.TableOptions,
 ) (string, []any, error) {
+	if err := validateTableIdentifiers(tableName, columns); err != nil {
+		return "", nil, err
+	}
+	err := validateNames(options.Engine, options.Charset, options.Collate)
+	if err != nil {
+		return "", nil, err
+	}
+
 	var builder strings.Builder
 
 	builder.WriteString("CREATE TABLE ")
 	if ifNotExists {
 		builder.WriteString("IF NOT EXISTS ")
 	}
-	builder.WriteString(fmt.Sprintf("`%s` (\n", tableName))
+	builder.WriteString(fmt.Sprintf("%s (\n", quoteIdentifier(tableName)))
 
 	var defs []string
 	// Build column definitions.
 	for _, col := range columns {
-		def := fmt.Sprintf("  `%s` %s", col.Name, col.Type)
+		def := fmt.Sprintf("  %s %s", quoteIdentifier(col.Name), col.Type)
 		if col.Extra != "" {
 			def += " " + col.Extra
 		}
@@ -351,7 +714,7 @@ func (q *Query) CreateTableQuery(
 			if *col.Default == "CURRENT_TIMESTAMP" || *col.Default == "NULL" {
 				def += *col.Default
 			} else {
-				def += fmt.Sprintf("'%s'", *col.Default)
+				def += quoter.Literal(*col.Default)
 			}
 		}
 		if col.AutoIncrement {
@@ -396,7 +759,11 @@ func (q *Query) CreateTableQuery(
 //   - []any: The values.
 //   - error: An error if the query could not be created.
 func (q *Query) UseDatabaseQuery(dbName string) (string, []any, error) {
-	return fmt.Sprintf("USE `%s`;", dbName), nil, nil
+	quotedName, err := quoter.Identifier(dbName)
+	if err != nil {
+		return "", nil, err
+	}
+	return fmt.Sprintf("USE %s;", quotedName), nil, nil
 }
 
 // SetVariableQuery generates a SQL query to set a session variable.
@@ -413,11 +780,16 @@ func (q *Query) UseDatabaseQuery(dbName string) (string, []any, error) {
 func (q *Query) SetVariableQuery(
 	variable string, value string,
 ) (string, []any, error) {
+	if err := extendeddatabase.ValidateName(variable); err != nil {
+		return "", nil, err
+	}
 	upperVar := strings.ToUpper(variable)
 	if upperVar == "NAMES" {
-		return fmt.Sprintf("SET NAMES '%s';", value), nil, nil
+		return fmt.Sprintf("SET NAMES %s;", quoter.Literal(value)), nil, nil
 	}
-	return fmt.Sprintf("SET %s = '%s';", variable, value), nil, nil
+	return fmt.Sprintf(
+		"SET %s = %s;", variable, quoter.Literal(value),
+	), nil, nil
 }
 
 // AdvisoryLock generates the query to acquire an advisory lock in MySQL.
@@ -463,7 +835,8 @@ func getLimitOffsetClauseFromPage(page *database.Page) string {
 	)
 }
 
-// getOrderClauseFromOrders returns an ORDER BY clause.
+// getOrderClauseFromOrders returns an ORDER BY clause. The directions are
+// written as ASC or DESC.
 func getOrderClauseFromOrders(orders []database.Order) string {
 	if len(orders) == 0 {
 		return ""
@@ -472,11 +845,14 @@ func getOrderClauseFromOrders(orders []database.Order) string {
 	for _, order := range orders {
 		if order.Table == "" {
 			orderClause += fmt.Sprintf(
-				" `%s` %s,", order.Field, order.Direction,
+				" %s %s,", quoteIdentifier(order.Field), extendeddatabase.SQLDirection(order.Direction),
 			)
 		} else {
 			orderClause += fmt.Sprintf(
-				" `%s`.`%s` %s,", order.Table, order.Field, order.Direction,
+				" %s.%s %s,",
+				quoteIdentifier(order.Table),
+				quoteIdentifier(order.Field),
+				extendeddatabase.SQLDirection(order.Direction),
 			)
 		}
 	}
@@ -486,9 +862,7 @@ func getOrderClauseFromOrders(orders []database.Order) string {
 // columnSelectorToString returns the string representation of a column
 // selector.
 func columnSelectorToString(columnSelector database.ColumnSelector) string {
-	return fmt.Sprintf(
-		"`%s`.`%s`", columnSelector.Table, columnSelector.Column,
-	)
+	return quoter.QuoteQualified(columnSelector.Table, columnSelector.Column)
 }
 
 // processSelectors processes selectors and returns conditions and values.
@@ -507,14 +881,16 @@ func processSelectors(selectors []database.Selector) ([]string, []any) {
 func projectionToString(projection database.Projection) string {
 	builder := strings.Builder{}
 	if projection.Table == "" {
-		builder.WriteString(fmt.Sprintf("`%s`", projection.Column))
+		builder.WriteString(quoteIdentifier(projection.Column))
 	} else {
-		builder.WriteString(fmt.Sprintf(
-			"`%s`.`%s`", projection.Table, projection.Column,
-		))
+		builder.WriteString(
+			quoter.QuoteQualified(projection.Table, projection.Column),
+		)
 	}
 	if projection.Alias != "" {
-		builder.WriteString(fmt.Sprintf(" AS `%s`", projection.Alias))
+		builder.WriteString(fmt.Sprintf(
+			" AS %s", quoteIdentifier(projection.Alias),
+		))
 	}
 	return builder.String()
 }
@@ -523,7 +899,7 @@ func projectionToString(projection database.Projection) string {
 func getInsertQueryColumnames(columns []string) string {
 	wrappedColumns := make([]string, len(columns))
 	for i, column := range columns {
-		wrappedColumns[i] = "`" + column + "`"
+		wrappedColumns[i] = quoteIdentifier(column)
 	}
 	columnames := strings.Join(wrappedColumns, ", ")
 	return columnames
@@ -549,9 +925,9 @@ func joinClause(joins []database.Join) string {
 			joinClause += " "
 		}
 		joinClause += fmt.Sprintf(
-			"%s JOIN `%s` ON %s = %s",
+			"%s JOIN %s ON %s = %s",
 			join.Type,
-			join.Table,
+			quoteIdentifier(join.Table),
 			columnSelectorToString(join.OnLeft),
 			columnSelectorToString(join.OnRight),
 		)
@@ -559,9 +935,17 @@ func joinClause(joins []database.Join) string {
 	return joinClause
 }
 
-// whereClause returns the string representation of a where clause.
-func whereClause(selectors []database.Selector) (string, []any) {
+// whereClause returns the string representation of a where clause. If the
+// filter is set, its condition is added to the selectors.
+func whereClause(
+	selectors []database.Selector, filter *extendeddatabase.Filter,
+) (string, []any) {
 	whereColumns, whereValues := processSelectors(selectors)
+	if filter != nil {
+		condition, values := filterCondition(*filter)
+		whereColumns = append(whereColumns, condition)
+		whereValues = append(whereValues, values...)
+	}
 	var whereClause string
 	if len(whereColumns) > 0 {
 		whereClause = "WHERE " + strings.Join(whereColumns, " AND ")
@@ -578,14 +962,24 @@ func getWhereClause(whereColumns []string) string {
 	return whereClause
 }
 
-// getSetClause returns the string representation of a SET clause.
+// getSetClause returns the string representation of a SET clause. The
+// columns of Increment values are incremented.
 func getSetClause(updates []database.Update) (string, []any) {
 	setClauseParts := make([]string, len(updates))
 	values := make([]any, len(updates))
 	for i, update := range updates {
+		if increment, ok := update.Value.(extendeddatabase.Increment); ok {
+			setClauseParts[i] = fmt.Sprintf(
+				"%s = %s + ?",
+				quoteIdentifier(update.Field),
+				quoteIdentifier(update.Field),
+			)
+			values[i] = increment.By
+			continue
+		}
 		setClauseParts[i] = fmt.Sprintf(
 			"%s = ?",
-			update.Field,
+			quoteIdentifier(update.Field),
 		)
 		values[i] = update.Value
 	}
@@ -608,29 +1002,29 @@ func writeDeleteOptions(
 
 // processSelector processes a selector and returns a condition and values.
 func processSelector(selector database.Selector) (string, []any) {
-	if selector.Predicate == in {
+	if selector.Predicate == in || selector.Predicate == notIn {
 		return processInSelector(selector)
 	}
 	return processDefaultSelector(selector)
 }
 
-// processInSelector processes an IN selector and returns conditions and values.
+// processInSelector processes an IN or NOT IN selector and returns conditions
+// and values.
 func processInSelector(selector database.Selector) (string, []any) {
+	column := keysetColumnToString(extendeddatabase.KeysetColumn{
+		Table:  selector.Table,
+		Column: selector.Column,
+	})
 	value := reflect.ValueOf(selector.Value)
 	if value.Kind() == reflect.Slice {
 		placeholders, values := createPlaceholdersAndValues(value)
-		column := fmt.Sprintf(
-			"`%s`.`%s` %s (%s)",
-			selector.Table,
-			selector.Column,
-			in,
-			placeholders,
-		)
-		return column, values
+		return fmt.Sprintf(
+			"%s %s (%s)", column, selector.Predicate, placeholders,
+		), values
 	}
 	// If value is not a slice, treat as a single value
 	return fmt.Sprintf(
-		"`%s`.`%s` %s (?)", selector.Table, selector.Column, in,
+		"%s %s (?)", column, selector.Predicate,
 	), []any{selector.Value}
 }
 
@@ -642,15 +1036,15 @@ func processDefaultSelector(selector database.Selector) (string, []any) {
 	}
 	if selector.Table == "" {
 		return fmt.Sprintf(
-			"`%s` %s ?",
-			selector.Column,
+			"%s %s ?",
+			quoteIdentifier(selector.Column),
 			selector.Predicate,
 		), []any{selector.Value}
 	} else {
 		return fmt.Sprintf(
-			"`%s`.`%s` %s ?",
-			selector.Table,
-			selector.Column,
+			"%s.%s %s ?",
+			quoteIdentifier(selector.Table),
+			quoteIdentifier(selector.Column),
 			selector.Predicate,
 		), []any{selector.Value}
 	}
@@ -671,10 +1065,16 @@ func processNullSelector(selector database.Selector) (string, []any) {
 // buildNullClause returns the string representation of a null clause.
 func buildNullClause(selector database.Selector, clause string) string {
 	if selector.Table == "" {
-		return fmt.Sprintf("`%s` %s %s", selector.Column, clause, null)
+		return fmt.Sprintf(
+			"%s %s %s", quoteIdentifier(selector.Column), clause, null,
+		)
 	}
 	return fmt.Sprintf(
-		"`%s`.`%s` %s %s", selector.Table, selector.Column, clause, null,
+		"%s.%s %s %s",
+		quoteIdentifier(selector.Table),
+		quoteIdentifier(selector.Column),
+		clause,
+		null,
 	)
 }
 
@@ -693,3 +1093,153 @@ func createPlaceholdersAndValues(value reflect.Value) (string, []any) {
 func createPlaceholders(count int) string {
 	return strings.TrimSuffix(strings.Repeat("?,", count), ",")
 }
+
+// keysetColumnToString returns the string representation of a keyset column.
+func keysetColumnToString(column extendeddatabase.KeysetColumn) string {
+	return quoter.QuoteQualified(column.Table, column.Column)
+}
+
+// filterCondition returns the condition of a filter expression. The groups
+// are parenthesized so that the condition can be combined with the other
+// conditions of the where clause.
+func filterCondition(filter extendeddatabase.Filter) (string, []any) {
+	if filter.Selector != nil {
+		return processSelector(*filter.Selector)
+	}
+	if filter.Search != nil {
+		return searchCondition(*filter.Search)
+	}
+	conditions := make([]string, len(filter.Filters))
+	var values []any
+	for i, child := range filter.Filters {
+		condition, childValues := filterCondition(child)
+		conditions[i] = condition
+		values = append(values, childValues...)
+	}
+	if filter.Operator == extendeddatabase.FilterNot {
+		return fmt.Sprintf("NOT (%s)", strings.Join(conditions, " AND ")), values
+	}
+	return fmt.Sprintf(
+		"(%s)", strings.Join(conditions, fmt.Sprintf(" %s ", filter.Operator)),
+	), values
+}
+
+// searchCondition returns the condition of a full-text search. The searched
+// columns must have a FULLTEXT index over exactly the same columns.
+func searchCondition(search extendeddatabase.Search) (string, []any) {
+	return searchMatch(search), []any{search.Query}
+}
+
+// searchMatch returns the MATCH ... AGAINST expression of a full-text search
+// in natural language mode. Its value is the relevance of the row.
+func searchMatch(search extendeddatabase.Search) string {
+	columns := make([]string, len(search.Columns))
+	for i, column := range search.Columns {
+		columns[i] = keysetColumnToString(extendeddatabase.KeysetColumn{
+			Table:  search.Table,
+			Column: column,
+		})
+	}
+	return fmt.Sprintf(
+		"MATCH (%s) AGAINST (? IN NATURAL LANGUAGE MODE)",
+		strings.Join(columns, ", "),
+	)
+}
+
+// filteredOrderClause returns the ORDER BY clause of a filtered get query. If
+// the filter has a search term that orders by relevance, the most relevant
+// rows come first and the orders are used to break ties.
+func filteredOrderClause(
+	orders []database.Order, filter *extendeddatabase.Filter,
+) (string, []any) {
+	var search *extendeddatabase.Search
+	if filter != nil {
+		search = filter.RelevanceSearch()
+	}
+	if search == nil {
+		return getOrderClauseFromOrders(orders), nil
+	}
+	orderClause := fmt.Sprintf("ORDER BY %s DESC", searchMatch(*search))
+	if len(orders) != 0 {
+		orderClause += "," + strings.TrimPrefix(
+			getOrderClauseFromOrders(orders), "ORDER BY",
+		)
+	}
+	return orderClause, []any{search.Query}
+}
+
+// mysqlDateFormats are the DATE_FORMAT formats of the date buckets.
+var mysqlDateFormats = map[extendeddatabase.DateBucket]string{
+	extendeddatabase.BucketHour:  "%Y-%m-%d %H:00:00",
+	extendeddatabase.BucketDay:   "%Y-%m-%d",
+	extendeddatabase.BucketWeek:  "%x-W%v",
+	extendeddatabase.BucketMonth: "%Y-%m",
+	extendeddatabase.BucketYear:  "%Y",
+}
+
+// groupByExpression returns the expression of a group. Date buckets are
+// formatted with DATE_FORMAT from the Unix nanoseconds of the column. The
+// date is added to the epoch instead of using FROM_UNIXTIME, which converts
+// it to the session time zone.
+func groupByExpression(groupBy extendeddatabase.GroupBy) string {
+	column := keysetColumnToString(extendeddatabase.KeysetColumn{
+		Table:  groupBy.Table,
+		Column: groupBy.Column,
+	})
+	format, ok := mysqlDateFormats[groupBy.Bucket]
+	if !ok {
+		return column
+	}
+	date := "TIMESTAMP('1970-01-01') + INTERVAL (" + column +
+		" DIV 1000) MICROSECOND"
+	return "DATE_FORMAT(" + date + ", '" + format + "')"
+}
+
+// aggregateExpression returns the expression of an aggregate.
+func aggregateExpression(aggregate extendeddatabase.Aggregate) string {
+	if aggregate.Column == "" {
+		return fmt.Sprintf("%s(*)", aggregate.Function)
+	}
+	return fmt.Sprintf(
+		"%s(%s)",
+		aggregate.Function,
+		keysetColumnToString(extendeddatabase.KeysetColumn{
+			Table:  aggregate.Table,
+			Column: aggregate.Column,
+		}),
+	)
+}
+
+// quoteIdentifier quotes an identifier with backticks.
+func quoteIdentifier(name string) string {
+	return quoter.QuoteIdentifier(name)
+}
+
+// validateTableIdentifiers validates the identifiers of a table and its
+// columns.
+func validateTableIdentifiers(
+	tableName string, columns []database.ColumnDefinition,
+) error {
+	if err := quoter.ValidateIdentifier(tableName); err != nil {
+		return err
+	}
+	for _, column := range columns {
+		if err := quoter.ValidateIdentifier(column.Name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateNames validates the unquoted names that are set.
+func validateNames(names ...string) error {
+	for _, name := range names {
+		if name == "" {
+			continue
+		}
+		if err := extendeddatabase.ValidateName(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,206 @@
+package mysql
+
+import (
+	"reflect"
+	"testing"
+
+	extendeddatabase "github.com/pakkasys/fluidapi-extended/database"
+	"github.com/pakkasys/fluidapi/database"
+)
+
+func expectQuery(
+	t *testing.T,
+	query string,
+	values []any,
+	expectedQuery string,
+	expectedValues []any,
+) {
+	t.Helper()
+	if query != expectedQuery {
+		t.Errorf("expected query\n%s\ngot\n%s", expectedQuery, query)
+	}
+	if !reflect.DeepEqual(values, expectedValues) {
+		t.Errorf("expected values %v, got %v", expectedValues, values)
+	}
+}
+
+func TestSearchCondition(t *testing.T) {
+	condition, values := searchCondition(extendeddatabase.Search{
+		Table: "posts", Columns: []string{"title", "body"}, Query: "go sql",
+	})
+	expectQuery(t, condition, values,
+		"MATCH (`posts`.`title`, `posts`.`body`) AGAINST (? IN NATURAL LANGUAGE MODE)",
+		[]any{"go sql"},
+	)
+}
+
+func TestFilteredOrderClause(t *testing.T) {
+	orders := []database.Order{{Table: "posts", Field: "id", Direction: "DESC"}}
+	search := extendeddatabase.Search{
+		Table:     "posts",
+		Columns:   []string{"title"},
+		Query:     "go",
+		Relevance: true,
+	}
+
+	orderClause, values := filteredOrderClause(orders, nil)
+	expectQuery(t, orderClause, values, "ORDER BY `posts`.`id` DESC", nil)
+
+	orderClause, values = filteredOrderClause(
+		orders, extendeddatabase.NewFilterSearch(search),
+	)
+	expectQuery(t, orderClause, values,
+		"ORDER BY MATCH (`posts`.`title`) AGAINST (? IN NATURAL LANGUAGE MODE) DESC,"+
+			" `posts`.`id` DESC",
+		[]any{"go"},
+	)
+}
+
+func TestGetFilteredSearch(t *testing.T) {
+	search := extendeddatabase.Search{
+		Table:     "posts",
+		Columns:   []string{"title"},
+		Query:     "go",
+		Relevance: true,
+	}
+	query, values, err := (&Query{}).GetFiltered(
+		"posts",
+		&database.GetOptions{},
+		nil,
+		extendeddatabase.NewFilterSearch(search),
+	)
+	if err != nil {
+		t.Fatalf("GetFiltered: %v", err)
+	}
+	match := "MATCH (`posts`.`title`) AGAINST (? IN NATURAL LANGUAGE MODE)"
+	expectQuery(t, query, values,
+		"SELECT * FROM `posts` WHERE "+match+" ORDER BY "+match+" DESC",
+		[]any{"go", "go"},
+	)
+}
+
+func TestAggregateDateBucket(t *testing.T) {
+	query, values, err := (&Query{}).Aggregate(
+		"orders",
+		&extendeddatabase.AggregateOptions{
+			GroupBy: []extendeddatabase.GroupBy{{
+				Column: "created", Bucket: extendeddatabase.BucketMonth, Alias: "month",
+			}},
+			Aggregates: []extendeddatabase.Aggregate{
+				{Function: extendeddatabase.AggregateCount, Alias: "count"},
+			},
+		},
+	)
+	if err != nil {
+		t.Fatalf("Aggregate: %v", err)
+	}
+	expectQuery(t, query, values,
+		"SELECT DATE_FORMAT(TIMESTAMP('1970-01-01') + INTERVAL (`created` DIV 1000)"+
+			" MICROSECOND, '%Y-%m') AS `month`,COUNT(*) AS `count` FROM `orders`"+
+			" GROUP BY DATE_FORMAT(TIMESTAMP('1970-01-01') + INTERVAL (`created` DIV 1000)"+
+			" MICROSECOND, '%Y-%m')",
+		nil,
+	)
+}
+
+func TestUpsertWithOptions(t *testing.T) {
+	q := &Query{}
+	insertedValues := []database.InsertedValuesFn{
+		func() ([]string, []any) {
+			return []string{"id", "email", "name"}, []any{1, "a@b.c", "A"}
+		},
+	}
+
+	query, values, err := q.UpsertWithOptions(
+		"users",
+		insertedValues,
+		extendeddatabase.UpsertOptions{DoNothing: true},
+	)
+	if err != nil {
+		t.Fatalf("UpsertWithOptions: %v", err)
+	}
+	expectQuery(t, query, values,
+		"INSERT INTO `users` (`id`, `email`, `name`) VALUES (?, ?, ?)"+
+			" ON DUPLICATE KEY UPDATE `id` = `id`",
+		[]any{1, "a@b.c", "A"},
+	)
+
+	query, values, err = q.UpsertWithOptions(
+		"users",
+		insertedValues,
+		extendeddatabase.UpsertOptions{ConflictColumns: []string{"email"}},
+	)
+	if err != nil {
+		t.Fatalf("UpsertWithOptions: %v", err)
+	}
+	expected := "INSERT INTO `users` (`id`, `email`, `name`) VALUES (?, ?, ?)" +
+		" ON DUPLICATE KEY UPDATE" +
+		" `id` = IF(`email` <=> VALUES(`email`), VALUES(`id`), `id`)," +
+		" `name` = IF(`email` <=> VALUES(`email`), VALUES(`name`), `name`)"
+	expectQuery(t, query, values, expected, []any{1, "a@b.c", "A"})
+
+	_, _, err = q.UpsertWithOptions(
+		"users",
+		insertedValues,
+		extendeddatabase.UpsertOptions{
+			WhereCondition: extendeddatabase.NewFilterTerm(database.Selector{
+				Column: "name", Predicate: "=", Value: "A",
+			}),
+		},
+	)
+	if err == nil {
+		t.Errorf("expected an error for a where condition")
+	}
+}
+
+// TestUpsertConflictColumns checks that every upsert method only updates a
+// row whose conflict columns have the inserted values.
+func TestUpsertConflictColumns(t *testing.T) {
+	q := &Query{}
+	insertedValues := func() ([]string, []any) {
+		return []string{"id", "email", "name"}, []any{1, "a@b.c", "A"}
+	}
+	insert := "INSERT INTO `users` (`id`, `email`, `name`) VALUES (?, ?, ?)"
+	guardedName := "`name` = IF(`email` <=> VALUES(`email`)," +
+		" VALUES(`name`), `name`)"
+
+	query, values := q.Upsert(
+		"users", insertedValues, []string{"email"}, []string{"name"},
+	)
+	expectQuery(t, query, values,
+		insert+" ON DUPLICATE KEY UPDATE "+guardedName,
+		[]any{1, "a@b.c", "A"},
+	)
+
+	query, values = q.UpsertMany(
+		"users",
+		[]database.InsertedValuesFn{insertedValues},
+		[]database.Projection{{Column: "email"}},
+	)
+	expectQuery(t, query, values,
+		insert+" ON DUPLICATE KEY UPDATE "+guardedName,
+		[]any{1, "a@b.c", "A"},
+	)
+
+	query, values, err := q.UpsertWithOptions(
+		"users",
+		[]database.InsertedValuesFn{insertedValues},
+		extendeddatabase.UpsertOptions{
+			ConflictColumns: []string{"email"},
+			UpdateColumns:   []string{"name"},
+		},
+	)
+	if err != nil {
+		t.Fatalf("UpsertWithOptions: %v", err)
+	}
+	expectQuery(t, query, values,
+		insert+" ON DUPLICATE KEY UPDATE "+guardedName,
+		[]any{1, "a@b.c", "A"},
+	)
+
+	query, values = q.Upsert("users", insertedValues, []string{"email"}, nil)
+	expectQuery(t, query, values,
+		insert+" ON DUPLICATE KEY UPDATE `id` = `id`",
+		[]any{1, "a@b.c", "A"},
+	)
+}
@@ -0,0 +1,151 @@
+package mysql
+
+import (
+	"fmt"
+	"strings"
+
+	extendeddatabase "github.com/pakkasys/fluidapi-extended/database"
+	"github.com/pakkasys/fluidapi/database"
+)
+
+// ColumnType returns the MySQL column type of a Go type. Times are stored
+// as Unix nanoseconds, as they are inserted by InsertedValues.
+//
+// Parameters:
+//   - goType: The Go type, e.g. "int64", "time.Time" or "[]byte".
+//
+// Returns:
+//   - string: The column type.
+//   - bool: Whether the Go type has a column type.
+func ColumnType(goType string) (string, bool) {
+	switch goType {
+	case "string":
+		return "VARCHAR(255)", true
+	case "bool":
+		return "TINYINT(1)", true
+	case "int8":
+		return "TINYINT", true
+	case "int16":
+		return "SMALLINT", true
+	case "int", "int32":
+		return "INT", true
+	case "int64", "time.Time":
+		return "BIGINT", true
+	case "uint8":
+		return "TINYINT UNSIGNED", true
+	case "uint16":
+		return "SMALLINT UNSIGNED", true
+	case "uint", "uint32":
+		return "INT UNSIGNED", true
+	case "uint64":
+		return "BIGINT UNSIGNED", true
+	case "float32":
+		return "FLOAT", true
+	case "float64":
+		return "DOUBLE", true
+	case "[]byte":
+		return "BLOB", true
+	default:
+		return "", false
+	}
+}
+
+// TableDefinition builds the definition of a table from the `db` and
+// `dbdef` tags of an entity struct.
+//
+// Parameters:
+//   - tableName: The name of the table.
+//   - entity: The entity struct or a pointer to it.
+//
+// Returns:
+//   - *extendeddatabase.TableDefinition: The table definition.
+//   - error: An error if the definition can not be built.
+func (q *Query) TableDefinition(
+	tableName string, entity any,
+) (*extendeddatabase.TableDefinition, error) {
+	return extendeddatabase.StructTableDefinition(tableName, entity, ColumnType)
+}
+
+// CreateTableQueries generates the SQL queries for creating the table of an
+// entity struct and its indexes. The table definition is built from the
+// `db` and `dbdef` tags of the struct.
+//
+// Parameters:
+//   - tableName: The name of the table.
+//   - ifNotExists: If true, adds IF NOT EXISTS to the table query.
+//   - entity: The entity struct or a pointer to it.
+//   - options: The table options.
+//
+// Returns:
+//   - []string: The SQL queries.
+//   - error: An error if the queries could not be created.
+func (q *Query) CreateTableQueries(
+	tableName string,
+	ifNotExists bool,
+	entity any,
+	options database.TableOptions,
+) ([]string, error) {
+	definition, err := q.TableDefinition(tableName, entity)
+	if err != nil {
+		return nil, err
+	}
+	query, _, err := q.CreateTableQuery(
+		tableName,
+		ifNotExists,
+		definition.Columns,
+		definition.Constraints,
+		options,
+	)
+	if err != nil {
+		return nil, err
+	}
+	queries := []string{query}
+	for _, index := range definition.Indexes {
+		query, _, err := q.CreateIndexQuery(tableName, index)
+		if err != nil {
+			return nil, err
+		}
+		queries = append(queries, query)
+	}
+	return queries, nil
+}
+
+// CreateIndexQuery generates a SQL query for creating an index.
+//
+// Parameters:
+//   - tableName: The name of the table.
+//   - index: The index.
+//
+// Returns:
+//   - string: The SQL query.
+//   - []any: The values.
+//   - error: An error if the index has no columns or an identifier is
+//     invalid.
+func (q *Query) CreateIndexQuery(
+	tableName string, index extendeddatabase.Index,
+) (string, []any, error) {
+	if len(index.Columns) == 0 {
+		return "", nil, fmt.Errorf("CreateIndexQuery: index %q has no columns", index.Name)
+	}
+	indexName, err := quoter.Identifier(index.Name)
+	if err != nil {
+		return "", nil, err
+	}
+	quotedTable, err := quoter.Identifier(tableName)
+	if err != nil {
+		return "", nil, err
+	}
+	columns, err := quoter.Identifiers(index.Columns)
+	if err != nil {
+		return "", nil, err
+	}
+	var builder strings.Builder
+	builder.WriteString("CREATE ")
+	if index.Unique {
+		builder.WriteString("UNIQUE ")
+	}
+	builder.WriteString(fmt.Sprintf(
+		"INDEX %s ON %s (%s);", indexName, quotedTable, columns,
+	))
+	return builder.String(), nil, nil
+}
@@ -0,0 +1,58 @@
+package postgres
+
+import (
+	"testing"
+
+	"github.com/pakkasys/fluidapi-extended/database/querytest"
+)
+
+// golden holds the expected queries of the conformance cases. The queries are
+// not executed since the tests have no PostgreSQL server, so only their SQL text
+// is checked.
+var golden = querytest.Golden{
+	"insert": `INSERT INTO "users" ("id", "name", "age", "group_id")` +
+		` VALUES ($1, $2, $3, $4)`,
+	"insert_many": `INSERT INTO "users" ("id", "name", "age", "group_id")` +
+		` VALUES ($1, $2, $3, $4), ($5, $6, $7, $8)`,
+	"upsert": `INSERT INTO "users" ("id", "name", "age")` +
+		` VALUES ($1, $2, $3)` +
+		` ON CONFLICT ("name") DO UPDATE SET "age" = EXCLUDED."age"`,
+	"upsert/do_nothing": `INSERT INTO "users" ("id", "name", "age")` +
+		` VALUES ($1, $2, $3) ON CONFLICT ("id") DO NOTHING`,
+	"upsert/options": `INSERT INTO "users" ("id", "name", "age", "group_id")` +
+		` VALUES ($1, $2, $3, $4), ($5, $6, $7, $8)` +
+		` ON CONFLICT ON CONSTRAINT "users_pkey" DO UPDATE SET` +
+		` "name" = EXCLUDED."name",` +
+		` "age" = EXCLUDED."age"`,
+	"upsert_many": `INSERT INTO "users" ("id", "name", "age")` +
+		` VALUES ($1, $2, $3), ($4, $5, $6)` +
+		` ON CONFLICT ON CONSTRAINT "users_pkey" DO UPDATE SET "name" = EXCLUDED."name"`,
+	"get/selectors": `SELECT * FROM "users"` +
+		` WHERE "users"."age" >= $1 AND "group_id" = $2`,
+	"get/null": `SELECT "id" FROM "users" WHERE "users"."age" IS NULL`,
+	"get/not_null": `SELECT "id" FROM "users"` +
+		` WHERE "age" IS NOT NULL` +
+		` ORDER BY "id" ASC`,
+	"get/in": `SELECT "users"."id" FROM "users"` +
+		` WHERE "users"."id" IN ($1, $2) AND "name" NOT IN ($3) AND "group_id" IN ($4)`,
+	"get/join": `SELECT "users"."name","groups"."name" AS "group_name" FROM "users"` +
+		` INNER JOIN "groups" ON "groups"."id" = "users"."group_id"` +
+		` ORDER BY "users"."id" ASC`,
+	"get/orders_page": `SELECT "name" FROM "users"` +
+		` ORDER BY "name" DESC` +
+		` LIMIT 2 OFFSET 1`,
+	"get/lock":   `SELECT "name" FROM "users" WHERE "id" = $1 FOR UPDATE`,
+	"count":      `SELECT COUNT(*) FROM "users" WHERE "users"."age" > $1`,
+	"count/page": `SELECT COUNT(*) FROM (SELECT * FROM "users" LIMIT 5 OFFSET 1) AS limited_result`,
+	"update":     `UPDATE "users" SET "age" = $1 WHERE "users"."id" = $2`,
+	"delete":     `DELETE FROM "users" WHERE "users"."age" < $1`,
+	"delete/limit": `DELETE FROM "users" WHERE "ctid" IN (SELECT "ctid" FROM "users"` +
+		` WHERE "id" > $1 ORDER BY "id" ASC` +
+		` LIMIT 1)`,
+}
+
+// TestConformanceSQLText checks the SQL text of the conformance cases. Unlike
+// the SQLite cases, the queries are not executed.
+func TestConformanceSQLText(t *testing.T) {
+	querytest.Run(t, &Query{}, querytest.Options{Golden: golden})
+}
@@ -0,0 +1,89 @@
+package errorchecker
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/pakkasys/fluidapi-extended/database"
+	"github.com/pakkasys/fluidapi/core"
+)
+
+// SQLState represents a PostgreSQL SQLSTATE error code.
+type SQLState string
+
+// Constants for PostgreSQL SQLSTATE error codes.
+const (
+	ForeignConstraintErrorCode   SQLState = "23503"
+	DuplicateEntryErrorCode      SQLState = "23505"
+	NotNullErrorCode             SQLState = "23502"
+	CheckConstraintErrorCode     SQLState = "23514"
+	DataTooLongErrorCode         SQLState = "22001"
+	OutOfRangeErrorCode          SQLState = "22003"
+	DeadlockErrorCode            SQLState = "40P01"
+	LockNotAvailableErrorCode    SQLState = "55P03"
+	ReadOnlyTransactionErrorCode SQLState = "25006"
+	CannotConnectNowErrorCode    SQLState = "57P03"
+)
+
+// errorsBySQLState are the API errors of the SQLSTATE codes.
+var errorsBySQLState = map[SQLState]*core.APIError{
+	DuplicateEntryErrorCode:      database.DuplicateEntryError,
+	ForeignConstraintErrorCode:   database.ForeignConstraintError,
+	NotNullErrorCode:             database.NotNullViolationError,
+	CheckConstraintErrorCode:     database.CheckConstraintError,
+	DataTooLongErrorCode:         database.DataTooLongError,
+	OutOfRangeErrorCode:          database.OutOfRangeError,
+	DeadlockErrorCode:            database.DeadlockError,
+	LockNotAvailableErrorCode:    database.LockTimeoutError,
+	ReadOnlyTransactionErrorCode: database.ReadOnlyDatabaseError,
+	CannotConnectNowErrorCode:    database.DatabaseBusyError,
+}
+
+// SQLStateError is an error that reports its SQLSTATE code. The errors of the
+// lib/pq and pgx drivers implement it, so the checker does not depend on a
+// driver.
+type SQLStateError interface {
+	error
+	SQLState() string
+}
+
+// ErrorChecker is used to check if an error is a PostgreSQL error.
+type ErrorChecker struct {
+	systemId string
+}
+
+// NewErrorChecker returns a new ErrorChecker.
+//
+// Parameters:
+//   - systemId: The origin of the checked errors.
+//
+// Returns:
+//   - *ErrorChecker: The new ErrorChecker.
+func NewErrorChecker(systemId string) *ErrorChecker {
+	return &ErrorChecker{
+		systemId: systemId,
+	}
+}
+
+// Check attempts to match a given error against common PostgreSQL errors.
+//
+// Parameters:
+//   - err: The error to check.
+//
+// Returns:
+//   - error: The checked error.
+func (c *ErrorChecker) Check(err error) error {
+	if err == nil {
+		return nil
+	}
+	var sqlStateErr SQLStateError
+	if errors.As(err, &sqlStateErr) {
+		apiErr, ok := errorsBySQLState[SQLState(sqlStateErr.SQLState())]
+		if ok {
+			return apiErr.WithData(err).WithOrigin(c.systemId)
+		}
+	} else if errors.Is(err, sql.ErrNoRows) {
+		return database.NoRowsError.WithOrigin(c.systemId)
+	}
+	return err
+}
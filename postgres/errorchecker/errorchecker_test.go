@@ -0,0 +1,55 @@
+package errorchecker
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/pakkasys/fluidapi-extended/database"
+	"github.com/pakkasys/fluidapi/core"
+)
+
+// testError is a driver error with a SQLSTATE code.
+type testError struct {
+	code string
+}
+
+func (e *testError) Error() string    { return "pq: error " + e.code }
+func (e *testError) SQLState() string { return e.code }
+
+func TestCheck(t *testing.T) {
+	checker := NewErrorChecker("db")
+	tests := []struct {
+		err      error
+		expected *core.APIError
+	}{
+		{&testError{"23505"}, database.DuplicateEntryError},
+		{fmt.Errorf("insert: %w", &testError{"23503"}), database.ForeignConstraintError},
+		{&testError{"23502"}, database.NotNullViolationError},
+		{&testError{"23514"}, database.CheckConstraintError},
+		{&testError{"22001"}, database.DataTooLongError},
+		{&testError{"22003"}, database.OutOfRangeError},
+		{&testError{"40P01"}, database.DeadlockError},
+		{&testError{"55P03"}, database.LockTimeoutError},
+		{&testError{"25006"}, database.ReadOnlyDatabaseError},
+		{sql.ErrNoRows, database.NoRowsError},
+		{fmt.Errorf("get: %w", sql.ErrNoRows), database.NoRowsError},
+	}
+	for _, tt := range tests {
+		var apiErr *core.APIError
+		if !errors.As(checker.Check(tt.err), &apiErr) ||
+			apiErr.ID != tt.expected.ID || apiErr.Origin != "db" {
+			t.Errorf("expected %s for %v, got %v", tt.expected.ID, tt.err, apiErr)
+		}
+	}
+
+	for _, err := range []error{&testError{"40001"}, errors.New("other")} {
+		if checked := checker.Check(err); checked != err {
+			t.Errorf("expected %v to be returned as is, got %v", err, checked)
+		}
+	}
+	if checker.Check(nil) != nil {
+		t.Errorf("expected nil")
+	}
+}
@@ -0,0 +1,1296 @@
+package postgres
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	extendeddatabase "github.com/pakkasys/fluidapi-extended/database"
+	"github.com/pakkasys/fluidapi/database"
+)
+
+// Constants for comparison operators.
+const (
+	in    = "IN"
+	notIn = "NOT IN"
+	is    = "IS"
+	isNot = "IS NOT"
+	null  = "NULL"
+)
+
+// quoter quotes the identifiers and literals of PostgreSQL queries.
+var quoter = extendeddatabase.Quoter{
+	IdentifierQuote:     '"',
+	MaxIdentifierLength: 63,
+	EscapeStrings:       true,
+}
+
+// Query is a query builder for PostgreSQL. The queries are built with "?"
+// placeholders that are numbered as $1, $2, ... before they are returned.
+type Query struct{}
+
+// Insert returns the query and values to insert an entity.
+//
+// Parameters:
+//   - tableName: The name of the database table.
+//   - insertedValues: Function used to get the columns and values to insert.
+//
+// Returns:
+//   - string: The query.
+//   - []any: The values.
+func (q *Query) Insert(
+	tableName string, insertedValues database.InsertedValuesFn,
+) (string, []any) {
+	columns, values := insertedValues()
+	columnames := getInsertQueryColumnames(columns)
+
+	query := fmt.Sprintf(
+		`INSERT INTO %s (%s) VALUES (%s)`,
+		quoteIdentifier(tableName),
+		columnames,
+		createPlaceholders(len(values)),
+	)
+
+	return numberPlaceholders(query), values
+}
+
+// InsertReturning returns the query and values to insert an entity and return
+// the given columns of the inserted row, such as the generated primary key.
+// PostgreSQL drivers do not report the last insert ID, so the query should be
+// run with QueryRow and the returned columns scanned from the row.
+//
+// Parameters:
+//   - tableName: The name of the database table.
+//   - insertedValues: Function used to get the columns and values to insert.
+//   - returning: The columns to return.
+//
+// Returns:
+//   - string: The query.
+//   - []any: The values.
+func (q *Query) InsertReturning(
+	tableName string,
+	insertedValues database.InsertedValuesFn,
+	returning []string,
+) (string, []any) {
+	query, values := q.Insert(tableName, insertedValues)
+	return query + returningClause(returning), values
+}
+
+// InsertMany returns the query and values to insert multiple entities.
+//
+// Parameters:
+//   - tableName: The name of the database table.
+//   - insertedValues: Functions used to get the columns and values to insert.
+//
+// Returns:
+//   - string: The query.
+//   - []any: The values.
+func (q *Query) InsertMany(
+	tableName string, insertedValues []database.InsertedValuesFn,
+) (string, []any) {
+	query, allValues := insertManyQuery(tableName, insertedValues)
+	return numberPlaceholders(query), allValues
+}
+
+// insertManyQuery returns the query and values to insert multiple entities
+// with "?" placeholders.
+func insertManyQuery(
+	tableName string, insertedValues []database.InsertedValuesFn,
+) (string, []any) {
+	if len(insertedValues) == 0 {
+		return "", nil
+	}
+
+	columns, _ := insertedValues[0]()
+	columnames := getInsertQueryColumnames(columns)
+
+	var allValues []any
+	valuePlaceholders := make([]string, len(insertedValues))
+	for i, iterInsertedValues := range insertedValues {
+		_, values := iterInsertedValues()
+		valuePlaceholders[i] = "(" + createPlaceholders(len(values)) + ")"
+		allValues = append(allValues, values...)
+	}
+
+	query := fmt.Sprintf(
+		`INSERT INTO %s (%s) VALUES %s`,
+		quoteIdentifier(tableName),
+		columnames,
+		strings.Join(valuePlaceholders, ", "),
+	)
+
+	return query, allValues
+}
+
+// UpsertMany creates an upsert query for a list of entities. PostgreSQL needs
+// a conflict target to update the conflicting rows, so the conflicts are
+// resolved on the primary key of the table, which PostgreSQL names
+// "<table>_pkey" by default. If no projections are given, conflicting rows are
+// left untouched.
+//
+// Parameters:
+//   - tableName: The name of the database table.
+//   - insertedValues: The functions used to get the columns and values to
+//     insert.
+//   - updateProjections: The projections of the entities to update.
+//
+// Returns:
+//   - string: The upsert query.
+//   - []any: The values.
+func (q *Query) UpsertMany(
+	tableName string,
+	insertedValues []database.InsertedValuesFn,
+	updateProjections []database.Projection,
+) (string, []any) {
+	if len(updateProjections) == 0 {
+		return q.upsert(tableName, insertedValues, &extendeddatabase.UpsertOptions{
+			DoNothing: true,
+		})
+	}
+
+	updateColumns := make([]string, len(updateProjections))
+	for i, proj := range updateProjections {
+		updateColumns[i] = proj.Column
+	}
+
+	return q.upsert(tableName, insertedValues, &extendeddatabase.UpsertOptions{
+		UpdateColumns: updateColumns,
+	})
+}
+
+// Upsert creates an upsert query for a single entity. If no update columns
+// are given, conflicting rows are left untouched.
+//
+// Parameters:
+//   - tableName: The name of the database table.
+//   - insertedValues: The function used to get the columns and values to insert.
+//   - conflictColumns: The columns that identify a conflicting row. They must
+//     match a unique index or constraint of the table.
+//   - updateColumns: The columns to update on conflict.
+//
+// Returns:
+//   - string: The upsert query.
+//   - []any: The values.
+func (q *Query) Upsert(
+	tableName string,
+	insertedValues database.InsertedValuesFn,
+	conflictColumns []string,
+	updateColumns []string,
+) (string, []any) {
+	return q.upsert(
+		tableName,
+		[]database.InsertedValuesFn{insertedValues},
+		&extendeddatabase.UpsertOptions{
+			ConflictColumns: conflictColumns,
+			UpdateColumns:   updateColumns,
+			DoNothing:       len(updateColumns) == 0,
+		},
+	)
+}
+
+// UpsertWithOptions creates an upsert query for a list of entities with the
+// conflict target and the update policy of the options. If no conflict
+// columns are given, the conflicting rows are updated on the primary key of
+// the table, which PostgreSQL names "<table>_pkey" by default. The columns of
+// the where condition that have no table refer to the conflicting row.
+//
+// Example:
+//
+//	q.UpsertWithOptions("users", values, extendeddatabase.UpsertOptions{
+//	    ConflictColumns: []string{"email"},
+//	    ExcludeColumns:  []string{"id", "created"},
+//	})
+//
+// Output:
+//
+//	INSERT INTO "users" ("id", "email", "name", "created")
+//	VALUES ($1, $2, $3, $4)
+//	ON CONFLICT ("email") DO UPDATE SET "name" = EXCLUDED."name"
+//
+// Parameters:
+//   - tableName: The name of the database table.
+//   - insertedValues: The functions used to get the columns and values to
+//     insert.
+//   - opts: The upsert options.
+//
+// Returns:
+//   - string: The upsert query.
+//   - []any: The values.
+//   - error: InvalidIdentifierError if an identifier is invalid.
+func (q *Query) UpsertWithOptions(
+	tableName string,
+	insertedValues []database.InsertedValuesFn,
+	opts extendeddatabase.UpsertOptions,
+) (string, []any, error) {
+	err := quoter.ValidateIdentifiers(append(
+		[]string{tableName}, opts.Identifiers()...,
+	)...)
+	if err != nil {
+		return "", nil, err
+	}
+	query, values := q.upsert(tableName, insertedValues, &opts)
+	return query, values, nil
+}
+
+// upsert creates an upsert query for a list of entities.
+func (q *Query) upsert(
+	tableName string,
+	insertedValues []database.InsertedValuesFn,
+	opts *extendeddatabase.UpsertOptions,
+) (string, []any) {
+	if len(insertedValues) == 0 {
+		return "", nil
+	}
+
+	insertQuery, values := insertManyQuery(tableName, insertedValues)
+
+	columns, _ := insertedValues[0]()
+	updateColumns := opts.UpdatedColumns(columns)
+
+	conflictTarget := ""
+	if len(opts.ConflictColumns) != 0 {
+		conflictTarget = " (" + getInsertQueryColumnames(opts.ConflictColumns) + ")"
+	} else if len(updateColumns) != 0 {
+		conflictTarget = " ON CONSTRAINT " + quoteIdentifier(tableName+"_pkey")
+	}
+
+	if len(updateColumns) == 0 {
+		return numberPlaceholders(fmt.Sprintf(
+			"%s ON CONFLICT%s DO NOTHING", insertQuery, conflictTarget,
+		)), values
+	}
+
+	builder := strings.Builder{}
+	builder.WriteString(fmt.Sprintf(
+		"%s ON CONFLICT%s DO UPDATE SET %s",
+		insertQuery,
+		conflictTarget,
+		excludedSetClause(updateColumns),
+	))
+	if opts.WhereCondition != nil {
+		// The unqualified columns would be ambiguous with the columns of the
+		// EXCLUDED row.
+		condition, conditionValues := filterCondition(
+			qualifyFilter(*opts.WhereCondition, tableName),
+		)
+		builder.WriteString(" WHERE " + condition)
+		values = append(values, conditionValues...)
+	}
+
+	return numberPlaceholders(builder.String()), values
+}
+
+// Get returns a get query. The names are quoted but not validated since the
+// method can not return an error. GetFiltered validates them.
+//
+//   - tableName: The name of the database table.
+//   - dbOptions: The options for the query.
+//
+// Returns:
+//   - string: The query.
+//   - []any: The values.
+func (q *Query) Get(
+	tableName string, opts *database.GetOptions,
+) (string, []any) {
+	return q.getFiltered(tableName, opts, nil, nil)
+}
+
+// GetKeyset returns a get query that selects the rows after the keyset. The
+// keyset condition is added to the selectors of the options. The orders of
+// the options should match the keyset columns. If the keyset is nil, the
+// query is a regular get query.
+//
+// Parameters:
+//   - tableName: The name of the table.
+//   - opts: The options for the query.
+//   - keyset: The keyset to select the rows after.
+//
+// Returns:
+//   - string: The query.
+//   - []any: The values.
+//   - error: InvalidIdentifierError if a name is invalid.
+func (q *Query) GetKeyset(
+	tableName string, opts *database.GetOptions,
+	keyset *extendeddatabase.Keyset,
+) (string, []any, error) {
+	return q.GetFiltered(tableName, opts, keyset, nil)
+}
+
+// GetFiltered returns a get query that selects the rows that match the filter
+// and come after the keyset. The conditions are added to the selectors of the
+// options. If the filter or keyset is nil, the condition is not added.
+//
+// Parameters:
+//   - tableName: The name of the table.
+//   - opts: The options for the query.
+//   - keyset: The keyset to select the rows after.
+//   - filter: The filter expression the rows must match.
+//
+// Returns:
+//   - string: The query.
+//   - []any: The values.
+//   - error: InvalidIdentifierError if a name is invalid.
+func (q *Query) GetFiltered(
+	tableName string, opts *database.GetOptions,
+	keyset *extendeddatabase.Keyset,
+	filter *extendeddatabase.Filter,
+) (string, []any, error) {
+	err := quoter.ValidateGetQuery(tableName, opts, keyset, filter)
+	if err != nil {
+		return "", nil, err
+	}
+	query, values := q.getFiltered(tableName, opts, keyset, filter)
+	return query, values, nil
+}
+
+// getFiltered builds the get query of GetFiltered without validating the
+// names.
+func (q *Query) getFiltered(
+	tableName string, opts *database.GetOptions,
+	keyset *extendeddatabase.Keyset,
+	filter *extendeddatabase.Filter,
+) (string, []any) {
+	whereColumns, whereValues := processSelectors(opts.Selectors)
+	if filter != nil {
+		condition, values := filterCondition(*filter)
+		whereColumns = append(whereColumns, condition)
+		whereValues = append(whereValues, values...)
+	}
+	if keyset != nil && len(keyset.Columns) != 0 {
+		condition, values := keyset.Condition(keysetColumnToString)
+		whereColumns = append(whereColumns, condition)
+		whereValues = append(whereValues, values...)
+	}
+	whereClause := getWhereClause(whereColumns)
+
+	builder := strings.Builder{}
+	builder.WriteString(fmt.Sprintf(
+		"SELECT %s",
+		strings.Join(projectionsToStrings(opts.Projections), ","),
+	))
+	builder.WriteString(fmt.Sprintf(` FROM %s`, quoteIdentifier(tableName)))
+	if len(opts.Joins) != 0 {
+		builder.WriteString(" " + joinClause(opts.Joins))
+	}
+	if whereClause != "" {
+		builder.WriteString(" " + whereClause)
+	}
+	orderClause, orderValues := filteredOrderClause(opts.Orders, filter)
+	if orderClause != "" {
+		builder.WriteString(" " + orderClause)
+	}
+	if opts.Page != nil {
+		builder.WriteString(" " + getLimitOffsetClauseFromPage(opts.Page))
+	}
+	if opts.Lock {
+		builder.WriteString(" FOR UPDATE")
+	}
+
+	return numberPlaceholders(builder.String()),
+		append(whereValues, orderValues...)
+}
+
+// Count returns a count query. The names are quoted but not validated since the
+// method can not return an error. CountFiltered validates them.
+//
+// Parameters:
+//   - tableName: The name of the database table.
+//   - dbOptions: The options for the query.
+//
+// Returns:
+//   - string: The query.
+//   - []any: The values.
+func (q *Query) Count(
+	tableName string, opts *database.CountOptions,
+) (string, []any) {
+	return q.countFiltered(tableName, opts, nil)
+}
+
+// CountFiltered returns a count query that counts the rows that match the
+// filter. If the filter is nil, the query is a regular count query.
+//
+// Parameters:
+//   - tableName: The name of the database table.
+//   - opts: The options for the query.
+//   - filter: The filter expression the rows must match.
+//
+// Returns:
+//   - string: The query.
+//   - []any: The values.
+//   - error: InvalidIdentifierError if a name is invalid.
+func (q *Query) CountFiltered(
+	tableName string,
+	opts *database.CountOptions,
+	filter *extendeddatabase.Filter,
+) (string, []any, error) {
+	if err := quoter.ValidateCountQuery(tableName, opts, filter); err != nil {
+		return "", nil, err
+	}
+	query, values := q.countFiltered(tableName, opts, filter)
+	return query, values, nil
+}
+
+// countFiltered builds the count query of CountFiltered without validating
+// the names.
+func (q *Query) countFiltered(
+	tableName string,
+	opts *database.CountOptions,
+	filter *extendeddatabase.Filter,
+) (string, []any) {
+	whereClause, whereValues := whereClause(opts.Selectors, filter)
+	joinStmt := joinClause(opts.Joins)
+
+	if opts.Page != nil {
+		// Build the inner query with pagination.
+		innerQuery := extendeddatabase.JoinClauses(
+			"SELECT * FROM "+quoteIdentifier(tableName),
+			joinStmt,
+			whereClause,
+			getLimitOffsetClauseFromPage(opts.Page),
+		)
+
+		// Wrap the inner query in an outer COUNT(*) query.
+		query := fmt.Sprintf(
+			"SELECT COUNT(*) FROM (%s) AS limited_result",
+			innerQuery,
+		)
+		return numberPlaceholders(query), whereValues
+	}
+
+	// Otherwise, build a simple COUNT query without pagination.
+	query := extendeddatabase.JoinClauses(
+		"SELECT COUNT(*) FROM "+quoteIdentifier(tableName),
+		joinStmt,
+		whereClause,
+	)
+
+	return numberPlaceholders(query), whereValues
+}
+
+// Aggregate returns a query that groups the rows that match the selectors and
+// computes the aggregates of each group. The groups and aggregates are
+// selected in order with their aliases.
+//
+// Parameters:
+//   - tableName: The name of the table.
+//   - opts: The options for the query.
+//
+// Returns:
+//   - string: The query.
+//   - []any: The values.
+//   - error: InvalidIdentifierError if a name is invalid.
+func (q *Query) Aggregate(
+	tableName string, opts *extendeddatabase.AggregateOptions,
+) (string, []any, error) {
+	if err := quoter.ValidateIdentifier(tableName); err != nil {
+		return "", nil, err
+	}
+	if err := quoter.ValidateAggregateOptions(opts); err != nil {
+		return "", nil, err
+	}
+	query, values := q.aggregate(tableName, opts)
+	return query, values, nil
+}
+
+// aggregate builds the aggregate query of Aggregate without validating the
+// names.
+func (q *Query) aggregate(
+	tableName string, opts *extendeddatabase.AggregateOptions,
+) (string, []any) {
+	var columns []string
+	var groupColumns []string
+	for _, groupBy := range opts.GroupBy {
+		expression := groupByExpression(groupBy)
+		columns = append(
+			columns, fmt.Sprintf(
+				`%s AS %s`, expression, quoteIdentifier(groupBy.Alias),
+			),
+		)
+		groupColumns = append(groupColumns, expression)
+	}
+	for _, aggregate := range opts.Aggregates {
+		columns = append(columns, fmt.Sprintf(
+			`%s AS %s`,
+			aggregateExpression(aggregate),
+			quoteIdentifier(aggregate.Alias),
+		))
+	}
+	whereColumns, values := processSelectors(opts.Selectors)
+
+	builder := strings.Builder{}
+	builder.WriteString(fmt.Sprintf(
+		`SELECT %s FROM %s`,
+		strings.Join(columns, ","),
+		quoteIdentifier(tableName),
+	))
+	if whereClause := getWhereClause(whereColumns); whereClause != "" {
+		builder.WriteString(" " + whereClause)
+	}
+	if len(groupColumns) != 0 {
+		builder.WriteString(" GROUP BY " + strings.Join(groupColumns, ","))
+	}
+	if len(opts.Having) != 0 {
+		conditions := make([]string, len(opts.Having))
+		for i, having := range opts.Having {
+			conditions[i] = fmt.Sprintf(
+				"%s %s ?",
+				aggregateExpression(having.Aggregate),
+				having.Predicate,
+			)
+			values = append(values, having.Value)
+		}
+		builder.WriteString(" HAVING " + strings.Join(conditions, " AND "))
+	}
+	if len(opts.Orders) != 0 {
+		builder.WriteString(" " + getOrderClauseFromOrders(opts.Orders))
+	}
+	if opts.Page != nil {
+		builder.WriteString(" " + getLimitOffsetClauseFromPage(opts.Page))
+	}
+	return numberPlaceholders(builder.String()), values
+}
+
+// UpdateQuery returns the SQL query and values for an update query.
+//
+// Parameters:
+//   - tableName: The name of the database table.
+//   - updates: The fields to update.
+//   - selectors: The selectors for the entities to update.
+//
+// Returns:
+//   - string: The query.
+//   - []any: The values.
+func (q *Query) UpdateQuery(
+	tableName string, updates []database.Update, selectors []database.Selector,
+) (string, []any) {
+	whereColumns, whereValues := processSelectors(selectors)
+
+	setClause, values := getSetClause(updates)
+	values = append(values, whereValues...)
+
+	builder := strings.Builder{}
+	builder.WriteString(fmt.Sprintf(
+		`UPDATE %s SET %s`,
+		quoteIdentifier(tableName),
+		setClause,
+	))
+	if len(whereColumns) != 0 {
+		builder.WriteString(" " + getWhereClause(whereColumns))
+	}
+
+	return numberPlaceholders(builder.String()), values
+}
+
+// UpdateReturning returns the SQL query and values for an update query that
+// returns the given columns of the updated rows.
+//
+// Parameters:
+//   - tableName: The name of the database table.
+//   - updates: The fields to update.
+//   - selectors: The selectors for the entities to update.
+//   - returning: The columns to return.
+//
+// Returns:
+//   - string: The query.
+//   - []any: The values.
+func (q *Query) UpdateReturning(
+	tableName string,
+	updates []database.Update,
+	selectors []database.Selector,
+	returning []string,
+) (string, []any) {
+	query, values := q.UpdateQuery(tableName, updates, selectors)
+	return query + returningClause(returning), values
+}
+
+// Delete returns the SQL query string and the values for the query.
+// PostgreSQL does not support ORDER BY and LIMIT in DELETE statements, so if
+// the options have a limit, the deleted rows are selected by their ctid in a
+// subquery.
+//
+// Parameters:
+//   - tableName: The name of the database table.
+//   - selectors: The selectors for the entities to delete.
+//   - opts: The options for the query.
+//
+// Returns:
+//   - string: The query.
+//   - []any: The values.
+func (q *Query) Delete(
+	tableName string,
+	selectors []database.Selector,
+	opts *database.DeleteOptions,
+) (string, []any) {
+	whereColumns, whereValues := processSelectors(selectors)
+	whereClause := getWhereClause(whereColumns)
+
+	if opts == nil || opts.Limit <= 0 {
+		query := strings.Trim(
+			fmt.Sprintf(
+				`DELETE FROM %s %s`, quoteIdentifier(tableName), whereClause,
+			), " ",
+		)
+		return numberPlaceholders(query), whereValues
+	}
+
+	builder := strings.Builder{}
+	builder.WriteString(fmt.Sprintf(
+		`SELECT "ctid" FROM %s`, quoteIdentifier(tableName),
+	))
+	if whereClause != "" {
+		builder.WriteString(" " + whereClause)
+	}
+	writeDeleteOptions(&builder, opts)
+
+	query := fmt.Sprintf(
+		`DELETE FROM %s WHERE "ctid" IN (%s)`,
+		quoteIdentifier(tableName),
+		builder.String(),
+	)
+	return numberPlaceholders(query), whereValues
+}
+
+// CreateDatabaseQuery generates a SQL query for creating a database.
+// PostgreSQL does not support IF NOT EXISTS for databases, so an error is
+// returned if it is requested. The charset and collate are set as the
+// encoding and the collation of the database.
+//
+// Parameters:
+//   - dbName: The name of the database to create.
+//   - ifNotExists: Must be false.
+//   - charset: The encoding of the database, e.g. "UTF8".
+//   - collate: The collation of the database, e.g. "en_US.UTF-8".
+//
+// Returns:
+//   - string: The SQL query.
+//   - []any: The values.
+//   - error: An error if the query could not be created.
+func (q *Query) CreateDatabaseQuery(
+	dbName string, ifNotExists bool, charset string, collate string,
+) (string, []any, error) {
+	if ifNotExists {
+		return "", nil, fmt.Errorf(
+			"CreateDatabaseQuery: PostgreSQL does not support IF NOT EXISTS for databases",
+		)
+	}
+	quotedName, err := quoter.Identifier(dbName)
+	if err != nil {
+		return "", nil, err
+	}
+	var builder strings.Builder
+	builder.WriteString("CREATE DATABASE " + quotedName)
+	if charset != "" {
+		builder.WriteString(" ENCODING " + quoter.Literal(charset))
+	}
+	if collate != "" {
+		builder.WriteString(" LC_COLLATE " + quoter.Literal(collate))
+	}
+	builder.WriteString(";")
+	return builder.String(), nil, nil
+}
+
+// CreateTableQuery generates a SQL query for creating a table. Auto-increment
+// columns are identity columns. The table options do not apply in PostgreSQL
+// and are ignored.
+//
+// Parameters:
+//   - tableName: The name of the table.
+//   - ifNotExists: If true, adds IF NOT EXISTS.
+//   - columns: The column definitions, one per column.
+//   - constraints: Additional table constraints as raw strings (e.g. unique
+//     keys or composite primary keys).
+//   - options: The table options.
+//
+// Returns:
+//   - string: The SQL query.
+//   - []any: The values.
+//   - error: An error if the query could not be created.
+func (q *Query) CreateTableQuery(
+	tableName string,
+	ifNotExists bool,
+	columns []database.ColumnDefinition,
+	constraints []string,
+	options database.TableOptions,
+) (string, []any, error) {
+	if err := quoter.ValidateIdentifier(tableName); err != nil {
+		return "", nil, err
+	}
+	for _, col := range columns {
+		if err := quoter.ValidateIdentifier(col.Name); err != nil {
+			return "", nil, err
+		}
+	}
+
+	var builder strings.Builder
+
+	builder.WriteString("CREATE TABLE ")
+	if ifNotExists {
+		builder.WriteString("IF NOT EXISTS ")
+	}
+	builder.WriteString(fmt.Sprintf("%s (\n", quoteIdentifier(tableName)))
+
+	var defs []string
+	// Build column definitions.
+	for _, col := range columns {
+		def := fmt.Sprintf("  %s %s", quoteIdentifier(col.Name), col.Type)
+		if col.Extra != "" {
+			def += " " + col.Extra
+		}
+		if col.AutoIncrement {
+			def += " GENERATED BY DEFAULT AS IDENTITY"
+		}
+		if col.NotNull {
+			def += " NOT NULL"
+		} else {
+			def += " NULL"
+		}
+		if col.Default != nil {
+			def += " DEFAULT "
+			// For values like CURRENT_TIMESTAMP or NULL, no quoting is done.
+			if *col.Default == "CURRENT_TIMESTAMP" || *col.Default == "NULL" {
+				def += *col.Default
+			} else {
+				def += quoter.Literal(*col.Default)
+			}
+		}
+		if col.PrimaryKey {
+			def += " PRIMARY KEY"
+		}
+		if col.Unique && !col.PrimaryKey {
+			def += " UNIQUE"
+		}
+		defs = append(defs, def)
+	}
+	// Append additional table constraints if provided.
+	for _, constraint := range constraints {
+		defs = append(defs, "  "+constraint)
+	}
+	builder.WriteString(strings.Join(defs, ",\n"))
+	builder.WriteString("\n);")
+	return builder.String(), nil, nil
+}
+
+// UseDatabaseQuery returns an error since a PostgreSQL connection can not
+// switch databases. The database must be selected when connecting.
+//
+// Parameters:
+//   - dbName: The name of the database to switch to.
+//
+// Returns:
+//   - string: An empty string.
+//   - []any: The values. It is nil.
+//   - error: An error since the query is not supported.
+func (q *Query) UseDatabaseQuery(dbName string) (string, []any, error) {
+	return "", nil, fmt.Errorf(
+		"UseDatabaseQuery: PostgreSQL can not switch to database %s", dbName,
+	)
+}
+
+// SetVariableQuery generates a SQL query to set a session variable.
+// For example, to set the time zone or the search path.
+// If the variable is "NAMES" (case-insensitive), the function uses the
+// syntax "SET NAMES 'value'", which sets the client encoding; otherwise it
+// uses "SET variable = 'value'".
+//
+// Parameters:
+//   - variable: The name of the variable to set.
+//   - value: The value to set the variable to.
+//
+// Returns:
+//   - string: The SQL query.
+//   - []any: The values.
+//   - error: An error if the query could not be created.
+func (q *Query) SetVariableQuery(
+	variable string, value string,
+) (string, []any, error) {
+	if err := extendeddatabase.ValidateName(variable); err != nil {
+		return "", nil, err
+	}
+	upperVar := strings.ToUpper(variable)
+	if upperVar == "NAMES" {
+		return fmt.Sprintf("SET NAMES %s;", quoter.Literal(value)), nil, nil
+	}
+	return fmt.Sprintf(
+		"SET %s = %s;", variable, quoter.Literal(value),
+	), nil, nil
+}
+
+// AdvisoryLock generates the query to try to acquire a session level advisory
+// lock in PostgreSQL. The lock name is hashed to the key of the lock. The
+// query returns 1 if the lock is acquired and 0 if it is held by another
+// session. It does not wait for the lock, so the caller must poll it until
+// the timeout.
+//
+// Parameters:
+//   - lockName: The name of the lock to acquire.
+//   - timeout: The timeout for the lock. It is not used.
+//
+// Returns:
+//   - string: The SQL query.
+//   - []any: The values.
+//   - error: An error if the query could not be created.
+func (q *Query) AdvisoryLock(
+	lockName string, timeout int,
+) (string, []any, error) {
+	return "SELECT CASE WHEN pg_try_advisory_lock(hashtext($1))" +
+		" THEN 1 ELSE 0 END;", []any{lockName}, nil
+}
+
+// AdvisoryUnlock generates the query to release an advisory lock in
+// PostgreSQL.
+//
+// Parameters:
+//   - lockName: The name of the lock to release.
+//
+// Returns:
+//   - string: The SQL query.
+//   - []any: The values.
+//   - error: An error if the query could not be created.
+func (q *Query) AdvisoryUnlock(lockName string) (string, []any, error) {
+	return "SELECT pg_advisory_unlock(hashtext($1));", []any{lockName}, nil
+}
+
+// numberPlaceholders replaces the "?" placeholders of a query with numbered
+// "$n" placeholders. Question marks in quoted strings and identifiers are left
+// as they are.
+//
+// Example:
+//
+//	numberPlaceholders(`SELECT * FROM "t" WHERE "a" = ? AND "b" != '?'`)
+//
+// Output:
+//
+//	SELECT * FROM "t" WHERE "a" = $1 AND "b" != '?'
+func numberPlaceholders(query string) string {
+	builder := strings.Builder{}
+	count := 0
+	var quote byte
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+			builder.WriteByte(c)
+		case c == '\'' || c == '"':
+			quote = c
+			builder.WriteByte(c)
+		case c == '?':
+			count++
+			builder.WriteString("$" + strconv.Itoa(count))
+		default:
+			builder.WriteByte(c)
+		}
+	}
+	return builder.String()
+}
+
+// returningClause returns the RETURNING clause of the columns, or an empty
+// string if there are no columns.
+func returningClause(columns []string) string {
+	if len(columns) == 0 {
+		return ""
+	}
+	return " RETURNING " + getInsertQueryColumnames(columns)
+}
+
+// excludedSetClause returns the SET clause that updates the columns with the
+// values of the row that was proposed for insertion.
+func excludedSetClause(columns []string) string {
+	sets := make([]string, len(columns))
+	for i, column := range columns {
+		sets[i] = fmt.Sprintf(
+			`%s = EXCLUDED.%s`,
+			quoteIdentifier(column),
+			quoteIdentifier(column),
+		)
+	}
+	return strings.Join(sets, ", ")
+}
+
+// getLimitOffsetClauseFromPage returns the LIMIT and OFFSET clause for a page.
+func getLimitOffsetClauseFromPage(page *database.Page) string {
+	if page == nil {
+		return ""
+	}
+	return fmt.Sprintf(
+		"LIMIT %d OFFSET %d",
+		page.Limit,
+		page.Offset,
+	)
+}
+
+// getOrderClauseFromOrders returns an ORDER BY clause. The directions are
+// written as ASC or DESC.
+func getOrderClauseFromOrders(orders []database.Order) string {
+	if len(orders) == 0 {
+		return ""
+	}
+	orderClause := "ORDER BY"
+	for _, order := range orders {
+		if order.Table == "" {
+			orderClause += fmt.Sprintf(
+				` %s %s,`, quoteIdentifier(order.Field), extendeddatabase.SQLDirection(order.Direction),
+			)
+		} else {
+			orderClause += fmt.Sprintf(
+				` %s.%s %s,`,
+				quoteIdentifier(order.Table),
+				quoteIdentifier(order.Field),
+				extendeddatabase.SQLDirection(order.Direction),
+			)
+		}
+	}
+	return strings.TrimSuffix(orderClause, ",")
+}
+
+// columnSelectorToString returns the string representation of a column
+// selector.
+func columnSelectorToString(columnSelector database.ColumnSelector) string {
+	return quoter.QuoteQualified(columnSelector.Table, columnSelector.Column)
+}
+
+// processSelectors processes selectors and returns conditions and values.
+func processSelectors(selectors []database.Selector) ([]string, []any) {
+	var whereColumns []string
+	var whereValues []any
+	for _, selector := range selectors {
+		col, vals := processSelector(selector)
+		whereColumns = append(whereColumns, col)
+		whereValues = append(whereValues, vals...)
+	}
+	return whereColumns, whereValues
+}
+
+// projectionToString returns the string representation of a projection.
+func projectionToString(projection database.Projection) string {
+	builder := strings.Builder{}
+	if projection.Table == "" {
+		builder.WriteString(quoteIdentifier(projection.Column))
+	} else {
+		builder.WriteString(
+			quoter.QuoteQualified(projection.Table, projection.Column),
+		)
+	}
+	if projection.Alias != "" {
+		builder.WriteString(fmt.Sprintf(
+			` AS %s`, quoteIdentifier(projection.Alias),
+		))
+	}
+	return builder.String()
+}
+
+// getInsertQueryColumnames returns the string representation of column names.
+func getInsertQueryColumnames(columns []string) string {
+	wrappedColumns := make([]string, len(columns))
+	for i, column := range columns {
+		wrappedColumns[i] = quoteIdentifier(column)
+	}
+	return strings.Join(wrappedColumns, ", ")
+}
+
+// projectionsToStrings returns the string representations of projections.
+func projectionsToStrings(projections []database.Projection) []string {
+	if len(projections) == 0 {
+		return []string{"*"}
+	}
+	projectionStrings := make([]string, len(projections))
+	for i, projection := range projections {
+		projectionStrings[i] = projectionToString(projection)
+	}
+	return projectionStrings
+}
+
+// joinClause returns the string representation of a join clause.
+func joinClause(joins []database.Join) string {
+	var joinClause string
+	for _, join := range joins {
+		if joinClause != "" {
+			joinClause += " "
+		}
+		joinClause += fmt.Sprintf(
+			`%s JOIN %s ON %s = %s`,
+			join.Type,
+			quoteIdentifier(join.Table),
+			columnSelectorToString(join.OnLeft),
+			columnSelectorToString(join.OnRight),
+		)
+	}
+	return joinClause
+}
+
+// whereClause returns the string representation of a where clause. If the
+// filter is set, its condition is added to the selectors.
+func whereClause(
+	selectors []database.Selector, filter *extendeddatabase.Filter,
+) (string, []any) {
+	whereColumns, whereValues := processSelectors(selectors)
+	if filter != nil {
+		condition, values := filterCondition(*filter)
+		whereColumns = append(whereColumns, condition)
+		whereValues = append(whereValues, values...)
+	}
+	return getWhereClause(whereColumns), whereValues
+}
+
+// getWhereClause returns the string representation of a where clause.
+func getWhereClause(whereColumns []string) string {
+	if len(whereColumns) > 0 {
+		return "WHERE " + strings.Join(whereColumns, " AND ")
+	}
+	return ""
+}
+
+// getSetClause returns the string representation of a SET clause. The
+// columns of Increment values are incremented.
+func getSetClause(updates []database.Update) (string, []any) {
+	setClauseParts := make([]string, len(updates))
+	values := make([]any, len(updates))
+	for i, update := range updates {
+		if increment, ok := update.Value.(extendeddatabase.Increment); ok {
+			setClauseParts[i] = fmt.Sprintf(
+				"%s = %s + ?",
+				quoteIdentifier(update.Field),
+				quoteIdentifier(update.Field),
+			)
+			values[i] = increment.By
+			continue
+		}
+		setClauseParts[i] = fmt.Sprintf("%s = ?", quoteIdentifier(update.Field))
+		values[i] = update.Value
+	}
+	return strings.Join(setClauseParts, ", "), values
+}
+
+// writeDeleteOptions writes the order and limit of the delete options to the
+// builder of the subquery that selects the deleted rows.
+func writeDeleteOptions(
+	builder *strings.Builder, opts *database.DeleteOptions,
+) {
+	orderClause := getOrderClauseFromOrders(opts.Orders)
+	if orderClause != "" {
+		builder.WriteString(" " + orderClause)
+	}
+	builder.WriteString(fmt.Sprintf(" LIMIT %d", opts.Limit))
+}
+
+// processSelector processes a selector and returns a condition and values.
+func processSelector(selector database.Selector) (string, []any) {
+	if selector.Predicate == in || selector.Predicate == notIn {
+		return processInSelector(selector)
+	}
+	return processDefaultSelector(selector)
+}
+
+// processInSelector processes an IN or NOT IN selector and returns conditions
+// and values.
+func processInSelector(selector database.Selector) (string, []any) {
+	column := selectorColumn(selector)
+	value := reflect.ValueOf(selector.Value)
+	if value.Kind() == reflect.Slice {
+		placeholders, values := createPlaceholdersAndValues(value)
+		return fmt.Sprintf(
+			"%s %s (%s)", column, selector.Predicate, placeholders,
+		), values
+	}
+	// If value is not a slice, treat as a single value
+	return fmt.Sprintf(
+		"%s %s (?)", column, selector.Predicate,
+	), []any{selector.Value}
+}
+
+// processDefaultSelector processes a default selector and returns conditions
+// and values.
+func processDefaultSelector(selector database.Selector) (string, []any) {
+	if selector.Value == nil {
+		return processNullSelector(selector)
+	}
+	return fmt.Sprintf(
+		"%s %s ?", selectorColumn(selector), selector.Predicate,
+	), []any{selector.Value}
+}
+
+// processNullSelector processes a null selector and returns conditions and
+// values.
+func processNullSelector(selector database.Selector) (string, []any) {
+	if selector.Predicate == "=" {
+		return buildNullClause(selector, is), nil
+	}
+	if selector.Predicate == "!=" {
+		return buildNullClause(selector, isNot), nil
+	}
+	return "", nil
+}
+
+// buildNullClause returns the string representation of a null clause.
+func buildNullClause(selector database.Selector, clause string) string {
+	return fmt.Sprintf("%s %s %s", selectorColumn(selector), clause, null)
+}
+
+// selectorColumn returns the quoted column of a selector.
+func selectorColumn(selector database.Selector) string {
+	return keysetColumnToString(extendeddatabase.KeysetColumn{
+		Table:  selector.Table,
+		Column: selector.Column,
+	})
+}
+
+// createPlaceholdersAndValues creates placeholders and values for a slice.
+func createPlaceholdersAndValues(value reflect.Value) (string, []any) {
+	placeholderCount := value.Len()
+	placeholders := createPlaceholders(placeholderCount)
+	values := make([]any, placeholderCount)
+	for i := 0; i < placeholderCount; i++ {
+		values[i] = value.Index(i).Interface()
+	}
+	return placeholders, values
+}
+
+// createPlaceholders creates placeholders for a slice.
+func createPlaceholders(count int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", count), ", ")
+}
+
+// keysetColumnToString returns the string representation of a keyset column.
+func keysetColumnToString(column extendeddatabase.KeysetColumn) string {
+	return quoter.QuoteQualified(column.Table, column.Column)
+}
+
+// filterCondition returns the condition of a filter expression. The groups
+// are parenthesized so that the condition can be combined with the other
+// conditions of the where clause.
+func filterCondition(filter extendeddatabase.Filter) (string, []any) {
+	if filter.Selector != nil {
+		return processSelector(*filter.Selector)
+	}
+	if filter.Search != nil {
+		return searchCondition(*filter.Search)
+	}
+	conditions := make([]string, len(filter.Filters))
+	var values []any
+	for i, child := range filter.Filters {
+		condition, childValues := filterCondition(child)
+		conditions[i] = condition
+		values = append(values, childValues...)
+	}
+	if filter.Operator == extendeddatabase.FilterNot {
+		return fmt.Sprintf("NOT (%s)", strings.Join(conditions, " AND ")), values
+	}
+	return fmt.Sprintf(
+		"(%s)", strings.Join(conditions, fmt.Sprintf(" %s ", filter.Operator)),
+	), values
+}
+
+// qualifyFilter returns a copy of the filter with the table set on the
+// selectors that have no table.
+func qualifyFilter(
+	filter extendeddatabase.Filter, tableName string,
+) extendeddatabase.Filter {
+	if filter.Selector != nil && filter.Selector.Table == "" {
+		selector := *filter.Selector
+		selector.Table = tableName
+		filter.Selector = &selector
+	}
+	if len(filter.Filters) != 0 {
+		filters := make([]extendeddatabase.Filter, len(filter.Filters))
+		for i, child := range filter.Filters {
+			filters[i] = qualifyFilter(child, tableName)
+		}
+		filter.Filters = filters
+	}
+	return filter
+}
+
+// searchCondition returns the condition of a full-text search. The searched
+// columns are matched as one document with the default text search
+// configuration. A GIN index on the same expression speeds up the search.
+func searchCondition(search extendeddatabase.Search) (string, []any) {
+	return fmt.Sprintf(
+		"%s @@ plainto_tsquery(?)", searchDocument(search),
+	), []any{search.Query}
+}
+
+// searchDocument returns the tsvector expression of the searched columns.
+func searchDocument(search extendeddatabase.Search) string {
+	columns := make([]string, len(search.Columns))
+	for i, column := range search.Columns {
+		columns[i] = fmt.Sprintf(
+			"coalesce(%s, '')",
+			keysetColumnToString(extendeddatabase.KeysetColumn{
+				Table:  search.Table,
+				Column: column,
+			}),
+		)
+	}
+	return fmt.Sprintf("to_tsvector(%s)", strings.Join(columns, " || ' ' || "))
+}
+
+// filteredOrderClause returns the ORDER BY clause of a filtered get query. If
+// the filter has a search term that orders by relevance, the rows are ordered
+// by their ts_rank, best match first, and the orders are used to break ties.
+func filteredOrderClause(
+	orders []database.Order, filter *extendeddatabase.Filter,
+) (string, []any) {
+	var search *extendeddatabase.Search
+	if filter != nil {
+		search = filter.RelevanceSearch()
+	}
+	if search == nil {
+		return getOrderClauseFromOrders(orders), nil
+	}
+	orderClause := fmt.Sprintf(
+		"ORDER BY ts_rank(%s, plainto_tsquery(?)) DESC", searchDocument(*search),
+	)
+	if len(orders) != 0 {
+		orderClause += "," + strings.TrimPrefix(
+			getOrderClauseFromOrders(orders), "ORDER BY",
+		)
+	}
+	return orderClause, []any{search.Query}
+}
+
+// postgresDateFormats are the to_char formats of the date buckets.
+var postgresDateFormats = map[extendeddatabase.DateBucket]string{
+	extendeddatabase.BucketHour:  "YYYY-MM-DD HH24:00:00",
+	extendeddatabase.BucketDay:   "YYYY-MM-DD",
+	extendeddatabase.BucketWeek:  `IYYY-"W"IW`,
+	extendeddatabase.BucketMonth: "YYYY-MM",
+	extendeddatabase.BucketYear:  "YYYY",
+}
+
+// groupByExpression returns the expression of a group. Date buckets are
+// formatted with to_char from the Unix nanoseconds of the column in UTC.
+func groupByExpression(groupBy extendeddatabase.GroupBy) string {
+	column := keysetColumnToString(extendeddatabase.KeysetColumn{
+		Table:  groupBy.Table,
+		Column: groupBy.Column,
+	})
+	format, ok := postgresDateFormats[groupBy.Bucket]
+	if !ok {
+		return column
+	}
+	date := "to_timestamp(" + column + " / 1e9) AT TIME ZONE 'UTC'"
+	return "to_char(" + date + ", '" + format + "')"
+}
+
+// aggregateExpression returns the expression of an aggregate.
+func aggregateExpression(aggregate extendeddatabase.Aggregate) string {
+	if aggregate.Column == "" {
+		return fmt.Sprintf("%s(*)", aggregate.Function)
+	}
+	return fmt.Sprintf(
+		"%s(%s)",
+		aggregate.Function,
+		keysetColumnToString(extendeddatabase.KeysetColumn{
+			Table:  aggregate.Table,
+			Column: aggregate.Column,
+		}),
+	)
+}
+
+// quoteIdentifier quotes an identifier with double quotes.
+func quoteIdentifier(name string) string {
+	return quoter.QuoteIdentifier(name)
+}
@@ -0,0 +1,376 @@
+package postgres
+
+import (
+	"reflect"
+	"testing"
+
+	extendeddatabase "github.com/pakkasys/fluidapi-extended/database"
+	"github.com/pakkasys/fluidapi/database"
+)
+
+func userValues(name string, age int) database.InsertedValuesFn {
+	return func() ([]string, []any) {
+		return []string{"name", "age"}, []any{name, age}
+	}
+}
+
+func expectQuery(
+	t *testing.T,
+	query string,
+	values []any,
+	expectedQuery string,
+	expectedValues []any,
+) {
+	t.Helper()
+	if query != expectedQuery {
+		t.Errorf("expected query\n%s\ngot\n%s", expectedQuery, query)
+	}
+	if !reflect.DeepEqual(values, expectedValues) {
+		t.Errorf("expected values %v, got %v", expectedValues, values)
+	}
+}
+
+func TestInsert(t *testing.T) {
+	q := &Query{}
+	query, values := q.Insert("users", userValues("a", 1))
+	expectQuery(t, query, values,
+		`INSERT INTO "users" ("name", "age") VALUES ($1, $2)`,
+		[]any{"a", 1},
+	)
+
+	query, values = q.InsertReturning("users", userValues("a", 1), []string{"id"})
+	expectQuery(t, query, values,
+		`INSERT INTO "users" ("name", "age") VALUES ($1, $2) RETURNING "id"`,
+		[]any{"a", 1},
+	)
+
+	query, values = q.InsertMany("users", []database.InsertedValuesFn{
+		userValues("a", 1), userValues("b", 2),
+	})
+	expectQuery(t, query, values,
+		`INSERT INTO "users" ("name", "age") VALUES ($1, $2), ($3, $4)`,
+		[]any{"a", 1, "b", 2},
+	)
+}
+
+func TestUpsert(t *testing.T) {
+	q := &Query{}
+	query, values := q.Upsert(
+		"users", userValues("a", 1), []string{"name"}, []string{"age"},
+	)
+	expectQuery(t, query, values,
+		`INSERT INTO "users" ("name", "age") VALUES ($1, $2)`+
+			` ON CONFLICT ("name") DO UPDATE SET "age" = EXCLUDED."age"`,
+		[]any{"a", 1},
+	)
+
+	query, _ = q.Upsert("users", userValues("a", 1), []string{"name"}, nil)
+	expected := `INSERT INTO "users" ("name", "age") VALUES ($1, $2)` +
+		` ON CONFLICT ("name") DO NOTHING`
+	if query != expected {
+		t.Errorf("expected %s, got %s", expected, query)
+	}
+
+	query, values = q.UpsertMany(
+		"users",
+		[]database.InsertedValuesFn{userValues("a", 1), userValues("b", 2)},
+		[]database.Projection{{Column: "name"}, {Column: "age"}},
+	)
+	expectQuery(t, query, values,
+		`INSERT INTO "users" ("name", "age") VALUES ($1, $2), ($3, $4)`+
+			` ON CONFLICT ON CONSTRAINT "users_pkey"`+
+			` DO UPDATE SET "name" = EXCLUDED."name", "age" = EXCLUDED."age"`,
+		[]any{"a", 1, "b", 2},
+	)
+}
+
+func TestUpsertWithOptions(t *testing.T) {
+	q := &Query{}
+	query, values, err := q.UpsertWithOptions(
+		"users",
+		[]database.InsertedValuesFn{userValues("a", 1)},
+		extendeddatabase.UpsertOptions{
+			ExcludeColumns: []string{"name"},
+			WhereCondition: extendeddatabase.NewFilterTerm(database.Selector{
+				Column: "age", Predicate: "<", Value: 5,
+			}),
+		},
+	)
+	if err != nil {
+		t.Fatalf("UpsertWithOptions: %v", err)
+	}
+	expectQuery(t, query, values,
+		`INSERT INTO "users" ("name", "age") VALUES ($1, $2)`+
+			` ON CONFLICT ON CONSTRAINT "users_pkey"`+
+			` DO UPDATE SET "age" = EXCLUDED."age" WHERE "users"."age" < $3`,
+		[]any{"a", 1, 5},
+	)
+
+	query, _, err = q.UpsertWithOptions(
+		"users",
+		[]database.InsertedValuesFn{userValues("a", 1)},
+		extendeddatabase.UpsertOptions{DoNothing: true},
+	)
+	expected := `INSERT INTO "users" ("name", "age") VALUES ($1, $2)` +
+		` ON CONFLICT DO NOTHING`
+	if err != nil || query != expected {
+		t.Errorf("expected %s, got %s (%v)", expected, query, err)
+	}
+
+	_, _, err = q.UpsertWithOptions(
+		"users",
+		[]database.InsertedValuesFn{userValues("a", 1)},
+		extendeddatabase.UpsertOptions{ConflictColumns: []string{""}},
+	)
+	if err == nil {
+		t.Errorf("expected invalid identifier error")
+	}
+}
+
+func TestGet(t *testing.T) {
+	q := &Query{}
+	query, values := q.Get("users", &database.GetOptions{
+		Selectors: []database.Selector{
+			{Table: "users", Column: "age", Predicate: ">=", Value: 18},
+			{Table: "users", Column: "id", Predicate: "IN", Value: []int{1, 2}},
+			{Column: "deleted", Predicate: "=", Value: nil},
+		},
+		Projections: []database.Projection{
+			{Table: "users", Column: "name", Alias: "user_name"},
+			{Table: "groups", Column: "name"},
+		},
+		Joins: []database.Join{{
+			Type:    "LEFT",
+			Table:   "groups",
+			OnLeft:  database.ColumnSelector{Table: "groups", Column: "id"},
+			OnRight: database.ColumnSelector{Table: "users", Column: "group_id"},
+		}},
+		Orders: []database.Order{{Table: "users", Field: "name", Direction: "ASC"}},
+		Page:   &database.Page{Offset: 20, Limit: 10},
+		Lock:   true,
+	})
+	expectQuery(t, query, values,
+		`SELECT "users"."name" AS "user_name","groups"."name" FROM "users"`+
+			` LEFT JOIN "groups" ON "groups"."id" = "users"."group_id"`+
+			` WHERE "users"."age" >= $1 AND "users"."id" IN ($2, $3)`+
+			` AND "deleted" IS NULL`+
+			` ORDER BY "users"."name" ASC LIMIT 10 OFFSET 20 FOR UPDATE`,
+		[]any{18, 1, 2},
+	)
+}
+
+func TestGetFiltered(t *testing.T) {
+	q := &Query{}
+	filter := extendeddatabase.NewFilterGroup(
+		extendeddatabase.FilterAnd,
+		*extendeddatabase.NewFilterSearch(extendeddatabase.Search{
+			Table:     "posts",
+			Columns:   []string{"title", "body"},
+			Query:     "go sql",
+			Relevance: true,
+		}),
+		*extendeddatabase.NewFilterGroup(
+			extendeddatabase.FilterOr,
+			*extendeddatabase.NewFilterTerm(database.Selector{
+				Column: "status", Predicate: "=", Value: "draft",
+			}),
+			*extendeddatabase.NewFilterTerm(database.Selector{
+				Column: "status", Predicate: "!=", Value: "deleted",
+			}),
+		),
+	)
+	keyset := &extendeddatabase.Keyset{
+		Columns: []extendeddatabase.KeysetColumn{
+			{Column: "created", Descending: true},
+			{Column: "id"},
+		},
+		Values: []any{100, 5},
+	}
+	query, values, err := q.GetFiltered(
+		"posts",
+		&database.GetOptions{
+			Selectors: []database.Selector{
+				{Column: "author", Predicate: "=", Value: "a"},
+			},
+			Orders: []database.Order{{Field: "id", Direction: "DESC"}},
+		},
+		keyset,
+		filter,
+	)
+	if err != nil {
+		t.Fatalf("GetFiltered: %v", err)
+	}
+	document := `to_tsvector(coalesce("posts"."title", '') || ' ' ||` +
+		` coalesce("posts"."body", ''))`
+	expectQuery(t, query, values,
+		`SELECT * FROM "posts" WHERE "author" = $1`+
+			` AND (`+document+` @@ plainto_tsquery($2)`+
+			` AND ("status" = $3 OR "status" != $4))`+
+			` AND (("created" < $5) OR ("created" = $6 AND "id" > $7))`+
+			` ORDER BY ts_rank(`+document+`, plainto_tsquery($8)) DESC, "id" DESC`,
+		[]any{"a", "go sql", "draft", "deleted", 100, 100, 5, "go sql"},
+	)
+}
+
+func TestCount(t *testing.T) {
+	q := &Query{}
+	selectors := []database.Selector{
+		{Table: "users", Column: "age", Predicate: ">", Value: 18},
+	}
+	query, values := q.Count("users", &database.CountOptions{
+		Selectors: selectors,
+	})
+	expectQuery(t, query, values,
+		`SELECT COUNT(*) FROM "users" WHERE "users"."age" > $1`,
+		[]any{18},
+	)
+
+	query, values = q.Count("users", &database.CountOptions{
+		Selectors: selectors,
+		Page:      &database.Page{Offset: 0, Limit: 5},
+	})
+	expectQuery(t, query, values,
+		`SELECT COUNT(*) FROM (SELECT * FROM "users" WHERE "users"."age" > $1`+
+			` LIMIT 5 OFFSET 0) AS limited_result`,
+		[]any{18},
+	)
+}
+
+func TestAggregate(t *testing.T) {
+	q := &Query{}
+	query, values, err := q.Aggregate("orders", &extendeddatabase.AggregateOptions{
+		Selectors: []database.Selector{
+			{Column: "status", Predicate: "=", Value: "paid"},
+		},
+		GroupBy: []extendeddatabase.GroupBy{
+			{Column: "created", Bucket: extendeddatabase.BucketWeek, Alias: "week"},
+		},
+		Aggregates: []extendeddatabase.Aggregate{
+			{Function: extendeddatabase.AggregateCount, Alias: "count"},
+			{Function: extendeddatabase.AggregateSum, Column: "total", Alias: "total"},
+		},
+		Having: []extendeddatabase.Having{{
+			Aggregate: extendeddatabase.Aggregate{
+				Function: extendeddatabase.AggregateSum, Column: "total",
+			},
+			Predicate: ">",
+			Value:     100,
+		}},
+		Orders: []database.Order{{Field: "week", Direction: "ASC"}},
+	})
+	if err != nil {
+		t.Fatalf("Aggregate: %v", err)
+	}
+	expectQuery(t, query, values,
+		`SELECT to_char(to_timestamp("created" / 1e9) AT TIME ZONE 'UTC',`+
+			` 'IYYY-"W"IW') AS "week",COUNT(*) AS "count",`+
+			`SUM("total") AS "total" FROM "orders" WHERE "status" = $1`+
+			` GROUP BY to_char(to_timestamp("created" / 1e9) AT TIME ZONE 'UTC',`+
+			` 'IYYY-"W"IW')`+
+			` HAVING SUM("total") > $2 ORDER BY "week" ASC`,
+		[]any{"paid", 100},
+	)
+}
+
+func TestUpdateQuery(t *testing.T) {
+	q := &Query{}
+	updates := []database.Update{{Field: "name", Value: "b"}}
+	selectors := []database.Selector{
+		{Table: "users", Column: "id", Predicate: "=", Value: 1},
+	}
+	query, values := q.UpdateQuery("users", updates, selectors)
+	expectQuery(t, query, values,
+		`UPDATE "users" SET "name" = $1 WHERE "users"."id" = $2`,
+		[]any{"b", 1},
+	)
+
+	query, _ = q.UpdateReturning("users", updates, selectors, []string{"id", "name"})
+	expected := `UPDATE "users" SET "name" = $1 WHERE "users"."id" = $2` +
+		` RETURNING "id", "name"`
+	if query != expected {
+		t.Errorf("expected %s, got %s", expected, query)
+	}
+}
+
+func TestDelete(t *testing.T) {
+	q := &Query{}
+	selectors := []database.Selector{
+		{Table: "users", Column: "age", Predicate: "<", Value: 18},
+	}
+	query, values := q.Delete("users", selectors, nil)
+	expectQuery(t, query, values,
+		`DELETE FROM "users" WHERE "users"."age" < $1`,
+		[]any{18},
+	)
+
+	query, values = q.Delete("users", selectors, &database.DeleteOptions{
+		Limit:  10,
+		Orders: []database.Order{{Field: "id", Direction: "ASC"}},
+	})
+	expectQuery(t, query, values,
+		`DELETE FROM "users" WHERE "ctid" IN (SELECT "ctid" FROM "users"`+
+			` WHERE "users"."age" < $1 ORDER BY "id" ASC LIMIT 10)`,
+		[]any{18},
+	)
+}
+
+func TestCreateTableQuery(t *testing.T) {
+	q := &Query{}
+	defaultValue := "active"
+	query, _, err := q.CreateTableQuery(
+		"users",
+		true,
+		[]database.ColumnDefinition{
+			{Name: "id", Type: "BIGINT", NotNull: true, PrimaryKey: true, AutoIncrement: true},
+			{Name: "email", Type: "VARCHAR(255)", NotNull: true, Unique: true},
+			{Name: "status", Type: "TEXT", Default: &defaultValue},
+		},
+		[]string{`CONSTRAINT "fk_users_group" FOREIGN KEY ("group_id") REFERENCES "groups" ("id")`},
+		database.TableOptions{Engine: "InnoDB"},
+	)
+	if err != nil {
+		t.Fatalf("CreateTableQuery: %v", err)
+	}
+	expected := `CREATE TABLE IF NOT EXISTS "users" (
+  "id" BIGINT GENERATED BY DEFAULT AS IDENTITY NOT NULL PRIMARY KEY,
+  "email" VARCHAR(255) NOT NULL UNIQUE,
+  "status" TEXT NULL DEFAULT 'active',
+  CONSTRAINT "fk_users_group" FOREIGN KEY ("group_id") REFERENCES "groups" ("id")
+);`
+	if query != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, query)
+	}
+}
+
+func TestSessionQueries(t *testing.T) {
+	q := &Query{}
+	if _, _, err := q.CreateDatabaseQuery("app", true, "", ""); err == nil {
+		t.Errorf("expected an IF NOT EXISTS error")
+	}
+	query, _, _ := q.CreateDatabaseQuery("app", false, "UTF8", "en_US.UTF-8")
+	if expected := `CREATE DATABASE "app" ENCODING 'UTF8' LC_COLLATE 'en_US.UTF-8';`; query != expected {
+		t.Errorf("expected %s, got %s", expected, query)
+	}
+	if _, _, err := q.UseDatabaseQuery("app"); err == nil {
+		t.Errorf("expected a use database error")
+	}
+	query, values, _ := q.AdvisoryLock("migrations", 60)
+	expectQuery(t, query, values,
+		"SELECT CASE WHEN pg_try_advisory_lock(hashtext($1)) THEN 1 ELSE 0 END;",
+		[]any{"migrations"},
+	)
+	query, values, _ = q.AdvisoryUnlock("migrations")
+	expectQuery(t, query, values,
+		"SELECT pg_advisory_unlock(hashtext($1));", []any{"migrations"},
+	)
+}
+
+func TestNumberPlaceholders(t *testing.T) {
+	query := numberPlaceholders(
+		`SELECT "a?" FROM "t" WHERE "b" = ? AND "c" != 'it''s ?' AND "d" IN (?, ?)`,
+	)
+	expected := `SELECT "a?" FROM "t" WHERE "b" = $1 AND "c" != 'it''s ?' AND "d" IN ($2, $3)`
+	if query != expected {
+		t.Errorf("expected %s, got %s", expected, query)
+	}
+}
@@ -0,0 +1,72 @@
+package sqlite
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/pakkasys/fluidapi-extended/database/querytest"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// golden holds the expected queries of the conformance cases.
+var golden = querytest.Golden{
+	"insert": `INSERT INTO "users" ("id", "name", "age", "group_id")` +
+		` VALUES (?, ?, ?, ?)`,
+	"insert_many": `INSERT INTO "users" ("id", "name", "age", "group_id")` +
+		` VALUES (?, ?, ?, ?), (?, ?, ?, ?)`,
+	"upsert": `INSERT INTO "users" ("id", "name", "age")` +
+		` VALUES (?, ?, ?) ON CONFLICT("name") DO UPDATE SET "age" = excluded."age"`,
+	"upsert/do_nothing": `INSERT INTO "users" ("id", "name", "age")` +
+		` VALUES (?, ?, ?) ON CONFLICT("id") DO NOTHING`,
+	"upsert/options": `INSERT INTO "users" ("id", "name", "age", "group_id")` +
+		` VALUES (?, ?, ?, ?), (?, ?, ?, ?)` +
+		` ON CONFLICT DO UPDATE SET "name" = excluded."name",` +
+		` "age" = excluded."age"`,
+	"upsert_many": `INSERT INTO "users" ("id", "name", "age")` +
+		` VALUES (?, ?, ?), (?, ?, ?)` +
+		` ON CONFLICT("name") DO UPDATE SET "age" = excluded."age"`,
+	"get/selectors": `SELECT * FROM "users"` +
+		` WHERE "users"."age" >= ? AND "group_id" = ?`,
+	"get/null": `SELECT "id" FROM "users" WHERE "users"."age" IS NULL`,
+	"get/not_null": `SELECT "id" FROM "users"` +
+		` WHERE "age" IS NOT NULL` +
+		` ORDER BY "id" ASC`,
+	"get/in": `SELECT "users"."id" FROM "users"` +
+		` WHERE "users"."id" IN (?,?) AND "name" NOT IN (?) AND "group_id" IN (?)`,
+	"get/join": `SELECT "users"."name","groups"."name" AS "group_name" FROM "users"` +
+		` INNER JOIN "groups" ON "groups"."id" = "users"."group_id"` +
+		` ORDER BY "users"."id" ASC`,
+	"get/orders_page": `SELECT "name" FROM "users"` +
+		` ORDER BY "name" DESC` +
+		` LIMIT 2 OFFSET 1`,
+	"get/lock":     `SELECT "name" FROM "users" WHERE "id" = ?`,
+	"count":        `SELECT COUNT(*) FROM "users" WHERE "users"."age" > ?`,
+	"count/page":   `SELECT COUNT(*) FROM (SELECT * FROM "users" LIMIT 5 OFFSET 1) AS limited_result`,
+	"update":       `UPDATE "users" SET "age" = ? WHERE "users"."id" = ?`,
+	"delete":       `DELETE FROM "users" WHERE "users"."age" < ?`,
+	"delete/limit": `DELETE FROM "users" WHERE "id" > ? ORDER BY "id" ASC LIMIT 1`,
+}
+
+func TestConformance(t *testing.T) {
+	querytest.Run(t, &Query{}, querytest.Options{
+		Golden: golden,
+		OpenDB: openMemoryDB,
+		// SQLite parses DELETE ... LIMIT only when it is compiled with
+		// SQLITE_ENABLE_UPDATE_DELETE_LIMIT.
+		SkipBehavior: []string{"delete/limit"},
+	})
+}
+
+// openMemoryDB opens an in-memory database. The pool is limited to one
+// connection since every connection has its own in-memory database.
+func openMemoryDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+	return db
+}
@@ -7,17 +7,48 @@ import (
 
 	"github.com/mattn/go-sqlite3"
 	"github.com/pakkasys/fluidapi-extended/database"
+	"github.com/pakkasys/fluidapi/core"
 )
 
 // SQLiteErrorCode represents a SQLite error code.
 type SQLiteErrorCode int
 
-// Common SQLite error codes.
+// Common SQLite extended error codes.
 var (
-	DuplicateEntryErrorCode    SQLiteErrorCode = SQLiteErrorCode(sqlite3.ErrConstraintUnique)
-	ForeignConstraintErrorCode SQLiteErrorCode = SQLiteErrorCode(sqlite3.ErrConstraintForeignKey)
+	DuplicateEntryErrorCode      SQLiteErrorCode = SQLiteErrorCode(sqlite3.ErrConstraintUnique)
+	DuplicatePrimaryKeyErrorCode SQLiteErrorCode = SQLiteErrorCode(sqlite3.ErrConstraintPrimaryKey)
+	ForeignConstraintErrorCode   SQLiteErrorCode = SQLiteErrorCode(sqlite3.ErrConstraintForeignKey)
+	NotNullErrorCode             SQLiteErrorCode = SQLiteErrorCode(sqlite3.ErrConstraintNotNull)
+	CheckConstraintErrorCode     SQLiteErrorCode = SQLiteErrorCode(sqlite3.ErrConstraintCheck)
 )
 
+// Common SQLite primary error codes. They match all the extended codes of
+// the primary code.
+var (
+	TooBigErrorCode   SQLiteErrorCode = SQLiteErrorCode(sqlite3.ErrTooBig)
+	BusyErrorCode     SQLiteErrorCode = SQLiteErrorCode(sqlite3.ErrBusy)
+	LockedErrorCode   SQLiteErrorCode = SQLiteErrorCode(sqlite3.ErrLocked)
+	ReadOnlyErrorCode SQLiteErrorCode = SQLiteErrorCode(sqlite3.ErrReadonly)
+)
+
+// errorsByExtendedCode are the API errors of the SQLite extended error codes.
+var errorsByExtendedCode = map[SQLiteErrorCode]*core.APIError{
+	DuplicateEntryErrorCode:      database.DuplicateEntryError,
+	DuplicatePrimaryKeyErrorCode: database.DuplicateEntryError,
+	ForeignConstraintErrorCode:   database.ForeignConstraintError,
+	NotNullErrorCode:             database.NotNullViolationError,
+	CheckConstraintErrorCode:     database.CheckConstraintError,
+}
+
+// errorsByCode are the API errors of the SQLite primary error codes. SQLite
+// reports deadlocks and lock timeouts as busy errors.
+var errorsByCode = map[SQLiteErrorCode]*core.APIError{
+	TooBigErrorCode:   database.DataTooLongError,
+	BusyErrorCode:     database.DatabaseBusyError,
+	LockedErrorCode:   database.DatabaseBusyError,
+	ReadOnlyErrorCode: database.ReadOnlyDatabaseError,
+}
+
 // ErrorChecker is used to check if an error is a SQLite error.
 type ErrorChecker struct {
 	systemId string
@@ -30,7 +61,10 @@ func NewErrorChecker(systemId string) *ErrorChecker {
 	}
 }
 
-// Check attempts to match a given error against common SQLite errors.
+// Check attempts to match a given error against common SQLite errors. The
+// known SQLite errors are returned as API errors with the SQLite error as
+// data. The data of the unique and foreign key errors is a
+// ConstraintViolation that wraps the SQLite error.
 //
 // Parameters:
 //   - err: The error to check.
@@ -41,22 +75,58 @@ func (c *ErrorChecker) Check(err error) error {
 	if err == nil {
 		return nil
 	}
-	if isSQLiteErrorCode(err, DuplicateEntryErrorCode) {
-		return database.DuplicateEntryError.WithData(err).WithOrigin(c.systemId)
-	} else if isSQLiteErrorCode(err, ForeignConstraintErrorCode) {
-		return database.ForeignConstraintError.WithData(err).WithOrigin(c.systemId)
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) {
+		apiErr, ok := errorsByExtendedCode[SQLiteErrorCode(sqliteErr.ExtendedCode)]
+		if !ok {
+			apiErr, ok = errorsByCode[SQLiteErrorCode(sqliteErr.Code)]
+		}
+		if ok {
+			return apiErr.WithData(errorData(apiErr, sqliteErr, err)).
+				WithOrigin(c.systemId)
+		}
 	} else if strings.Contains(err.Error(), sql.ErrNoRows.Error()) {
 		return database.NoRowsError.WithOrigin(c.systemId)
 	}
 	return err
 }
 
-// isSQLiteErrorCode checks whether 'err' is a sqlite3.Error
-// and if its ExtendedCode or Code matches the desired code.
-func isSQLiteErrorCode(err error, code SQLiteErrorCode) bool {
-	var sqliteErr sqlite3.Error
-	if errors.As(err, &sqliteErr) {
-		return int(sqliteErr.ExtendedCode) == int(code)
+// errorData returns the data of the API error of a SQLite error. The
+// constraint errors are described by a ConstraintViolation and the other
+// errors are returned as is.
+func errorData(
+	apiErr *core.APIError, sqliteErr sqlite3.Error, err error,
+) any {
+	if apiErr != database.DuplicateEntryError &&
+		apiErr != database.ForeignConstraintError {
+		return err
+	}
+	return constraintViolation(sqliteErr.Error(), err)
+}
+
+// constraintViolation parses a constraint error message, e.g. "UNIQUE
+// constraint failed: users.email, users.name". SQLite reports the columns of
+// unique keys, or the name of a unique index on expressions, but not the
+// columns of foreign keys.
+func constraintViolation(
+	message string, err error,
+) *database.ConstraintViolation {
+	violation := &database.ConstraintViolation{Err: err}
+	_, columns, ok := strings.Cut(message, "constraint failed: ")
+	if !ok {
+		return violation
+	}
+	if index, ok := strings.CutPrefix(columns, "index '"); ok {
+		violation.Key = strings.TrimSuffix(index, "'")
+		return violation
+	}
+	for _, column := range strings.Split(columns, ", ") {
+		table, name, ok := strings.Cut(column, ".")
+		if !ok {
+			continue
+		}
+		violation.Table = table
+		violation.Columns = append(violation.Columns, name)
 	}
-	return false
+	return violation
 }
@@ -0,0 +1,99 @@
+package errorchecker
+
+import (
+	"database/sql"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/mattn/go-sqlite3"
+	"github.com/pakkasys/fluidapi-extended/database"
+	"github.com/pakkasys/fluidapi/core"
+)
+
+func TestCheck(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+	for _, statement := range []string{
+		"PRAGMA foreign_keys = ON",
+		"CREATE TABLE groups (id INTEGER PRIMARY KEY)",
+		"CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT NOT NULL UNIQUE," +
+			" age INTEGER CHECK (age >= 0), group_id INTEGER REFERENCES groups (id))",
+		"INSERT INTO users (id, name) VALUES (1, 'a')",
+	} {
+		if _, err := db.Exec(statement); err != nil {
+			t.Fatalf("exec %q: %v", statement, err)
+		}
+	}
+
+	checker := NewErrorChecker("db")
+	tests := []struct {
+		query    string
+		expected *core.APIError
+	}{
+		{"INSERT INTO users (id, name) VALUES (2, 'a')", database.DuplicateEntryError},
+		{"INSERT INTO users (id, name) VALUES (1, 'b')", database.DuplicateEntryError},
+		{"INSERT INTO users (id, name, group_id) VALUES (2, 'b', 1)", database.ForeignConstraintError},
+		{"INSERT INTO users (id) VALUES (2)", database.NotNullViolationError},
+		{"INSERT INTO users (id, name, age) VALUES (2, 'b', -1)", database.CheckConstraintError},
+	}
+	for _, tt := range tests {
+		_, err := db.Exec(tt.query)
+		var apiErr *core.APIError
+		if !errors.As(checker.Check(err), &apiErr) ||
+			apiErr.ID != tt.expected.ID || apiErr.Origin != "db" {
+			t.Errorf("expected %s for %q, got %v", tt.expected.ID, tt.query, err)
+		}
+	}
+
+	for _, tt := range []struct {
+		query   string
+		table   string
+		columns []string
+	}{
+		{"INSERT INTO users (id, name) VALUES (2, 'a')", "users", []string{"name"}},
+		{"INSERT INTO users (id, name) VALUES (1, 'b')", "users", []string{"id"}},
+		{"INSERT INTO users (id, name, group_id) VALUES (2, 'b', 1)", "", nil},
+	} {
+		_, err := db.Exec(tt.query)
+		var apiErr *core.APIError
+		if !errors.As(checker.Check(err), &apiErr) {
+			t.Fatalf("expected an API error for %q, got %v", tt.query, err)
+		}
+		violation, ok := apiErr.Data.(*database.ConstraintViolation)
+		if !ok {
+			t.Fatalf("expected a constraint violation for %q, got %T", tt.query, apiErr.Data)
+		}
+		if violation.Table != tt.table || !slices.Equal(violation.Columns, tt.columns) {
+			t.Errorf("expected %s %v for %q, got %+v", tt.table, tt.columns, tt.query, *violation)
+		}
+	}
+
+	for _, tt := range []struct {
+		err      error
+		expected *core.APIError
+	}{
+		{sqlite3.Error{Code: sqlite3.ErrBusy, ExtendedCode: sqlite3.ErrBusySnapshot}, database.DatabaseBusyError},
+		{sqlite3.Error{Code: sqlite3.ErrLocked}, database.DatabaseBusyError},
+		{sqlite3.Error{Code: sqlite3.ErrReadonly}, database.ReadOnlyDatabaseError},
+		{sqlite3.Error{Code: sqlite3.ErrTooBig}, database.DataTooLongError},
+		{sql.ErrNoRows, database.NoRowsError},
+	} {
+		var apiErr *core.APIError
+		if !errors.As(checker.Check(tt.err), &apiErr) ||
+			apiErr.ID != tt.expected.ID || apiErr.Origin != "db" {
+			t.Errorf("expected %s for %v, got %v", tt.expected.ID, tt.err, apiErr)
+		}
+	}
+
+	if err := errors.New("other"); checker.Check(err) != err {
+		t.Errorf("expected the error to be returned as is")
+	}
+	if checker.Check(nil) != nil {
+		t.Errorf("expected nil")
+	}
+}
@@ -5,12 +5,14 @@ import (
 	"reflect"
 	"strings"
 
+	extendeddatabase "github.com/pakkasys/fluidapi-extended/database"
 	"github.com/pakkasys/fluidapi/database"
 )
 
 // Constants for comparison operators.
 const (
 	in    = "IN"
+	notIn = "NOT IN"
 	is    = "IS"
 	isNot = "IS NOT"
 	null  = "NULL"
@@ -22,6 +24,9 @@ const (
 	SET_FOREIGN_KEYS = "FOREIGN_KEYS"
 )
 
+// quoter quotes the identifiers and literals of SQLite queries.
+var quoter = extendeddatabase.Quoter{IdentifierQuote: '"'}
+
 // Query is a query builder for MySQL.
 type Query struct{}
 
@@ -45,8 +50,8 @@ func (q *Query) Insert(
 		", ",
 	)
 	query := fmt.Sprintf(
-		"INSERT INTO \"%s\" (%s) VALUES (%s)",
-		tableName,
+		"INSERT INTO %s (%s) VALUES (%s)",
+		quoteIdentifier(tableName),
 		columnames,
 		valuePlaceholders,
 	)
@@ -87,24 +92,39 @@ func (q *Query) InsertMany(
 	}
 
 	query := fmt.Sprintf(
-		"INSERT INTO \"%s\" (%s) VALUES %s",
-		tableName,
+		"INSERT INTO %s (%s) VALUES %s",
+		quoteIdentifier(tableName),
 		columnames,
 		strings.Join(valuePlaceholders, ", "),
 	)
 	return query, allValues
 }
 
-// UpsertMany creates an upsert query for a list of entities.
-// Note: SQLite does not support partial upsert like MySQL. This
-// implementation uses "INSERT OR REPLACE" which replaces the entire row.
+// FirstInsertID returns the generated ID of the first row of a multi-row
+// insert. SQLite reports the rowid of the last row as the last insert ID,
+// and the rowids of the rows of a single insert are consecutive.
+//
+// Parameters:
+//   - lastInsertID: The last insert ID reported by the database.
+//   - rowCount: The number of inserted rows.
+//
+// Returns:
+//   - int64: The generated ID of the first row.
+func (q *Query) FirstInsertID(lastInsertID int64, rowCount int) int64 {
+	return lastInsertID - int64(rowCount) + 1
+}
+
+// UpsertMany creates an upsert query for a list of entities. The conflicts
+// are resolved on the projected columns, which must match a unique index of
+// the table, and all the inserted columns except "id" and "created" are
+// updated. If no projections are given, the conflicts are resolved on any
+// unique index of the table.
 //
 // Parameters:
 //   - tableName: The name of the table.
 //   - insertedValues: A slice of functions that return the columns and values
 //     to insert.
-//   - updateProjections: A slice of functions that return the columns and
-//     values to update.
+//   - updateProjections: The projections of the conflict columns.
 //
 // Returns:
 //   - string: The query.
@@ -114,73 +134,143 @@ func (q *Query) UpsertMany(
 	insertedValues []database.InsertedValuesFn,
 	updateProjections []database.Projection,
 ) (string, []any) {
-	if len(insertedValues) == 0 {
-		return "", nil
+	conflictColumns := make([]string, len(updateProjections))
+	for i, proj := range updateProjections {
+		conflictColumns[i] = proj.Column
 	}
 
-	// Get columns from the first row; assume they're consistent for all rows.
-	cols, _ := insertedValues[0]()
-
-	// Build the multi-row VALUES placeholders and gather all parameters.
-	var allValues []any
-	var placeholdersArr []string
+	// The conflict columns are not updated since they match the existing row.
+	return q.upsert(tableName, insertedValues, &extendeddatabase.UpsertOptions{
+		ConflictColumns: conflictColumns,
+		ExcludeColumns:  []string{"id", "created"},
+	})
+}
 
-	for _, iv := range insertedValues {
-		_, rowVals := iv()
+// Upsert creates an upsert query for a single entity. If no update columns
+// are given, conflicting rows are left untouched.
+//
+// Parameters:
+//   - tableName: The name of the table.
+//   - insertedValues: The function used to get the columns and values to
+//     insert.
+//   - conflictColumns: The columns that identify a conflicting row.
+//   - updateColumns: The columns to update on conflict.
+//
+// Returns:
+//   - string: The query.
+//   - []any: The values.
+func (q *Query) Upsert(
+	tableName string,
+	insertedValues database.InsertedValuesFn,
+	conflictColumns []string,
+	updateColumns []string,
+) (string, []any) {
+	return q.upsert(
+		tableName,
+		[]database.InsertedValuesFn{insertedValues},
+		&extendeddatabase.UpsertOptions{
+			ConflictColumns: conflictColumns,
+			UpdateColumns:   updateColumns,
+			DoNothing:       len(updateColumns) == 0,
+		},
+	)
+}
 
-		// Create a list of "?" placeholders matching the row length.
-		placeholder := make([]string, len(rowVals))
-		for i := range placeholder {
-			placeholder[i] = "?"
-		}
+// UpsertWithOptions creates an upsert query for a list of entities with the
+// conflict target and the update policy of the options.
+//
+// Example:
+//
+//	q.UpsertWithOptions("users", values, extendeddatabase.UpsertOptions{
+//	    ConflictColumns: []string{"email"},
+//	    ExcludeColumns:  []string{"id", "created"},
+//	})
+//
+// Output:
+//
+//	INSERT INTO "users" ("id", "email", "name", "created") VALUES (?, ?, ?, ?)
+//	ON CONFLICT("email") DO UPDATE SET "name" = excluded."name"
+//
+// Parameters:
+//   - tableName: The name of the table.
+//   - insertedValues: A slice of functions that return the columns and values
+//     to insert.
+//   - opts: The upsert options.
+//
+// Returns:
+//   - string: The query.
+//   - []any: The values.
+//   - error: InvalidIdentifierError if an identifier is invalid.
+func (q *Query) UpsertWithOptions(
+	tableName string,
+	insertedValues []database.InsertedValuesFn,
+	opts extendeddatabase.UpsertOptions,
+) (string, []any, error) {
+	err := quoter.ValidateIdentifiers(append(
+		[]string{tableName}, opts.Identifiers()...,
+	)...)
+	if err != nil {
+		return "", nil, err
+	}
+	query, values := q.upsert(tableName, insertedValues, &opts)
+	return query, values, nil
+}
 
-		placeholdersArr = append(
-			placeholdersArr,
-			fmt.Sprintf("(%s)", strings.Join(placeholder, ", ")),
-		)
-		allValues = append(allValues, rowVals...)
+// upsert creates an upsert query for a list of entities. The conflict target
+// is omitted if no conflict columns are given, which SQLite supports in the
+// last ON CONFLICT clause since 3.35.0.
+func (q *Query) upsert(
+	tableName string,
+	insertedValues []database.InsertedValuesFn,
+	opts *extendeddatabase.UpsertOptions,
+) (string, []any) {
+	if len(insertedValues) == 0 {
+		return "", nil
 	}
 
-	// Base INSERT statement (no semicolon yet).
-	insertQuery := fmt.Sprintf(
-		"INSERT INTO %s (%s) VALUES %s",
-		tableName,
-		strings.Join(cols, ", "),
-		strings.Join(placeholdersArr, ", "),
-	)
+	insertQuery, values := q.InsertMany(tableName, insertedValues)
 
-	// Build the DO UPDATE SET clause. Skip "id" and "created" so they're not
-	// overwritten if a row with the same 'id' already exists.
-	var sets []string
-	for _, col := range cols {
-		if col == "id" || col == "created" {
-			continue
-		}
-		sets = append(
-			sets,
-			fmt.Sprintf("%s=excluded.%s", col, col),
-		)
+	conflictTarget := ""
+	if len(opts.ConflictColumns) != 0 {
+		conflictTarget = "(" + getInsertQueryColumnames(opts.ConflictColumns) + ")"
 	}
 
-	projectedColumns := []string{}
-	for _, proj := range updateProjections {
-		projectedColumns = append(projectedColumns, proj.Column)
+	columns, _ := insertedValues[0]()
+	updateColumns := opts.UpdatedColumns(columns)
+	if len(updateColumns) == 0 {
+		return fmt.Sprintf(
+			"%s ON CONFLICT%s DO NOTHING", insertQuery, conflictTarget,
+		), values
 	}
 
-	// If your primary key is something else (or composite), replace (id).
-	conflictClause := fmt.Sprintf(
-		"ON CONFLICT(%s) DO UPDATE SET %s",
-		strings.Join(projectedColumns, ", "),
-		strings.Join(sets, ", "),
-	)
+	sets := make([]string, len(updateColumns))
+	for i, col := range updateColumns {
+		sets[i] = fmt.Sprintf(
+			`%s = excluded.%s`, quoteIdentifier(col), quoteIdentifier(col),
+		)
+	}
 
-	// Final upsert query.
-	upsertQuery := fmt.Sprintf("%s %s", insertQuery, conflictClause)
+	builder := strings.Builder{}
+	builder.WriteString(fmt.Sprintf(
+		"%s ON CONFLICT%s DO UPDATE SET %s",
+		insertQuery,
+		conflictTarget,
+		strings.Join(sets, ", "),
+	))
+	if opts.WhereCondition != nil {
+		condition, conditionValues := filterCondition(
+			*opts.WhereCondition, nil,
+		)
+		builder.WriteString(" WHERE " + condition)
+		values = append(values, conditionValues...)
+	}
 
-	return upsertQuery, allValues
+	return builder.String(), values
 }
 
-// Get returns a SELECT query for retrieving entities.
+// Get returns a SELECT query for retrieving entities. The names are quoted but
+// not validated since the method can not return an error. GetFiltered validates
+// them.
 //
 // Parameters:
 //   - tableName: The name of the table.
@@ -193,22 +283,103 @@ func (q *Query) Get(
 	tableName string,
 	opts *database.GetOptions,
 ) (string, []any) {
-	whereClause, whereValues := whereClause(opts.Selectors)
+	return q.getFiltered(tableName, opts, nil, nil)
+}
 
+// GetKeyset returns a get query that selects the rows after the keyset. The
+// keyset condition is added to the selectors of the options. The orders of
+// the options should match the keyset columns. If the keyset is nil, the
+// query is a regular get query.
+//
+// Parameters:
+//   - tableName: The name of the table.
+//   - opts: The options for the query.
+//   - keyset: The keyset to select the rows after.
+//
+// Returns:
+//   - string: The query.
+//   - []any: The values.
+//   - error: InvalidIdentifierError if a name is invalid.
+func (q *Query) GetKeyset(
+	tableName string,
+	opts *database.GetOptions,
+	keyset *extendeddatabase.Keyset,
+) (string, []any, error) {
+	return q.GetFiltered(tableName, opts, keyset, nil)
+}
+
+// GetFiltered returns a get query that selects the rows that match the filter
+// and come after the keyset. The conditions are added to the selectors of the
+// options. If the filter or keyset is nil, the condition is not added.
+//
+// Parameters:
+//   - tableName: The name of the table.
+//   - opts: The options for the query.
+//   - keyset: The keyset to select the rows after.
+//   - filter: The filter expression the rows must match.
+//
+// Returns:
+//   - string: The query.
+//   - []any: The values.
+//   - error: InvalidIdentifierError if a name is invalid.
+func (q *Query) GetFiltered(
+	tableName string,
+	opts *database.GetOptions,
+	keyset *extendeddatabase.Keyset,
+	filter *extendeddatabase.Filter,
+) (string, []any, error) {
+	err := quoter.ValidateGetQuery(tableName, opts, keyset, filter)
+	if err != nil {
+		return "", nil, err
+	}
+	query, values := q.getFiltered(tableName, opts, keyset, filter)
+	return query, values, nil
+}
+
+// getFiltered builds the get query of GetFiltered without validating the
+// names.
+func (q *Query) getFiltered(
+	tableName string,
+	opts *database.GetOptions,
+	keyset *extendeddatabase.Keyset,
+	filter *extendeddatabase.Filter,
+) (string, []any) {
+	ftsJoin, joinedSearch := searchJoin(tableName, filter)
+	whereColumns, whereValues := processSelectors(opts.Selectors)
+	if filter != nil {
+		condition, values := filterCondition(*filter, joinedSearch)
+		whereColumns = append(whereColumns, condition)
+		whereValues = append(whereValues, values...)
+	}
+	if keyset != nil && len(keyset.Columns) != 0 {
+		condition, values := keyset.Condition(keysetColumnToString)
+		whereColumns = append(whereColumns, condition)
+		whereValues = append(whereValues, values...)
+	}
+	whereClause := getWhereClause(whereColumns)
+
+	projections := projectionsToStrings(opts.Projections)
+	if joinedSearch != nil && len(opts.Projections) == 0 {
+		// The columns of the full-text table are not selected.
+		projections = []string{quoteIdentifier(tableName) + ".*"}
+	}
 	builder := strings.Builder{}
 	builder.WriteString(fmt.Sprintf(
-		"SELECT %s",
-		strings.Join(projectionsToStrings(opts.Projections), ","),
+		"SELECT %s", strings.Join(projections, ","),
 	))
-	builder.WriteString(fmt.Sprintf(" FROM \"%s\"", tableName))
+	builder.WriteString(fmt.Sprintf(" FROM %s", quoteIdentifier(tableName)))
 	if len(opts.Joins) != 0 {
 		builder.WriteString(" " + joinClause(opts.Joins))
 	}
+	if ftsJoin != "" {
+		builder.WriteString(" " + ftsJoin)
+	}
 	if whereClause != "" {
 		builder.WriteString(" " + whereClause)
 	}
-	if len(opts.Orders) != 0 {
-		builder.WriteString(" " + getOrderClauseFromOrders(opts.Orders))
+	orderClause := filteredOrderClause(opts.Orders, joinedSearch)
+	if orderClause != "" {
+		builder.WriteString(" " + orderClause)
 	}
 	if opts.Page != nil {
 		builder.WriteString(" " + getLimitOffsetClauseFromPage(opts.Page))
@@ -217,7 +388,9 @@ func (q *Query) Get(
 	return builder.String(), whereValues
 }
 
-// Count returns a query to count the entities.
+// Count returns a query to count the entities. The names are quoted but not
+// validated since the method can not return an error. CountFiltered validates
+// them.
 //
 // Parameters:
 //   - tableName: The name of the table.
@@ -230,21 +403,55 @@ func (q *Query) Count(
 	tableName string,
 	opts *database.CountOptions,
 ) (string, []any) {
-	whereClause, whereValues := whereClause(opts.Selectors)
-	joinStmt := joinClause(opts.Joins)
+	return q.countFiltered(tableName, opts, nil)
+}
+
+// CountFiltered returns a query to count the entities that match the filter.
+// If the filter is nil, the query is a regular count query.
+//
+// Parameters:
+//   - tableName: The name of the table.
+//   - opts: The database options.
+//   - filter: The filter expression the entities must match.
+//
+// Returns:
+//   - string: The query.
+//   - []any: The values.
+//   - error: InvalidIdentifierError if a name is invalid.
+func (q *Query) CountFiltered(
+	tableName string,
+	opts *database.CountOptions,
+	filter *extendeddatabase.Filter,
+) (string, []any, error) {
+	if err := quoter.ValidateCountQuery(tableName, opts, filter); err != nil {
+		return "", nil, err
+	}
+	query, values := q.countFiltered(tableName, opts, filter)
+	return query, values, nil
+}
+
+// countFiltered builds the count query of CountFiltered without validating
+// the names.
+func (q *Query) countFiltered(
+	tableName string,
+	opts *database.CountOptions,
+	filter *extendeddatabase.Filter,
+) (string, []any) {
+	ftsJoin, joinedSearch := searchJoin(tableName, filter)
+	whereClause, whereValues := whereClause(
+		opts.Selectors, filter, joinedSearch,
+	)
+	joinStmt := extendeddatabase.JoinClauses(joinClause(opts.Joins), ftsJoin)
 
 	// If pagination is provided, wrap the limited query in a subquery.
 	if opts.Page != nil {
 		// Build the inner query.
-		innerBuilder := strings.Builder{}
-		innerBuilder.WriteString(fmt.Sprintf(
-			"SELECT * FROM \"%s\" %s %s %s",
-			tableName,
+		innerQuery := extendeddatabase.JoinClauses(
+			"SELECT * FROM "+quoteIdentifier(tableName),
 			joinStmt,
 			whereClause,
 			getLimitOffsetClauseFromPage(opts.Page),
-		))
-		innerQuery := innerBuilder.String()
+		)
 
 		// Wrap the inner query with an outer COUNT(*)
 		query := fmt.Sprintf(
@@ -255,17 +462,97 @@ func (q *Query) Count(
 	}
 
 	// Otherwise, build a simple query without pagination.
-	builder := strings.Builder{}
-	builder.WriteString(fmt.Sprintf(
-		"SELECT COUNT(*) FROM \"%s\" %s %s",
-		tableName,
+	query := extendeddatabase.JoinClauses(
+		"SELECT COUNT(*) FROM "+quoteIdentifier(tableName),
 		joinStmt,
 		whereClause,
-	))
-	query := builder.String()
+	)
 	return query, whereValues
 }
 
+// Aggregate returns a query that groups the rows that match the selectors and
+// computes the aggregates of each group. The groups and aggregates are
+// selected in order with their aliases.
+//
+// Parameters:
+//   - tableName: The name of the table.
+//   - opts: The options for the query.
+//
+// Returns:
+//   - string: The query.
+//   - []any: The values.
+//   - error: InvalidIdentifierError if a name is invalid.
+func (q *Query) Aggregate(
+	tableName string, opts *extendeddatabase.AggregateOptions,
+) (string, []any, error) {
+	if err := quoter.ValidateIdentifier(tableName); err != nil {
+		return "", nil, err
+	}
+	if err := quoter.ValidateAggregateOptions(opts); err != nil {
+		return "", nil, err
+	}
+	query, values := q.aggregate(tableName, opts)
+	return query, values, nil
+}
+
+// aggregate builds the aggregate query of Aggregate without validating the
+// names.
+func (q *Query) aggregate(
+	tableName string, opts *extendeddatabase.AggregateOptions,
+) (string, []any) {
+	var columns []string
+	var groupColumns []string
+	for _, groupBy := range opts.GroupBy {
+		expression := groupByExpression(groupBy)
+		columns = append(
+			columns, fmt.Sprintf(
+				"%s AS %s", expression, quoteIdentifier(groupBy.Alias),
+			),
+		)
+		groupColumns = append(groupColumns, expression)
+	}
+	for _, aggregate := range opts.Aggregates {
+		columns = append(columns, fmt.Sprintf(
+			"%s AS %s",
+			aggregateExpression(aggregate),
+			quoteIdentifier(aggregate.Alias),
+		))
+	}
+	whereColumns, values := processSelectors(opts.Selectors)
+
+	builder := strings.Builder{}
+	builder.WriteString(fmt.Sprintf(
+		"SELECT %s FROM %s",
+		strings.Join(columns, ","),
+		quoteIdentifier(tableName),
+	))
+	if whereClause := getWhereClause(whereColumns); whereClause != "" {
+		builder.WriteString(" " + whereClause)
+	}
+	if len(groupColumns) != 0 {
+		builder.WriteString(" GROUP BY " + strings.Join(groupColumns, ","))
+	}
+	if len(opts.Having) != 0 {
+		conditions := make([]string, len(opts.Having))
+		for i, having := range opts.Having {
+			conditions[i] = fmt.Sprintf(
+				"%s %s ?",
+				aggregateExpression(having.Aggregate),
+				having.Predicate,
+			)
+			values = append(values, having.Value)
+		}
+		builder.WriteString(" HAVING " + strings.Join(conditions, " AND "))
+	}
+	if len(opts.Orders) != 0 {
+		builder.WriteString(" " + getOrderClauseFromOrders(opts.Orders))
+	}
+	if opts.Page != nil {
+		builder.WriteString(" " + getLimitOffsetClauseFromPage(opts.Page))
+	}
+	return builder.String(), values
+}
+
 // UpdateQuery returns the query and values for an UPDATE.
 //
 // Parameters:
@@ -287,8 +574,8 @@ func (q *Query) UpdateQuery(
 
 	builder := strings.Builder{}
 	builder.WriteString(fmt.Sprintf(
-		"UPDATE \"%s\" SET %s",
-		tableName,
+		"UPDATE %s SET %s",
+		quoteIdentifier(tableName),
 		setClause,
 	))
 	if len(whereColumns) != 0 {
@@ -321,9 +608,9 @@ func (q *Query) Delete(
 	}
 
 	builder := strings.Builder{}
-	builder.WriteString(
-		fmt.Sprintf("DELETE FROM \"%s\" %s", tableName, whereClause),
-	)
+	builder.WriteString(extendeddatabase.JoinClauses(
+		"DELETE FROM "+quoteIdentifier(tableName), whereClause,
+	))
 
 	if opts != nil {
 		writeDeleteOptions(&builder, opts)
@@ -367,18 +654,27 @@ func (q *Query) CreateTableQuery(
 	constraints []string,
 	opts database.TableOptions,
 ) (string, []any, error) {
+	if err := quoter.ValidateIdentifier(tableName); err != nil {
+		return "", nil, err
+	}
+	for _, col := range columns {
+		if err := quoter.ValidateIdentifier(col.Name); err != nil {
+			return "", nil, err
+		}
+	}
+
 	var builder strings.Builder
 
 	builder.WriteString("CREATE TABLE ")
 	if ifNotExists {
 		builder.WriteString("IF NOT EXISTS ")
 	}
-	builder.WriteString(fmt.Sprintf("`%s` (\n", tableName))
+	builder.WriteString(fmt.Sprintf("%s (\n", quoteIdentifier(tableName)))
 
 	var defs []string
 	// Build column definitions.
 	for _, col := range columns {
-		def := fmt.Sprintf("  `%s` %s", col.Name, col.Type)
+		def := fmt.Sprintf("  %s %s", quoteIdentifier(col.Name), col.Type)
 		if col.Extra != "" {
 			// SQLite does not support specifying character set or collation.
 			def += " " + col.Extra
@@ -391,16 +687,17 @@ func (q *Query) CreateTableQuery(
 			if *col.Default == "CURRENT_TIMESTAMP" || *col.Default == "NULL" {
 				def += *col.Default
 			} else {
-				def += fmt.Sprintf("'%s'", *col.Default)
+				def += quoter.Literal(*col.Default)
 			}
 		}
-		if col.AutoIncrement {
-			// In SQLite, the auto-increment column must be an INTEGER PRIMARY KEY.
-			def += " AUTOINCREMENT"
-		}
 		if col.PrimaryKey {
 			def += " PRIMARY KEY"
 		}
+		if col.AutoIncrement {
+			// In SQLite, the auto-increment column must be an INTEGER PRIMARY
+			// KEY and AUTOINCREMENT must follow PRIMARY KEY.
+			def += " AUTOINCREMENT"
+		}
 		if col.Unique && !col.PrimaryKey {
 			def += " UNIQUE"
 		}
@@ -454,7 +751,9 @@ func (q *Query) SetVariableQuery(
 				value,
 			)
 		}
-		return fmt.Sprintf("PRAGMA journal_mode = '%s';", value), nil, nil
+		return fmt.Sprintf(
+			"PRAGMA journal_mode = %s;", quoter.Literal(value),
+		), nil, nil
 	case SET_FOREIGN_KEYS:
 		if value != "ON" && value != "OFF" {
 			return "", nil, fmt.Errorf(
@@ -471,7 +770,8 @@ func (q *Query) SetVariableQuery(
 	}
 }
 
-// getOrderClauseFromOrders returns an ORDER BY clause.
+// getOrderClauseFromOrders returns an ORDER BY clause. The directions are
+// written as ASC or DESC.
 func getOrderClauseFromOrders(orders []database.Order) string {
 	if len(orders) == 0 {
 		return ""
@@ -479,13 +779,8 @@ func getOrderClauseFromOrders(orders []database.Order) string {
 
 	orderClause := "ORDER BY"
 	for _, order := range orders {
-		if order.Table == "" {
-			orderClause += fmt.Sprintf(" \"%s\" %s,", order.Field,
-				order.Direction)
-		} else {
-			orderClause += fmt.Sprintf(" \"%s\".\"%s\" %s,", order.Table,
-				order.Field, order.Direction)
-		}
+		orderClause += fmt.Sprintf(" %s %s,",
+			quoter.QuoteQualified(order.Table, order.Field), extendeddatabase.SQLDirection(order.Direction))
 	}
 
 	return strings.TrimSuffix(orderClause, ",")
@@ -512,10 +807,7 @@ func getLimitOffsetClauseFromPage(page *database.Page) string {
 // columnSelectorToString returns the string representation of a column
 // selector.
 func columnSelectorToString(colSel database.ColumnSelector) string {
-	return fmt.Sprintf("\"%s\".\"%s\"",
-		colSel.Table,
-		colSel.Column,
-	)
+	return quoter.QuoteQualified(colSel.Table, colSel.Column)
 }
 
 // processSelectors processes selectors and returns conditions and values.
@@ -535,16 +827,13 @@ func projectionToString(proj database.Projection) string {
 	builder := strings.Builder{}
 
 	if proj.Table == "" {
-		builder.WriteString(fmt.Sprintf("\"%s\"", proj.Column))
+		builder.WriteString(quoteIdentifier(proj.Column))
 	} else {
-		builder.WriteString(fmt.Sprintf("\"%s\".\"%s\"",
-			proj.Table,
-			proj.Column,
-		))
+		builder.WriteString(quoter.QuoteQualified(proj.Table, proj.Column))
 	}
 
 	if proj.Alias != "" {
-		builder.WriteString(fmt.Sprintf(" AS \"%s\"", proj.Alias))
+		builder.WriteString(fmt.Sprintf(" AS %s", quoteIdentifier(proj.Alias)))
 	}
 
 	return builder.String()
@@ -554,7 +843,7 @@ func projectionToString(proj database.Projection) string {
 func getInsertQueryColumnames(columns []string) string {
 	wrappedColumns := make([]string, len(columns))
 	for i, col := range columns {
-		wrappedColumns[i] = "\"" + col + "\""
+		wrappedColumns[i] = quoteIdentifier(col)
 	}
 	return strings.Join(wrappedColumns, ", ")
 }
@@ -580,9 +869,9 @@ func joinClause(joins []database.Join) string {
 			clause += " "
 		}
 		clause += fmt.Sprintf(
-			"%s JOIN \"%s\" ON %s = %s",
+			"%s JOIN %s ON %s = %s",
 			join.Type,
-			join.Table,
+			quoteIdentifier(join.Table),
 			columnSelectorToString(join.OnLeft),
 			columnSelectorToString(join.OnRight),
 		)
@@ -590,9 +879,20 @@ func joinClause(joins []database.Join) string {
 	return clause
 }
 
-// whereClause returns the string representation of a WHERE clause.
-func whereClause(selectors []database.Selector) (string, []any) {
+// whereClause returns the string representation of a WHERE clause. If the
+// filter is set, its condition is added to the selectors. The joined search is
+// matched against its joined full-text table.
+func whereClause(
+	selectors []database.Selector,
+	filter *extendeddatabase.Filter,
+	joinedSearch *extendeddatabase.Search,
+) (string, []any) {
 	whereCols, whereVals := processSelectors(selectors)
+	if filter != nil {
+		condition, values := filterCondition(*filter, joinedSearch)
+		whereCols = append(whereCols, condition)
+		whereVals = append(whereVals, values...)
+	}
 
 	var clause string
 	if len(whereCols) > 0 {
@@ -610,13 +910,23 @@ func getWhereClause(whereColumns []string) string {
 	return ""
 }
 
-// getSetClause returns the string representation of a SET clause.
+// getSetClause returns the string representation of a SET clause. The
+// columns of Increment values are incremented.
 func getSetClause(updates []database.Update) (string, []any) {
 	setParts := make([]string, len(updates))
 	values := make([]any, len(updates))
 
 	for i, update := range updates {
-		setParts[i] = fmt.Sprintf("%s = ?", update.Field)
+		if increment, ok := update.Value.(extendeddatabase.Increment); ok {
+			setParts[i] = fmt.Sprintf(
+				"%s = %s + ?",
+				quoteIdentifier(update.Field),
+				quoteIdentifier(update.Field),
+			)
+			values[i] = increment.By
+			continue
+		}
+		setParts[i] = fmt.Sprintf("%s = ?", quoteIdentifier(update.Field))
 		values[i] = update.Value
 	}
 
@@ -640,28 +950,33 @@ func writeDeleteOptions(
 
 // processSelector processes a selector and returns conditions and values.
 func processSelector(selector database.Selector) (string, []any) {
-	if selector.Predicate == in {
+	if selector.Predicate == in || selector.Predicate == notIn {
 		return processInSelector(selector)
 	}
 	return processDefaultSelector(selector)
 }
 
-// processInSelector processes an IN selector and returns conditions and values.
+// processInSelector processes an IN or NOT IN selector and returns conditions
+// and values.
 func processInSelector(selector database.Selector) (string, []any) {
 	var col string
 	if selector.Table != "" {
-		col = fmt.Sprintf("\"%s\".\"%s\"", selector.Table, selector.Column)
+		col = quoter.QuoteQualified(selector.Table, selector.Column)
 	} else {
-		col = fmt.Sprintf("\"%s\"", selector.Column)
+		col = quoteIdentifier(selector.Column)
 	}
 
 	value := reflect.ValueOf(selector.Value)
 	if value.Kind() == reflect.Slice {
 		placeholders, values := createPlaceholdersAndValues(value)
-		return fmt.Sprintf("%s %s (%s)", col, in, placeholders), values
+		return fmt.Sprintf(
+			"%s %s (%s)", col, selector.Predicate, placeholders,
+		), values
 	}
 	// If value is not a slice, treat it as a single value.
-	return fmt.Sprintf("%s %s (?)", col, in), []any{selector.Value}
+	return fmt.Sprintf(
+		"%s %s (?)", col, selector.Predicate,
+	), []any{selector.Value}
 }
 
 // processDefaultSelector processes a default selector and returns conditions
@@ -670,12 +985,9 @@ func processDefaultSelector(selector database.Selector) (string, []any) {
 	if selector.Value == nil {
 		return processNullSelector(selector)
 	}
-	if selector.Table == "" {
-		return fmt.Sprintf("\"%s\" %s ?", selector.Column,
-			selector.Predicate), []any{selector.Value}
-	}
-	return fmt.Sprintf("\"%s\".\"%s\" %s ?", selector.Table,
-		selector.Column, selector.Predicate), []any{selector.Value}
+	return fmt.Sprintf("%s %s ?",
+		quoter.QuoteQualified(selector.Table, selector.Column),
+		selector.Predicate), []any{selector.Value}
 }
 
 // processNullSelector processes a null selector and returns conditions and
@@ -692,11 +1004,8 @@ func processNullSelector(selector database.Selector) (string, []any) {
 
 // buildNullClause returns the string representation of a null clause.
 func buildNullClause(selector database.Selector, clause string) string {
-	if selector.Table == "" {
-		return fmt.Sprintf("\"%s\" %s %s", selector.Column, clause, null)
-	}
-	return fmt.Sprintf("\"%s\".\"%s\" %s %s",
-		selector.Table, selector.Column, clause, null)
+	return fmt.Sprintf("%s %s %s",
+		quoter.QuoteQualified(selector.Table, selector.Column), clause, null)
 }
 
 // createPlaceholdersAndValues creates placeholders and values for a slice.
@@ -714,3 +1023,181 @@ func createPlaceholdersAndValues(value reflect.Value) (string, []any) {
 func createPlaceholders(count int) string {
 	return strings.TrimSuffix(strings.Repeat("?,", count), ",")
 }
+
+// keysetColumnToString returns the string representation of a keyset column.
+func keysetColumnToString(column extendeddatabase.KeysetColumn) string {
+	return quoter.QuoteQualified(column.Table, column.Column)
+}
+
+// filterCondition returns the condition of a filter expression. The groups
+// are parenthesized so that the condition can be combined with the other
+// conditions of the WHERE clause. The joined search is matched against its
+// joined full-text table.
+func filterCondition(
+	filter extendeddatabase.Filter, joinedSearch *extendeddatabase.Search,
+) (string, []any) {
+	if filter.Selector != nil {
+		return processSelector(*filter.Selector)
+	}
+	if filter.Search != nil {
+		return searchCondition(*filter.Search, filter.Search == joinedSearch)
+	}
+	conditions := make([]string, len(filter.Filters))
+	var values []any
+	for i, child := range filter.Filters {
+		condition, childValues := filterCondition(child, joinedSearch)
+		conditions[i] = condition
+		values = append(values, childValues...)
+	}
+	if filter.Operator == extendeddatabase.FilterNot {
+		return fmt.Sprintf("NOT (%s)", strings.Join(conditions, " AND ")), values
+	}
+	return fmt.Sprintf(
+		"(%s)", strings.Join(conditions, fmt.Sprintf(" %s ", filter.Operator)),
+	), values
+}
+
+// searchJoin returns the join of the FTS5 table of the relevance search of
+// the filter, so that the rows can be ordered by their rank. The columns of
+// the other tables should be qualified since the full-text table usually has
+// columns of the same names.
+//
+// Parameters:
+//   - tableName: The name of the searched table.
+//   - filter: The filter expression. It can be nil.
+//
+// Returns:
+//   - string: The join clause, or an empty string if nothing is joined.
+//   - *extendeddatabase.Search: The joined search, or nil.
+func searchJoin(
+	tableName string, filter *extendeddatabase.Filter,
+) (string, *extendeddatabase.Search) {
+	if filter == nil {
+		return "", nil
+	}
+	search := filter.RelevanceSearch()
+	if search == nil {
+		return "", nil
+	}
+	table := search.Table
+	if table == "" {
+		table = tableName
+	}
+	ftsTable := search.FTSTableName()
+	return fmt.Sprintf(
+		"JOIN %s ON %s = %s",
+		quoteIdentifier(ftsTable),
+		keysetColumnToString(extendeddatabase.KeysetColumn{
+			Table: ftsTable, Column: "rowid",
+		}),
+		keysetColumnToString(extendeddatabase.KeysetColumn{
+			Table: table, Column: search.FTSKeyColumn(),
+		}),
+	), search
+}
+
+// searchCondition returns the condition of a full-text search. A joined search
+// is matched against the joined FTS5 table. The searches that are not joined,
+// such as the searches of OR and NOT groups, are matched with a subquery.
+func searchCondition(
+	search extendeddatabase.Search, joined bool,
+) (string, []any) {
+	ftsTable := quoteIdentifier(search.FTSTableName())
+	if joined {
+		return fmt.Sprintf("%s MATCH ?", ftsTable), []any{search.FTS5Query()}
+	}
+	return fmt.Sprintf(
+		"%s IN (SELECT \"rowid\" FROM %s WHERE %s MATCH ?)",
+		keysetColumnToString(extendeddatabase.KeysetColumn{
+			Table:  search.Table,
+			Column: search.FTSKeyColumn(),
+		}),
+		ftsTable,
+		ftsTable,
+	), []any{search.FTS5Query()}
+}
+
+// filteredOrderClause returns the ORDER BY clause of a filtered get query. If
+// a relevance search is joined, the rows are ordered by the FTS5 rank of the
+// joined full-text table, which is the bm25 score by default, best match
+// first, and the orders are used to break ties.
+func filteredOrderClause(
+	orders []database.Order, joinedSearch *extendeddatabase.Search,
+) string {
+	if joinedSearch == nil {
+		return getOrderClauseFromOrders(orders)
+	}
+	orderClause := fmt.Sprintf(
+		"ORDER BY %s ASC",
+		keysetColumnToString(extendeddatabase.KeysetColumn{
+			Table: joinedSearch.FTSTableName(), Column: "rank",
+		}),
+	)
+	if len(orders) != 0 {
+		orderClause += "," + strings.TrimPrefix(
+			getOrderClauseFromOrders(orders), "ORDER BY",
+		)
+	}
+	return orderClause
+}
+
+// sqliteDateFormats are the strftime formats of the date buckets. Weeks are
+// ISO weeks, see isoWeekExpression.
+var sqliteDateFormats = map[extendeddatabase.DateBucket]string{
+	extendeddatabase.BucketHour:  "%Y-%m-%d %H:00:00",
+	extendeddatabase.BucketDay:   "%Y-%m-%d",
+	extendeddatabase.BucketMonth: "%Y-%m",
+	extendeddatabase.BucketYear:  "%Y",
+}
+
+// groupByExpression returns the expression of a group. Date buckets are
+// formatted with strftime from the Unix nanoseconds of the column.
+func groupByExpression(groupBy extendeddatabase.GroupBy) string {
+	column := keysetColumnToString(extendeddatabase.KeysetColumn{
+		Table:  groupBy.Table,
+		Column: groupBy.Column,
+	})
+	date := "datetime(" + column + " / 1000000000, 'unixepoch')"
+	if groupBy.Bucket == extendeddatabase.BucketWeek {
+		return isoWeekExpression(date)
+	}
+	format, ok := sqliteDateFormats[groupBy.Bucket]
+	if !ok {
+		return column
+	}
+	return "strftime('" + format + "', " + date + ")"
+}
+
+// isoWeekExpression returns the expression that formats a date as its ISO
+// week, e.g. 2021-W01, like the other dialects. The ISO week and its year are
+// the ones of the Thursday of the week of the date, since strftime supports
+// the ISO week formats only in recent SQLite versions.
+func isoWeekExpression(date string) string {
+	thursday := date + ", '-3 days', 'weekday 4'"
+	return fmt.Sprintf(
+		"strftime('%%Y', %s) || '-W' ||"+
+			" printf('%%02d', (strftime('%%j', %s) - 1) / 7 + 1)",
+		thursday,
+		thursday,
+	)
+}
+
+// aggregateExpression returns the expression of an aggregate.
+func aggregateExpression(aggregate extendeddatabase.Aggregate) string {
+	if aggregate.Column == "" {
+		return fmt.Sprintf("%s(*)", aggregate.Function)
+	}
+	return fmt.Sprintf(
+		"%s(%s)",
+		aggregate.Function,
+		keysetColumnToString(extendeddatabase.KeysetColumn{
+			Table:  aggregate.Table,
+			Column: aggregate.Column,
+		}),
+	)
+}
+
+// quoteIdentifier quotes an identifier with double quotes.
+func quoteIdentifier(name string) string {
+	return quoter.QuoteIdentifier(name)
+}
@@ -0,0 +1,228 @@
+package sqlite
+
+import (
+	"database/sql"
+	"reflect"
+	"testing"
+	"time"
+
+	extendeddatabase "github.com/pakkasys/fluidapi-extended/database"
+	"github.com/pakkasys/fluidapi/database"
+)
+
+func expectQuery(
+	t *testing.T,
+	query string,
+	values []any,
+	expectedQuery string,
+	expectedValues []any,
+) {
+	t.Helper()
+	if query != expectedQuery {
+		t.Errorf("expected query\n%s\ngot\n%s", expectedQuery, query)
+	}
+	if !reflect.DeepEqual(values, expectedValues) {
+		t.Errorf("expected values %v, got %v", expectedValues, values)
+	}
+}
+
+func TestSearchCondition(t *testing.T) {
+	search := extendeddatabase.Search{
+		Table: "posts", Columns: []string{"title"}, Query: "go",
+	}
+
+	condition, values := searchCondition(search, true)
+	expectQuery(t, condition, values,
+		`"posts_fts" MATCH ?`,
+		[]any{`{title} : "go"`},
+	)
+
+	condition, values = searchCondition(search, false)
+	expectQuery(t, condition, values,
+		`"posts"."rowid" IN (SELECT "rowid" FROM "posts_fts" WHERE "posts_fts" MATCH ?)`,
+		[]any{`{title} : "go"`},
+	)
+}
+
+func TestFilteredOrderClause(t *testing.T) {
+	orders := []database.Order{{Table: "posts", Field: "id", Direction: "DESC"}}
+	search := &extendeddatabase.Search{Table: "posts", FTSTable: "search"}
+
+	expectQuery(t, filteredOrderClause(orders, nil), nil,
+		`ORDER BY "posts"."id" DESC`, nil,
+	)
+	expectQuery(t, filteredOrderClause(orders, search), nil,
+		`ORDER BY "search"."rank" ASC, "posts"."id" DESC`, nil,
+	)
+	expectQuery(t, filteredOrderClause(nil, search), nil,
+		`ORDER BY "search"."rank" ASC`, nil,
+	)
+}
+
+func TestGetFilteredSearch(t *testing.T) {
+	q := &Query{}
+	relevance := extendeddatabase.Search{
+		Table:     "posts",
+		Columns:   []string{"title", "body"},
+		Query:     "go sql",
+		Relevance: true,
+	}
+	other := extendeddatabase.Search{
+		Table: "posts", Columns: []string{"title"}, Query: "draft",
+	}
+	filter := extendeddatabase.NewFilterGroup(
+		extendeddatabase.FilterAnd,
+		*extendeddatabase.NewFilterSearch(relevance),
+		*extendeddatabase.NewFilterGroup(
+			extendeddatabase.FilterOr,
+			*extendeddatabase.NewFilterSearch(other),
+			*extendeddatabase.NewFilterTerm(database.Selector{
+				Table: "posts", Column: "status", Predicate: "=", Value: "new",
+			}),
+		),
+	)
+	ftsQuery := `{title body} : "go" AND {title body} : "sql"`
+
+	query, values, err := q.GetFiltered(
+		"posts",
+		&database.GetOptions{
+			Orders: []database.Order{
+				{Table: "posts", Field: "id", Direction: "DESC"},
+			},
+			Page: &database.Page{Offset: 0, Limit: 10},
+		},
+		nil,
+		filter,
+	)
+	if err != nil {
+		t.Fatalf("GetFiltered: %v", err)
+	}
+	expectQuery(t, query, values,
+		`SELECT "posts".* FROM "posts"`+
+			` JOIN "posts_fts" ON "posts_fts"."rowid" = "posts"."rowid"`+
+			` WHERE ("posts_fts" MATCH ?`+
+			` AND ("posts"."rowid" IN (SELECT "rowid" FROM "posts_fts" WHERE "posts_fts" MATCH ?)`+
+			` OR "posts"."status" = ?))`+
+			` ORDER BY "posts_fts"."rank" ASC, "posts"."id" DESC LIMIT 10 OFFSET 0`,
+		[]any{ftsQuery, `{title} : "draft"`, "new"},
+	)
+
+	query, values, err = q.CountFiltered(
+		"posts", &database.CountOptions{}, filter,
+	)
+	if err != nil {
+		t.Fatalf("CountFiltered: %v", err)
+	}
+	expectQuery(t, query, values,
+		`SELECT COUNT(*) FROM "posts"`+
+			` JOIN "posts_fts" ON "posts_fts"."rowid" = "posts"."rowid"`+
+			` WHERE ("posts_fts" MATCH ?`+
+			` AND ("posts"."rowid" IN (SELECT "rowid" FROM "posts_fts" WHERE "posts_fts" MATCH ?)`+
+			` OR "posts"."status" = ?))`,
+		[]any{ftsQuery, `{title} : "draft"`, "new"},
+	)
+}
+
+func TestAggregateDateBuckets(t *testing.T) {
+	db := openMemoryDB(t)
+	if _, err := db.Exec(`CREATE TABLE "events" ("at" BIGINT)`); err != nil {
+		t.Fatalf("create table: %v", err)
+	}
+	// Times are stored as Unix nanoseconds.
+	for _, at := range []time.Time{
+		time.Date(2021, 1, 3, 0, 0, 0, 0, time.UTC),
+		time.Date(2021, 1, 4, 0, 0, 0, 0, time.UTC),
+		time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC),
+		time.Date(2024, 12, 29, 0, 0, 0, 0, time.UTC),
+		time.Date(2024, 12, 30, 12, 0, 0, 0, time.UTC),
+	} {
+		_, err := db.Exec(`INSERT INTO "events" VALUES (?)`, at.UnixNano())
+		if err != nil {
+			t.Fatalf("insert: %v", err)
+		}
+	}
+
+	for _, tt := range []struct {
+		bucket   extendeddatabase.DateBucket
+		expected []string
+	}{
+		// The weeks match the %x-W%v format of MySQL and IYYY-"W"IW of
+		// PostgreSQL.
+		{
+			extendeddatabase.BucketWeek,
+			[]string{"2020-W53", "2021-W01", "2024-W05", "2024-W52", "2025-W01"},
+		},
+		{
+			extendeddatabase.BucketMonth,
+			[]string{"2021-01", "2024-01", "2024-12"},
+		},
+		{
+			extendeddatabase.BucketHour,
+			[]string{
+				"2021-01-03 00:00:00", "2021-01-04 00:00:00",
+				"2024-01-31 00:00:00", "2024-12-29 00:00:00",
+				"2024-12-30 12:00:00",
+			},
+		},
+	} {
+		t.Run(string(tt.bucket), func(t *testing.T) {
+			query, values, err := (&Query{}).Aggregate(
+				"events",
+				&extendeddatabase.AggregateOptions{
+					GroupBy: []extendeddatabase.GroupBy{{
+						Column: "at", Bucket: tt.bucket, Alias: "bucket",
+					}},
+					Aggregates: []extendeddatabase.Aggregate{
+						{Function: extendeddatabase.AggregateCount, Alias: "count"},
+					},
+					Orders: []database.Order{{Field: "bucket", Direction: "ASC"}},
+				},
+			)
+			if err != nil {
+				t.Fatalf("Aggregate: %v", err)
+			}
+			rows, err := db.Query(query, values...)
+			if err != nil {
+				t.Fatalf("query %q: %v", query, err)
+			}
+			defer rows.Close()
+			var buckets []string
+			for rows.Next() {
+				var bucket sql.NullString
+				var count int
+				if err := rows.Scan(&bucket, &count); err != nil {
+					t.Fatalf("scan: %v", err)
+				}
+				buckets = append(buckets, bucket.String)
+			}
+			if !reflect.DeepEqual(buckets, tt.expected) {
+				t.Errorf("expected buckets %v, got %v", tt.expected, buckets)
+			}
+		})
+	}
+}
+
+func TestFilteredInvalidName(t *testing.T) {
+	q := &Query{}
+	orders := []database.Order{{Field: "id\x00", Direction: "ASC"}}
+	if _, _, err := q.GetFiltered(
+		"posts", &database.GetOptions{Orders: orders}, nil, nil,
+	); err == nil {
+		t.Errorf("expected an error for an invalid order field")
+	}
+
+	filter := extendeddatabase.NewFilterSearch(extendeddatabase.Search{
+		Table: "posts", Columns: []string{""}, Query: "go",
+	})
+	if _, _, err := q.CountFiltered(
+		"posts", &database.CountOptions{}, filter,
+	); err == nil {
+		t.Errorf("expected an error for an invalid search column")
+	}
+
+	if _, _, err := q.Aggregate("events", &extendeddatabase.AggregateOptions{
+		GroupBy: []extendeddatabase.GroupBy{{Column: "at", Alias: "a\x00"}},
+	}); err == nil {
+		t.Errorf("expected an error for an invalid alias")
+	}
+}
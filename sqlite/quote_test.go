@@ -0,0 +1,192 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	extendeddatabase "github.com/pakkasys/fluidapi-extended/database"
+	"github.com/pakkasys/fluidapi/core"
+	"github.com/pakkasys/fluidapi/database"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func TestInvalidIdentifiers(t *testing.T) {
+	query := &Query{}
+	_, _, err := query.CreateTableQuery(
+		"users", true, []database.ColumnDefinition{{Name: "", Type: "TEXT"}},
+		nil, database.TableOptions{},
+	)
+	var apiErr *core.APIError
+	if !errors.As(err, &apiErr) ||
+		apiErr.ID != extendeddatabase.InvalidIdentifierError.ID {
+		t.Errorf("expected invalid identifier error, got %v", err)
+	}
+	_, _, err = query.CreateIndexQuery("users", extendeddatabase.Index{
+		Name: "idx\x00", Columns: []string{"name"},
+	})
+	if !errors.As(err, &apiErr) ||
+		apiErr.ID != extendeddatabase.InvalidIdentifierError.ID {
+		t.Errorf("expected invalid identifier error, got %v", err)
+	}
+}
+
+// FuzzQuoteSQLite checks that SQLite reads the quoted identifiers and
+// literals back as the values they were quoted from.
+func FuzzQuoteSQLite(f *testing.F) {
+	for _, seed := range []string{
+		"value", `"`, "'", `'); DROP TABLE users; --`, `a"."b`, `\'`,
+	} {
+		f.Add(seed)
+	}
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		f.Fatalf("open: %v", err)
+	}
+	defer db.Close()
+
+	f.Fuzz(func(t *testing.T, value string) {
+		// The query text is passed to SQLite as a C string, so it can not
+		// contain NUL characters.
+		if !utf8.ValidString(value) || strings.ContainsRune(value, 0) {
+			t.Skip()
+		}
+		query := "SELECT " + quoter.Literal(value)
+		if quoter.ValidateIdentifier(value) == nil {
+			query += " AS " + quoteIdentifier(value)
+		}
+		rows, err := db.Query(query)
+		if err != nil {
+			t.Fatalf("query %s: %v", query, err)
+		}
+		defer rows.Close()
+		columns, err := rows.Columns()
+		if err != nil {
+			t.Fatalf("columns: %v", err)
+		}
+		if len(columns) != 1 {
+			t.Fatalf("expected 1 column for %s, got %v", query, columns)
+		}
+		if quoter.ValidateIdentifier(value) == nil && columns[0] != value {
+			t.Fatalf("expected column %q, got %q", value, columns[0])
+		}
+		var read string
+		if !rows.Next() {
+			t.Fatalf("no rows for %s", query)
+		}
+		if err := rows.Scan(&read); err != nil {
+			t.Fatalf("scan: %v", err)
+		}
+		if read != value {
+			t.Fatalf("expected %q, got %q", value, read)
+		}
+	})
+}
+
+// FuzzGetFilteredSQLite checks that the get queries built from names,
+// predicates and order directions that pass the validation run in SQLite and
+// have no other text than the quoted identifiers outside of the builder's own
+// keywords.
+func FuzzGetFilteredSQLite(f *testing.F) {
+	for _, seed := range [][3]string{
+		{"name", "=", "DESC"},
+		{"name", "NOT IN", "ascending"},
+		{"name", "= 1 OR 1 =", "ASC"},
+		{"name", "=", "DESC; DROP TABLE items"},
+		{`a"; DROP TABLE items; --`, ">=", ""},
+	} {
+		f.Add(seed[0], seed[1], seed[2])
+	}
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		f.Fatalf("open: %v", err)
+	}
+	defer db.Close()
+	// Every connection has its own in-memory database.
+	db.SetMaxOpenConns(1)
+
+	f.Fuzz(func(t *testing.T, column string, predicate string, direction string) {
+		order := database.Order{
+			Table:     "items",
+			Field:     column,
+			Direction: database.OrderDirection(direction),
+		}
+		query, values, err := (&Query{}).GetFiltered(
+			"items",
+			&database.GetOptions{
+				Selectors: []database.Selector{{
+					Table:     "items",
+					Column:    column,
+					Predicate: database.Predicate(predicate),
+					Value:     1,
+				}},
+				Orders: []database.Order{order},
+				Page:   &database.Page{Limit: 10},
+			},
+			extendeddatabase.NewKeyset([]database.Order{order}, []any{0}),
+			nil,
+		)
+		if err != nil {
+			var apiErr *core.APIError
+			if !errors.As(err, &apiErr) {
+				t.Fatalf("expected an API error, got %v", err)
+			}
+			switch apiErr.ID {
+			case extendeddatabase.InvalidIdentifierError.ID,
+				extendeddatabase.InvalidPredicateError.ID,
+				extendeddatabase.InvalidOrderDirectionError.ID:
+			default:
+				t.Fatalf("unexpected error %v", err)
+			}
+			return
+		}
+
+		rest := withoutIdentifiers(t, query)
+		for _, text := range []string{";", "'", "--", "/*"} {
+			if strings.Contains(rest, text) {
+				t.Fatalf("unexpected %q in %s", text, query)
+			}
+		}
+		if strings.Count(rest, "?") != len(values) {
+			t.Fatalf("%d values for %s", len(values), query)
+		}
+
+		if _, err := db.Exec(`DROP TABLE IF EXISTS "items"`); err != nil {
+			t.Fatalf("drop table: %v", err)
+		}
+		if _, err := db.Exec(fmt.Sprintf(
+			`CREATE TABLE "items" (%s INTEGER)`, quoteIdentifier(column),
+		)); err != nil {
+			t.Fatalf("create table: %v", err)
+		}
+		rows, err := db.Query(query, values...)
+		if err != nil {
+			t.Fatalf("query %s: %v", query, err)
+		}
+		rows.Close()
+	})
+}
+
+// withoutIdentifiers returns query with the quoted identifiers removed.
+func withoutIdentifiers(t *testing.T, query string) string {
+	var rest strings.Builder
+	quoted := false
+	for i := 0; i < len(query); i++ {
+		switch {
+		case query[i] == '"' && quoted && i+1 < len(query) && query[i+1] == '"':
+			i++
+		case query[i] == '"':
+			quoted = !quoted
+		case !quoted:
+			rest.WriteByte(query[i])
+		}
+	}
+	if quoted {
+		t.Fatalf("unclosed identifier in %s", query)
+	}
+	return rest.String()
+}
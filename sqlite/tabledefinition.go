@@ -0,0 +1,135 @@
+package sqlite
+
+import (
+	"fmt"
+	"strings"
+
+	extendeddatabase "github.com/pakkasys/fluidapi-extended/database"
+	"github.com/pakkasys/fluidapi/database"
+)
+
+// ColumnType returns the SQLite column type of a Go type. Times are stored
+// as Unix nanoseconds, as they are inserted by InsertedValues.
+//
+// Parameters:
+//   - goType: The Go type, e.g. "int64", "time.Time" or "[]byte".
+//
+// Returns:
+//   - string: The column type.
+//   - bool: Whether the Go type has a column type.
+func ColumnType(goType string) (string, bool) {
+	switch goType {
+	case "string":
+		return "TEXT", true
+	case "bool", "int", "int8", "int16", "int32", "int64", "uint", "uint8",
+		"uint16", "uint32", "uint64", "time.Time":
+		return "INTEGER", true
+	case "float32", "float64":
+		return "REAL", true
+	case "[]byte":
+		return "BLOB", true
+	default:
+		return "", false
+	}
+}
+
+// TableDefinition builds the definition of a table from the `db` and
+// `dbdef` tags of an entity struct.
+//
+// Parameters:
+//   - tableName: The name of the table.
+//   - entity: The entity struct or a pointer to it.
+//
+// Returns:
+//   - *extendeddatabase.TableDefinition: The table definition.
+//   - error: An error if the definition can not be built.
+func (q *Query) TableDefinition(
+	tableName string, entity any,
+) (*extendeddatabase.TableDefinition, error) {
+	return extendeddatabase.StructTableDefinition(tableName, entity, ColumnType)
+}
+
+// CreateTableQueries generates the SQL queries for creating the table of an
+// entity struct and its indexes. The table definition is built from the
+// `db` and `dbdef` tags of the struct.
+//
+// Parameters:
+//   - tableName: The name of the table.
+//   - ifNotExists: If true, adds IF NOT EXISTS to the table query.
+//   - entity: The entity struct or a pointer to it.
+//   - options: The table options.
+//
+// Returns:
+//   - []string: The SQL queries.
+//   - error: An error if the queries could not be created.
+func (q *Query) CreateTableQueries(
+	tableName string,
+	ifNotExists bool,
+	entity any,
+	options database.TableOptions,
+) ([]string, error) {
+	definition, err := q.TableDefinition(tableName, entity)
+	if err != nil {
+		return nil, err
+	}
+	query, _, err := q.CreateTableQuery(
+		tableName,
+		ifNotExists,
+		definition.Columns,
+		definition.Constraints,
+		options,
+	)
+	if err != nil {
+		return nil, err
+	}
+	queries := []string{query}
+	for _, index := range definition.Indexes {
+		query, _, err := q.CreateIndexQuery(tableName, index)
+		if err != nil {
+			return nil, err
+		}
+		queries = append(queries, query)
+	}
+	return queries, nil
+}
+
+// CreateIndexQuery generates a SQL query for creating an index if it does
+// not exist.
+//
+// Parameters:
+//   - tableName: The name of the table.
+//   - index: The index.
+//
+// Returns:
+//   - string: The SQL query.
+//   - []any: The values.
+//   - error: An error if the index has no columns or an identifier is
+//     invalid.
+func (q *Query) CreateIndexQuery(
+	tableName string, index extendeddatabase.Index,
+) (string, []any, error) {
+	if len(index.Columns) == 0 {
+		return "", nil, fmt.Errorf("CreateIndexQuery: index %q has no columns", index.Name)
+	}
+	indexName, err := quoter.Identifier(index.Name)
+	if err != nil {
+		return "", nil, err
+	}
+	quotedTable, err := quoter.Identifier(tableName)
+	if err != nil {
+		return "", nil, err
+	}
+	columns, err := quoter.Identifiers(index.Columns)
+	if err != nil {
+		return "", nil, err
+	}
+	var builder strings.Builder
+	builder.WriteString("CREATE ")
+	if index.Unique {
+		builder.WriteString("UNIQUE ")
+	}
+	builder.WriteString(fmt.Sprintf(
+		"INDEX IF NOT EXISTS %s ON %s (%s);", indexName, quotedTable, columns,
+	))
+	return builder.String(), nil, nil
+}
@@ -0,0 +1,70 @@
+package sqlite
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/pakkasys/fluidapi/database"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func TestCreateTableQueries(t *testing.T) {
+	type user struct {
+		ID    int64  `db:"id" dbdef:"pk,autoinc"`
+		Email string `db:"email" dbdef:"unique_index"`
+	}
+	type order struct {
+		ID       int64     `db:"id" dbdef:"pk,autoinc"`
+		UserID   int64     `db:"user_id" dbdef:"fk=users.id,ondelete=CASCADE,index"`
+		Total    float64   `db:"total" dbdef:"default=0"`
+		PlacedAt time.Time `db:"placed_at"`
+		Note     *string   `db:"note"`
+	}
+
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+	query := &Query{}
+	for _, table := range []struct {
+		name   string
+		entity any
+	}{
+		{"users", user{}},
+		{"orders", &order{}},
+	} {
+		queries, err := query.CreateTableQueries(
+			table.name, true, table.entity, database.TableOptions{},
+		)
+		if err != nil {
+			t.Fatalf("CreateTableQueries: %v", err)
+		}
+		for _, query := range queries {
+			if _, err := db.Exec(query); err != nil {
+				t.Fatalf("exec %q: %v", query, err)
+			}
+		}
+	}
+
+	if _, err := db.Exec("PRAGMA foreign_keys = ON"); err != nil {
+		t.Fatalf("enable foreign keys: %v", err)
+	}
+	if _, err := db.Exec(
+		"INSERT INTO orders (user_id, placed_at) VALUES (1, 0)",
+	); err == nil {
+		t.Errorf("expected foreign key error")
+	}
+	if _, err := db.Exec("INSERT INTO users (email) VALUES ('a'), ('a')"); err == nil {
+		t.Errorf("expected unique index error")
+	}
+	var indexes int
+	if err := db.QueryRow(
+		"SELECT COUNT(*) FROM sqlite_master WHERE type = 'index' AND name IN ('uq_users_email', 'idx_orders_user_id')",
+	).Scan(&indexes); err != nil || indexes != 2 {
+		t.Errorf("expected 2 indexes, got %d, %v", indexes, err)
+	}
+}
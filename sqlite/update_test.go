@@ -0,0 +1,46 @@
+package sqlite
+
+import (
+	"testing"
+
+	extendeddatabase "github.com/pakkasys/fluidapi-extended/database"
+	"github.com/pakkasys/fluidapi/database"
+)
+
+func TestUpdateQueryIncrement(t *testing.T) {
+	db := openMemoryDB(t)
+	for _, statement := range []string{
+		`CREATE TABLE "items" ("id" INTEGER PRIMARY KEY, "name" TEXT,` +
+			` "version" INTEGER)`,
+		`INSERT INTO "items" VALUES (1, 'a', 3)`,
+	} {
+		if _, err := db.Exec(statement); err != nil {
+			t.Fatalf("exec %q: %v", statement, err)
+		}
+	}
+
+	query, values := (&Query{}).UpdateQuery(
+		"items",
+		[]database.Update{
+			{Field: "name", Value: "b"},
+			{Field: "version", Value: extendeddatabase.Increment{By: 1}},
+		},
+		[]database.Selector{{Column: "id", Predicate: "=", Value: 1}},
+	)
+	expected := `UPDATE "items" SET "name" = ?, "version" = "version" + ?` +
+		` WHERE "id" = ?`
+	if query != expected {
+		t.Errorf("expected query\n%s\ngot\n%s", expected, query)
+	}
+	if _, err := db.Exec(query, values...); err != nil {
+		t.Fatalf("exec: %v", err)
+	}
+	var version int64
+	row := db.QueryRow(`SELECT "version" FROM "items" WHERE "id" = 1`)
+	if err := row.Scan(&version); err != nil {
+		t.Fatalf("scan: %v", err)
+	}
+	if version != 4 {
+		t.Errorf("expected version 4, got %d", version)
+	}
+}
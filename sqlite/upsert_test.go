@@ -0,0 +1,148 @@
+package sqlite
+
+import (
+	"reflect"
+	"testing"
+
+	extendeddatabase "github.com/pakkasys/fluidapi-extended/database"
+	"github.com/pakkasys/fluidapi/database"
+)
+
+func TestUpsertWithOptions(t *testing.T) {
+	db := openMemoryDB(t)
+	for _, statement := range []string{
+		`CREATE TABLE "items" ("id" INTEGER PRIMARY KEY, "code" TEXT UNIQUE,` +
+			` "name" TEXT, "locked" INTEGER, "created" INTEGER)`,
+		`INSERT INTO "items" VALUES (1, 'a', 'old a', 0, 10), (2, 'b', 'old b', 1, 20)`,
+	} {
+		if _, err := db.Exec(statement); err != nil {
+			t.Fatalf("exec %q: %v", statement, err)
+		}
+	}
+
+	columns := []string{"id", "code", "name", "locked", "created"}
+	itemValues := func(values ...any) database.InsertedValuesFn {
+		return func() ([]string, []any) {
+			return columns, values
+		}
+	}
+	query, values, err := (&Query{}).UpsertWithOptions(
+		"items",
+		[]database.InsertedValuesFn{
+			itemValues(10, "a", "new a", 0, 99),
+			itemValues(11, "b", "new b", 0, 99),
+			itemValues(12, "c", "new c", 0, 99),
+		},
+		extendeddatabase.UpsertOptions{
+			ConflictColumns: []string{"code"},
+			ExcludeColumns:  []string{"id", "created", "locked"},
+			WhereCondition: extendeddatabase.NewFilterTerm(database.Selector{
+				Column: "locked", Predicate: "=", Value: 0,
+			}),
+		},
+	)
+	if err != nil {
+		t.Fatalf("UpsertWithOptions: %v", err)
+	}
+	expected := `INSERT INTO "items" ("id", "code", "name", "locked", "created")` +
+		` VALUES (?, ?, ?, ?, ?), (?, ?, ?, ?, ?), (?, ?, ?, ?, ?)` +
+		` ON CONFLICT("code") DO UPDATE SET "name" = excluded."name"` +
+		` WHERE "locked" = ?`
+	if query != expected {
+		t.Errorf("expected query\n%s\ngot\n%s", expected, query)
+	}
+	if _, err := db.Exec(query, values...); err != nil {
+		t.Fatalf("exec: %v", err)
+	}
+
+	rows, err := db.Query(`SELECT "id", "code", "name", "created" FROM "items" ORDER BY "id"`)
+	if err != nil {
+		t.Fatalf("query: %v", err)
+	}
+	defer rows.Close()
+	var items [][]any
+	for rows.Next() {
+		var id, created int64
+		var code, name string
+		if err := rows.Scan(&id, &code, &name, &created); err != nil {
+			t.Fatalf("scan: %v", err)
+		}
+		items = append(items, []any{id, code, name, created})
+	}
+	expectedItems := [][]any{
+		{int64(1), "a", "new a", int64(10)},
+		{int64(2), "b", "old b", int64(20)},
+		{int64(12), "c", "new c", int64(99)},
+	}
+	if !reflect.DeepEqual(items, expectedItems) {
+		t.Errorf("expected items %v, got %v", expectedItems, items)
+	}
+}
+
+func TestUpsert(t *testing.T) {
+	db := openMemoryDB(t)
+	statement := `CREATE TABLE "items" ("id" INTEGER PRIMARY KEY,` +
+		` "code" TEXT UNIQUE, "name" TEXT)`
+	if _, err := db.Exec(statement); err != nil {
+		t.Fatalf("exec %q: %v", statement, err)
+	}
+	itemValues := func(code string, name string) database.InsertedValuesFn {
+		return func() ([]string, []any) {
+			return []string{"code", "name"}, []any{code, name}
+		}
+	}
+
+	for _, tt := range []struct {
+		name          string
+		values        database.InsertedValuesFn
+		updateColumns []string
+		expectedQuery string
+		expectedName  string
+	}{
+		{
+			name:          "Insert",
+			values:        itemValues("a", "first"),
+			updateColumns: []string{"name"},
+			expectedQuery: `INSERT INTO "items" ("code", "name") VALUES (?, ?)` +
+				` ON CONFLICT("code") DO UPDATE SET "name" = excluded."name"`,
+			expectedName: "first",
+		},
+		{
+			name:          "Update",
+			values:        itemValues("a", "second"),
+			updateColumns: []string{"name"},
+			expectedQuery: `INSERT INTO "items" ("code", "name") VALUES (?, ?)` +
+				` ON CONFLICT("code") DO UPDATE SET "name" = excluded."name"`,
+			expectedName: "second",
+		},
+		{
+			name:   "DoNothing",
+			values: itemValues("a", "third"),
+			expectedQuery: `INSERT INTO "items" ("code", "name") VALUES (?, ?)` +
+				` ON CONFLICT("code") DO NOTHING`,
+			expectedName: "second",
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			query, values := (&Query{}).Upsert(
+				"items", tt.values, []string{"code"}, tt.updateColumns,
+			)
+			if query != tt.expectedQuery {
+				t.Errorf("expected query\n%s\ngot\n%s", tt.expectedQuery, query)
+			}
+			if _, err := db.Exec(query, values...); err != nil {
+				t.Fatalf("exec: %v", err)
+			}
+			var count int
+			var name string
+			row := db.QueryRow(`SELECT COUNT(*), MAX("name") FROM "items"`)
+			if err := row.Scan(&count, &name); err != nil {
+				t.Fatalf("scan: %v", err)
+			}
+			if count != 1 || name != tt.expectedName {
+				t.Errorf("expected 1 item named %q, got %d named %q",
+					tt.expectedName, count, name)
+			}
+		})
+	}
+}